@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/database"
+	"nexushub-personal/internal/migrate"
+	"nexushub-personal/internal/migrate/migrations"
+
+	"gorm.io/gorm/logger"
+)
+
+const usage = `Usage: migrate <command> [arguments]
+
+Commands:
+  up              apply all pending migrations
+  down [N]        roll back the last N migrations (default 1)
+  status          show applied and pending migrations
+  create <name>   create a new migration file
+
+Flags:
+`
+
+func main() {
+	dir := flag.String("dir", "internal/migrate/migrations", "directory for new migration files (create only)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// create 只生成文件，不需要连接数据库
+	if args[0] == "create" {
+		if len(args) < 2 {
+			log.Fatal("create requires a migration name")
+		}
+		path, err := migrate.Create(*dir, args[1], time.Now())
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		fmt.Println("Created", path)
+		return
+	}
+
+	if err := config.Init(); err != nil {
+		log.Fatalf("Failed to initialize configuration: %v", err)
+	}
+	db, err := database.Open(config.AppConfig.DB, logger.Default.LogMode(logger.Warn))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	migrator, err := migrate.New(db, migrations.All())
+	if err != nil {
+		log.Fatalf("Invalid migrations: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied  %s_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %s_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("Nothing to roll back")
+		}
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-9s %s_%s  %s\n", s.State, s.Version, s.Name, appliedAt)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"nexushub-personal/internal/config"
 	"nexushub-personal/internal/database"
+	"nexushub-personal/internal/lifecycle"
 	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/metrics"
 	"nexushub-personal/internal/router"
+	"nexushub-personal/internal/service"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -19,39 +26,140 @@ func main() {
 	log.Println("Configuration loaded and validated successfully")
 
 	// Initialize logger
-	logDir := "./logs"
-	if err := logger.Init(logDir, logger.INFO); err != nil {
+	logCfg := config.AppConfig.Log
+	logLevel, _ := logger.ParseLevel(logCfg.Level) // already validated
+	if err := logger.Init(logger.Options{
+		Dir:        logCfg.Dir,
+		Level:      logLevel,
+		Format:     logCfg.Format,
+		MaxSizeMB:  logCfg.MaxSizeMB,
+		MaxAgeDays: logCfg.MaxAgeDays,
+		Compress:   logCfg.Compress,
+	}); err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer logger.Close()
 
 	logger.Info("NexusHub Personal starting...")
-	logger.Info("Server mode: %s", config.AppConfig.Server.GinMode)
+	logger.Info("Server mode", "mode", config.AppConfig.Server.GinMode)
 
 	// Initialize database
-	if err := database.Init(); err != nil {
-		logger.Fatal("Failed to initialize database: %v", err)
+	db, err := database.Init()
+	if err != nil {
+		logger.Fatal("Failed to initialize database", "error", err)
 	}
 	logger.Info("Database initialized successfully")
 
+	// Wire services: database, storage and logger are injected here.
+	// appLogger is used by background jobs; handlers rebind services to the
+	// request logger via WithLogger so request_id is attached to their logs
+	appLogger := logger.Default()
+	services := service.NewServices(db, service.NewCloudStorageProvider(appLogger), appLogger)
+
+	// Components stop in reverse order: HTTP server, background jobs, then the DB pool
+	app := lifecycle.New(appLogger)
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Fatal("Failed to get database pool", "error", err)
+	}
+	app.OnStop("database pool", func(context.Context) error { return sqlDB.Close() })
+
+	// Metrics computed at scrape time: DB pool and storage usage
+	metrics.Default.MustRegister(
+		metrics.NewDBStatsCollector(sqlDB),
+		metrics.CollectorFunc(func(w *metrics.Writer) {
+			usage, err := services.Files.Usage()
+			if err != nil {
+				appLogger.Warn("Failed to collect storage usage", "error", err)
+				return
+			}
+			w.Gauge("nexushub_storage_files", "Number of stored files.", float64(usage.Files))
+			w.Gauge("nexushub_storage_used_bytes", "Total size of stored files in bytes.", float64(usage.Bytes))
+		}),
+	)
+
+	// Hot-reload log level, CORS origins and rate limits on SIGHUP or config file change
+	config.OnReload(func(rc config.RuntimeConfig) {
+		if level, err := logger.ParseLevel(rc.LogLevel); err == nil {
+			logger.SetLevel(level)
+		}
+	})
+	app.Go("config watcher", func(ctx context.Context) { config.Watch(ctx, 5*time.Second) })
+
+	// Start audit log retention
+	app.Go("audit retention", func(ctx context.Context) {
+		services.Audit.RunRetention(ctx, config.AppConfig.Audit.RetentionDays, 24*time.Hour)
+	})
+
+	// Start note revision pruning
+	app.Go("revision pruning", func(ctx context.Context) {
+		services.Notes.RunRevisionPruning(ctx, config.AppConfig.Revisions, 24*time.Hour)
+	})
+
+	// Build missing search index entries
+	app.Go("search indexer", services.Search.RunIndexer)
+
+	// Start scheduled backups
+	if config.AppConfig.Backup.Enabled {
+		target, err := service.NewBackupTarget(config.AppConfig.Backup)
+		if err != nil {
+			logger.Fatal("Failed to initialize backup target", "error", err)
+		}
+		interval := time.Duration(config.AppConfig.Backup.IntervalHours) * time.Hour
+		backups := service.NewBackupService(db, services.Files, target, config.AppConfig.Backup, appLogger)
+		app.Go("scheduled backups", func(ctx context.Context) { backups.Run(ctx, interval) })
+		logger.Info("Scheduled backups enabled", "interval", interval, "target", config.AppConfig.Backup.Target)
+	}
+
 	// Setup router
-	r := router.SetupRouter()
+	r, stopRouter := router.SetupRouter(services)
+	app.OnStop("config reload listeners", func(context.Context) error { stopRouter(); return nil })
+
+	serverCfg := config.AppConfig.Server
+	srv := &http.Server{
+		Addr:              ":" + serverCfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Duration(serverCfg.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(serverCfg.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(serverCfg.IdleTimeoutSeconds) * time.Second,
+	}
+	app.OnStop("http server", srv.Shutdown)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Graceful shutdown
+	// Start server
+	serverErr := make(chan error, 1)
 	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+		logger.Info("Server starting", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
 
+	exitCode := 0
+	select {
+	case <-ctx.Done():
 		logger.Info("Shutting down server...")
-		logger.Close()
-		os.Exit(0)
-	}()
+	case err := <-serverErr:
+		logger.Error("Server failed", "error", err)
+		exitCode = 1
+	}
+	stop()
 
-	// Start server
-	addr := ":" + config.AppConfig.Server.Port
-	logger.Info("Server starting on %s", addr)
-	if err := r.Run(addr); err != nil {
-		logger.Fatal("Failed to start server: %v", err)
+	// Graceful shutdown: drain in-flight requests, stop background jobs, close the DB pool
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(serverCfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Shutdown finished with errors", "error", err)
+		exitCode = 1
+	}
+	logger.Info("Server stopped")
+
+	if exitCode != 0 {
+		cancel()
+		logger.Close()
+		os.Exit(exitCode)
 	}
 }
@@ -8,6 +8,8 @@ var (
 	ErrTokenExpired       = errors.New("token has expired")
 	ErrTokenInvalid       = errors.New("token is invalid")
 	ErrUnauthorized       = errors.New("unauthorized access")
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrAccountLocked      = errors.New("account temporarily locked")
 
 	// 用户相关错误
 	ErrUserNotFound      = errors.New("user not found")
@@ -27,6 +29,20 @@ var (
 	ErrResourceNotFound  = errors.New("resource not found")
 	ErrResourceForbidden = errors.New("access to resource is forbidden")
 	ErrInvalidID         = errors.New("invalid id parameter")
+	ErrRevisionNotFound  = errors.New("revision not found")
+	ErrVersionConflict   = errors.New("resource was modified by another request")
+	ErrTagNotFound       = errors.New("tag not found")
+
+	// 分享相关错误
+	ErrShareExpired          = errors.New("share link has expired")
+	ErrSharePasswordRequired = errors.New("share link requires a password")
+	ErrSharePasswordInvalid  = errors.New("share link password is incorrect")
+	ErrShareWithSelf         = errors.New("cannot share with yourself")
+	ErrInvalidResourceType   = errors.New("invalid resource type")
+
+	// 导入导出相关错误
+	ErrInvalidArchive     = errors.New("invalid export archive")
+	ErrUnsupportedVersion = errors.New("unsupported export format version")
 
 	// 数据验证错误
 	ErrInvalidInput      = errors.New("invalid input data")
@@ -5,11 +5,18 @@ import (
 	"net/http"
 )
 
-// Response 统一响应结构
+// 请求ID在gin上下文中的key和对应的请求/响应头
+const (
+	RequestIDKey    = "request_id"
+	RequestIDHeader = "X-Request-ID"
+)
+
+// Response 统一响应结构，错误响应附带请求ID便于对照日志排查
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 // Success 成功响应
@@ -33,17 +40,27 @@ func SuccessWithMessage(c *gin.Context, message string, data interface{}) {
 // Error 错误响应
 func Error(c *gin.Context, httpStatus int, message string) {
 	c.JSON(httpStatus, Response{
-		Code:    httpStatus,
-		Message: message,
+		Code:      httpStatus,
+		Message:   message,
+		RequestID: c.GetString(RequestIDKey),
+	})
+}
+
+// LegacyError 旧格式的错误响应 {"error": message}，部分前端页面读取 error 字段，同样附带请求ID
+func LegacyError(c *gin.Context, httpStatus int, message string) {
+	c.JSON(httpStatus, gin.H{
+		"error":      message,
+		"request_id": c.GetString(RequestIDKey),
 	})
 }
 
 // ErrorWithData 带数据的错误响应
 func ErrorWithData(c *gin.Context, httpStatus int, message string, data interface{}) {
 	c.JSON(httpStatus, Response{
-		Code:    httpStatus,
-		Message: message,
-		Data:    data,
+		Code:      httpStatus,
+		Message:   message,
+		Data:      data,
+		RequestID: c.GetString(RequestIDKey),
 	})
 }
 
@@ -85,3 +102,8 @@ func Created(c *gin.Context, data interface{}) {
 		Data:    data,
 	})
 }
+
+// TooManyRequests 429错误
+func TooManyRequests(c *gin.Context, message string) {
+	Error(c, http.StatusTooManyRequests, message)
+}
@@ -1,12 +1,13 @@
 package config
 
+// CloudStorageConfig 云存储配置，Provider为空表示使用本地存储
 type CloudStorageConfig struct {
-	Provider  string
-	AccessKey string
-	SecretKey string
-	Bucket    string
-	Endpoint  string
-	Region    string
+	Provider  string `yaml:"provider"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Bucket    string `yaml:"bucket"`
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
 }
 
 type CloudProvider string
@@ -18,15 +19,9 @@ const (
 	ProviderTencent CloudProvider = "tencent"
 )
 
+// GetCloudStorageConfig 获取启动时加载的云存储配置
 func (c *Config) GetCloudStorageConfig() *CloudStorageConfig {
-	return &CloudStorageConfig{
-		Provider:  getEnv("CLOUD_STORAGE_PROVIDER", ""),
-		AccessKey: getEnv("CLOUD_STORAGE_ACCESS_KEY", ""),
-		SecretKey: getEnv("CLOUD_STORAGE_SECRET_KEY", ""),
-		Bucket:    getEnv("CLOUD_STORAGE_BUCKET", ""),
-		Endpoint:  getEnv("CLOUD_STORAGE_ENDPOINT", ""),
-		Region:    getEnv("CLOUD_STORAGE_REGION", ""),
-	}
+	return &c.CloudStorage
 }
 
 // GetCloudStorageURL 获取云存储的文件访问URL
@@ -1,109 +1,484 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// Config 应用配置。加载顺序(后者覆盖前者)：内置默认值 -> YAML配置文件 -> 环境变量(.env)
 type Config struct {
-	Server  ServerConfig
-	DB      DBConfig
-	Storage StorageConfig
-	User    UserConfig
-	JWT     JWTConfig
+	Server       ServerConfig       `yaml:"server"`
+	DB           DBConfig           `yaml:"db"`
+	Storage      StorageConfig      `yaml:"storage"`
+	CloudStorage CloudStorageConfig `yaml:"cloud_storage"`
+	User         UserConfig         `yaml:"user"`
+	Auth         AuthConfig         `yaml:"auth"`
+	JWT          JWTConfig          `yaml:"jwt"`
+	Log          LogConfig          `yaml:"log"`
+	CORS         CORSConfig         `yaml:"cors"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
+	Health       HealthConfig       `yaml:"health"`
+	Audit        AuditConfig        `yaml:"audit"`
+	Revisions    RevisionConfig     `yaml:"revisions"`
+	Backup       BackupConfig       `yaml:"backup"`
+	OIDC         OIDCConfig         `yaml:"oidc"`
 }
 
 type ServerConfig struct {
-	Port    string
-	GinMode string
+	Port                   string `yaml:"port"`
+	GinMode                string `yaml:"gin_mode"`
+	ReadTimeoutSeconds     int    `yaml:"read_timeout_seconds"`     // 读取整个请求(含上传内容)的超时，0 表示不限制
+	WriteTimeoutSeconds    int    `yaml:"write_timeout_seconds"`    // 写出响应的超时，0 表示不限制
+	IdleTimeoutSeconds     int    `yaml:"idle_timeout_seconds"`     // keep-alive 空闲连接超时
+	ShutdownTimeoutSeconds int    `yaml:"shutdown_timeout_seconds"` // 关闭时等待进行中请求和后台任务结束的最长时间
+
+	// TrustedProxies 反向代理的IP或CIDR，只有来自这些地址的 X-Forwarded-For 才用于确定客户端IP；
+	// 为空时不信任任何转发头，按连接的对端地址限流
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
+// 数据库驱动
+const (
+	DBDriverMySQL    = "mysql"
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+)
+
 type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	Driver      string `yaml:"driver"`
+	Path        string `yaml:"path"`         // SQLite数据库文件路径
+	SSLMode     string `yaml:"ssl_mode"`     // PostgreSQL sslmode
+	AutoMigrate bool   `yaml:"auto_migrate"` // 启动时自动执行未执行的迁移；关闭后需先运行 migrate up
+	Host        string `yaml:"host"`
+	Port        string `yaml:"port"`
+	User        string `yaml:"user"`
+	Password    string `yaml:"password"`
+	DBName      string `yaml:"name"`
 }
 
 type StorageConfig struct {
-	Path          string
-	MaxUploadSize int64
+	Path          string `yaml:"path"`
+	MaxUploadSize int64  `yaml:"max_upload_size"`
+	MaxImportSize int64  `yaml:"max_import_size"` // 导入的备份压缩包大小上限
 }
 
 type UserConfig struct {
-	DefaultUserID int
+	DefaultUserID int `yaml:"default_user_id"` // 单用户模式下未登录请求使用的用户
+}
+
+// 认证模式
+const (
+	AuthModeSingle = "single" // 单用户模式：未登录请求视为默认用户
+	AuthModeMulti  = "multi"  // 多用户模式：所有API请求必须携带有效token
+)
+
+type AuthConfig struct {
+	Mode       string   `yaml:"mode"`
+	AdminUsers []string `yaml:"admin_users"` // 管理员用户名，可以管理备份等涉及所有用户的数据；必须携带有效token
+}
+
+// IsMultiUser 是否为严格的多用户模式
+func (c *Config) IsMultiUser() bool {
+	return c.Auth.Mode == AuthModeMulti
+}
+
+// IsAdmin 用户名是否在管理员列表中
+func (c *Config) IsAdmin(username string) bool {
+	return slices.Contains(c.Auth.AdminUsers, username)
 }
 
 type JWTConfig struct {
-	Secret      string
-	ExpireHours int
+	Secret      string `yaml:"secret"`
+	ExpireHours int    `yaml:"expire_hours"`
+}
+
+// 日志级别
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// 日志输出格式
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogConfig 日志配置，其中 Level 可热更新
+type LogConfig struct {
+	Level      string `yaml:"level"`        // debug | info | warn | error
+	Format     string `yaml:"format"`       // text | json
+	Dir        string `yaml:"dir"`          // 日志目录
+	MaxSizeMB  int    `yaml:"max_size_mb"`  // 单个日志文件大小上限，超过后切分，0表示只按天轮转
+	MaxAgeDays int    `yaml:"max_age_days"` // 轮转后的日志保留天数，0表示永久保留
+	Compress   bool   `yaml:"compress"`     // 轮转后的日志是否gzip压缩
+}
+
+// CORSConfig 跨域配置(可热更新)
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"` // 允许的来源，"*" 表示任意来源
+}
+
+// RateLimitConfig 限流与登录防爆破配置(可热更新)
+type RateLimitConfig struct {
+	LoginPerMinute      int `yaml:"login_per_minute"`      // 每个IP每分钟允许的登录/注册请求数
+	LoginMaxFailures    int `yaml:"login_max_failures"`    // 连续失败多少次后锁定账号
+	LoginLockoutMinutes int `yaml:"login_lockout_minutes"` // 锁定时长(分钟)
+	LoginBackoffSeconds int `yaml:"login_backoff_seconds"` // 失败退避基数(秒)，每次失败翻倍
+	APIPerMinute        int `yaml:"api_per_minute"`        // 昂贵接口每个用户每分钟允许的请求数
+	APIBurst            int `yaml:"api_burst"`             // 昂贵接口允许的突发请求数
+}
+
+// MetricsConfig Prometheus指标配置
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"` // 是否开放 /metrics
+	Token   string `yaml:"token"`   // 非空时抓取需携带 Authorization: Bearer <token>，为空时只允许本机抓取
+}
+
+// HealthConfig 就绪检查配置
+type HealthConfig struct {
+	CheckTimeoutSeconds int   `yaml:"check_timeout_seconds"` // 单项依赖检查的超时
+	MinFreeDiskMB       int64 `yaml:"min_free_disk_mb"`      // Storage.Path 所在磁盘的最小剩余空间，低于该值视为未就绪
+	CacheSeconds        int   `yaml:"cache_seconds"`         // 检查结果的缓存时间，期间的请求不再重复探测，0表示不缓存
+}
+
+// 备份存储位置
+const (
+	BackupTargetLocal = "local" // 本地目录
+	BackupTargetCloud = "cloud" // 云存储桶
+)
+
+// BackupConfig 自动备份配置
+type BackupConfig struct {
+	Enabled       bool   `yaml:"enabled"`        // 是否启用定时备份
+	IntervalHours int    `yaml:"interval_hours"` // 备份间隔(小时)
+	Target        string `yaml:"target"`         // local | cloud
+	Dir           string `yaml:"dir"`            // 本地备份目录
+	KeepDaily     int    `yaml:"keep_daily"`     // 保留最近N天每天一份
+	KeepWeekly    int    `yaml:"keep_weekly"`    // 保留最近N周每周一份
+	KeepMonthly   int    `yaml:"keep_monthly"`   // 保留最近N月每月一份
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	RetentionDays int `yaml:"retention_days"` // 审计日志保留天数，0表示永久保留
+}
+
+// RevisionConfig 笔记历史版本保留策略：最近 KeepAllDays 天内的版本全部保留，
+// 更早的版本每天只保留最后一个，超过 KeepDailyDays 天的删除；每篇笔记的最新版本始终保留
+type RevisionConfig struct {
+	KeepAllDays   int `yaml:"keep_all_days"`   // 全部保留的天数
+	KeepDailyDays int `yaml:"keep_daily_days"` // 每天保留一个版本的天数，0表示永久保留
+}
+
+// OIDCConfig OpenID Connect登录配置
+type OIDCConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	IssuerURL        string   `yaml:"issuer_url"`
+	ClientID         string   `yaml:"client_id"`
+	ClientSecret     string   `yaml:"client_secret"`
+	RedirectURL      string   `yaml:"redirect_url"` // 回调地址，需指向 /api/v1/auth/oidc/callback
+	Scopes           []string `yaml:"scopes"`
+	FrontendRedirect string   `yaml:"frontend_redirect"` // 登录成功后跳转的前端地址，令牌附加在URL片段中；为空则直接返回JSON
 }
 
 var AppConfig *Config
 
+// DefaultConfigFile 未设置 CONFIG_FILE 时尝试读取的配置文件，不存在则忽略
+const DefaultConfigFile = "config.yaml"
+
+// configFile 当前使用的配置文件路径，热更新时重新读取
+var configFile string
+
 func Init() error {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	AppConfig = &Config{
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = DefaultConfigFile
+		if _, err := os.Stat(path); err != nil {
+			path = ""
+		}
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		return fmt.Errorf("configuration validation failed: %v", err)
+	}
+	if path != "" {
+		log.Printf("Loaded configuration file %s", path)
+	}
+
+	configFile = path
+	Set(cfg)
+	return nil
+}
+
+// Load 读取配置：默认值 -> path指向的YAML文件(为空则跳过) -> 环境变量，并校验结果。
+// 配置文件中出现未知字段视为错误，避免拼写错误的配置被静默忽略
+func Load(path string) (*Config, error) {
+	cfg := defaultConfig()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	cfg.applyEnv()
+	if cfg.DB.Port == "" {
+		cfg.DB.Port = defaultDBPort(cfg.DB.Driver)
+	}
+	cfg.DB.Driver = strings.ToLower(cfg.DB.Driver)
+	cfg.Log.Level = strings.ToLower(cfg.Log.Level)
+	cfg.Log.Format = strings.ToLower(cfg.Log.Format)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// defaultConfig 内置默认值
+func defaultConfig() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port:    getEnv("SERVER_PORT", "8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:                   "8080",
+			GinMode:                "debug",
+			ReadTimeoutSeconds:     300,
+			WriteTimeoutSeconds:    300,
+			IdleTimeoutSeconds:     120,
+			ShutdownTimeoutSeconds: 30,
 		},
 		DB: DBConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "3306"),
-			User:     getEnv("DB_USER", "root"),
-			Password: getEnv("DB_PASSWORD", ""),
-			DBName:   getEnv("DB_NAME", "nexushub_personal"),
+			Driver:      DBDriverMySQL,
+			Path:        "./storage/nexushub.db",
+			SSLMode:     "disable",
+			AutoMigrate: true,
+			Host:        "localhost",
+			User:        "root",
+			DBName:      "nexushub_personal",
 		},
 		Storage: StorageConfig{
-			Path:          getEnv("STORAGE_PATH", "./storage"),
-			MaxUploadSize: getEnvAsInt64("MAX_UPLOAD_SIZE", 100*1024*1024), // 100MB
+			Path:          "./storage",
+			MaxUploadSize: 100 * 1024 * 1024,  // 100MB
+			MaxImportSize: 1024 * 1024 * 1024, // 1GB
 		},
 		User: UserConfig{
 			DefaultUserID: 1,
 		},
+		Auth: AuthConfig{
+			Mode: AuthModeSingle,
+		},
 		JWT: JWTConfig{
-			Secret:      getEnv("JWT_SECRET", "default-secret-change-in-production"),
-			ExpireHours: getEnvAsInt("JWT_EXPIRE_HOURS", 168), // 7 days
+			Secret:      "default-secret-change-in-production",
+			ExpireHours: 168, // 7 days
+		},
+		Log: LogConfig{
+			Level:      LogLevelInfo,
+			Format:     LogFormatText,
+			Dir:        "./logs",
+			MaxSizeMB:  100,
+			MaxAgeDays: 30,
+			Compress:   true,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+		},
+		RateLimit: RateLimitConfig{
+			LoginPerMinute:      10,
+			LoginMaxFailures:    5,
+			LoginLockoutMinutes: 15,
+			LoginBackoffSeconds: 1,
+			APIPerMinute:        30,
+			APIBurst:            5,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		Health: HealthConfig{
+			CheckTimeoutSeconds: 3,
+			MinFreeDiskMB:       512,
+			CacheSeconds:        5,
+		},
+		Audit: AuditConfig{
+			RetentionDays: 90,
+		},
+		Revisions: RevisionConfig{
+			KeepAllDays:   7,
+			KeepDailyDays: 90,
+		},
+		Backup: BackupConfig{
+			IntervalHours: 24,
+			Target:        BackupTargetLocal,
+			Dir:           "./storage/backups",
+			KeepDaily:     7,
+			KeepWeekly:    4,
+			KeepMonthly:   6,
+		},
+		OIDC: OIDCConfig{
+			Scopes: []string{"openid", "email", "profile"},
 		},
 	}
+}
 
-	// Validate configuration
-	if err := AppConfig.Validate(); err != nil {
-		return fmt.Errorf("configuration validation failed: %v", err)
+// loadFile 用YAML配置文件覆盖当前值，文件中未出现的字段保持不变
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
 	}
-
 	return nil
 }
 
+// applyEnv 用环境变量覆盖当前值，未设置的变量保持配置文件或默认值
+func (c *Config) applyEnv() {
+	c.Server.Port = getEnv("SERVER_PORT", c.Server.Port)
+	c.Server.GinMode = getEnv("GIN_MODE", c.Server.GinMode)
+	c.Server.ReadTimeoutSeconds = getEnvAsInt("SERVER_READ_TIMEOUT_SECONDS", c.Server.ReadTimeoutSeconds)
+	c.Server.WriteTimeoutSeconds = getEnvAsInt("SERVER_WRITE_TIMEOUT_SECONDS", c.Server.WriteTimeoutSeconds)
+	c.Server.IdleTimeoutSeconds = getEnvAsInt("SERVER_IDLE_TIMEOUT_SECONDS", c.Server.IdleTimeoutSeconds)
+	c.Server.ShutdownTimeoutSeconds = getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT_SECONDS", c.Server.ShutdownTimeoutSeconds)
+	c.Server.TrustedProxies = getEnvAsList("SERVER_TRUSTED_PROXIES", c.Server.TrustedProxies)
+
+	c.DB.Driver = getEnv("DB_DRIVER", c.DB.Driver)
+	c.DB.Path = getEnv("DB_PATH", c.DB.Path)
+	c.DB.SSLMode = getEnv("DB_SSLMODE", c.DB.SSLMode)
+	c.DB.AutoMigrate = getEnvAsBool("DB_AUTO_MIGRATE", c.DB.AutoMigrate)
+	c.DB.Host = getEnv("DB_HOST", c.DB.Host)
+	c.DB.Port = getEnv("DB_PORT", c.DB.Port)
+	c.DB.User = getEnv("DB_USER", c.DB.User)
+	c.DB.Password = getEnv("DB_PASSWORD", c.DB.Password)
+	c.DB.DBName = getEnv("DB_NAME", c.DB.DBName)
+
+	c.Storage.Path = getEnv("STORAGE_PATH", c.Storage.Path)
+	c.Storage.MaxUploadSize = getEnvAsInt64("MAX_UPLOAD_SIZE", c.Storage.MaxUploadSize)
+	c.Storage.MaxImportSize = getEnvAsInt64("MAX_IMPORT_SIZE", c.Storage.MaxImportSize)
+
+	c.CloudStorage.Provider = getEnv("CLOUD_STORAGE_PROVIDER", c.CloudStorage.Provider)
+	c.CloudStorage.AccessKey = getEnv("CLOUD_STORAGE_ACCESS_KEY", c.CloudStorage.AccessKey)
+	c.CloudStorage.SecretKey = getEnv("CLOUD_STORAGE_SECRET_KEY", c.CloudStorage.SecretKey)
+	c.CloudStorage.Bucket = getEnv("CLOUD_STORAGE_BUCKET", c.CloudStorage.Bucket)
+	c.CloudStorage.Endpoint = getEnv("CLOUD_STORAGE_ENDPOINT", c.CloudStorage.Endpoint)
+	c.CloudStorage.Region = getEnv("CLOUD_STORAGE_REGION", c.CloudStorage.Region)
+
+	c.User.DefaultUserID = getEnvAsInt("DEFAULT_USER_ID", c.User.DefaultUserID)
+	c.Auth.Mode = getEnv("AUTH_MODE", c.Auth.Mode)
+	c.Auth.AdminUsers = getEnvAsList("AUTH_ADMIN_USERS", c.Auth.AdminUsers)
+	c.JWT.Secret = getEnv("JWT_SECRET", c.JWT.Secret)
+	c.JWT.ExpireHours = getEnvAsInt("JWT_EXPIRE_HOURS", c.JWT.ExpireHours)
+
+	c.Log.Level = getEnv("LOG_LEVEL", c.Log.Level)
+	c.Log.Format = getEnv("LOG_FORMAT", c.Log.Format)
+	c.Log.Dir = getEnv("LOG_DIR", c.Log.Dir)
+	c.Log.MaxSizeMB = getEnvAsInt("LOG_MAX_SIZE_MB", c.Log.MaxSizeMB)
+	c.Log.MaxAgeDays = getEnvAsInt("LOG_MAX_AGE_DAYS", c.Log.MaxAgeDays)
+	c.Log.Compress = getEnvAsBool("LOG_COMPRESS", c.Log.Compress)
+	c.CORS.AllowedOrigins = getEnvAsList("CORS_ALLOWED_ORIGINS", c.CORS.AllowedOrigins)
+
+	c.RateLimit.LoginPerMinute = getEnvAsInt("RATE_LIMIT_LOGIN_PER_MINUTE", c.RateLimit.LoginPerMinute)
+	c.RateLimit.LoginMaxFailures = getEnvAsInt("RATE_LIMIT_LOGIN_MAX_FAILURES", c.RateLimit.LoginMaxFailures)
+	c.RateLimit.LoginLockoutMinutes = getEnvAsInt("RATE_LIMIT_LOGIN_LOCKOUT_MINUTES", c.RateLimit.LoginLockoutMinutes)
+	c.RateLimit.LoginBackoffSeconds = getEnvAsInt("RATE_LIMIT_LOGIN_BACKOFF_SECONDS", c.RateLimit.LoginBackoffSeconds)
+	c.RateLimit.APIPerMinute = getEnvAsInt("RATE_LIMIT_API_PER_MINUTE", c.RateLimit.APIPerMinute)
+	c.RateLimit.APIBurst = getEnvAsInt("RATE_LIMIT_API_BURST", c.RateLimit.APIBurst)
+
+	c.Metrics.Enabled = getEnvAsBool("METRICS_ENABLED", c.Metrics.Enabled)
+	c.Metrics.Token = getEnv("METRICS_TOKEN", c.Metrics.Token)
+
+	c.Health.CheckTimeoutSeconds = getEnvAsInt("HEALTH_CHECK_TIMEOUT_SECONDS", c.Health.CheckTimeoutSeconds)
+	c.Health.MinFreeDiskMB = getEnvAsInt64("HEALTH_MIN_FREE_DISK_MB", c.Health.MinFreeDiskMB)
+	c.Health.CacheSeconds = getEnvAsInt("HEALTH_CACHE_SECONDS", c.Health.CacheSeconds)
+
+	c.Audit.RetentionDays = getEnvAsInt("AUDIT_RETENTION_DAYS", c.Audit.RetentionDays)
+
+	c.Revisions.KeepAllDays = getEnvAsInt("REVISION_KEEP_ALL_DAYS", c.Revisions.KeepAllDays)
+	c.Revisions.KeepDailyDays = getEnvAsInt("REVISION_KEEP_DAILY_DAYS", c.Revisions.KeepDailyDays)
+
+	c.Backup.Enabled = getEnvAsBool("BACKUP_ENABLED", c.Backup.Enabled)
+	c.Backup.IntervalHours = getEnvAsInt("BACKUP_INTERVAL_HOURS", c.Backup.IntervalHours)
+	c.Backup.Target = getEnv("BACKUP_TARGET", c.Backup.Target)
+	c.Backup.Dir = getEnv("BACKUP_DIR", c.Backup.Dir)
+	c.Backup.KeepDaily = getEnvAsInt("BACKUP_KEEP_DAILY", c.Backup.KeepDaily)
+	c.Backup.KeepWeekly = getEnvAsInt("BACKUP_KEEP_WEEKLY", c.Backup.KeepWeekly)
+	c.Backup.KeepMonthly = getEnvAsInt("BACKUP_KEEP_MONTHLY", c.Backup.KeepMonthly)
+
+	c.OIDC.Enabled = getEnvAsBool("OIDC_ENABLED", c.OIDC.Enabled)
+	c.OIDC.IssuerURL = getEnv("OIDC_ISSUER_URL", c.OIDC.IssuerURL)
+	c.OIDC.ClientID = getEnv("OIDC_CLIENT_ID", c.OIDC.ClientID)
+	c.OIDC.ClientSecret = getEnv("OIDC_CLIENT_SECRET", c.OIDC.ClientSecret)
+	c.OIDC.RedirectURL = getEnv("OIDC_REDIRECT_URL", c.OIDC.RedirectURL)
+	c.OIDC.Scopes = getEnvAsList("OIDC_SCOPES", c.OIDC.Scopes)
+	c.OIDC.FrontendRedirect = getEnv("OIDC_FRONTEND_REDIRECT", c.OIDC.FrontendRedirect)
+}
+
+// defaultDBPort 返回数据库驱动的默认端口
+func defaultDBPort(driver string) string {
+	if driver == DBDriverPostgres {
+		return "5432"
+	}
+	return "3306"
+}
+
 // Validate 验证配置是否有效
 func (c *Config) Validate() error {
 	// Validate server config
 	if c.Server.Port == "" {
 		return fmt.Errorf("server port cannot be empty")
 	}
-
-	// Validate database config
-	if c.DB.Host == "" {
-		return fmt.Errorf("database host cannot be empty")
+	if c.Server.ReadTimeoutSeconds < 0 || c.Server.WriteTimeoutSeconds < 0 || c.Server.IdleTimeoutSeconds < 0 {
+		return fmt.Errorf("server timeouts cannot be negative")
 	}
-	if c.DB.Port == "" {
-		return fmt.Errorf("database port cannot be empty")
+	if c.Server.ShutdownTimeoutSeconds <= 0 {
+		return fmt.Errorf("server shutdown timeout must be positive")
 	}
-	if c.DB.User == "" {
-		return fmt.Errorf("database user cannot be empty")
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy %q: must be an IP or CIDR", proxy)
+		}
 	}
-	if c.DB.DBName == "" {
-		return fmt.Errorf("database name cannot be empty")
+
+	// Validate database config
+	switch c.DB.Driver {
+	case DBDriverSQLite:
+		if c.DB.Path == "" {
+			return fmt.Errorf("database path cannot be empty for sqlite")
+		}
+	case DBDriverMySQL, DBDriverPostgres:
+		if c.DB.Host == "" {
+			return fmt.Errorf("database host cannot be empty")
+		}
+		if c.DB.Port == "" {
+			return fmt.Errorf("database port cannot be empty")
+		}
+		if c.DB.User == "" {
+			return fmt.Errorf("database user cannot be empty")
+		}
+		if c.DB.DBName == "" {
+			return fmt.Errorf("database name cannot be empty")
+		}
+	default:
+		return fmt.Errorf("unsupported database driver %q (expected mysql, postgres or sqlite)", c.DB.Driver)
 	}
 
 	// Validate storage config
@@ -113,10 +488,34 @@ func (c *Config) Validate() error {
 	if c.Storage.MaxUploadSize <= 0 {
 		return fmt.Errorf("max upload size must be positive")
 	}
+	if c.Storage.MaxImportSize <= 0 {
+		return fmt.Errorf("max import size must be positive")
+	}
 	if c.Storage.MaxUploadSize > 1024*1024*1024 { // 1GB
 		log.Printf("Warning: max upload size is very large: %d bytes", c.Storage.MaxUploadSize)
 	}
 
+	// Validate cloud storage config
+	switch CloudProvider(c.CloudStorage.Provider) {
+	case "":
+	case ProviderMinIO, ProviderAWS, ProviderAliyun, ProviderTencent:
+		if c.CloudStorage.Bucket == "" {
+			return fmt.Errorf("cloud storage bucket cannot be empty")
+		}
+	default:
+		return fmt.Errorf("unsupported cloud storage provider %q", c.CloudStorage.Provider)
+	}
+
+	// Validate user config
+	if c.User.DefaultUserID <= 0 {
+		return fmt.Errorf("default user ID must be positive")
+	}
+
+	// Validate auth config
+	if c.Auth.Mode != AuthModeSingle && c.Auth.Mode != AuthModeMulti {
+		return fmt.Errorf("auth mode must be %q or %q, got %q", AuthModeSingle, AuthModeMulti, c.Auth.Mode)
+	}
+
 	// Validate JWT config
 	if c.JWT.Secret == "" {
 		return fmt.Errorf("JWT secret cannot be empty")
@@ -131,6 +530,81 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT expire hours must be positive")
 	}
 
+	// Validate log config
+	switch c.Log.Level {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		return fmt.Errorf("log level must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.Format != LogFormatText && c.Log.Format != LogFormatJSON {
+		return fmt.Errorf("log format must be %q or %q, got %q", LogFormatText, LogFormatJSON, c.Log.Format)
+	}
+	if c.Log.Dir == "" {
+		return fmt.Errorf("log directory cannot be empty")
+	}
+	if c.Log.MaxSizeMB < 0 || c.Log.MaxAgeDays < 0 {
+		return fmt.Errorf("log size and age limits cannot be negative")
+	}
+
+	// Validate rate limit config
+	if c.RateLimit.LoginPerMinute <= 0 || c.RateLimit.APIPerMinute <= 0 {
+		return fmt.Errorf("rate limits must be positive")
+	}
+	if c.RateLimit.APIBurst <= 0 {
+		return fmt.Errorf("rate limit burst must be positive")
+	}
+	if c.RateLimit.LoginMaxFailures <= 0 {
+		return fmt.Errorf("login max failures must be positive")
+	}
+	if c.RateLimit.LoginLockoutMinutes <= 0 || c.RateLimit.LoginBackoffSeconds <= 0 {
+		return fmt.Errorf("login lockout and backoff durations must be positive")
+	}
+
+	// Validate OIDC config
+	if c.OIDC.Enabled {
+		if c.OIDC.IssuerURL == "" || c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "" {
+			return fmt.Errorf("OIDC issuer URL, client ID and redirect URL are required when OIDC is enabled")
+		}
+	}
+
+	// Validate health config
+	if c.Health.CheckTimeoutSeconds <= 0 {
+		return fmt.Errorf("health check timeout must be positive")
+	}
+	if c.Health.MinFreeDiskMB < 0 {
+		return fmt.Errorf("minimum free disk space cannot be negative")
+	}
+	if c.Health.CacheSeconds < 0 {
+		return fmt.Errorf("health cache seconds cannot be negative")
+	}
+
+	// Validate audit config
+	if c.Audit.RetentionDays < 0 {
+		return fmt.Errorf("audit retention days cannot be negative")
+	}
+
+	// Validate revision retention
+	if c.Revisions.KeepAllDays < 0 || c.Revisions.KeepDailyDays < 0 {
+		return fmt.Errorf("revision retention days cannot be negative")
+	}
+	if c.Revisions.KeepDailyDays > 0 && c.Revisions.KeepDailyDays < c.Revisions.KeepAllDays {
+		return fmt.Errorf("revision keep_daily_days must be 0 or at least keep_all_days")
+	}
+
+	// Validate backup config
+	if c.Backup.Target != BackupTargetLocal && c.Backup.Target != BackupTargetCloud {
+		return fmt.Errorf("backup target must be %q or %q, got %q", BackupTargetLocal, BackupTargetCloud, c.Backup.Target)
+	}
+	if c.Backup.Target == BackupTargetLocal && c.Backup.Dir == "" {
+		return fmt.Errorf("backup directory cannot be empty")
+	}
+	if c.Backup.Enabled && c.Backup.IntervalHours <= 0 {
+		return fmt.Errorf("backup interval hours must be positive")
+	}
+	if c.Backup.KeepDaily < 0 || c.Backup.KeepWeekly < 0 || c.Backup.KeepMonthly < 0 {
+		return fmt.Errorf("backup retention counts cannot be negative")
+	}
+
 	return nil
 }
 
@@ -154,6 +628,19 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("Warning: Invalid bool value for %s: %s, using default: %t", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
 func getEnvAsInt64(key string, defaultValue int64) int64 {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -166,3 +653,12 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	}
 	return value
 }
+
+// getEnvAsList 读取逗号或空格分隔的列表
+func getEnvAsList(key string, defaultValue []string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	return strings.Fields(strings.ReplaceAll(valueStr, ",", " "))
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayersFileAndEnv(t *testing.T) {
+	path := writeConfigFile(t, `
+db:
+  driver: sqlite
+  path: /tmp/nexushub.db
+user:
+  default_user_id: 7
+cors:
+  allowed_origins: [https://a.example.com]
+rate_limit:
+  api_per_minute: 60
+`)
+	t.Setenv("RATE_LIMIT_API_PER_MINUTE", "90")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.DB.Driver != DBDriverSQLite || cfg.User.DefaultUserID != 7 {
+		t.Errorf("file values not applied: %+v %+v", cfg.DB, cfg.User)
+	}
+	if cfg.RateLimit.APIPerMinute != 90 {
+		t.Errorf("env should override file, got %d", cfg.RateLimit.APIPerMinute)
+	}
+	if cfg.RateLimit.APIBurst != 5 || cfg.Server.Port != "8080" {
+		t.Errorf("defaults not kept for unset fields: %+v", cfg.RateLimit)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://a.example.com" {
+		t.Errorf("unexpected cors origins: %v", cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestLoadRejectsInvalidFile(t *testing.T) {
+	cases := map[string]string{
+		"unknown field": "server:\n  prot: 9090\n",
+		"invalid value": "db:\n  driver: sqlite\nlog:\n  level: verbose\n",
+		"bad user id":   "db:\n  driver: sqlite\nuser:\n  default_user_id: 0\n",
+		"bad proxy":     "db:\n  driver: sqlite\nserver:\n  trusted_proxies: [proxy.local]\n",
+	}
+	for name, content := range cases {
+		if _, err := Load(writeConfigFile(t, content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestReloadAppliesRuntimeSection(t *testing.T) {
+	path := writeConfigFile(t, "db:\n  driver: sqlite\nlog:\n  level: info\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile = path
+	Set(cfg)
+	t.Cleanup(func() { configFile = "" })
+
+	var got, removed RuntimeConfig
+	OnReload(func(rc RuntimeConfig) { got = rc })
+	unsubscribe := OnReload(func(rc RuntimeConfig) { removed = rc })
+	unsubscribe()
+
+	os.WriteFile(path, []byte("db:\n  driver: sqlite\nlog:\n  level: debug\nrate_limit:\n  api_burst: 9\n"), 0600)
+	if err := Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if rc := Runtime(); rc.LogLevel != LogLevelDebug || rc.RateLimit.APIBurst != 9 {
+		t.Errorf("runtime section not updated: %+v", rc)
+	}
+	if got.LogLevel != LogLevelDebug {
+		t.Errorf("listener not called: %+v", got)
+	}
+	if removed.LogLevel != "" {
+		t.Errorf("unsubscribed listener was called: %+v", removed)
+	}
+
+	// 校验失败时保留旧配置
+	os.WriteFile(path, []byte("log:\n  level: loud\n"), 0600)
+	if err := Reload(); err == nil || !strings.Contains(err.Error(), "log level") {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if Runtime().LogLevel != LogLevelDebug {
+		t.Error("invalid reload must keep the previous configuration")
+	}
+}
+
+func TestRestartRequiredIgnoresRuntimeSection(t *testing.T) {
+	a, b := defaultConfig(), defaultConfig()
+	b.Log.Level = LogLevelDebug
+	b.RateLimit.APIBurst = 50
+	if changed := restartRequired(a, b); len(changed) != 0 {
+		t.Errorf("hot-reloadable changes reported: %v", changed)
+	}
+	b.Server.Port = "9090"
+	if changed := restartRequired(a, b); len(changed) != 1 || changed[0] != "Server" {
+		t.Errorf("unexpected restart sections: %v", changed)
+	}
+}
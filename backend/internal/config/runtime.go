@@ -0,0 +1,165 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RuntimeConfig 可在不重启的情况下热更新的配置段。
+// AppConfig 中对应字段保留启动时的值，运行期间应通过 Runtime() 读取当前值
+type RuntimeConfig struct {
+	LogLevel    string
+	CORSOrigins []string
+	RateLimit   RateLimitConfig
+}
+
+var (
+	runtimeMu sync.RWMutex
+	runtime   RuntimeConfig
+	listeners []*listener
+)
+
+// listener 热更新回调，用指针标识以便注销
+type listener struct {
+	fn func(RuntimeConfig)
+}
+
+// runtimeSection 提取可热更新的配置段
+func (c *Config) runtimeSection() RuntimeConfig {
+	return RuntimeConfig{
+		LogLevel:    c.Log.Level,
+		CORSOrigins: slices.Clone(c.CORS.AllowedOrigins),
+		RateLimit:   c.RateLimit,
+	}
+}
+
+// Set 设置全局配置并以其初始化运行时配置段(测试中也通过它注入配置)
+func Set(cfg *Config) {
+	AppConfig = cfg
+	runtimeMu.Lock()
+	runtime = cfg.runtimeSection()
+	runtimeMu.Unlock()
+}
+
+// Runtime 返回当前运行时配置的副本
+func Runtime() RuntimeConfig {
+	runtimeMu.RLock()
+	defer runtimeMu.RUnlock()
+	rc := runtime
+	rc.CORSOrigins = slices.Clone(runtime.CORSOrigins)
+	return rc
+}
+
+// OnReload 注册配置热更新回调，每次重新加载成功后以新的运行时配置调用。
+// 返回的函数注销该回调，回调的所有者关闭时应调用它
+func OnReload(fn func(RuntimeConfig)) (unsubscribe func()) {
+	l := &listener{fn: fn}
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	listeners = append(listeners, l)
+	return func() {
+		runtimeMu.Lock()
+		defer runtimeMu.Unlock()
+		listeners = slices.DeleteFunc(listeners, func(other *listener) bool { return other == l })
+	}
+}
+
+// Reload 重新读取配置文件和环境变量。新配置校验失败时保留旧配置并返回错误；
+// 只有运行时配置段会生效，其余字段的变化需要重启，仅记录警告
+func Reload() error {
+	cfg, err := Load(configFile)
+	if err != nil {
+		return err
+	}
+
+	if AppConfig != nil {
+		for _, section := range restartRequired(AppConfig, cfg) {
+			log.Printf("Config section %q changed; restart required for it to take effect", section)
+		}
+	}
+
+	runtimeMu.Lock()
+	runtime = cfg.runtimeSection()
+	rc := runtime
+	fns := slices.Clone(listeners)
+	runtimeMu.Unlock()
+
+	for _, l := range fns {
+		l.fn(rc)
+	}
+	log.Printf("Configuration reloaded: log level=%s, cors origins=%v", rc.LogLevel, rc.CORSOrigins)
+	return nil
+}
+
+// restartRequired 列出不支持热更新但发生了变化的配置段
+func restartRequired(old, updated *Config) []string {
+	a, b := *old, *updated
+	// 排除可热更新的字段
+	a.Log.Level, b.Log.Level = "", ""
+	a.CORS, b.CORS = CORSConfig{}, CORSConfig{}
+	a.RateLimit, b.RateLimit = RateLimitConfig{}, RateLimitConfig{}
+
+	var changed []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, va.Type().Field(i).Name)
+		}
+	}
+	return changed
+}
+
+// Watch 在收到SIGHUP或配置文件修改时间变化时重新加载配置，直到ctx取消；
+// 未使用配置文件时只响应SIGHUP
+func Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	modTime := fileModTime(configFile)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reload := func(reason string) {
+		if err := Reload(); err != nil {
+			log.Printf("Config reload (%s) failed, keeping current configuration: %v", reason, err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			modTime = fileModTime(configFile)
+			reload("SIGHUP")
+		case <-ticker.C:
+			if configFile == "" {
+				continue
+			}
+			if mt := fileModTime(configFile); !mt.Equal(modTime) {
+				modTime = mt
+				reload("file change")
+			}
+		}
+	}
+}
+
+// fileModTime 返回文件修改时间，文件不存在时返回零值
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -36,9 +36,46 @@ var FileExtensions = map[string][]string{
 	FileTypeAudio:    {".mp3", ".wav", ".flac", ".aac", ".ogg"},
 }
 
+// 资源类型(用于分享等跨资源功能)
+const (
+	ResourceNote       = "note"
+	ResourceFile       = "file"
+	ResourceCollection = "collection"
+	ResourcePost       = "post"
+	ResourceTask       = "task"
+	ResourceEvent      = "event"
+	ResourceBookmark   = "bookmark"
+	ResourceChat       = "chat"
+
+	ResourceNoteTemplate = "note_template"
+	ResourceNotebook     = "notebook"
+)
+
+// 审计动作
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// 分享权限
+const (
+	SharePermissionRead = "read"
+	SharePermissionEdit = "edit"
+)
+
 // 默认配置
 const (
 	DefaultPageSize = 20
 	MaxFileSize     = 10 * 1024 * 1024 // 10MB
 	TokenExpireHours = 24
 )
+
+// Code Arena 各语言依赖的工具链命令
+var CodeToolchains = map[string]string{
+	"go":         "go",
+	"python":     "python",
+	"javascript": "node",
+	"c":          "gcc",
+	"cpp":        "g++",
+}
@@ -37,6 +37,8 @@ const (
 	ErrTokenExpired      = "Token has expired"
 	ErrTokenInvalid      = "Invalid token"
 	ErrPermissionDenied  = "Permission denied"
+	ErrTooManyRequests   = "Too many requests, please try again later"
+	ErrAccountLocked     = "Too many failed login attempts, account temporarily locked"
 )
 
 // 文件操作错误消息
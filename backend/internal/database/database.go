@@ -4,101 +4,153 @@ import (
 	"fmt"
 	"log"
 	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/migrate"
+	"nexushub-personal/internal/migrate/migrations"
 	"nexushub-personal/internal/model"
+	"os"
+	"path/filepath"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
+// Init 按全局配置连接数据库并完成迁移与默认数据初始化，返回的连接由调用方注入到各服务
+func Init() (*gorm.DB, error) {
+	cfg := config.AppConfig.DB
 
-func Init() error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.AppConfig.DB.User,
-		config.AppConfig.DB.Password,
-		config.AppConfig.DB.Host,
-		config.AppConfig.DB.Port,
-		config.AppConfig.DB.DBName,
-	)
+	db, err := Open(cfg, logger.Default.LogMode(logger.Info))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	log.Printf("Database connected successfully (driver=%s)", cfg.Driver)
+
+	if err := Setup(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Open 按配置的驱动打开数据库连接
+func Open(cfg config.DBConfig, gormLogger logger.Interface) (*gorm.DB, error) {
+	gormConfig := &gorm.Config{Logger: gormLogger}
+
+	switch cfg.Driver {
+	case config.DBDriverSQLite:
+		if dir := filepath.Dir(cfg.Path); dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create database directory: %v", err)
+			}
+		}
+		// 纯Go实现的SQLite驱动，无需cgo；WAL + busy_timeout 减少并发写入时的锁冲突
+		dsn := cfg.Path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+		return gorm.Open(sqlite.Open(dsn), gormConfig)
+
+	case config.DBDriverPostgres:
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+		return gorm.Open(postgres.Open(dsn), gormConfig)
+
+	case config.DBDriverMySQL:
+		createMySQLDatabase(cfg)
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName)
+		return gorm.Open(mysql.Open(dsn), gormConfig)
+
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
+}
 
-	// First, try to connect to MySQL without specifying database to create it
+// createMySQLDatabase 数据库不存在时尝试创建(需要账号有CREATE权限，失败仅记录警告)
+func createMySQLDatabase(cfg config.DBConfig) {
 	rootDSN := fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8mb4&parseTime=True&loc=Local",
-		config.AppConfig.DB.User,
-		config.AppConfig.DB.Password,
-		config.AppConfig.DB.Host,
-		config.AppConfig.DB.Port,
-	)
+		cfg.User, cfg.Password, cfg.Host, cfg.Port)
 
-	// Connect to MySQL server (without database)
 	rootDB, err := gorm.Open(mysql.Open(rootDSN), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		log.Printf("Warning: Could not connect to MySQL server: %v", err)
-	} else {
-		// Create database if it doesn't exist
-		rootDB.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", config.AppConfig.DB.DBName))
-		rootDB.Exec("FLUSH PRIVILEGES")
+		return
+	}
+	if sqlDB, err := rootDB.DB(); err == nil {
+		defer sqlDB.Close()
 	}
 
-	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	err = rootDB.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", cfg.DBName)).Error
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %v", err)
+		log.Printf("Warning: Could not create database %s: %v", cfg.DBName, err)
 	}
+}
 
-	log.Println("Database connected successfully")
+// runMigrations 启用自动迁移时执行所有未执行的迁移，否则仅检查数据库结构是否为最新
+func runMigrations(db *gorm.DB) error {
+	migrator, err := migrate.New(db, migrations.All())
+	if err != nil {
+		return err
+	}
+
+	if !config.AppConfig.DB.AutoMigrate {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("database has %d pending migrations (next: %s_%s), run `migrate up` first",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
 
-	// Auto migrate models
-	if err := autoMigrate(); err != nil {
+	applied, err := migrator.Up()
+	for _, m := range applied {
+		log.Printf("Applied migration %s_%s", m.Version, m.Name)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to migrate database: %v", err)
 	}
+	return nil
+}
+
+// Setup 在给定连接上执行建表迁移并初始化默认数据
+func Setup(db *gorm.DB) error {
+	// Versioned schema migrations
+	if err := runMigrations(db); err != nil {
+		return err
+	}
 
 	// Initialize default user
-	if err := initDefaultUser(); err != nil {
+	if err := initDefaultUser(db); err != nil {
 		return fmt.Errorf("failed to initialize default user: %v", err)
 	}
 
 	// Initialize default theme
-	if err := initDefaultTheme(); err != nil {
+	if err := initDefaultTheme(db); err != nil {
 		return fmt.Errorf("failed to initialize default theme: %v", err)
 	}
 
 	return nil
 }
 
-func autoMigrate() error {
-	return DB.AutoMigrate(
-		&model.User{},
-		&model.Note{},
-		&model.File{},
-		&model.Task{},
-		&model.Bookmark{},
-		&model.Theme{},
-		&model.ChatMessage{},
-		&model.Collection{},
-		&model.Event{},
-		&model.Post{},
-	)
-}
-
-func initDefaultUser() error {
+// initDefaultUser 数据库中没有用户时创建 ID 为 User.DefaultUserID 的默认用户
+func initDefaultUser(db *gorm.DB) error {
 	var count int64
-	if err := DB.Model(&model.User{}).Count(&count).Error; err != nil {
+	if err := db.Model(&model.User{}).Count(&count).Error; err != nil {
 		return err
 	}
 
 	if count == 0 {
 		defaultUser := &model.User{
-			ID:       1,
+			ID:       uint(config.AppConfig.User.DefaultUserID),
 			Username: "admin",
 			Nickname: "NexusHub User",
 			Bio:      "Welcome to NexusHub Personal Workstation",
 		}
-		if err := DB.Create(defaultUser).Error; err != nil {
+		if err := db.Create(defaultUser).Error; err != nil {
 			return err
 		}
 		log.Println("Default user created successfully")
@@ -107,22 +159,23 @@ func initDefaultUser() error {
 	return nil
 }
 
-func initDefaultTheme() error {
+// initDefaultTheme 没有任何主题时为默认用户创建主题
+func initDefaultTheme(db *gorm.DB) error {
 	var count int64
-	if err := DB.Model(&model.Theme{}).Count(&count).Error; err != nil {
+	if err := db.Model(&model.Theme{}).Count(&count).Error; err != nil {
 		return err
 	}
 
 	if count == 0 {
 		defaultTheme := &model.Theme{
-			UserID:         1,
+			UserID:         uint(config.AppConfig.User.DefaultUserID),
 			ThemeName:      "dark",
 			PrimaryColor:   "#000000",
 			SecondaryColor: "#ffffff",
 			ThemeTemplate:  "default",
 			MusicVolume:    0.5,
 		}
-		if err := DB.Create(defaultTheme).Error; err != nil {
+		if err := db.Create(defaultTheme).Error; err != nil {
 			return err
 		}
 		log.Println("Default theme created successfully")
@@ -0,0 +1,63 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/database"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/testutil"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestSetupOnSQLite(t *testing.T) {
+	db := testutil.SetupDB(t, nil)
+
+	var user model.User
+	if err := db.First(&user, config.AppConfig.User.DefaultUserID).Error; err != nil {
+		t.Fatalf("default user not created: %v", err)
+	}
+
+	// 长文本字段在SQLite上不应被截断
+	content := strings.Repeat("长文本", 100000)
+	note := model.Note{UserID: user.ID, Title: "long", Content: content}
+	if err := db.Create(&note).Error; err != nil {
+		t.Fatalf("create note: %v", err)
+	}
+	var loaded model.Note
+	if err := db.First(&loaded, note.ID).Error; err != nil {
+		t.Fatalf("load note: %v", err)
+	}
+	if loaded.Content != content {
+		t.Fatalf("content length mismatch: got %d, want %d", len(loaded.Content), len(content))
+	}
+
+	// 重复执行迁移应保持幂等
+	if err := database.Setup(db); err != nil {
+		t.Fatalf("second setup: %v", err)
+	}
+}
+
+func TestSetupSeedsConfiguredDefaultUser(t *testing.T) {
+	cfg := testutil.NewConfig(t)
+	cfg.User.DefaultUserID = 7
+	db := testutil.SetupDB(t, cfg)
+
+	var user model.User
+	if err := db.First(&user, 7).Error; err != nil {
+		t.Fatalf("default user not created with the configured ID: %v", err)
+	}
+	var theme model.Theme
+	if err := db.First(&theme).Error; err != nil || theme.UserID != 7 {
+		t.Fatalf("default theme not owned by the default user: %+v %v", theme, err)
+	}
+}
+
+func TestOpenUnsupportedDriver(t *testing.T) {
+	_, err := database.Open(config.DBConfig{Driver: "oracle"}, logger.Default.LogMode(logger.Silent))
+	if err == nil {
+		t.Fatal("expected error for unsupported driver")
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+func NewAuditHandler(services *service.Services) *AuditHandler {
+	return &AuditHandler{
+		service: services.Audit,
+	}
+}
+
+// GetAll 查询当前用户的审计日志
+// 支持过滤: resource_type, resource_id, action, from, to (RFC3339 或 YYYY-MM-DD), page, page_size
+func (h *AuditHandler) GetAll(c *gin.Context) {
+	filter := service.AuditFilter{
+		ActorID:      middleware.GetCurrentUserID(c),
+		ResourceType: c.Query("resource_type"),
+		Action:       c.Query("action"),
+	}
+
+	if v := c.Query("resource_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			common.BadRequest(c, "Invalid resource_id")
+			return
+		}
+		filter.ResourceID = uint(id)
+	}
+
+	var err error
+	if filter.From, err = parseTimeQuery(c.Query("from")); err != nil {
+		common.BadRequest(c, "Invalid from: "+err.Error())
+		return
+	}
+	if filter.To, err = parseTimeQuery(c.Query("to")); err != nil {
+		common.BadRequest(c, "Invalid to: "+err.Error())
+		return
+	}
+
+	filter.Page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || filter.Page < 1 {
+		filter.Page = 1
+	}
+	filter.PageSize, err = strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	logs, total, err := h.service.List(filter)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+
+	common.Success(c, gin.H{
+		"logs":      logs,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// parseTimeQuery 解析RFC3339或YYYY-MM-DD格式的时间参数
+func parseTimeQuery(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// recordAudit 记录一次变更操作，失败只写日志不影响请求结果
+func recordAudit(audit *service.AuditService, c *gin.Context, action, resourceType string, resourceID uint, before, after interface{}) {
+	err := audit.Record(service.AuditEntry{
+		ActorID:      middleware.GetCurrentUserID(c),
+		IP:           c.ClientIP(),
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Action:       action,
+		Before:       before,
+		After:        after,
+	})
+	if err != nil {
+		reqLog(c).Error("Failed to record audit log", "error", err, "action", action, "resource_type", resourceType, "resource_id", resourceID)
+	}
+}
@@ -1,18 +1,26 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
-	"nexushub-personal/internal/database"
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
 	"nexushub-personal/internal/middleware"
 	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/utils"
+	"nexushub-personal/internal/validator"
 
 	"github.com/gin-gonic/gin"
 )
 
-type AuthHandler struct{}
+type AuthHandler struct {
+	users *service.UserService
+	guard *middleware.LoginGuard
+}
 
-func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{}
+func NewAuthHandler(services *service.Services, guard *middleware.LoginGuard) *AuthHandler {
+	return &AuthHandler{users: services.Users, guard: guard}
 }
 
 type RegisterRequest struct {
@@ -40,35 +48,82 @@ type UserProfile struct {
 
 // Register 用户注册
 func (h *AuthHandler) Register(c *gin.Context) {
-	// 认证功能已取消：返回默认用户信息与提示
-	defaultUserID := middleware.GetCurrentUserID(c)
-	var user model.User
-	if err := database.DB.First(&user, defaultUserID).Error; err != nil {
-		c.JSON(http.StatusOK, AuthResponse{Token: "", User: UserProfile{ID: 0, Username: "guest", Email: ""}, Message: "auth disabled"})
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+	if err := validator.ValidateUsername(req.Username); err != nil {
+		common.BadRequest(c, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, AuthResponse{
-		Token:   "",
-		User:    UserProfile{ID: user.ID, Username: user.Username, Email: user.Email},
-		Message: "auth disabled",
-	})
+	user, err := h.users.Register(req.Username, req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, common.ErrUserAlreadyExists) {
+			common.Conflict(c, constants.ErrUsernameExists)
+			return
+		}
+		reqLog(c).Error("Failed to register user", "error", err)
+		common.InternalServerError(c, constants.ErrDatabaseError)
+		return
+	}
+
+	h.respondWithToken(c, user, constants.MsgRegisterSuccess)
 }
 
 // Login 用户登录
 func (h *AuthHandler) Login(c *gin.Context) {
-	// 认证功能已取消：返回默认用户信息与提示
-	defaultUserID := middleware.GetCurrentUserID(c)
-	var user model.User
-	if err := database.DB.First(&user, defaultUserID).Error; err != nil {
-		c.JSON(http.StatusOK, AuthResponse{Token: "", User: UserProfile{ID: 0, Username: "guest", Email: ""}, Message: "auth disabled"})
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	ip := c.ClientIP()
+	if locked, wait := h.guard.Check(req.Username, ip); wait > 0 {
+		middleware.SetRetryAfter(c, wait)
+		if locked {
+			common.TooManyRequests(c, constants.ErrAccountLocked)
+		} else {
+			common.TooManyRequests(c, constants.ErrTooManyRequests)
+		}
+		return
+	}
+
+	user, err := h.users.GetByUsername(req.Username)
+	if err != nil {
+		h.guard.Fail(req.Username, ip)
+		reqLog(c).Warn("Login failed: unknown user", "username", req.Username, "client_ip", ip)
+		common.Unauthorized(c, constants.ErrInvalidCredentials)
+		return
+	}
+
+	ok, err := utils.VerifyPassword(req.Password, user.Password)
+	if err != nil || !ok {
+		h.guard.Fail(req.Username, ip)
+		reqLog(c).Warn("Login failed: wrong password", "username", req.Username, "client_ip", ip)
+		common.Unauthorized(c, constants.ErrInvalidCredentials)
+		return
+	}
+
+	h.guard.Succeed(req.Username, ip)
+	h.respondWithToken(c, user, constants.MsgLoginSuccess)
+}
+
+// respondWithToken 为用户签发JWT并返回
+func (h *AuthHandler) respondWithToken(c *gin.Context, user *model.User, message string) {
+	token, err := middleware.GenerateToken(user.ID, user.Username)
+	if err != nil {
+		reqLog(c).Error("Failed to generate token", "error", err)
+		common.InternalServerError(c, constants.ErrInternalServer)
 		return
 	}
 
 	c.JSON(http.StatusOK, AuthResponse{
-		Token:   "",
+		Token:   token,
 		User:    UserProfile{ID: user.ID, Username: user.Username, Email: user.Email},
-		Message: "auth disabled",
+		Message: message,
 	})
 }
 
@@ -76,9 +131,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 
-	var user model.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.users.GetByID(userID)
+	if err != nil {
+		common.LegacyError(c, http.StatusNotFound, "User not found")
 		return
 	}
 
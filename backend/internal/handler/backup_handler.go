@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BackupHandler struct {
+	service *service.BackupService
+	users   *service.UserService
+}
+
+func NewBackupHandler(services *service.Services) *BackupHandler {
+	h := &BackupHandler{users: services.Users}
+	target, err := service.NewBackupTarget(config.AppConfig.Backup)
+	if err != nil {
+		services.Log.Error("Backup target unavailable", "error", err)
+		return h
+	}
+	h.service = service.NewBackupService(services.DB, services.Files, target, config.AppConfig.Backup, services.Log)
+	return h
+}
+
+// authorize 备份涉及所有用户的数据，恢复会覆盖全部数据，仅 Auth.AdminUsers 中的用户携带有效token时可操作。
+// 单用户模式下未登录请求映射到的默认用户不被视为管理员
+func (h *BackupHandler) authorize(c *gin.Context) bool {
+	if !middleware.IsAuthenticated(c) {
+		common.Unauthorized(c, "Sign in as an administrator to manage backups")
+		return false
+	}
+	user, err := h.users.GetByID(middleware.GetCurrentUserID(c))
+	if err != nil || !config.AppConfig.IsAdmin(user.Username) {
+		common.Forbidden(c, "Only the administrator can manage backups")
+		return false
+	}
+	if h.service == nil {
+		common.Error(c, http.StatusServiceUnavailable, "Backup target unavailable")
+		return false
+	}
+	return true
+}
+
+// List 列出所有备份
+func (h *BackupHandler) List(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	backups, err := h.service.List()
+	if err != nil {
+		reqLog(c).Error("Failed to list backups", "error", err)
+		common.InternalServerError(c, "Failed to list backups")
+		return
+	}
+	common.Success(c, backups)
+}
+
+// Create 立即创建备份
+func (h *BackupHandler) Create(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	backup, err := h.service.Create()
+	if err != nil {
+		h.handleError(c, "Backup failed", err)
+		return
+	}
+	common.Created(c, backup)
+}
+
+// Verify 校验备份完整性
+func (h *BackupHandler) Verify(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	result, err := h.service.Verify(c.Param("name"))
+	if err != nil {
+		h.handleError(c, "Verification failed", err)
+		return
+	}
+	common.Success(c, result)
+}
+
+// Restore 用指定备份替换当前全部数据
+func (h *BackupHandler) Restore(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	report, err := h.service.Restore(c.Param("name"))
+	if err != nil {
+		h.handleError(c, "Restore failed", err)
+		return
+	}
+	common.Success(c, report)
+}
+
+func (h *BackupHandler) handleError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrBackupNotFound):
+		common.NotFound(c, "Backup not found")
+	case errors.Is(err, service.ErrBackupBusy), errors.Is(err, service.ErrBackupExists):
+		common.Conflict(c, err.Error())
+	case service.IsBackupError(err):
+		common.BadRequest(c, err.Error())
+	default:
+		reqLog(c).Error(message, "error", err)
+		common.InternalServerError(c, message)
+	}
+}
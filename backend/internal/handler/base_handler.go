@@ -1,133 +1,310 @@
 package handler
 
 import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nexushub-personal/internal/common"
 	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/markdown"
 	"nexushub-personal/internal/middleware"
-	"nexushub-personal/pkg/utils"
-	"strconv"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// CRUDService 定义CRUD服务接口
+// CRUDService 定义CRUD服务接口，service.BaseService 及嵌入它的服务都实现了该接口
 type CRUDService[T any] interface {
-	GetAll(userID uint) ([]T, error)
+	Resource() string
+	GetAll(userID uint, filter map[string]string) ([]T, error)
 	GetByID(id, userID uint) (*T, error)
+	GetEditable(id, userID uint) (*T, error)
 	Create(entity *T) error
-	Update(entity *T) error
+	Update(id, userID uint, entity *T) error
 	Delete(id, userID uint) error
 }
 
-// Entity 定义实体接口 - 需要有ID和UserID字段
-type Entity interface {
-	SetID(id uint)
+// Entity 定义实体接口 - 需要能读取ID并设置所有者
+type Entity[T any] interface {
+	*T
+	GetID() uint
 	SetUserID(userID uint)
 }
 
-// BaseHandler 通用CRUD Handler基类
-type BaseHandler[T Entity] struct {
-	service     CRUDService[T]
-	resourceName string // 资源名称，用于错误消息
+// BaseHandler 通用CRUD Handler基类，所有变更都会写入审计日志
+type BaseHandler[T any, P Entity[T]] struct {
+	service      CRUDService[T]
+	audit        *service.AuditService
+	files        *service.FileService // 把正文中的附件链接替换为签名链接
+	resourceName string               // 资源名称，用于错误消息
+	filters      []string             // 透传给 GetAll 的查询参数
+
+	// withLogger 返回绑定请求日志的服务，服务记录日志时由具体的handler设置，见 scoped
+	withLogger func(log logger.Logger) CRUDService[T]
 }
 
-// NewBaseHandler 创建基础Handler
-func NewBaseHandler[T Entity](service CRUDService[T], resourceName string) *BaseHandler[T] {
-	return &BaseHandler[T]{
-		service:      service,
+// NewBaseHandler 创建基础Handler，filters 为列表接口支持的查询参数
+func NewBaseHandler[T any, P Entity[T]](svc CRUDService[T], services *service.Services, resourceName string, filters ...string) *BaseHandler[T, P] {
+	return &BaseHandler[T, P]{
+		service:      svc,
+		audit:        services.Audit,
+		files:        services.Files,
 		resourceName: resourceName,
+		filters:      filters,
+	}
+}
+
+// scoped 返回绑定当前请求日志的服务，使服务中的日志带上请求ID
+func (h *BaseHandler[T, P]) scoped(c *gin.Context) CRUDService[T] {
+	if h.withLogger == nil {
+		return h.service
+	}
+	return h.withLogger(reqLog(c))
+}
+
+// parseID 解析路径中的ID，失败时返回400
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.BadRequest(c, constants.ErrInvalidID)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// reqLog 返回附带当前请求ID的日志记录器
+func reqLog(c *gin.Context) logger.Logger {
+	return logger.FromContext(c.Request.Context())
+}
+
+// isNotFound 判断错误是否表示记录不存在(或当前用户无权访问)
+func isNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, common.ErrResourceNotFound)
+}
+
+// setETag 带版本号的记录在响应头中返回 ETag，客户端更新时通过 If-Match 回传
+func setETag(c *gin.Context, entity interface{}) {
+	if v, ok := entity.(model.Versioned); ok {
+		c.Header("ETag", `"`+strconv.Itoa(v.GetVersion())+`"`)
 	}
 }
 
-// GetAll 获取所有资源
-func (h *BaseHandler[T]) GetAll(c *gin.Context) {
+// noteResponse、postResponse 在笔记和文章的响应中附带正文渲染后的HTML和目录
+type noteResponse struct {
+	*model.Note
+	ContentHTML string             `json:"content_html,omitempty"`
+	TOC         []markdown.Heading `json:"toc,omitempty"`
+}
+
+type postResponse struct {
+	*model.Post
+	ContentHTML string             `json:"content_html,omitempty"`
+	TOC         []markdown.Heading `json:"toc,omitempty"`
+}
+
+// renderContent 正文为Markdown的资源返回附带 content_html 和 toc 的响应，其他资源原样返回。
+// content_html 中当前用户可读的附件链接替换为签名链接，浏览器无需 Authorization 头即可加载
+func renderContent(c *gin.Context, files *service.FileService, entity interface{}) interface{} {
 	userID := middleware.GetCurrentUserID(c)
-	items, err := h.service.GetAll(userID)
+	return renderContentFor(files.WithLogger(reqLog(c)), entity, func(file *model.File) bool {
+		_, err := files.GetReadable(file.ID, userID)
+		return err == nil
+	})
+}
+
+// renderContentFor 同 renderContent，由 readable 决定哪些附件链接可以签名
+func renderContentFor(files *service.FileService, entity interface{}, readable func(file *model.File) bool) interface{} {
+	switch e := entity.(type) {
+	case *model.Note:
+		doc := markdown.Render(e.Content)
+		return noteResponse{Note: e, ContentHTML: files.SignContentLinks(doc.HTML, readable), TOC: doc.TOC}
+	case *model.Post:
+		doc := markdown.Render(e.Content)
+		return postResponse{Post: e, ContentHTML: files.SignContentLinks(doc.HTML, readable), TOC: doc.TOC}
+	}
+	return entity
+}
+
+// errIfMatchWildcard If-Match: * 只表示资源存在，不指定版本，接受它等于放弃并发检查
+var errIfMatchWildcard = errors.New(`If-Match "*" is not accepted, send the version ETag such as "3"`)
+
+// ifMatchVersion 解析 If-Match 请求头中的版本号，"*" 返回 errIfMatchWildcard；
+// 未携带该请求头时 present 为false
+func ifMatchVersion(c *gin.Context) (version int, present bool, err error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+	if header == "*" {
+		return 0, true, errIfMatchWildcard
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err = strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return 0, true, errors.New("If-Match must be a single version ETag such as \"3\"")
+	}
+	return version, true, nil
+}
+
+// respondError 记录不存在时返回404，输入无效时返回400，其余返回500
+func (h *BaseHandler[T, P]) respondError(c *gin.Context, err error) {
+	if isNotFound(err) {
+		common.NotFound(c, h.resourceName+" not found")
+		return
+	}
+	if errors.Is(err, common.ErrInvalidInput) {
+		common.BadRequest(c, err.Error())
+		return
+	}
+	common.InternalServerError(c, err.Error())
+}
+
+// GetAll 获取所有资源，列表不渲染 content_html，需要时按ID获取单条记录
+func (h *BaseHandler[T, P]) GetAll(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	filter := make(map[string]string, len(h.filters)+1)
+	for _, param := range h.filters {
+		filter[param] = c.Query(param)
+	}
+	filter["tag"] = c.Query("tag") // 只对带标签的资源生效
+
+	items, err := h.service.GetAll(userID, filter)
 	if err != nil {
-		utils.InternalServerError(c, err.Error())
+		common.InternalServerError(c, err.Error())
 		return
 	}
-	utils.SuccessResponse(c, items)
+	common.Success(c, items)
 }
 
 // GetByID 根据ID获取资源
-func (h *BaseHandler[T]) GetByID(c *gin.Context) {
+func (h *BaseHandler[T, P]) GetByID(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		utils.BadRequestError(c, constants.ErrInvalidID)
+	id, ok := parseID(c)
+	if !ok {
 		return
 	}
 
-	item, err := h.service.GetByID(uint(id), userID)
+	item, err := h.service.GetByID(id, userID)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.NotFoundError(c, h.resourceName+" not found")
-		} else {
-			utils.InternalServerError(c, err.Error())
-		}
+		h.respondError(c, err)
 		return
 	}
-	utils.SuccessResponse(c, item)
+	setETag(c, P(item))
+	common.Success(c, renderContent(c, h.files, P(item)))
 }
 
 // Create 创建资源
-func (h *BaseHandler[T]) Create(c *gin.Context) {
+func (h *BaseHandler[T, P]) Create(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	var entity T
-	if err := c.ShouldBindJSON(&entity); err != nil {
-		utils.BadRequestError(c, err.Error())
+	entity := P(new(T))
+	if err := c.ShouldBindJSON(entity); err != nil {
+		common.BadRequest(c, err.Error())
 		return
 	}
 
 	entity.SetUserID(userID)
-	if err := h.service.Create(&entity); err != nil {
-		utils.InternalServerError(c, err.Error())
+	if err := h.scoped(c).Create(entity); err != nil {
+		if errors.Is(err, common.ErrInvalidInput) {
+			common.BadRequest(c, err.Error())
+			return
+		}
+		common.InternalServerError(c, err.Error())
 		return
 	}
-	utils.CreatedResponse(c, entity)
+	recordAudit(h.audit, c, constants.AuditActionCreate, h.service.Resource(), entity.GetID(), nil, entity)
+	common.Created(c, renderContent(c, h.files, entity))
 }
 
-// Update 更新资源
-func (h *BaseHandler[T]) Update(c *gin.Context) {
+// Update 更新资源，所有者或拥有edit权限的被分享者可以更新。
+// 带版本号的资源必须通过 If-Match 请求头或请求体的 version 字段指定基于的版本，
+// 版本已被其他请求修改时返回409和服务器上的当前内容
+func (h *BaseHandler[T, P]) Update(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	entity := P(new(T))
+	if err := c.ShouldBindJSON(entity); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	before, err := h.service.GetEditable(id, userID)
 	if err != nil {
-		utils.BadRequestError(c, constants.ErrInvalidID)
+		h.respondError(c, err)
+		return
+	}
+	if v, ok := any(entity).(model.Versioned); ok {
+		version, present, err := ifMatchVersion(c)
+		if errors.Is(err, errIfMatchWildcard) {
+			common.Error(c, http.StatusPreconditionRequired, err.Error())
+			return
+		}
+		if err != nil {
+			common.BadRequest(c, err.Error())
+			return
+		}
+		if present {
+			v.SetVersion(version)
+		} else if v.GetVersion() == 0 {
+			common.Error(c, http.StatusPreconditionRequired, "If-Match header or version field is required")
+			return
+		}
+	}
+	if err := h.scoped(c).Update(id, userID, entity); err != nil {
+		if errors.Is(err, common.ErrVersionConflict) {
+			h.respondConflict(c, id, userID)
+			return
+		}
+		h.respondError(c, err)
 		return
 	}
 
-	var entity T
-	if err := c.ShouldBindJSON(&entity); err != nil {
-		utils.BadRequestError(c, err.Error())
+	updated, err := h.service.GetByID(id, userID)
+	if err != nil {
+		h.respondError(c, err)
 		return
 	}
+	recordAudit(h.audit, c, constants.AuditActionUpdate, h.service.Resource(), id, before, updated)
+	setETag(c, P(updated))
+	common.Success(c, renderContent(c, h.files, P(updated)))
+}
 
-	entity.SetID(uint(id))
-	entity.SetUserID(userID)
-	if err := h.service.Update(&entity); err != nil {
-		utils.InternalServerError(c, err.Error())
+// respondConflict 返回409及服务器上的当前内容，客户端据此合并后重新提交
+func (h *BaseHandler[T, P]) respondConflict(c *gin.Context, id, userID uint) {
+	current, err := h.service.GetByID(id, userID)
+	if err != nil {
+		h.respondError(c, err)
 		return
 	}
-	utils.SuccessResponse(c, entity)
+	setETag(c, P(current))
+	common.ErrorWithData(c, http.StatusConflict, h.resourceName+" was modified by another request", renderContent(c, h.files, P(current)))
 }
 
-// Delete 删除资源
-func (h *BaseHandler[T]) Delete(c *gin.Context) {
+// Delete 删除资源，只有所有者可以删除
+func (h *BaseHandler[T, P]) Delete(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		utils.BadRequestError(c, constants.ErrInvalidID)
+	id, ok := parseID(c)
+	if !ok {
 		return
 	}
 
-	if err := h.service.Delete(uint(id), userID); err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.NotFoundError(c, h.resourceName+" not found")
-		} else {
-			utils.InternalServerError(c, err.Error())
-		}
+	before, err := h.service.GetByID(id, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	if err := h.scoped(c).Delete(id, userID); err != nil {
+		h.respondError(c, err)
 		return
 	}
-	utils.MessageResponse(c, h.resourceName+" deleted successfully")
+	recordAudit(h.audit, c, constants.AuditActionDelete, h.service.Resource(), id, before, nil)
+	common.SuccessWithMessage(c, h.resourceName+" deleted successfully", nil)
 }
@@ -15,9 +15,9 @@ type ChatHandler struct {
 	service *service.ChatService
 }
 
-func NewChatHandler() *ChatHandler {
+func NewChatHandler(services *service.Services) *ChatHandler {
 	return &ChatHandler{
-		service: service.NewChatService(),
+		service: services.Chat,
 	}
 }
 
@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 	"time"
 
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/metrics"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,6 +24,16 @@ func NewCodeHandler() *CodeHandler {
 	return &CodeHandler{}
 }
 
+// 执行结果，用于 nexushub_code_executions_total 指标
+const (
+	verdictOK            = "ok"
+	verdictRuntimeError  = "runtime_error"
+	verdictCompileError  = "compile_error"
+	verdictTimeout       = "timeout"
+	verdictUnsupported   = "unsupported"
+	verdictInternalError = "internal_error"
+)
+
 type RunRequest struct {
 	Language string `json:"language" binding:"required"`
 	Code     string `json:"code" binding:"required"`
@@ -30,11 +43,16 @@ type RunRequest struct {
 func (h *CodeHandler) RunCode(c *gin.Context) {
 	var req RunRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		common.LegacyError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	output, err := h.executeCode(req.Language, req.Code, req.Input)
+	output, verdict, err := h.executeCode(req.Language, req.Code, req.Input)
+	language := req.Language
+	if verdict == verdictUnsupported {
+		language = "other" // 避免任意语言名成为标签值
+	}
+	metrics.CodeExecutions.Inc(language, verdict)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"output": output, "error": err.Error()})
 		return
@@ -43,11 +61,12 @@ func (h *CodeHandler) RunCode(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"output": output})
 }
 
-func (h *CodeHandler) executeCode(lang, code, input string) (string, error) {
+// executeCode 执行代码，返回输出和执行结果(verdict*)
+func (h *CodeHandler) executeCode(lang, code, input string) (string, string, error) {
 	// Create temp directory
 	tmpDir := filepath.Join(os.TempDir(), "nexushub_code")
 	if err := os.MkdirAll(tmpDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create temp dir: %v", err)
+		return "", verdictInternalError, fmt.Errorf("failed to create temp dir: %v", err)
 	}
 
 	var cmd *exec.Cmd
@@ -57,19 +76,19 @@ func (h *CodeHandler) executeCode(lang, code, input string) (string, error) {
 	case "go":
 		filename = filepath.Join(tmpDir, "main.go")
 		if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
-			return "", err
+			return "", verdictInternalError, err
 		}
 		cmd = exec.Command("go", "run", filename)
 	case "python":
 		filename = filepath.Join(tmpDir, "script.py")
 		if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
-			return "", err
+			return "", verdictInternalError, err
 		}
 		cmd = exec.Command("python", filename)
 	case "javascript":
 		filename = filepath.Join(tmpDir, "script.js")
 		if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
-			return "", err
+			return "", verdictInternalError, err
 		}
 		cmd = exec.Command("node", filename)
 	case "c":
@@ -77,13 +96,13 @@ func (h *CodeHandler) executeCode(lang, code, input string) (string, error) {
 		filename = filepath.Join(tmpDir, "main.c")
 		exeName := filepath.Join(tmpDir, "main.exe")
 		if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
-			return "", err
+			return "", verdictInternalError, err
 		}
 		// 编译：gcc main.c -o main.exe
 		// 注意：这里假设服务器安装了 gcc
 		buildCmd := exec.Command("gcc", filename, "-o", exeName)
 		if out, err := buildCmd.CombinedOutput(); err != nil {
-			return string(out), fmt.Errorf("compilation failed")
+			return string(out), verdictCompileError, fmt.Errorf("compilation failed")
 		}
 		cmd = exec.Command(exeName)
 		defer os.Remove(exeName) // 运行后删除 exe
@@ -92,17 +111,17 @@ func (h *CodeHandler) executeCode(lang, code, input string) (string, error) {
 		filename = filepath.Join(tmpDir, "main.cpp")
 		exeName := filepath.Join(tmpDir, "main.exe")
 		if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
-			return "", err
+			return "", verdictInternalError, err
 		}
 		// 编译：g++ main.cpp -o main.exe
 		buildCmd := exec.Command("g++", filename, "-o", exeName)
 		if out, err := buildCmd.CombinedOutput(); err != nil {
-			return string(out), fmt.Errorf("compilation failed")
+			return string(out), verdictCompileError, fmt.Errorf("compilation failed")
 		}
 		cmd = exec.Command(exeName)
 		defer os.Remove(exeName)
 	default:
-		return "", fmt.Errorf("unsupported language: %s", lang)
+		return "", verdictUnsupported, fmt.Errorf("unsupported language: %s", lang)
 	}
 
 	// Set context with timeout
@@ -126,7 +145,7 @@ func (h *CodeHandler) executeCode(lang, code, input string) (string, error) {
 	if input != "" {
 		stdin, err := cmd.StdinPipe()
 		if err != nil {
-			return "", fmt.Errorf("failed to get stdin pipe: %v", err)
+			return "", verdictInternalError, fmt.Errorf("failed to get stdin pipe: %v", err)
 		}
 		go func() {
 			defer stdin.Close()
@@ -142,16 +161,16 @@ func (h *CodeHandler) executeCode(lang, code, input string) (string, error) {
 	os.Remove(filename)
 
 	if ctx.Err() == context.DeadlineExceeded {
-		return output + "\n[Execution timed out]", fmt.Errorf("execution timed out")
+		return output + "\n[Execution timed out]", verdictTimeout, fmt.Errorf("execution timed out")
 	}
 
 	if err != nil {
 		// 返回具体的错误信息，不仅是 output，也包含 err.Error()
 		if len(output) == 0 {
-			return fmt.Sprintf("Error executing command: %v", err), nil
+			return fmt.Sprintf("Error executing command: %v", err), verdictRuntimeError, nil
 		}
-		return output, nil
+		return output, verdictRuntimeError, nil
 	}
 
-	return output, nil
+	return output, verdictOK, nil
 }
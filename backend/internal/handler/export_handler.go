@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	service *service.ExportService
+}
+
+func NewExportHandler(services *service.Services) *ExportHandler {
+	return &ExportHandler{
+		service: services.Export,
+	}
+}
+
+// Export 以ZIP格式流式导出当前用户的全部数据
+func (h *ExportHandler) Export(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	filename := fmt.Sprintf("nexushub-export-%s.zip", time.Now().Format("20060102-150405"))
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，出错时只能中断连接并记录日志
+	if err := h.service.WithLogger(reqLog(c)).Export(userID, c.Writer); err != nil {
+		reqLog(c).Error("Export failed", "error", err, "user_id", userID)
+		c.Abort()
+		return
+	}
+	reqLog(c).Info("Export finished", "user_id", userID)
+}
+
+// Import 从导出的ZIP恢复数据
+// 表单字段 file 为导出包；conflict 参数: skip(默认) | overwrite | duplicate
+func (h *ExportHandler) Import(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.AppConfig.Storage.MaxImportSize)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			common.Error(c, http.StatusRequestEntityTooLarge, "Archive exceeds maximum import size")
+			return
+		}
+		common.BadRequest(c, "No archive uploaded")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		common.BadRequest(c, "Cannot read uploaded archive")
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.WithLogger(reqLog(c)).Import(userID, file, header.Size, c.DefaultQuery("conflict", service.ConflictSkip))
+	if err != nil {
+		switch {
+		case errors.Is(err, common.ErrInvalidArchive), errors.Is(err, common.ErrUnsupportedVersion),
+			errors.Is(err, common.ErrInvalidInput):
+			common.BadRequest(c, err.Error())
+		default:
+			reqLog(c).Error("Import failed", "error", err, "user_id", userID)
+			common.InternalServerError(c, "Import failed")
+		}
+		return
+	}
+
+	common.Success(c, report)
+}
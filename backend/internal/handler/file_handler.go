@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"io"
+	"mime"
+	"net/http"
 	"nexushub-personal/internal/common"
-	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/constants"
 	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/model"
 	"nexushub-personal/internal/service"
 	"strconv"
 	"strings"
@@ -13,11 +17,13 @@ import (
 
 type FileHandler struct {
 	service *service.FileService
+	audit   *service.AuditService
 }
 
-func NewFileHandler() *FileHandler {
+func NewFileHandler(services *service.Services) *FileHandler {
 	return &FileHandler{
-		service: service.NewFileService(),
+		service: services.Files,
+		audit:   services.Audit,
 	}
 }
 
@@ -35,20 +41,20 @@ func (h *FileHandler) GetAll(c *gin.Context) {
 		pageSize = 20
 	}
 
-	files, total, err := h.service.GetAll(userID, page, pageSize)
+	files, total, err := h.service.GetAll(userID, c.Query("tag"), page, pageSize)
 	if err != nil {
-		logger.Error("Failed to get all files: %v, user_id=%d", err, userID)
+		reqLog(c).Error("Failed to get all files", "error", err, "user_id", userID)
 		common.InternalServerError(c, "Failed to retrieve files")
 		return
 	}
 
-	logger.Info("Retrieved %d files for user_id=%d, page=%d, page_size=%d, total=%d", len(files), userID, page, pageSize, total)
+	reqLog(c).Info("Retrieved files", "count", len(files), "user_id", userID, "page", page, "page_size", pageSize, "total", total)
 	common.Success(c, gin.H{
 		"files":      files,
 		"total":      total,
 		"page":       page,
 		"page_size":  pageSize,
-		"total_pages": (total + pageSize - 1) / pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
 	})
 }
 
@@ -56,14 +62,14 @@ func (h *FileHandler) GetByID(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		logger.Warn("Invalid file ID parameter: %v", err)
+		reqLog(c).Warn("Invalid file ID parameter", "error", err)
 		common.BadRequest(c, "Invalid file ID")
 		return
 	}
 
 	file, err := h.service.GetByID(uint(id), userID)
 	if err != nil {
-		logger.Warn("File not found: id=%d, user_id=%d", id, userID)
+		reqLog(c).Warn("File not found", "id", id, "user_id", userID)
 		common.NotFound(c, "File not found")
 		return
 	}
@@ -76,19 +82,19 @@ func (h *FileHandler) GetByCategory(c *gin.Context) {
 	category := c.Param("category")
 
 	if category == "" {
-		logger.Warn("Empty category parameter")
+		reqLog(c).Warn("Empty category parameter")
 		common.BadRequest(c, "Category parameter is required")
 		return
 	}
 
-	files, err := h.service.GetByCategory(category, userID)
+	files, err := h.service.GetByCategory(category, c.Query("tag"), userID)
 	if err != nil {
-		logger.Error("Failed to get files by category: %v, category=%s, user_id=%d", err, category, userID)
+		reqLog(c).Error("Failed to get files by category", "error", err, "category", category, "user_id", userID)
 		common.InternalServerError(c, "Failed to retrieve files")
 		return
 	}
 
-	logger.Info("Retrieved %d files for category=%s, user_id=%d", len(files), category, userID)
+	reqLog(c).Info("Retrieved files by category", "count", len(files), "category", category, "user_id", userID)
 	common.Success(c, files)
 }
 
@@ -97,12 +103,12 @@ func (h *FileHandler) Upload(c *gin.Context) {
 
 	file, err := c.FormFile("file")
 	if err != nil {
-		logger.Warn("No file in upload request: %v, user_id=%d", err, userID)
+		reqLog(c).Warn("No file in upload request", "error", err, "user_id", userID)
 		common.BadRequest(c, "No file uploaded")
 		return
 	}
 
-	uploadedFile, err := h.service.Upload(file, userID)
+	uploadedFile, err := h.service.WithLogger(reqLog(c)).Upload(file, userID)
 	if err != nil {
 		// 错误已在service层记录
 		if err == common.ErrFileToLarge {
@@ -114,6 +120,7 @@ func (h *FileHandler) Upload(c *gin.Context) {
 		}
 		return
 	}
+	recordAudit(h.audit, c, constants.AuditActionCreate, constants.ResourceFile, uploadedFile.ID, nil, uploadedFile)
 
 	// Generate accessible URL path for the uploaded file
 	relativePath := ""
@@ -141,7 +148,7 @@ func (h *FileHandler) Rename(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		logger.Warn("Invalid file ID for rename: %v", err)
+		reqLog(c).Warn("Invalid file ID for rename", "error", err)
 		common.BadRequest(c, "Invalid file ID")
 		return
 	}
@@ -154,7 +161,13 @@ func (h *FileHandler) Rename(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Rename(uint(id), userID, req.NewName); err != nil {
+	before, err := h.service.GetByID(uint(id), userID)
+	if err != nil {
+		common.NotFound(c, "File not found")
+		return
+	}
+
+	if err := h.service.WithLogger(reqLog(c)).Rename(uint(id), userID, req.NewName); err != nil {
 		if err == common.ErrFileNotFound {
 			common.NotFound(c, "File not found")
 		} else {
@@ -162,40 +175,136 @@ func (h *FileHandler) Rename(c *gin.Context) {
 		}
 		return
 	}
+	if after, err := h.service.GetByID(uint(id), userID); err == nil {
+		recordAudit(h.audit, c, constants.AuditActionUpdate, constants.ResourceFile, after.ID, before, after)
+	}
 
 	common.SuccessWithMessage(c, "File renamed successfully", nil)
 }
 
+// SetTags 修改文件的标签，tags 为逗号分隔的标签名
+func (h *FileHandler) SetTags(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Tags string `json:"tags" binding:"max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	before, err := h.service.GetByID(id, userID)
+	if err != nil {
+		common.NotFound(c, "File not found")
+		return
+	}
+	after, err := h.service.SetTags(id, userID, req.Tags)
+	if err != nil {
+		if err == common.ErrFileNotFound {
+			common.NotFound(c, "File not found")
+		} else {
+			reqLog(c).Error("Failed to set file tags", "error", err, "id", id)
+			common.InternalServerError(c, "Failed to update file tags")
+		}
+		return
+	}
+	recordAudit(h.audit, c, constants.AuditActionUpdate, constants.ResourceFile, after.ID, before, after)
+	common.Success(c, after)
+}
+
 func (h *FileHandler) Download(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		logger.Warn("Invalid file ID for download: %v", err)
+		reqLog(c).Warn("Invalid file ID for download", "error", err)
 		common.BadRequest(c, "Invalid file ID")
 		return
 	}
 
 	file, err := h.service.GetByID(uint(id), userID)
 	if err != nil {
-		logger.Warn("File not found for download: id=%d, user_id=%d", id, userID)
+		reqLog(c).Warn("File not found for download", "id", id, "user_id", userID)
 		common.NotFound(c, "File not found")
 		return
 	}
 
-	logger.Info("File download: id=%d, filename=%s, user_id=%d", id, file.FileName, userID)
+	reqLog(c).Info("File download", "id", id, "filename", file.FileName, "user_id", userID)
 	c.File(file.FilePath)
 }
 
+// Content 以内联方式返回文件内容，service.FileContentURL 生成的链接指向这里。
+// 支持云存储；笔记的附件对能访问该笔记的用户可见。
+// 携带 expires 和 signature 参数时为 service.SignedContentURL 生成的签名链接，无需认证
+func (h *FileHandler) Content(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var file *model.File
+	var err error
+	if signature := c.Query("signature"); signature != "" {
+		file, err = h.service.GetBySignature(id, c.Query("expires"), signature)
+	} else {
+		file, err = h.service.GetReadable(id, middleware.GetCurrentUserID(c))
+	}
+	if err != nil {
+		common.NotFound(c, "File not found")
+		return
+	}
+	serveFileContent(c, h.service, file, "inline")
+}
+
+// serveFileContent 从本地或云存储读取文件内容并返回，disposition 为 inline 或 attachment
+func serveFileContent(c *gin.Context, files *service.FileService, file *model.File, disposition string) {
+	blob, err := files.OpenBlob(file)
+	if err != nil {
+		reqLog(c).Error("Failed to open file content", "error", err, "id", file.ID)
+		common.NotFound(c, "File content not found")
+		return
+	}
+	defer blob.Close()
+
+	contentType := file.MimeType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(file.Extension)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// 上传的 HTML、SVG 与应用同源，禁止其中的脚本执行和类型嗅探
+	c.Header("Content-Security-Policy", "sandbox")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.FileName}))
+	if rs, ok := blob.(io.ReadSeeker); ok {
+		c.Header("Content-Type", contentType)
+		http.ServeContent(c.Writer, c.Request, file.FileName, file.UpdatedAt, rs)
+		return
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, blob, nil)
+}
+
 func (h *FileHandler) Delete(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		logger.Warn("Invalid file ID for deletion: %v", err)
+		reqLog(c).Warn("Invalid file ID for deletion", "error", err)
 		common.BadRequest(c, "Invalid file ID")
 		return
 	}
 
-	if err := h.service.Delete(uint(id), userID); err != nil {
+	before, err := h.service.GetByID(uint(id), userID)
+	if err != nil {
+		common.NotFound(c, "File not found")
+		return
+	}
+
+	if err := h.service.WithLogger(reqLog(c)).Delete(uint(id), userID); err != nil {
 		// 错误已在service层记录
 		if err == common.ErrFileNotFound {
 			common.NotFound(c, "File not found")
@@ -204,6 +313,7 @@ func (h *FileHandler) Delete(c *gin.Context) {
 		}
 		return
 	}
+	recordAudit(h.audit, c, constants.AuditActionDelete, constants.ResourceFile, before.ID, before, nil)
 
 	common.SuccessWithMessage(c, "File deleted successfully", nil)
 }
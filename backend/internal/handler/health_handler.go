@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 存活与就绪探针，供 Kubernetes 等编排系统使用
+type HealthHandler struct {
+	health *service.HealthService
+}
+
+// NewHealthHandler 创建探针处理器
+func NewHealthHandler(services *service.Services) *HealthHandler {
+	return &HealthHandler{health: services.Health}
+}
+
+// Livez 进程存活即返回200，不检查任何依赖，避免依赖故障导致容器被反复重启
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz 检查全部依赖：关键依赖失败返回503，仅非关键依赖异常时返回200并标记为 degraded。
+// 接口无需认证，只返回整体状态和各项检查的名称与状态，失败原因记录在日志中
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.health.Readiness(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report.Summary())
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"time"
 
+	"nexushub-personal/internal/common"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,7 +27,7 @@ type CheckResponse struct {
 func (h *MonitorHandler) CheckHealth(c *gin.Context) {
 	var req CheckRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		common.LegacyError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
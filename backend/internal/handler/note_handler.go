@@ -1,10 +1,14 @@
 package handler
 
 import (
-	"net/http"
+	"errors"
+	"io"
 	"strconv"
+	"strings"
 
 	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/logger"
 	"nexushub-personal/internal/middleware"
 	"nexushub-personal/internal/model"
 	"nexushub-personal/internal/service"
@@ -13,91 +17,472 @@ import (
 )
 
 type NoteHandler struct {
-	service *service.NoteService
+	*BaseHandler[model.Note, *model.Note]
+	notes     *service.NoteService
+	templates *service.NoteTemplateService
+	files     *service.FileService
 }
 
-func NewNoteHandler() *NoteHandler {
-	return &NoteHandler{
-		service: service.NewNoteService(),
+func NewNoteHandler(services *service.Services) *NoteHandler {
+	h := &NoteHandler{
+		BaseHandler: NewBaseHandler[model.Note](services.Notes, services, "Note"),
+		notes:       services.Notes,
+		templates:   services.Templates,
+		files:       services.Files,
 	}
+	h.withLogger = func(log logger.Logger) CRUDService[model.Note] {
+		return h.notes.WithLogger(log)
+	}
+	return h
 }
 
-func (h *NoteHandler) GetAll(c *gin.Context) {
+// FromTemplateRequest 从模板创建笔记的请求，均可省略：date 默认为今天，title 默认使用模板标题
+type FromTemplateRequest struct {
+	Date  string `json:"date"`
+	Title string `json:"title" binding:"max=255"`
+}
+
+// MoveNotesRequest 把笔记移到笔记本的请求，notebook_id 为 null 表示移出笔记本
+type MoveNotesRequest struct {
+	NoteIDs    []uint `json:"note_ids" binding:"required,min=1,max=500"`
+	NotebookID *uint  `json:"notebook_id"`
+}
+
+// ReorderNotesRequest 手动排序的请求，按 note_ids 的顺序排列
+type ReorderNotesRequest struct {
+	NoteIDs []uint `json:"note_ids" binding:"required,min=1,max=1000"`
+}
+
+// AttachmentResponse 笔记附件：文件信息、稳定的内容链接和可以直接插入正文的Markdown
+type AttachmentResponse struct {
+	model.File
+	URL      string `json:"url"`
+	Markdown string `json:"markdown"`
+}
+
+// markdownLabelEscaper 转义Markdown链接文字中的方括号
+var markdownLabelEscaper = strings.NewReplacer("[", `\[`, "]", `\]`)
+
+func newAttachmentResponse(file model.File) AttachmentResponse {
+	url := service.FileContentURL(file.ID)
+	markdown := "[" + markdownLabelEscaper.Replace(file.FileName) + "](" + url + ")"
+	if strings.HasPrefix(file.MimeType, "image/") {
+		markdown = "!" + markdown
+	}
+	return AttachmentResponse{File: file, URL: url, Markdown: markdown}
+}
+
+// parseRevision 解析版本号，失败时返回400
+func parseRevision(c *gin.Context, value string) (int, bool) {
+	rev, err := strconv.Atoi(value)
+	if err != nil || rev < 1 {
+		common.BadRequest(c, "Invalid revision")
+		return 0, false
+	}
+	return rev, true
+}
+
+// respondRevisionError 版本不存在时返回404，其余同 respondError
+func (h *NoteHandler) respondRevisionError(c *gin.Context, err error) {
+	if errors.Is(err, common.ErrRevisionNotFound) {
+		common.NotFound(c, "Revision not found")
+		return
+	}
+	h.respondError(c, err)
+}
+
+// GetRevisions 获取笔记的历史版本列表(不含正文)
+func (h *NoteHandler) GetRevisions(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.notes.Revisions(id, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	common.Success(c, revisions)
+}
+
+// GetRevision 获取笔记某个历史版本的完整内容
+func (h *NoteHandler) GetRevision(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	rev, ok := parseRevision(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	revision, err := h.notes.Revision(id, userID, rev)
+	if err != nil {
+		h.respondRevisionError(c, err)
+		return
+	}
+	common.Success(c, revision)
+}
+
+// DiffRevisions 按行比较两个历史版本，查询参数 from、to 为版本号
+func (h *NoteHandler) DiffRevisions(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	from, ok := parseRevision(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := parseRevision(c, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	diff, err := h.notes.DiffRevisions(id, userID, from, to)
+	if err != nil {
+		h.respondRevisionError(c, err)
+		return
+	}
+	common.Success(c, diff)
+}
+
+// RestoreRevision 将笔记恢复为某个历史版本，需要编辑权限
+func (h *NoteHandler) RestoreRevision(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	notes, err := h.service.GetAll(userID)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	rev, ok := parseRevision(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	before, err := h.notes.GetEditable(id, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	restored, err := h.notes.WithLogger(reqLog(c)).RestoreRevision(id, userID, rev)
+	if err != nil {
+		h.respondRevisionError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, constants.AuditActionUpdate, constants.ResourceNote, id, before, restored)
+	common.SuccessWithMessage(c, "Note restored to revision "+strconv.Itoa(rev), renderContent(c, h.files, restored))
+}
+
+// GetBacklinks 获取链接到该笔记的其他笔记
+func (h *NoteHandler) GetBacklinks(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	backlinks, err := h.notes.Backlinks(id, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	common.Success(c, backlinks)
+}
+
+// GetGraph 获取笔记关系图(节点和链接)
+func (h *NoteHandler) GetGraph(c *gin.Context) {
+	graph, err := h.notes.Graph(middleware.GetCurrentUserID(c))
 	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
 	}
-	common.Success(c, notes)
+	common.Success(c, graph)
 }
 
-func (h *NoteHandler) GetByID(c *gin.Context) {
+// CreateFromTemplate 从模板创建笔记，模板中的占位符按请求的日期展开
+func (h *NoteHandler) CreateFromTemplate(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req FromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		common.BadRequest(c, err.Error())
+		return
+	}
+	date, err := service.ParseNoteDate(req.Date)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID")
+		common.BadRequest(c, err.Error())
 		return
 	}
 
-	note, err := h.service.GetByID(uint(id), userID)
+	note, err := h.templates.CreateNote(id, userID, date, req.Title)
 	if err != nil {
-		common.NotFound(c, "Note not found")
+		if isNotFound(err) {
+			common.NotFound(c, "Note template not found")
+			return
+		}
+		common.InternalServerError(c, err.Error())
 		return
 	}
-	common.Success(c, note)
+	recordAudit(h.audit, c, constants.AuditActionCreate, constants.ResourceNote, note.ID, nil, note)
+	setETag(c, note)
+	common.Created(c, renderContent(c, h.files, note))
 }
 
-func (h *NoteHandler) Create(c *gin.Context) {
+// GetDaily 获取某天(YYYY-MM-DD 或 today)的日记笔记，不存在时用日记模板创建(返回201)，
+// 重复请求返回同一篇笔记
+func (h *NoteHandler) GetDaily(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	var note model.Note
-	if err := c.ShouldBindJSON(&note); err != nil {
+	date, err := service.ParseNoteDate(c.Param("date"))
+	if err != nil {
 		common.BadRequest(c, err.Error())
 		return
 	}
 
-	note.UserID = userID
-	if err := h.service.Create(&note); err != nil {
+	note, created, err := h.templates.Daily(userID, date)
+	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
 	}
-	common.Created(c, note)
+	if created {
+		recordAudit(h.audit, c, constants.AuditActionCreate, constants.ResourceNote, note.ID, nil, note)
+	}
+	setETag(c, note)
+	if created {
+		common.Created(c, renderContent(c, h.files, note))
+		return
+	}
+	common.Success(c, renderContent(c, h.files, note))
 }
 
-func (h *NoteHandler) Update(c *gin.Context) {
+// Delete 删除笔记。查询参数 remove_attachments=true 时同时删除上传到该笔记、
+// 且没有被其他笔记、文章或分享使用的附件
+func (h *NoteHandler) Delete(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	removeAttachments, _ := strconv.ParseBool(c.Query("remove_attachments"))
+
+	before, err := h.notes.GetByID(id, userID)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID")
+		h.respondError(c, err)
+		return
+	}
+	if err := h.notes.WithLogger(reqLog(c)).Delete(id, userID); err != nil {
+		h.respondError(c, err)
 		return
 	}
+	recordAudit(h.audit, c, constants.AuditActionDelete, constants.ResourceNote, id, before, nil)
 
-	var note model.Note
-	if err := c.ShouldBindJSON(&note); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	removed := []uint{}
+	if removeAttachments {
+		files, err := h.files.WithLogger(reqLog(c)).RemoveUnusedAttachments(id, userID)
+		for i := range files {
+			recordAudit(h.audit, c, constants.AuditActionDelete, constants.ResourceFile, files[i].ID, &files[i], nil)
+			removed = append(removed, files[i].ID)
+		}
+		if err != nil {
+			reqLog(c).Error("Failed to remove attachments of deleted note", "error", err, "note_id", id)
+			common.InternalServerError(c, "Note deleted but failed to remove its attachments")
+			return
+		}
+	}
+	common.SuccessWithMessage(c, "Note deleted successfully", gin.H{"removed_attachments": removed})
+}
+
+// GetAttachments 获取上传到笔记的附件
+func (h *NoteHandler) GetAttachments(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
 		return
 	}
 
-	note.ID = uint(id)
-	note.UserID = userID
-	if err := h.service.Update(&note); err != nil {
+	files, err := h.files.NoteAttachments(id, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	attachments := make([]AttachmentResponse, len(files))
+	for i := range files {
+		attachments[i] = newAttachmentResponse(files[i])
+	}
+	common.Success(c, attachments)
+}
+
+// UploadAttachment 上传文件(表单字段 file)作为笔记的附件，需要笔记的编辑权限。
+// 返回的链接只依赖文件ID，文件改名或迁移到云存储后仍然有效
+func (h *NoteHandler) UploadAttachment(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		common.BadRequest(c, "No file uploaded")
+		return
+	}
+
+	file, err := h.files.WithLogger(reqLog(c)).UploadToNote(id, header, userID)
+	if err != nil {
+		switch {
+		case isNotFound(err):
+			h.respondError(c, err)
+		case errors.Is(err, common.ErrFileToLarge), errors.Is(err, common.ErrInvalidFileType), errors.Is(err, common.ErrInvalidFileName):
+			common.BadRequest(c, err.Error())
+		default:
+			common.InternalServerError(c, "File upload failed")
+		}
+		return
+	}
+	recordAudit(h.audit, c, constants.AuditActionCreate, constants.ResourceFile, file.ID, nil, file)
+	common.Created(c, newAttachmentResponse(*file))
+}
+
+// GetAll 获取笔记列表。查询参数：
+// tag；notebook_id(笔记本ID，none 表示不属于任何笔记本)，recursive=true 时包含子笔记本；
+// archived(默认 false 只返回未归档的，true 只返回归档的，all 全部)；sort=manual 按手动排序；
+// 指定 page 或 page_size 时分页返回 {notes, total, page, page_size, total_pages}，否则返回全部笔记。
+// 列表中的笔记不包含 content_html
+func (h *NoteHandler) GetAll(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	query := service.NoteQuery{Tag: c.Query("tag"), Sort: c.Query("sort")}
+
+	switch notebook := c.Query("notebook_id"); notebook {
+	case "":
+	case "none":
+		query.Unfiled = true
+	default:
+		id, err := strconv.ParseUint(notebook, 10, 32)
+		if err != nil {
+			common.BadRequest(c, "Invalid notebook_id")
+			return
+		}
+		nb := uint(id)
+		query.NotebookID = &nb
+		query.Recursive, _ = strconv.ParseBool(c.Query("recursive"))
+	}
+
+	switch archived := c.DefaultQuery("archived", "false"); archived {
+	case "all":
+	default:
+		value, err := strconv.ParseBool(archived)
+		if err != nil {
+			common.BadRequest(c, "archived must be true, false or all")
+			return
+		}
+		query.Archived = &value
+	}
+
+	_, paged := c.GetQuery("page")
+	if _, ok := c.GetQuery("page_size"); ok {
+		paged = true
+	}
+	if paged {
+		var err error
+		query.Page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || query.Page < 1 {
+			query.Page = 1
+		}
+		query.PageSize, err = strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if err != nil || query.PageSize < 1 || query.PageSize > 100 {
+			query.PageSize = 20
+		}
+	}
+
+	notes, total, err := h.notes.List(userID, query)
+	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
 	}
-	common.Success(c, note)
+	if !paged {
+		common.Success(c, notes)
+		return
+	}
+	common.Success(c, gin.H{
+		"notes":       notes,
+		"total":       total,
+		"page":        query.Page,
+		"page_size":   query.PageSize,
+		"total_pages": (total + int64(query.PageSize) - 1) / int64(query.PageSize),
+	})
 }
 
-func (h *NoteHandler) Delete(c *gin.Context) {
+// Move 把自己的笔记移到某个笔记本或移出笔记本
+func (h *NoteHandler) Move(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req MoveNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	moved, err := h.notes.Move(req.NoteIDs, userID, req.NotebookID)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID")
+		h.respondError(c, err)
 		return
 	}
+	ids := make([]uint, len(moved))
+	for i := range moved {
+		after := moved[i]
+		after.NotebookID = req.NotebookID
+		recordAudit(h.audit, c, constants.AuditActionUpdate, constants.ResourceNote, after.ID, &moved[i], &after)
+		ids[i] = after.ID
+	}
+	common.Success(c, gin.H{"moved": ids})
+}
 
-	if err := h.service.Delete(uint(id), userID); err != nil {
+// Reorder 按请求中的顺序设置笔记的手动排序位置，配合 sort=manual 查询使用
+func (h *NoteHandler) Reorder(c *gin.Context) {
+	var req ReorderNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+	if err := h.notes.Reorder(req.NoteIDs, middleware.GetCurrentUserID(c)); err != nil {
 		common.InternalServerError(c, err.Error())
 		return
 	}
-	common.SuccessWithMessage(c, "Note deleted successfully", nil)
+	common.SuccessWithMessage(c, "Notes reordered successfully", nil)
+}
+
+// Archive 归档笔记，归档的笔记默认不出现在列表中
+func (h *NoteHandler) Archive(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// Unarchive 取消归档
+func (h *NoteHandler) Unarchive(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *NoteHandler) setArchived(c *gin.Context, archived bool) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	before, err := h.notes.GetByID(id, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	after, err := h.notes.SetArchived(id, userID, archived)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, constants.AuditActionUpdate, constants.ResourceNote, id, before, after)
+	setETag(c, after)
+	common.Success(c, renderContent(c, h.files, after))
 }
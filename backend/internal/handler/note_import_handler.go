@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NoteImportHandler struct {
+	service *service.NoteImportService
+}
+
+func NewNoteImportHandler(services *service.Services) *NoteImportHandler {
+	return &NoteImportHandler{
+		service: services.NoteImport,
+	}
+}
+
+// Import 从 Markdown 文件夹(含 Obsidian 仓库)的ZIP或 Evernote 的 .enex 导入笔记，上传字段为 file。
+// 查询参数：format(markdown、enex，默认按扩展名)；folders(notebooks 转为笔记本，tags 转为标签)；
+// conflict(skip、overwrite、duplicate，按标题匹配已有笔记)；notebook_id 导入到该笔记本下；
+// dry_run=true 只返回将要执行的操作
+func (h *NoteImportHandler) Import(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.AppConfig.Storage.MaxImportSize)
+
+	opts := service.NoteImportOptions{
+		Format:   c.Query("format"),
+		Folders:  c.Query("folders"),
+		Conflict: c.DefaultQuery("conflict", service.ConflictSkip),
+	}
+	opts.DryRun, _ = strconv.ParseBool(c.Query("dry_run"))
+	if notebook := c.Query("notebook_id"); notebook != "" {
+		id, err := strconv.ParseUint(notebook, 10, 32)
+		if err != nil {
+			common.BadRequest(c, "Invalid notebook_id")
+			return
+		}
+		notebookID := uint(id)
+		opts.NotebookID = &notebookID
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			common.Error(c, http.StatusRequestEntityTooLarge, "File exceeds maximum import size")
+			return
+		}
+		common.BadRequest(c, "No file uploaded")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		common.BadRequest(c, "Cannot read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.WithLogger(reqLog(c)).Import(userID, header.Filename, file, header.Size, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, common.ErrInvalidArchive), errors.Is(err, common.ErrInvalidInput):
+			common.BadRequest(c, err.Error())
+		default:
+			reqLog(c).Error("Note import failed", "error", err, "user_id", userID)
+			common.InternalServerError(c, "Import failed")
+		}
+		return
+	}
+
+	common.Success(c, report)
+}
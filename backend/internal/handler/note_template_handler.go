@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+)
+
+type NoteTemplateHandler struct {
+	*BaseHandler[model.NoteTemplate, *model.NoteTemplate]
+}
+
+func NewNoteTemplateHandler(services *service.Services) *NoteTemplateHandler {
+	return &NoteTemplateHandler{
+		BaseHandler: NewBaseHandler[model.NoteTemplate](services.Templates, services, "Note template"),
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotebookHandler struct {
+	*BaseHandler[model.Notebook, *model.Notebook]
+	notebooks *service.NotebookService
+}
+
+func NewNotebookHandler(services *service.Services) *NotebookHandler {
+	return &NotebookHandler{
+		BaseHandler: NewBaseHandler[model.Notebook](services.Notebooks, services, "Notebook"),
+		notebooks:   services.Notebooks,
+	}
+}
+
+// MoveNotebookRequest 移动笔记本的请求，parent_id 为 null 表示移到顶层
+type MoveNotebookRequest struct {
+	ParentID  *uint `json:"parent_id"`
+	SortOrder int   `json:"sort_order"`
+}
+
+// GetTree 以树的形式获取全部笔记本及其中的笔记数
+func (h *NotebookHandler) GetTree(c *gin.Context) {
+	tree, err := h.notebooks.Tree(middleware.GetCurrentUserID(c))
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, tree)
+}
+
+// Move 移动笔记本到其他笔记本下或顶层，并设置在同一层级中的排序位置
+func (h *NotebookHandler) Move(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req MoveNotebookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	before, err := h.notebooks.GetByID(id, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	after, err := h.notebooks.Move(id, userID, req.ParentID, req.SortOrder)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	recordAudit(h.audit, c, constants.AuditActionUpdate, constants.ResourceNotebook, id, before, after)
+	common.Success(c, after)
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/oidc"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateTTL 授权请求的有效期
+const oidcStateTTL = 10 * time.Minute
+
+// pendingOIDCLogin 一次进行中的授权请求
+type pendingOIDCLogin struct {
+	CodeVerifier string
+	Nonce        string
+	LinkUserID   uint // 非0时授权完成后把身份关联到该用户，见 Link
+}
+
+type OIDCHandler struct {
+	cfg         config.OIDCConfig
+	httpClient  *http.Client
+	states      middleware.RateLimitStore
+	userService *service.UserService
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCHandler(cfg config.OIDCConfig, httpClient *http.Client, users *service.UserService) *OIDCHandler {
+	return &OIDCHandler{
+		cfg:         cfg,
+		httpClient:  httpClient,
+		states:      middleware.NewMemoryStore(),
+		userService: users,
+	}
+}
+
+// getProvider 懒加载身份提供方，服务发现失败时下次请求重试
+func (h *OIDCHandler) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.provider != nil {
+		return h.provider, nil
+	}
+	provider, err := oidc.NewProvider(ctx, oidc.Config{
+		IssuerURL:    h.cfg.IssuerURL,
+		ClientID:     h.cfg.ClientID,
+		ClientSecret: h.cfg.ClientSecret,
+		RedirectURL:  h.cfg.RedirectURL,
+		Scopes:       h.cfg.Scopes,
+	}, h.httpClient)
+	if err != nil {
+		return nil, err
+	}
+	h.provider = provider
+	return provider, nil
+}
+
+// Login 跳转到身份提供方的授权页面
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, ok := h.authorize(c, 0)
+	if !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Link 已登录的用户把OIDC身份关联到自己的账号，返回授权页面的地址，由前端跳转。
+// 关联只能通过这里显式发起，登录时不会按邮箱自动关联已有账号
+func (h *OIDCHandler) Link(c *gin.Context) {
+	authURL, ok := h.authorize(c, middleware.GetCurrentUserID(c))
+	if !ok {
+		return
+	}
+	common.Success(c, gin.H{"url": authURL})
+}
+
+// authorize 创建授权请求并返回授权页面的地址，linkUserID 非0时授权完成后关联到该用户
+func (h *OIDCHandler) authorize(c *gin.Context, linkUserID uint) (string, bool) {
+	provider, err := h.getProvider(c.Request.Context())
+	if err != nil {
+		reqLog(c).Error("OIDC provider unavailable", "error", err)
+		common.Error(c, http.StatusBadGateway, "Identity provider unavailable")
+		return "", false
+	}
+
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return "", false
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return "", false
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return "", false
+	}
+
+	h.states.Set("oidc:"+state, pendingOIDCLogin{CodeVerifier: verifier, Nonce: nonce, LinkUserID: linkUserID}, oidcStateTTL)
+	return provider.AuthCodeURL(state, nonce, challenge), true
+}
+
+// Callback 处理身份提供方回调：校验state、换取令牌、验证ID Token并签发本系统的JWT
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		reqLog(c).Warn("OIDC authorization failed", "error", errCode, "error_description", c.Query("error_description"))
+		common.Unauthorized(c, "Authorization failed: "+errCode)
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		common.BadRequest(c, "Missing state or code")
+		return
+	}
+
+	value, ok := h.states.Get("oidc:" + state)
+	if !ok {
+		common.BadRequest(c, "Invalid or expired state")
+		return
+	}
+	h.states.Delete("oidc:" + state) // state 只能使用一次
+	pending := value.(pendingOIDCLogin)
+
+	provider, err := h.getProvider(c.Request.Context())
+	if err != nil {
+		reqLog(c).Error("OIDC provider unavailable", "error", err)
+		common.Error(c, http.StatusBadGateway, "Identity provider unavailable")
+		return
+	}
+
+	token, err := provider.Exchange(c.Request.Context(), code, pending.CodeVerifier)
+	if err != nil {
+		reqLog(c).Warn("OIDC code exchange failed", "error", err)
+		common.Unauthorized(c, "Failed to exchange authorization code")
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(c.Request.Context(), token.IDToken, pending.Nonce)
+	if err != nil {
+		reqLog(c).Warn("OIDC id token verification failed", "error", err)
+		common.Unauthorized(c, "Invalid ID token")
+		return
+	}
+
+	identity := service.OIDCIdentity{
+		Issuer:            claims.Issuer,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+	}
+	var user *model.User
+	if pending.LinkUserID != 0 {
+		user, err = h.userService.LinkOIDCIdentity(pending.LinkUserID, identity)
+	} else {
+		user, err = h.userService.FindOrCreateOIDCUser(identity)
+	}
+	if err != nil {
+		reqLog(c).Warn("OIDC user provisioning failed", "error", err, "sub", claims.Subject)
+		switch {
+		case errors.Is(err, common.ErrUnauthorized), errors.Is(err, common.ErrInvalidInput):
+			common.Forbidden(c, err.Error())
+		case errors.Is(err, common.ErrUserAlreadyExists), errors.Is(err, common.ErrDuplicateEntry):
+			common.Conflict(c, err.Error())
+		default:
+			common.InternalServerError(c, "Failed to provision user")
+		}
+		return
+	}
+	if pending.LinkUserID != 0 {
+		reqLog(c).Info("OIDC identity linked", "user_id", user.ID, "sub", claims.Subject)
+	}
+
+	jwtToken, err := middleware.GenerateToken(user.ID, user.Username)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	reqLog(c).Info("OIDC login succeeded", "user_id", user.ID, "username", user.Username)
+
+	if h.cfg.FrontendRedirect != "" {
+		fragment := url.Values{}
+		fragment.Set("token", jwtToken)
+		c.Redirect(http.StatusFound, h.cfg.FrontendRedirect+"#"+fragment.Encode())
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Token:   jwtToken,
+		User:    UserProfile{ID: user.ID, Username: user.Username, Email: user.Email},
+		Message: constants.MsgLoginSuccess,
+	})
+}
@@ -3,6 +3,8 @@ package handler
 import (
 	"net/http"
 
+	"nexushub-personal/internal/common"
+
 	"github.com/gin-gonic/gin"
 	"github.com/mmcdole/gofeed"
 )
@@ -20,14 +22,14 @@ type RSSRequest struct {
 func (h *RSSHandler) GetFeed(c *gin.Context) {
 	var req RSSRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		common.LegacyError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	fp := gofeed.NewParser()
 	feed, err := fp.ParseURL(req.URL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse RSS feed"})
+		common.LegacyError(c, http.StatusInternalServerError, "Failed to parse RSS feed")
 		return
 	}
 
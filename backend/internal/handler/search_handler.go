@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHandler 全局搜索
+type SearchHandler struct {
+	search *service.SearchService
+}
+
+func NewSearchHandler(services *service.Services) *SearchHandler {
+	return &SearchHandler{search: services.Search}
+}
+
+// Search 在全部资源中搜索，q 支持 type:、tag:、before:、after: 过滤条件，limit 为每组返回的数量
+func (h *SearchHandler) Search(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	results, err := h.search.Search(userID, c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, common.ErrInvalidInput) {
+			common.BadRequest(c, err.Error())
+			return
+		}
+		reqLog(c).Error("Search failed", "error", err, "user_id", userID)
+		common.InternalServerError(c, "Search failed")
+		return
+	}
+	common.Success(c, results)
+}
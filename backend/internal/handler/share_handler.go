@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareHandler struct {
+	service *service.ShareService
+	files   *service.FileService
+	guard   *middleware.LoginGuard
+}
+
+// NewShareHandler 创建分享处理器，guard 限制公开链接的密码尝试
+func NewShareHandler(services *service.Services, guard *middleware.LoginGuard) *ShareHandler {
+	return &ShareHandler{
+		service: services.Shares,
+		files:   services.Files,
+		guard:   guard,
+	}
+}
+
+type ShareRequest struct {
+	ResourceType string `json:"resource_type" binding:"required,oneof=note file collection post"`
+	ResourceID   uint   `json:"resource_id" binding:"required"`
+	Username     string `json:"username" binding:"required"`
+	Permission   string `json:"permission" binding:"omitempty,oneof=read edit"`
+}
+
+type ShareLinkRequest struct {
+	ResourceType string     `json:"resource_type" binding:"required,oneof=note file collection post"`
+	ResourceID   uint       `json:"resource_id" binding:"required"`
+	Password     string     `json:"password"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// ShareUnlockRequest 解锁带密码的公开链接
+type ShareUnlockRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ShareLinkResponse 公开链接响应，附带可访问的URL
+type ShareLinkResponse struct {
+	model.Share
+	URL string `json:"url"`
+}
+
+// GetOwned 获取当前用户创建的分享
+func (h *ShareHandler) GetOwned(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	shares, err := h.service.ListOwned(userID)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, shares)
+}
+
+// GetReceived 获取分享给当前用户的记录
+func (h *ShareHandler) GetReceived(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	shares, err := h.service.ListReceived(userID)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, shares)
+}
+
+// ShareWithUser 将记录分享给另一个用户
+func (h *ShareHandler) ShareWithUser(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	share, err := h.service.ShareWithUser(userID, req.ResourceType, req.ResourceID, req.Username, req.Permission)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	reqLog(c).Info("Shared resource with user", "resource_type", share.ResourceType, "resource_id", share.ResourceID,
+		"shared_with_id", share.SharedWithID, "permission", share.Permission, "owner_id", userID)
+	common.Created(c, share)
+}
+
+// CreateLink 创建公开分享链接
+func (h *ShareHandler) CreateLink(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req ShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		common.BadRequest(c, "expires_at must be in the future")
+		return
+	}
+
+	share, err := h.service.CreateLink(userID, req.ResourceType, req.ResourceID, req.Password, req.ExpiresAt)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	reqLog(c).Info("Created public link", "resource_type", share.ResourceType, "resource_id", share.ResourceID, "owner_id", userID)
+	common.Created(c, ShareLinkResponse{Share: *share, URL: "/s/" + share.Token})
+}
+
+// Revoke 撤销分享或公开链接
+func (h *ShareHandler) Revoke(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.BadRequest(c, constants.ErrInvalidID)
+		return
+	}
+
+	if err := h.service.Revoke(uint(id), userID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	common.SuccessWithMessage(c, "Share revoked successfully", nil)
+}
+
+// GetPublic 通过公开链接访问资源(无需登录)
+func (h *ShareHandler) GetPublic(c *gin.Context) {
+	share, resource, ok := h.resolveLink(c)
+	if !ok {
+		return
+	}
+
+	common.Success(c, gin.H{
+		"resource_type": share.ResourceType,
+		"expires_at":    share.ExpiresAt,
+		"resource":      renderContentFor(h.files.WithLogger(reqLog(c)), resource, sharedAttachment(share)),
+	})
+}
+
+// sharedAttachment 公开链接页面只为分享的笔记自身的附件生成签名链接
+func sharedAttachment(share *model.Share) func(file *model.File) bool {
+	return func(file *model.File) bool {
+		return share.ResourceType == constants.ResourceNote && file.NoteID != nil && *file.NoteID == share.ResourceID
+	}
+}
+
+// DownloadPublic 通过公开链接下载文件(无需登录)
+func (h *ShareHandler) DownloadPublic(c *gin.Context) {
+	share, resource, ok := h.resolveLink(c)
+	if !ok {
+		return
+	}
+
+	file, ok := resource.(*model.File)
+	if !ok || share.ResourceType != constants.ResourceFile {
+		common.BadRequest(c, "Shared resource is not a file")
+		return
+	}
+
+	reqLog(c).Info("Public file download", "id", file.ID, "filename", file.FileName, "share_id", share.ID)
+	serveFileContent(c, h.files, file, "attachment")
+}
+
+// shareAccessCookie 解锁公开链接后保存访问凭证的Cookie，路径限定为该链接
+const shareAccessCookie = "share_access"
+
+// UnlockPublic 校验公开链接的密码，签发短期有效的访问凭证：写入限定在该链接路径下的 HttpOnly Cookie，
+// 同时在响应中返回，跨域前端可通过 X-Share-Access 请求头或 access 查询参数携带
+func (h *ShareHandler) UnlockPublic(c *gin.Context) {
+	var req ShareUnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+	token := c.Param("token")
+	if !h.checkGuard(c, token) {
+		return
+	}
+	access, expiresAt, err := h.service.UnlockLink(token, req.Password, time.Now())
+	if err != nil {
+		h.handlePasswordError(c, token, err)
+		return
+	}
+	h.guard.Succeed(guardKey(c, token), c.ClientIP())
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(shareAccessCookie, access, int(time.Until(expiresAt).Seconds()), "/s/"+token, "", c.Request.TLS != nil, true)
+	common.Success(c, gin.H{"access_token": access, "expires_at": expiresAt})
+}
+
+// resolveLink 解析公开链接。带密码的链接接受 X-Share-Password 请求头，或 UnlockPublic 签发的访问凭证
+// (X-Share-Access 请求头、access 查询参数或Cookie)。密码错误计入失败次数，多次失败后退避或锁定，
+// 与登录的防爆破策略相同
+func (h *ShareHandler) resolveLink(c *gin.Context) (*model.Share, interface{}, bool) {
+	token, password := c.Param("token"), sharePassword(c)
+	if access := shareAccess(c); password == "" && access != "" {
+		share, resource, err := h.service.ResolveLinkWithAccess(token, access)
+		if err != nil {
+			h.handleError(c, err)
+			return nil, nil, false
+		}
+		return share, resource, true
+	}
+
+	if !h.checkGuard(c, token) {
+		return nil, nil, false
+	}
+	share, resource, err := h.service.ResolveLink(token, password)
+	if err != nil {
+		h.handlePasswordError(c, token, err)
+		return nil, nil, false
+	}
+	if password != "" {
+		h.guard.Succeed(guardKey(c, token), c.ClientIP())
+	}
+	return share, resource, true
+}
+
+// guardKey 失败次数按链接令牌和访问者IP组合计数，一个访问者猜错密码不会锁定其他收到链接的人；
+// 同一IP对所有链接的失败次数另行累计
+func guardKey(c *gin.Context, token string) string {
+	return token + "@" + c.ClientIP()
+}
+
+// checkGuard 访问者因多次猜错密码处于退避或锁定期间时返回429
+func (h *ShareHandler) checkGuard(c *gin.Context, token string) bool {
+	if _, wait := h.guard.Check(guardKey(c, token), c.ClientIP()); wait > 0 {
+		middleware.SetRetryAfter(c, wait)
+		common.TooManyRequests(c, constants.ErrTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// handlePasswordError 密码错误时记录失败次数，再按 handleError 响应
+func (h *ShareHandler) handlePasswordError(c *gin.Context, token string, err error) {
+	if errors.Is(err, common.ErrSharePasswordInvalid) {
+		h.guard.Fail(guardKey(c, token), c.ClientIP())
+		reqLog(c).Warn("Wrong password for public link", "client_ip", c.ClientIP())
+	}
+	h.handleError(c, err)
+}
+
+// sharePassword 从 X-Share-Password 请求头读取分享密码。不接受查询参数，
+// 避免密码出现在请求日志、代理和浏览器历史记录中
+func sharePassword(c *gin.Context) string {
+	return c.GetHeader("X-Share-Password")
+}
+
+// shareAccess 读取解锁后的访问凭证，依次查找 X-Share-Access 请求头、access 查询参数和Cookie
+func shareAccess(c *gin.Context) string {
+	if access := c.GetHeader("X-Share-Access"); access != "" {
+		return access
+	}
+	if access := c.Query("access"); access != "" {
+		return access
+	}
+	access, _ := c.Cookie(shareAccessCookie)
+	return access
+}
+
+func (h *ShareHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, common.ErrResourceNotFound), errors.Is(err, common.ErrUserNotFound):
+		common.NotFound(c, err.Error())
+	case errors.Is(err, common.ErrShareExpired):
+		common.Error(c, http.StatusGone, err.Error())
+	case errors.Is(err, common.ErrSharePasswordRequired), errors.Is(err, common.ErrSharePasswordInvalid):
+		common.Unauthorized(c, err.Error())
+	case errors.Is(err, common.ErrShareWithSelf), errors.Is(err, common.ErrInvalidResourceType):
+		common.BadRequest(c, err.Error())
+	default:
+		common.InternalServerError(c, err.Error())
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagHandler 标签列表、重命名与合并
+type TagHandler struct {
+	tags *service.TagService
+}
+
+func NewTagHandler(services *service.Services) *TagHandler {
+	return &TagHandler{tags: services.Tags}
+}
+
+// RenameTagRequest 重命名标签请求
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// MergeTagsRequest 合并标签请求，sources 中的标签合并到 target
+type MergeTagsRequest struct {
+	Target  uint   `json:"target" binding:"required"`
+	Sources []uint `json:"sources" binding:"required,min=1"`
+}
+
+// respondTagError 标签不存在返回404，名称无效返回400，其余返回500
+func respondTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, common.ErrTagNotFound):
+		common.NotFound(c, "Tag not found")
+	case errors.Is(err, common.ErrInvalidInput):
+		common.BadRequest(c, err.Error())
+	default:
+		reqLog(c).Error("Tag operation failed", "error", err)
+		common.InternalServerError(c, err.Error())
+	}
+}
+
+// GetAll 获取当前用户的全部标签及各类资源上的使用次数
+func (h *TagHandler) GetAll(c *gin.Context) {
+	tags, err := h.tags.List(middleware.GetCurrentUserID(c))
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+	common.Success(c, tags)
+}
+
+// Rename 重命名标签并改写所有使用它的资源；新名称与已有标签相同时合并到该标签
+func (h *TagHandler) Rename(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	tag, err := h.tags.WithLogger(reqLog(c)).Rename(userID, id, req.Name)
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+	common.Success(c, tag)
+}
+
+// Merge 把多个标签合并为一个
+func (h *TagHandler) Merge(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, err.Error())
+		return
+	}
+
+	tag, err := h.tags.WithLogger(reqLog(c)).Merge(userID, req.Target, req.Sources)
+	if err != nil {
+		respondTagError(c, err)
+		return
+	}
+	common.Success(c, tag)
+}
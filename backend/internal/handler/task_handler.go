@@ -1,101 +1,16 @@
 package handler
 
 import (
-	"net/http"
-	"nexushub-personal/internal/middleware"
 	"nexushub-personal/internal/model"
 	"nexushub-personal/internal/service"
-	"strconv"
-
-	"github.com/gin-gonic/gin"
 )
 
 type TaskHandler struct {
-	service *service.TaskService
+	*BaseHandler[model.Task, *model.Task]
 }
 
-func NewTaskHandler() *TaskHandler {
+func NewTaskHandler(services *service.Services) *TaskHandler {
 	return &TaskHandler{
-		service: service.NewTaskService(),
-	}
-}
-
-func (h *TaskHandler) GetAll(c *gin.Context) {
-	userID := middleware.GetCurrentUserID(c)
-	tasks, err := h.service.GetAll(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, tasks)
-}
-
-func (h *TaskHandler) GetByID(c *gin.Context) {
-	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
-		return
-	}
-
-	task, err := h.service.GetByID(uint(id), userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		return
-	}
-	c.JSON(http.StatusOK, task)
-}
-
-func (h *TaskHandler) Create(c *gin.Context) {
-	userID := middleware.GetCurrentUserID(c)
-	var task model.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	task.UserID = userID
-	if err := h.service.Create(&task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusCreated, task)
-}
-
-func (h *TaskHandler) Update(c *gin.Context) {
-	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
-		return
-	}
-
-	var task model.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	task.ID = uint(id)
-	task.UserID = userID
-	if err := h.service.Update(&task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, task)
-}
-
-func (h *TaskHandler) Delete(c *gin.Context) {
-	userID := middleware.GetCurrentUserID(c)
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
-		return
-	}
-
-	if err := h.service.Delete(uint(id), userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		BaseHandler: NewBaseHandler[model.Task](services.Tasks, services, "Task"),
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
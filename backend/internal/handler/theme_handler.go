@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"nexushub-personal/internal/common"
 	"nexushub-personal/internal/middleware"
 	"nexushub-personal/internal/model"
 	"nexushub-personal/internal/service"
@@ -13,9 +14,9 @@ type ThemeHandler struct {
 	service *service.ThemeService
 }
 
-func NewThemeHandler() *ThemeHandler {
+func NewThemeHandler(services *service.Services) *ThemeHandler {
 	return &ThemeHandler{
-		service: service.NewThemeService(),
+		service: services.Themes,
 	}
 }
 
@@ -23,7 +24,7 @@ func (h *ThemeHandler) Get(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	theme, err := h.service.Get(userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Theme not found"})
+		common.LegacyError(c, http.StatusNotFound, "Theme not found")
 		return
 	}
 	c.JSON(http.StatusOK, theme)
@@ -33,13 +34,13 @@ func (h *ThemeHandler) Update(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	var theme model.Theme
 	if err := c.ShouldBindJSON(&theme); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		common.LegacyError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	theme.UserID = userID
 	if err := h.service.Update(&theme); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		common.LegacyError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, theme)
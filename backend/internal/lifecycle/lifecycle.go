@@ -0,0 +1,103 @@
+// Package lifecycle 管理进程内长期运行的组件(HTTP服务、定时任务、数据库连接池)的启停顺序。
+// 组件按注册顺序启动，关闭时按相反顺序停止：先停止接收请求，再停止后台任务，最后关闭数据库。
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"nexushub-personal/internal/logger"
+)
+
+// ErrStopped Manager 已关闭后再注册组件
+var ErrStopped = errors.New("lifecycle manager already stopped")
+
+type component struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager 组件生命周期管理器
+type Manager struct {
+	log logger.Logger
+
+	mu         sync.Mutex
+	components []component
+	stopped    bool
+}
+
+// New 创建生命周期管理器
+func New(log logger.Logger) *Manager {
+	return &Manager{log: log}
+}
+
+// OnStop 注册一个关闭时调用的停止函数，如 http.Server.Shutdown 或关闭数据库连接池
+func (m *Manager) OnStop(name string, stop func(ctx context.Context) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return ErrStopped
+	}
+	m.components = append(m.components, component{name: name, stop: stop})
+	return nil
+}
+
+// Go 在独立的goroutine中运行后台任务，run 需要在ctx取消后尽快返回；
+// 关闭时取消ctx并等待run返回(或等到关闭超时)
+func (m *Manager) Go(name string, run func(ctx context.Context)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	err := m.OnStop(name, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return fmt.Errorf("did not stop in time: %w", stopCtx.Err())
+		}
+	})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				m.log.Error("Background job panicked", "job", name, "panic", r)
+			}
+		}()
+		run(ctx)
+	}()
+	return nil
+}
+
+// Shutdown 按注册的相反顺序停止所有组件。某个组件停止失败或超时不会阻止后续组件停止，
+// 返回所有错误的合并结果
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return nil
+	}
+	m.stopped = true
+	components := m.components
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		m.log.Info("Stopping component", "name", c.name)
+		if err := c.stop(ctx); err != nil {
+			m.log.Error("Failed to stop component", "name", c.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		m.log.Info("Stopped component", "name", c.name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/logger"
+)
+
+func TestShutdownStopsInReverseOrder(t *testing.T) {
+	m := New(logger.Discard())
+	var order []string
+	m.OnStop("database", func(context.Context) error {
+		order = append(order, "database")
+		return nil
+	})
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		order = append(order, "worker")
+	})
+	m.OnStop("server", func(context.Context) error {
+		order = append(order, "server")
+		return errors.New("boom")
+	})
+
+	err := m.Shutdown(context.Background())
+	if err == nil {
+		t.Fatal("expected the server error to be returned")
+	}
+	if want := []string{"server", "worker", "database"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("stop order = %v, want %v", order, want)
+	}
+	if err := m.OnStop("late", func(context.Context) error { return nil }); !errors.Is(err, ErrStopped) {
+		t.Fatalf("register after shutdown: %v", err)
+	}
+}
+
+func TestShutdownTimesOutStuckWorker(t *testing.T) {
+	m := New(logger.Discard())
+	release := make(chan struct{})
+	defer close(release)
+	m.Go("stuck", func(context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
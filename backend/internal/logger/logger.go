@@ -1,11 +1,20 @@
+// Package logger 基于 log/slog 的分级结构化日志。
+// Info/Warn 等方法接受消息和 slog 风格的属性键值对，也可通过 Slog() 直接使用 slog.Logger；
+// 请求ID等上下文信息由 FromContext 返回的 Logger 自动附加到每一行。
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,128 +28,272 @@ const (
 	FATAL
 )
 
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// levelFatal slog中没有FATAL级别，使用比ERROR更高的自定义级别
+const levelFatal = slog.LevelError + 4
+
+// Options 日志初始化参数
+type Options struct {
+	Dir        string   // 日志目录
+	Level      LogLevel // 最低输出级别
+	Format     string   // text | json
+	MaxSizeMB  int      // 单个文件大小上限(MB)，超过后切分，0表示不限制
+	MaxAgeDays int      // 轮转后的文件保留天数，0表示永久保留
+	Compress   bool     // 轮转后的文件是否gzip压缩
+}
+
 var (
-	debugLog *log.Logger
-	infoLog  *log.Logger
-	warnLog  *log.Logger
-	errorLog *log.Logger
-	fatalLog *log.Logger
-
-	logFile    *os.File
-	logLevel   LogLevel = INFO
-	isInitialized bool
+	levelVar slog.LevelVar
+	base     atomic.Pointer[slog.Logger]
+
+	mu      sync.Mutex
+	logFile *RotatingFile
 )
 
-// Init 初始化日志系统
-func Init(logDir string, level LogLevel) error {
-	if isInitialized {
+func init() {
+	// Init 之前的日志全部丢弃，与之前未初始化时不输出的行为一致
+	base.Store(slog.New(slog.DiscardHandler))
+	levelVar.Set(slog.LevelInfo)
+}
+
+// Init 初始化日志系统：输出到控制台和按天/大小自动轮转的日志文件，
+// 并接管标准库 log 包的输出
+func Init(opts Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if logFile != nil {
 		return nil
 	}
 
-	// 创建日志目录
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %v", err)
+	file, err := NewRotatingFile(opts.Dir, "app", opts.MaxSizeMB, opts.MaxAgeDays, opts.Compress)
+	if err != nil {
+		return err
 	}
+	logFile = file
+	SetLevel(opts.Level)
 
-	// 创建日志文件
-	logFilePath := filepath.Join(logDir, fmt.Sprintf("app_%s.log", time.Now().Format("2006-01-02")))
-	var err error
-	logFile, err = os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return fmt.Errorf("failed to open log file: %v", err)
+	l := slog.New(NewHandler(io.MultiWriter(os.Stdout, file), opts.Format))
+	base.Store(l)
+	slog.SetDefault(l)
+
+	Info("Logger initialized successfully")
+	return nil
+}
+
+// NewHandler 创建与全局日志相同格式的 slog.Handler，级别跟随 SetLevel
+func NewHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       &levelVar,
+		ReplaceAttr: replaceAttr,
 	}
+	var h slog.Handler
+	if format == FormatJSON {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return contextHandler{h}
+}
 
-	// 设置日志输出到文件和控制台
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
+// replaceAttr 源码位置只保留文件名和行号，并为自定义的FATAL级别命名
+func replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			a.Value = slog.StringValue(filepath.Base(src.File) + ":" + strconv.Itoa(src.Line))
+		}
+	case slog.LevelKey:
+		if lvl, ok := a.Value.Any().(slog.Level); ok && lvl >= levelFatal {
+			a.Value = slog.StringValue("FATAL")
+		}
+	}
+	return a
+}
 
-	// 初始化不同级别的日志记录器
-	debugLog = log.New(multiWriter, "[DEBUG] ", log.Ldate|log.Ltime|log.Lshortfile)
-	infoLog = log.New(multiWriter, "[INFO]  ", log.Ldate|log.Ltime|log.Lshortfile)
-	warnLog = log.New(multiWriter, "[WARN]  ", log.Ldate|log.Ltime|log.Lshortfile)
-	errorLog = log.New(multiWriter, "[ERROR] ", log.Ldate|log.Ltime|log.Lshortfile)
-	fatalLog = log.New(multiWriter, "[FATAL] ", log.Ldate|log.Ltime|log.Lshortfile)
+type requestIDKey struct{}
 
-	logLevel = level
-	isInitialized = true
+// WithRequestID 把请求ID放入context，之后使用该context记录的日志都会带上 request_id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
 
-	Info("Logger initialized successfully")
-	return nil
+// RequestID 返回context中的请求ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler 从context中取出请求ID附加到日志记录
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Slog 返回全局的 slog.Logger，用于记录带属性的结构化日志
+func Slog() *slog.Logger {
+	return base.Load()
 }
 
 // Close 关闭日志文件
 func Close() error {
-	if logFile != nil {
-		return logFile.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if logFile == nil {
+		return nil
 	}
-	return nil
+	err := logFile.Close()
+	logFile = nil
+	base.Store(slog.New(slog.DiscardHandler))
+	return err
 }
 
-// Debug 调试日志
-func Debug(format string, v ...interface{}) {
-	if logLevel <= DEBUG && isInitialized {
-		debugLog.Output(2, fmt.Sprintf(format, v...))
+// logAttrs 记录一条日志，args 为 slog 风格的键值对或 slog.Attr；
+// skip 为 runtime.Callers 的跳过层数，保证源码位置指向调用方
+func logAttrs(ctx context.Context, level slog.Level, skip int, msg string, args []any) {
+	l := base.Load()
+	if !l.Enabled(ctx, level) {
+		return
 	}
+	var pcs [1]uintptr
+	runtime.Callers(skip, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.Handler().Handle(ctx, r)
+}
+
+// Debug 调试日志，args 为属性键值对，如 Debug("Cache miss", "key", key)
+func Debug(msg string, args ...any) {
+	logAttrs(context.Background(), slog.LevelDebug, 3, msg, args)
 }
 
 // Info 信息日志
-func Info(format string, v ...interface{}) {
-	if logLevel <= INFO && isInitialized {
-		infoLog.Output(2, fmt.Sprintf(format, v...))
-	}
+func Info(msg string, args ...any) {
+	logAttrs(context.Background(), slog.LevelInfo, 3, msg, args)
 }
 
 // Warn 警告日志
-func Warn(format string, v ...interface{}) {
-	if logLevel <= WARN && isInitialized {
-		warnLog.Output(2, fmt.Sprintf(format, v...))
-	}
+func Warn(msg string, args ...any) {
+	logAttrs(context.Background(), slog.LevelWarn, 3, msg, args)
 }
 
 // Error 错误日志
-func Error(format string, v ...interface{}) {
-	if logLevel <= ERROR && isInitialized {
-		errorLog.Output(2, fmt.Sprintf(format, v...))
-	}
+func Error(msg string, args ...any) {
+	logAttrs(context.Background(), slog.LevelError, 3, msg, args)
 }
 
 // Fatal 致命错误日志(会导致程序退出)
-func Fatal(format string, v ...interface{}) {
-	if isInitialized {
-		fatalLog.Output(2, fmt.Sprintf(format, v...))
-	}
+func Fatal(msg string, args ...any) {
+	logAttrs(context.Background(), levelFatal, 3, msg, args)
+	Close()
 	os.Exit(1)
 }
 
-// SetLevel 设置日志级别
+// SetLevel 设置日志级别，可在运行时热更新
 func SetLevel(level LogLevel) {
-	logLevel = level
+	levelVar.Set(toSlogLevel(level))
 }
 
 // GetLevel 获取当前日志级别
 func GetLevel() LogLevel {
-	return logLevel
+	switch l := levelVar.Level(); {
+	case l < slog.LevelInfo:
+		return DEBUG
+	case l < slog.LevelWarn:
+		return INFO
+	case l < slog.LevelError:
+		return WARN
+	case l < levelFatal:
+		return ERROR
+	}
+	return FATAL
 }
 
-// RotateLog 轮转日志文件(每天创建新文件)
-func RotateLog(logDir string) error {
-	if logFile != nil {
-		logFile.Close()
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case DEBUG:
+		return slog.LevelDebug
+	case INFO:
+		return slog.LevelInfo
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
 	}
+	return levelFatal
+}
 
-	logFilePath := filepath.Join(logDir, fmt.Sprintf("app_%s.log", time.Now().Format("2006-01-02")))
-	var err error
-	logFile, err = os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return fmt.Errorf("failed to rotate log file: %v", err)
+// ParseLevel 解析日志级别名称(debug/info/warn/error，不区分大小写)
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
 	}
+	return INFO, fmt.Errorf("unknown log level %q", name)
+}
 
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-	debugLog.SetOutput(multiWriter)
-	infoLog.SetOutput(multiWriter)
-	warnLog.SetOutput(multiWriter)
-	errorLog.SetOutput(multiWriter)
-	fatalLog.SetOutput(multiWriter)
+// Logger 日志接口，服务通过构造函数注入，测试中可替换为 Discard。
+// args 与 slog 相同，为交替的键值对或 slog.Attr
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
 
-	Info("Log rotated successfully")
-	return nil
+// ctxLogger 写入全局日志，并附加context中的请求ID
+type ctxLogger struct {
+	ctx context.Context
+}
+
+func (l ctxLogger) Debug(msg string, args ...any) { logAttrs(l.ctx, slog.LevelDebug, 3, msg, args) }
+func (l ctxLogger) Info(msg string, args ...any)  { logAttrs(l.ctx, slog.LevelInfo, 3, msg, args) }
+func (l ctxLogger) Warn(msg string, args ...any)  { logAttrs(l.ctx, slog.LevelWarn, 3, msg, args) }
+func (l ctxLogger) Error(msg string, args ...any) { logAttrs(l.ctx, slog.LevelError, 3, msg, args) }
+
+// Default 返回写入全局日志文件的 Logger
+func Default() Logger {
+	return ctxLogger{context.Background()}
+}
+
+// FromContext 返回附加了ctx中请求ID的 Logger，用于请求处理过程中的日志
+func FromContext(ctx context.Context) Logger {
+	return ctxLogger{ctx}
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...any) {}
+func (discardLogger) Info(string, ...any)  {}
+func (discardLogger) Warn(string, ...any)  {}
+func (discardLogger) Error(string, ...any) {}
+
+// Discard 返回丢弃所有输出的 Logger
+func Discard() Logger {
+	return discardLogger{}
 }
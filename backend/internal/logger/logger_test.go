@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLogIncludesRequestIDAndCaller(t *testing.T) {
+	var buf bytes.Buffer
+	prev := base.Load()
+	base.Store(slog.New(NewHandler(&buf, FormatJSON)))
+	t.Cleanup(func() { base.Store(prev) })
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	FromContext(ctx).Warn("Disk almost full", "used_percent", 91)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	if line["msg"] != "Disk almost full" || line["used_percent"] != float64(91) || line["level"] != "WARN" || line["request_id"] != "req-123" {
+		t.Errorf("unexpected record: %v", line)
+	}
+	if src, _ := line["source"].(string); !strings.HasPrefix(src, "logger_test.go:") {
+		t.Errorf("source should point at the caller, got %v", line["source"])
+	}
+
+	buf.Reset()
+	SetLevel(ERROR)
+	t.Cleanup(func() { SetLevel(INFO) })
+	Info("suppressed")
+	if buf.Len() != 0 {
+		t.Errorf("info logged below level: %s", buf.String())
+	}
+}
+
+func TestRotatingFileBySizeAndDay(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	f, err := newRotatingFile(dir, "app", 10, 0, true, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Write([]byte("0123456789"))
+	f.Write([]byte("abc")) // 超过大小，切分
+	now = now.Add(24 * time.Hour)
+	f.Write([]byte("next day"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	want := []string{"app_2026-03-01.1.log.gz", "app_2026-03-01.log.gz", "app_2026-03-02.log"}
+	if len(names) != len(want) {
+		t.Fatalf("files = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("files = %v, want %v", names, want)
+		}
+	}
+
+	zf, _ := os.Open(filepath.Join(dir, "app_2026-03-01.1.log.gz"))
+	defer zf.Close()
+	zr, err := gzip.NewReader(zf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != "0123456789" {
+		t.Errorf("compressed content = %q", data)
+	}
+}
+
+func TestRotatingFileRemovesExpired(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "app_2020-01-01.log.gz")
+	os.WriteFile(old, []byte("x"), 0644)
+	stale := time.Now().Add(-60 * 24 * time.Hour)
+	os.Chtimes(old, stale, stale)
+
+	f, err := NewRotatingFile(dir, "app", 0, 30, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expired log file should be removed, stat err = %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile 按天和大小自动轮转的日志文件。
+// 当前文件为 <prefix>_<日期>.log；跨天或超过大小上限时关闭当前文件，
+// 超过大小的文件重命名为 <prefix>_<日期>.<n>.log，旧文件按配置压缩为 .gz 并在过期后删除
+type RotatingFile struct {
+	dir      string
+	prefix   string
+	maxSize  int64 // 0 表示不按大小切分
+	maxAge   time.Duration
+	compress bool
+	now      func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	day  string
+	size int64
+
+	cleanupMu sync.Mutex // 串行执行压缩与清理
+	wg        sync.WaitGroup
+}
+
+// NewRotatingFile 在dir下创建轮转日志文件；maxSizeMB<=0 不按大小切分，maxAgeDays<=0 不删除旧文件
+func NewRotatingFile(dir, prefix string, maxSizeMB, maxAgeDays int, compress bool) (*RotatingFile, error) {
+	return newRotatingFile(dir, prefix, int64(maxSizeMB)*1024*1024, maxAgeDays, compress, time.Now)
+}
+
+func newRotatingFile(dir, prefix string, maxSize int64, maxAgeDays int, compress bool, now func() time.Time) (*RotatingFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+	f := &RotatingFile{
+		dir:      dir,
+		prefix:   prefix,
+		maxSize:  maxSize,
+		maxAge:   time.Duration(maxAgeDays) * 24 * time.Hour,
+		compress: compress,
+		now:      now,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	// 处理上次运行遗留的未压缩/过期文件
+	f.cleanupAsync()
+	return f, nil
+}
+
+// Write 实现 io.Writer，必要时先轮转
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	day := f.now().Format("2006-01-02")
+	if day != f.day || (f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize) {
+		if err := f.rotate(day != f.day); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close 关闭当前文件并等待后台压缩完成
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+func (f *RotatingFile) currentPath(day string) string {
+	return filepath.Join(f.dir, fmt.Sprintf("%s_%s.log", f.prefix, day))
+}
+
+// open 打开(追加)当天的日志文件；调用方需持有 mu 或处于初始化阶段
+func (f *RotatingFile) open() error {
+	day := f.now().Format("2006-01-02")
+	file, err := os.OpenFile(f.currentPath(day), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
+	f.file, f.day, f.size = file, day, info.Size()
+	return nil
+}
+
+// rotate 关闭当前文件并打开新文件；newDay 为false时表示因大小切分，需先给旧文件改名
+func (f *RotatingFile) rotate(newDay bool) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if !newDay {
+		old := f.currentPath(f.day)
+		if err := os.Rename(old, f.nextBackupPath(f.day)); err != nil {
+			return fmt.Errorf("failed to rotate log file: %v", err)
+		}
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.cleanupAsync()
+	return nil
+}
+
+// nextBackupPath 返回当天第一个未被占用的切分文件名
+func (f *RotatingFile) nextBackupPath(day string) string {
+	for n := 1; ; n++ {
+		path := filepath.Join(f.dir, fmt.Sprintf("%s_%s.%d.log", f.prefix, day, n))
+		if !exists(path) && !exists(path+".gz") {
+			return path
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (f *RotatingFile) cleanupAsync() {
+	if !f.compress && f.maxAge <= 0 {
+		return
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.cleanup()
+	}()
+}
+
+// cleanup 压缩非当前的日志文件并删除过期文件
+func (f *RotatingFile) cleanup() {
+	f.cleanupMu.Lock()
+	defer f.cleanupMu.Unlock()
+
+	// 在持有 mu 时列出文件，保证当前正在写入的文件不会被处理
+	f.mu.Lock()
+	active := f.currentPath(f.day)
+	matches, _ := filepath.Glob(filepath.Join(f.dir, f.prefix+"_*.log*"))
+	f.mu.Unlock()
+	sort.Strings(matches)
+	cutoff := f.now().Add(-f.maxAge)
+	for _, path := range matches {
+		if path == active {
+			continue
+		}
+		if f.maxAge > 0 {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(path)
+				continue
+			}
+		}
+		if f.compress && strings.HasSuffix(path, ".log") {
+			if err := gzipFile(path); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to compress log file %s: %v\n", path, err)
+			}
+		}
+	}
+}
+
+// gzipFile 把文件压缩为 path.gz 并删除原文件，保留修改时间以便按时间清理
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	zw.ModTime = info.ModTime()
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
@@ -0,0 +1,209 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 代码高亮使用的CSS类名，样式由前端提供
+const (
+	classKeyword = "hl-k"
+	classString  = "hl-s"
+	classComment = "hl-c"
+	classNumber  = "hl-n"
+	classBuiltin = "hl-b"
+)
+
+// language 一种语言的词法规则
+type language struct {
+	keywords     map[string]bool
+	builtins     map[string]bool
+	lineComments []string
+	blockComment [2]string
+	quotes       string // 字符串的引号字符
+	caseFold     bool   // 关键字不区分大小写(如SQL)
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	cStyle = [2]string{"/*", "*/"}
+
+	languages = map[string]*language{
+		"go": {
+			keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+			builtins:     words("true false nil iota append cap close copy delete len make new panic print println recover any bool byte error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr"),
+			lineComments: []string{"//"}, blockComment: cStyle, quotes: "\"'`",
+		},
+		"javascript": {
+			keywords:     words("async await break case catch class const continue debugger default delete do else export extends finally for from function if import in instanceof let new of return static super switch this throw try typeof var void while with yield interface type enum implements"),
+			builtins:     words("true false null undefined NaN Infinity console window document Promise Array Object String Number Boolean Map Set JSON Math"),
+			lineComments: []string{"//"}, blockComment: cStyle, quotes: "\"'`",
+		},
+		"python": {
+			keywords:     words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield match case"),
+			builtins:     words("True False None self print len range str int float list dict set tuple open super isinstance"),
+			lineComments: []string{"#"}, quotes: "\"'",
+		},
+		"java": {
+			keywords:     words("abstract assert break case catch class const continue default do else enum extends final finally for goto if implements import instanceof interface native new package private protected public return static super switch synchronized this throw throws try void volatile while var record struct namespace using typedef template typename sizeof auto extern register union signed unsigned inline virtual override"),
+			builtins:     words("true false null nullptr NULL int long short byte char float double boolean bool string String void"),
+			lineComments: []string{"//"}, blockComment: cStyle, quotes: "\"'",
+		},
+		"rust": {
+			keywords:     words("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while"),
+			builtins:     words("true false Some None Ok Err Option Result String Vec Box i8 i16 i32 i64 i128 isize u8 u16 u32 u64 u128 usize f32 f64 bool char str"),
+			lineComments: []string{"//"}, blockComment: cStyle, quotes: "\"",
+		},
+		"sql": {
+			keywords:     words("select from where and or not insert into values update set delete create table index view drop alter add primary key foreign references join left right inner outer on group by order having limit offset as distinct union all in is like between case when then else end exists default unique"),
+			builtins:     words("null true false count sum avg min max int integer varchar text bigint datetime timestamp boolean"),
+			lineComments: []string{"--"}, blockComment: cStyle, quotes: "'\"", caseFold: true,
+		},
+		"bash": {
+			keywords:     words("if then else elif fi for while until do done case esac function in return export local readonly select"),
+			builtins:     words("echo cd ls cat grep sed awk rm cp mv mkdir sudo exit source true false"),
+			lineComments: []string{"#"}, quotes: "\"'",
+		},
+		"yaml": {
+			builtins:     words("true false null yes no on off"),
+			lineComments: []string{"#"}, quotes: "\"'",
+		},
+		"json": {
+			builtins: words("true false null"),
+			quotes:   "\"",
+		},
+	}
+
+	// languageAliases 代码块语言标记的常见别名
+	languageAliases = map[string]string{
+		"golang": "go", "js": "javascript", "jsx": "javascript", "ts": "javascript", "typescript": "javascript",
+		"tsx": "javascript", "vue": "javascript", "py": "python", "c": "java", "cpp": "java", "c++": "java",
+		"cs": "java", "csharp": "java", "kotlin": "java", "kt": "java", "rs": "rust", "mysql": "sql",
+		"sqlite": "sql", "sh": "bash", "shell": "bash", "zsh": "bash", "yml": "yaml",
+	}
+)
+
+// lookupLanguage 按代码块的语言标记查找词法规则，未知语言返回nil
+func lookupLanguage(name string) *language {
+	name = strings.ToLower(name)
+	if alias, ok := languageAliases[name]; ok {
+		name = alias
+	}
+	return languages[name]
+}
+
+// highlight 把代码转义为HTML，已知语言时为关键字、字符串、注释和数字加上高亮标记
+func highlight(code, lang string) string {
+	l := lookupLanguage(lang)
+	if l == nil {
+		return html.EscapeString(code)
+	}
+
+	var b strings.Builder
+	span := func(class, text string) {
+		b.WriteString(`<span class="` + class + `">` + html.EscapeString(text) + "</span>")
+	}
+	for i := 0; i < len(code); {
+		rest := code[i:]
+
+		if n := l.commentLength(rest); n > 0 {
+			span(classComment, rest[:n])
+			i += n
+			continue
+		}
+
+		c := rest[0]
+		if strings.IndexByte(l.quotes, c) >= 0 {
+			n := stringLength(rest, c)
+			span(classString, rest[:n])
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case c >= '0' && c <= '9':
+			n := 1
+			for n < len(rest) && (isWordByte(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			span(classNumber, rest[:n])
+			i += n
+		case unicode.IsLetter(r) || c == '_':
+			n := 0
+			for n < len(rest) && isWordByte(rest[n]) {
+				n++
+			}
+			if n == 0 {
+				n = size // 非ASCII字母
+			}
+			word := rest[:n]
+			key := word
+			if l.caseFold {
+				key = strings.ToLower(word)
+			}
+			switch {
+			case l.keywords[key]:
+				span(classKeyword, word)
+			case l.builtins[key]:
+				span(classBuiltin, word)
+			default:
+				b.WriteString(html.EscapeString(word))
+			}
+			i += n
+		default:
+			b.WriteString(html.EscapeString(rest[:size]))
+			i += size
+		}
+	}
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// commentLength 返回 s 开头注释的字节数，不是注释时返回0
+func (l *language) commentLength(s string) int {
+	for _, prefix := range l.lineComments {
+		if strings.HasPrefix(s, prefix) {
+			if n := strings.IndexByte(s, '\n'); n >= 0 {
+				return n
+			}
+			return len(s)
+		}
+	}
+	if open := l.blockComment[0]; open != "" && strings.HasPrefix(s, open) {
+		if n := strings.Index(s[len(open):], l.blockComment[1]); n >= 0 {
+			return len(open) + n + len(l.blockComment[1])
+		}
+		return len(s)
+	}
+	return 0
+}
+
+// stringLength 返回 s 开头以 quote 包围的字符串的字节数，未闭合时到行尾为止
+func stringLength(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
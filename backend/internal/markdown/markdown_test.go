@@ -0,0 +1,203 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	nethtml "golang.org/x/net/html"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"emphasis", "*a **b** c* and ~~gone~~", "<p><em>a <strong>b</strong> c</em> and <del>gone</del></p>\n"},
+		{"intraword underscore", "snake_case_word", "<p>snake_case_word</p>\n"},
+		{"code span", "use `a < b` here", "<p>use <code>a &lt; b</code> here</p>\n"},
+		{"escapes and entities", `\*not em\* &copy; &bogus;`, "<p>*not em* © &amp;bogus;</p>\n"},
+		{"hard break", "line  \nnext", "<p>line<br/>\nnext</p>\n"},
+		{"reference link", "[docs][d]\n\n[d]: /docs \"Docs\"", `<p><a href="/docs" title="Docs">docs</a></p>` + "\n"},
+		{"bare url", "see www.example.com/a).", `<p>see <a href="http://www.example.com/a" rel="nofollow noreferrer">www.example.com/a</a>).</p>` + "\n"},
+		{"setext heading", "Title\n===", `<h1 id="title">Title</h1>` + "\n"},
+		{"nested list", "- a\n  - b\n- c", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n<li>c</li>\n</ul>\n"},
+		{"loose ordered list", "3. a\n\n4. b", "<ol start=\"3\">\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ol>\n"},
+		{"task list", "- [x] done\n- [ ] todo", `<ul class="contains-task-list">` + "\n" +
+			`<li class="task-list-item"><input checked="" disabled="" type="checkbox"/> done</li>` + "\n" +
+			`<li class="task-list-item"><input disabled="" type="checkbox"/> todo</li>` + "\n</ul>\n"},
+		{"table", "| a | b |\n|:-:|--:|\n| `x\\|y` | 2 |", "<table>\n<thead>\n<tr>\n<th align=\"center\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n" +
+			"<tbody>\n<tr>\n<td align=\"center\"><code>x|y</code></td>\n<td align=\"right\">2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"blockquote", "> quote\nlazy", "<blockquote>\n<p>quote\nlazy</p>\n</blockquote>\n"},
+		{"highlighted code", "```go\nreturn \"s\" // c\n```", `<pre><code class="language-go"><span class="hl-k">return</span> <span class="hl-s">&#34;s&#34;</span> <span class="hl-c">// c</span>` + "\n</code></pre>\n"},
+		{"unknown language", "```brainfuck\n<+>\n```", `<pre><code class="language-brainfuck">&lt;+&gt;` + "\n</code></pre>\n"},
+		{"indented code", "    a\n    b", "<pre><code>a\nb\n</code></pre>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src).HTML; got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"<script>alert(1)</script>ok", "ok"},
+		{`<div onclick="x" style="color:red">hi <b>there</b></div>`, "<div>hi <b>there</b></div>"},
+		{`<a href="/notes/1">x</a>`, `<a href="/notes/1">x</a>`},
+		{`<a href="https://example.com">x</a>`, `<a href="https://example.com" rel="nofollow noreferrer">x</a>`},
+		{`<img src="data:image/png;base64,AAAA">`, `<img src="data:image/png;base64,AAAA">`},
+		{`<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{`<span class="hl-k">k</span><span class="evil">e</span>`, `<span class="hl-k">k</span><span>e</span>`},
+		{`<input type="text" value="x"><input type="checkbox" checked>`, `<input type="checkbox" checked="">`},
+		{"<iframe src=x></iframe><style>p{}</style>after", "after"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.src); got != tt.want {
+			t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestRenderXSSVectors 常见的XSS写法，无论写成Markdown还是原始HTML都不能留下可执行的内容
+func TestRenderXSSVectors(t *testing.T) {
+	vectors := []string{
+		"[x](javascript:alert(1))",
+		"[x](JaVaScRiPt:alert(1))",
+		"[x](&#106;avascript:alert(1))",
+		"[x](&#x6A;&#x61;&#x76;&#x61;&#x73;&#x63;&#x72;&#x69;&#x70;&#x74;&#x3A;alert(1))",
+		"[x](java&#x09;script:alert(1))",
+		"[x](<java\tscript:alert(1)>)",
+		"[x](< javascript:alert(1)>)",
+		"![x](javascript:alert(1))",
+		"[x][r]\n\n[r]: javascript:alert(1)",
+		"<javascript:alert(1)>",
+		`<a href="javascript&colon;alert(1)">x</a>`,
+		`<a href="&#0000106&#0000097&#0000118&#0000097&#0000115&#0000099&#0000114&#0000105&#0000112&#0000116&#0000058alert(1)">x</a>`,
+		"<a href=\"java\tscript:alert(1)\">x</a>",
+		"<a href=\" \x01javascript:alert(1)\">x</a>",
+		"<a href=\"vbscript:alert(1)\">x</a>",
+		`<svg onload=alert(1)>`,
+		`<svg><script>alert(1)</script></svg>`,
+		`<img src=x onerror=alert(1)>`,
+		`<body onload=alert(1)>`,
+		`<details open ontoggle=alert(1)>`,
+		`<iframe src="javascript:alert(1)"></iframe>`,
+		`<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+		`<object data="javascript:alert(1)"></object>`,
+		`<form action="javascript:alert(1)"><button>x</button></form>`,
+		`<a href="x" style="background:url(javascript:alert(1))">x</a>`,
+		"[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+		"![x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+		`<a href="data:text/html,<script>alert(1)</script>">x</a>`,
+		`<img src="data:image/svg+xml,<svg onload=alert(1)>">`,
+		`<a href="DATA:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`,
+		"```\"><script>alert(1)</script>\nx\n```",
+		"# <img src=x onerror=alert(1)>",
+		"| <script>alert(1)</script> |\n|---|\n| x |",
+	}
+	for _, src := range vectors {
+		assertSafeHTML(t, src, Render(src).HTML)
+	}
+}
+
+// assertSafeHTML 检查渲染结果中没有危险的标签、事件属性、样式和可执行的链接地址
+func assertSafeHTML(t *testing.T, src, out string) {
+	t.Helper()
+	z := nethtml.NewTokenizer(strings.NewReader(out))
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			return
+		}
+		if tt != nethtml.StartTagToken && tt != nethtml.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		switch tok.Data {
+		case "script", "svg", "math", "iframe", "object", "embed", "form", "button", "style", "body", "base", "meta", "link":
+			t.Errorf("Render(%q) kept <%s>: %s", src, tok.Data, out)
+		}
+		for _, a := range tok.Attr {
+			val := strings.ToLower(strings.Map(func(r rune) rune {
+				if r <= ' ' {
+					return -1
+				}
+				return r
+			}, a.Val))
+			switch {
+			case strings.HasPrefix(a.Key, "on"), a.Key == "style", a.Key == "action", a.Key == "formaction":
+				t.Errorf("Render(%q) kept attribute %s: %s", src, a.Key, out)
+			case (a.Key == "href" || a.Key == "src") && (strings.Contains(val, "script:") ||
+				strings.HasPrefix(val, "data:") && !strings.HasPrefix(val, "data:image/png")):
+				t.Errorf("Render(%q) kept %s=%q: %s", src, a.Key, a.Val, out)
+			}
+		}
+	}
+}
+
+func TestRenderTOCAndExcerpt(t *testing.T) {
+	doc := Render("# Hello *World*\n\nIntro with **bold** text.\n\n## Hello World\n\n```go\nfunc hidden() {}\n```\n\n## 中文 标题!\n\nMore.")
+
+	want := []Heading{
+		{Level: 1, Text: "Hello World", ID: "hello-world"},
+		{Level: 2, Text: "Hello World", ID: "hello-world-1"},
+		{Level: 2, Text: "中文 标题!", ID: "中文-标题"},
+	}
+	if !reflect.DeepEqual(doc.TOC, want) {
+		t.Fatalf("TOC = %+v", doc.TOC)
+	}
+	if strings.Contains(doc.Text, "hidden") || !strings.Contains(doc.Text, "Intro with bold text.") {
+		t.Fatalf("Text = %q", doc.Text)
+	}
+	if got := doc.Excerpt(100); got != "Intro with bold text. More." {
+		t.Fatalf("Excerpt = %q", got)
+	}
+	if got := doc.Excerpt(5); got != "Intro..." {
+		t.Fatalf("Excerpt(5) = %q", got)
+	}
+	// 只有标题时用标题文字作为摘要
+	if got := Render("# Only heading").Excerpt(100); got != "Only heading" {
+		t.Fatalf("Excerpt of heading-only document = %q", got)
+	}
+}
+
+func TestRenderStripsDangerousMarkdown(t *testing.T) {
+	html := Render("[x](javascript:alert(1)) ![i](javascript:x) <script>alert(1)</script>\n\n<div onmouseover=\"x\">ok</div>").HTML
+	for _, bad := range []string{"javascript", "script", "onmouseover", "alert"} {
+		if strings.Contains(html, bad) {
+			t.Errorf("rendered HTML contains %q: %s", bad, html)
+		}
+	}
+}
+
+func TestRenderPathologicalLinks(t *testing.T) {
+	inputs := map[string]string{
+		"nested brackets":   strings.Repeat("[", 40000) + strings.Repeat("]", 40000),
+		"unclosed brackets": strings.Repeat("[a]", 30000),
+		"stray closers":     "[" + strings.Repeat("a]", 40000),
+		"open destination":  strings.Repeat("[a](", 40000),
+		"angle destination": strings.Repeat("[a](<", 30000),
+	}
+	for name, src := range inputs {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			Render(src)
+			if d := time.Since(start); d > 2*time.Second {
+				t.Errorf("rendering %d bytes took %v", len(src), d)
+			}
+		})
+	}
+
+	long := "[" + strings.Repeat("a", 1000) + "]\n\n[" + strings.Repeat("a", 1000) + "]: /x"
+	if strings.Contains(Render(long).HTML, "href") {
+		t.Error("labels over 999 characters must not resolve as references")
+	}
+}
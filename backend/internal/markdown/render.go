@@ -0,0 +1,271 @@
+// Package markdown 把笔记和文章的Markdown正文渲染为经过清理的HTML。
+// 解析使用 goldmark(CommonMark和GFM扩展)，清理使用 bluemonday 的UGC策略，并为代码块加上语法高亮
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	nethtml "golang.org/x/net/html"
+)
+
+// Heading 目录中的一个标题
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+// Document 渲染结果
+type Document struct {
+	HTML string    // 清理后的HTML
+	TOC  []Heading // 按出现顺序排列的标题
+	Text string    // 渲染后的纯文本，不含代码块
+
+	summary string // 不含标题的纯文本，用于生成摘要
+}
+
+// md 支持GFM扩展(表格、任务列表、删除线、裸链接)的Markdown解析器。允许原始HTML，输出统一由 Sanitize 清理
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		gmhtml.WithUnsafe(),
+		gmhtml.WithXHTML(),
+		renderer.WithNodeRenderers(util.Prioritized(codeRenderer{}, 100)),
+	),
+)
+
+// maxLinkScan 一行中 "](" 的个数乘以行长度的上限。goldmark 解析每个行内链接的地址时都会扫描到行尾，
+// 超长且链接极多的行会使解析时间成平方增长
+const maxLinkScan = 1 << 26
+
+// guardLinks 对超过 maxLinkScan 的行转义 "](" 中的括号，这些行中的行内链接按普通文字显示
+func guardLinks(src string) string {
+	if len(src)*strings.Count(src, "](") <= maxLinkScan {
+		return src
+	}
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		if len(line)*strings.Count(line, "](") > maxLinkScan {
+			lines[i] = strings.ReplaceAll(line, "](", `]\(`)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Render 把Markdown渲染为HTML，并提取目录和纯文本
+func Render(src string) *Document {
+	source := []byte(guardLinks(src))
+	root := md.Parser().Parse(text.NewReader(source))
+	toc := annotate(root, source)
+
+	var b bytes.Buffer
+	if err := md.Renderer().Render(&b, source, root); err != nil {
+		b.Reset() // 只有写入失败时才会出错，bytes.Buffer 不会发生
+	}
+
+	doc := &Document{HTML: Sanitize(b.String()), TOC: toc}
+	doc.Text, doc.summary = extractText(doc.HTML)
+	return doc
+}
+
+// Excerpt 从正文(不含标题和代码)中截取前 n 个字符作为摘要，超出部分用省略号表示
+func (d *Document) Excerpt(n int) string {
+	text := d.summary
+	if text == "" {
+		text = d.Text
+	}
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > n {
+		return strings.TrimSpace(string(runes[:n])) + "..."
+	}
+	return string(runes)
+}
+
+// annotate 为标题生成锚点并收集目录，为任务列表加上前端使用的CSS类
+func annotate(root ast.Node, source []byte) []Heading {
+	var toc []Heading
+	used := make(map[string]bool)
+	next := make(map[string]int)
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			title := strings.TrimSpace(nodeText(n, source))
+			id := headingID(title, used, next)
+			n.SetAttributeString("id", []byte(id))
+			toc = append(toc, Heading{Level: n.Level, Text: title, ID: id})
+			return ast.WalkSkipChildren, nil
+		case *extast.TaskCheckBox:
+			// 复选框位于列表项的第一个段落中
+			if item := n.Parent().Parent(); item != nil && item.Kind() == ast.KindListItem {
+				item.SetAttributeString("class", []byte("task-list-item"))
+				item.Parent().SetAttributeString("class", []byte("contains-task-list"))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return toc
+}
+
+// nodeText 返回节点中的文字内容，图片取其替代文字
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		case *ast.AutoLink:
+			b.Write(c.Label(source))
+		case *ast.RawHTML:
+		default:
+			b.WriteString(nodeText(c, source))
+		}
+	}
+	return html.UnescapeString(b.String())
+}
+
+// headingID 根据标题文字生成锚点，重复的锚点依次加上 -1、-2 后缀
+func headingID(title string, used map[string]bool, next map[string]int) string {
+	slug := Slugify(title)
+	if slug == "" {
+		slug = "section"
+	}
+	n := next[slug]
+	id := slug
+	if n > 0 {
+		id = slug + "-" + strconv.Itoa(n)
+	}
+	for used[id] {
+		n++
+		id = slug + "-" + strconv.Itoa(n)
+	}
+	next[slug] = n + 1
+	used[id] = true
+	return id
+}
+
+// codeRenderer 输出带语法高亮的围栏代码块
+type codeRenderer struct{}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (codeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, renderFencedCode)
+}
+
+func renderFencedCode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+	var code strings.Builder
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		code.Write(line.Value(source))
+	}
+	lang := html.UnescapeString(string(n.Language(source)))
+
+	_, _ = w.WriteString("<pre><code")
+	if lang != "" {
+		_, _ = w.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	_, _ = w.WriteString(">" + highlight(code.String(), lang) + "</code></pre>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// Slugify 把标题文字转换为锚点：字母转为小写，空白转为连字符，去掉其他标点
+func Slugify(text string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-':
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r):
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// blockTextTags 会在纯文本中产生换行的标签
+var blockTextTags = map[string]bool{
+	"p": true, "li": true, "blockquote": true, "div": true, "tr": true, "br": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "dt": true, "dd": true,
+	"table": true, "ul": true, "ol": true, "pre": true, "summary": true,
+}
+
+// extractText 从清理后的HTML中提取纯文本，跳过代码块；summary 另外跳过标题
+func extractText(doc string) (text, summary string) {
+	z := nethtml.NewTokenizer(strings.NewReader(doc))
+	var all, body strings.Builder
+	inPre, inHeading := 0, 0
+	breakLine := func() {
+		all.WriteByte('\n')
+		body.WriteByte('\n')
+	}
+	for {
+		tt := z.Next()
+		switch tt {
+		case nethtml.ErrorToken:
+			return joinLines(all.String()), joinLines(body.String())
+		case nethtml.TextToken:
+			if inPre > 0 {
+				continue
+			}
+			// 源码中的换行只是空白，换行由块级标签决定
+			text := strings.ReplaceAll(string(z.Text()), "\n", " ")
+			all.WriteString(text)
+			if inHeading == 0 {
+				body.WriteString(text)
+			}
+		case nethtml.StartTagToken, nethtml.EndTagToken, nethtml.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			delta := 1
+			if tt == nethtml.EndTagToken {
+				delta = -1
+			}
+			switch {
+			case tag == "pre" && tt != nethtml.SelfClosingTagToken:
+				inPre += delta
+			case len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' && tt != nethtml.SelfClosingTagToken:
+				inHeading += delta
+			case tag == "td" || tag == "th":
+				all.WriteByte(' ')
+				body.WriteByte(' ')
+			}
+			if blockTextTags[tag] {
+				breakLine()
+			}
+		}
+	}
+}
+
+// joinLines 合并每行内的多余空白并去掉空行
+func joinLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"database/sql"
+	"runtime"
+	"time"
+)
+
+// Default 应用使用的全局注册表，由 /metrics 输出
+var Default = NewRegistry()
+
+// 应用指标
+var (
+	// HTTPRequests 按方法、路由模板和状态码统计的请求数
+	HTTPRequests = NewCounterVec("nexushub_http_requests_total",
+		"Total number of HTTP requests by method, route and status.", "method", "route", "status")
+	// HTTPDuration 按方法、路由模板和状态码统计的请求耗时
+	HTTPDuration = NewHistogramVec("nexushub_http_request_duration_seconds",
+		"HTTP request latency in seconds by method, route and status.", DefBuckets, "method", "route", "status")
+	// UploadBytes 成功上传的文件字节数，storage 为 local 或 cloud
+	UploadBytes = NewCounterVec("nexushub_upload_bytes_total",
+		"Total bytes of successfully uploaded files by storage backend.", "storage")
+	// CodeExecutions Code Arena 按语言和结果统计的执行次数
+	CodeExecutions = NewCounterVec("nexushub_code_executions_total",
+		"Total number of Code Arena executions by language and verdict.", "language", "verdict")
+)
+
+func init() {
+	Default.MustRegister(HTTPRequests, HTTPDuration, UploadBytes, CodeExecutions, CollectorFunc(collectGoRuntime))
+}
+
+var startTime = time.Now()
+
+// collectGoRuntime 输出Go运行时指标
+func collectGoRuntime(w *Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	w.Gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	w.Gauge("go_threads", "Number of OS threads created.", float64(threadCount()))
+	w.Gauge("go_info", "Information about the Go environment.", 1, "version", runtime.Version())
+	w.Gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(ms.Alloc))
+	w.Counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", float64(ms.TotalAlloc))
+	w.Gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(ms.Sys))
+	w.Gauge("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", float64(ms.HeapAlloc))
+	w.Gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(ms.HeapInuse))
+	w.Gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(ms.HeapObjects))
+	w.Gauge("go_memstats_next_gc_bytes", "Number of heap bytes when next garbage collection will take place.", float64(ms.NextGC))
+	w.Counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(ms.NumGC))
+	w.Counter("go_gc_pause_seconds_total", "Total GC stop-the-world pause time in seconds.", float64(ms.PauseTotalNs)/1e9)
+	w.Gauge("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", float64(startTime.Unix()))
+}
+
+func threadCount() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
+
+// NewDBStatsCollector 输出数据库连接池状态
+func NewDBStatsCollector(db *sql.DB) Collector {
+	return CollectorFunc(func(w *Writer) {
+		s := db.Stats()
+		w.Gauge("nexushub_db_max_open_connections", "Maximum number of open connections to the database.", float64(s.MaxOpenConnections))
+		w.Gauge("nexushub_db_open_connections", "The number of established connections both in use and idle.", float64(s.OpenConnections))
+		w.Gauge("nexushub_db_in_use_connections", "The number of connections currently in use.", float64(s.InUse))
+		w.Gauge("nexushub_db_idle_connections", "The number of idle connections.", float64(s.Idle))
+		w.Counter("nexushub_db_wait_count_total", "The total number of connections waited for.", float64(s.WaitCount))
+		w.Counter("nexushub_db_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", s.WaitDuration.Seconds())
+		w.Counter("nexushub_db_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", float64(s.MaxIdleClosed))
+		w.Counter("nexushub_db_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", float64(s.MaxLifetimeClosed))
+	})
+}
@@ -0,0 +1,295 @@
+// Package metrics 以Prometheus文本格式(text/plain; version=0.0.4)导出应用指标。
+// 只实现了本项目用到的计数器、直方图和抓取时计算的仪表，不依赖官方客户端库。
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector 在每次抓取时把自己的样本写入 Writer
+type Collector interface {
+	Collect(w *Writer)
+}
+
+// CollectorFunc 函数形式的 Collector，用于抓取时才计算的指标(连接池、存储用量等)
+type CollectorFunc func(w *Writer)
+
+// Collect 实现 Collector
+func (f CollectorFunc) Collect(w *Writer) { f(w) }
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// MustRegister 注册指标，按注册顺序输出
+func (r *Registry) MustRegister(cs ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, cs...)
+}
+
+// Gather 生成所有指标的文本格式输出
+func (r *Registry) Gather() []byte {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	w := &Writer{}
+	for _, c := range collectors {
+		c.Collect(w)
+	}
+	return w.buf.Bytes()
+}
+
+// Handler 返回输出所有指标的HTTP处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		rw.Write(r.Gather())
+	})
+}
+
+// Writer 按Prometheus文本格式写出样本，同名样本连续写出时只输出一次HELP/TYPE
+type Writer struct {
+	buf  bytes.Buffer
+	last string
+}
+
+// Gauge 写出一个gauge样本，labels 为成对的名称和值
+func (w *Writer) Gauge(name, help string, value float64, labels ...string) {
+	w.header(name, help, "gauge")
+	w.sample(name, labels, value)
+}
+
+// Counter 写出一个counter样本，labels 为成对的名称和值
+func (w *Writer) Counter(name, help string, value float64, labels ...string) {
+	w.header(name, help, "counter")
+	w.sample(name, labels, value)
+}
+
+func (w *Writer) header(name, help, typ string) {
+	if w.last == name {
+		return
+	}
+	w.last = name
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+func (w *Writer) sample(name string, labels []string, value float64) {
+	w.buf.WriteString(name)
+	if len(labels) > 0 {
+		w.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			fmt.Fprintf(&w.buf, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		w.buf.WriteByte('}')
+	}
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(formatFloat(value))
+	w.buf.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// vec 按标签值分组保存样本的公共部分
+type vec[E any] struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu      sync.Mutex
+	entries map[string]*E
+	values  map[string][]string
+}
+
+func newVec[E any](name, help string, labelNames []string) vec[E] {
+	return vec[E]{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		entries:    make(map[string]*E),
+		values:     make(map[string][]string),
+	}
+}
+
+// entry 返回标签值对应的条目，不存在时用 create 创建；调用方需持有 mu
+func (v *vec[E]) entry(labelValues []string, create func() *E) *E {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	e, ok := v.entries[key]
+	if !ok {
+		e = create()
+		v.entries[key] = e
+		v.values[key] = append([]string(nil), labelValues...)
+	}
+	return e
+}
+
+// sortedKeys 返回排序后的key，保证输出稳定；调用方需持有 mu
+func (v *vec[E]) sortedKeys() []string {
+	keys := make([]string, 0, len(v.entries))
+	for k := range v.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return slices.Compare(v.values[keys[i]], v.values[keys[j]]) < 0
+	})
+	return keys
+}
+
+// labelPairs 把标签值与标签名组合成 Writer 需要的成对形式
+func (v *vec[E]) labelPairs(key string, extra ...string) []string {
+	values := v.values[key]
+	pairs := make([]string, 0, 2*len(values)+len(extra))
+	for i, name := range v.labelNames {
+		pairs = append(pairs, name, values[i])
+	}
+	return append(pairs, extra...)
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	vec[float64]
+}
+
+// NewCounterVec 创建计数器，labelNames 为空时即普通计数器
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{newVec[float64](name, help, labelNames)}
+}
+
+// Inc 计数加一
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加 delta(不能为负)
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.entry(labelValues, func() *float64 { return new(float64) }) += delta
+}
+
+// Value 返回标签值对应的当前计数
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.entries[strings.Join(labelValues, "\xff")]; ok {
+		return *v
+	}
+	return 0
+}
+
+// Collect 实现 Collector
+func (c *CounterVec) Collect(w *Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := c.sortedKeys()
+	if len(keys) == 0 && len(c.labelNames) == 0 {
+		w.Counter(c.name, c.help, 0)
+		return
+	}
+	for _, key := range keys {
+		w.Counter(c.name, c.help, *c.entries[key], c.labelPairs(key)...)
+	}
+}
+
+// DefBuckets 默认的延迟直方图桶(秒)
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64 // 与 buckets 一一对应，非累计
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	vec[histogram]
+	buckets []float64
+}
+
+// NewHistogramVec 创建直方图，buckets 需升序排列
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		vec:     newVec[histogram](name, help, labelNames),
+		buckets: buckets,
+	}
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e := h.entry(labelValues, func() *histogram {
+		return &histogram{counts: make([]uint64, len(h.buckets))}
+	})
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		e.counts[i]++
+	}
+	e.sum += value
+	e.count++
+}
+
+// Collect 实现 Collector
+func (h *HistogramVec) Collect(w *Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := h.sortedKeys()
+	if len(keys) == 0 {
+		return
+	}
+
+	w.header(h.name, h.help, "histogram")
+	for _, key := range keys {
+		e := h.entries[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += e.counts[i]
+			w.sample(h.name+"_bucket", h.labelPairs(key, "le", formatFloat(upper)), float64(cumulative))
+		}
+		w.sample(h.name+"_bucket", h.labelPairs(key, "le", "+Inf"), float64(e.count))
+		w.sample(h.name+"_sum", h.labelPairs(key), e.sum)
+		w.sample(h.name+"_count", h.labelPairs(key), float64(e.count))
+	}
+}
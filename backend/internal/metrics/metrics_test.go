@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextFormat(t *testing.T) {
+	reg := NewRegistry()
+	requests := NewCounterVec("test_requests_total", "Requests.", "route", "status")
+	latency := NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	reg.MustRegister(requests, latency, CollectorFunc(func(w *Writer) {
+		w.Gauge("test_temperature", "Line one\nline two.", 21.5, "room", `say "hi"`)
+	}))
+
+	requests.Inc("/notes/:id", "200")
+	requests.Add(2, "/notes/:id", "200")
+	requests.Inc("/notes", "500")
+	latency.Observe(0.05, "/notes")
+	latency.Observe(0.5, "/notes")
+	latency.Observe(3, "/notes")
+
+	want := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{route="/notes",status="500"} 1
+test_requests_total{route="/notes/:id",status="200"} 3
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/notes",le="0.1"} 1
+test_latency_seconds_bucket{route="/notes",le="1"} 2
+test_latency_seconds_bucket{route="/notes",le="+Inf"} 3
+test_latency_seconds_sum{route="/notes"} 3.55
+test_latency_seconds_count{route="/notes"} 3
+# HELP test_temperature Line one\nline two.
+# TYPE test_temperature gauge
+test_temperature{room="say \"hi\""} 21.5
+`
+	if got := string(reg.Gather()); got != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDefaultRegistryIncludesRuntime(t *testing.T) {
+	out := string(Default.Gather())
+	for _, name := range []string{"go_goroutines", "go_memstats_heap_alloc_bytes", "go_gc_cycles_total"} {
+		if !strings.Contains(out, "\n"+name+" ") && !strings.Contains(out, "\n"+name+"{") {
+			t.Errorf("missing %s", name)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"nexushub-personal/internal/common"
 	"nexushub-personal/internal/config"
 	"strings"
 	"time"
@@ -56,7 +57,7 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			common.LegacyError(c, http.StatusUnauthorized, "Authorization header is required")
 			c.Abort()
 			return
 		}
@@ -64,14 +65,14 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Bearer Token格式
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+			common.LegacyError(c, http.StatusUnauthorized, "Authorization header format must be Bearer {token}")
 			c.Abort()
 			return
 		}
 
 		claims, err := ParseToken(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			common.LegacyError(c, http.StatusUnauthorized, "Invalid or expired token")
 			c.Abort()
 			return
 		}
@@ -79,6 +80,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 将用户信息存入上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("authenticated", true)
 		c.Next()
 	}
 }
@@ -92,53 +94,66 @@ func GetCurrentUserID(c *gin.Context) uint {
 	return userID.(uint)
 }
 
-// OptionalAuthMiddleware 可选认证中间件 - 允许访客访问,如果有token则设置用户信息
+// IsAuthenticated 请求是否携带了有效token。单用户模式下未登录请求映射到的默认用户不算已认证
+func IsAuthenticated(c *gin.Context) bool {
+	return c.GetBool("authenticated")
+}
+
+// OptionalAuthMiddleware API认证中间件
+// 携带token时必须有效，否则返回401；未携带token时，单用户模式下视为默认用户，
+// 多用户模式(AUTH_MODE=multi)下直接返回401
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
-		// 严格模式：默认情况下，如果没有token，我们应该拒绝敏感操作
-		// 但是为了保持"个人工作站"的易用性，我们允许本地开发环境(localhost)的请求自动获得管理员权限
-		// 或者当配置文件明确设置为"单用户模式"时。
-
-		// 检查是否是本地请求
-		clientIP := c.ClientIP()
-		isLocal := clientIP == "::1" || clientIP == "127.0.0.1" || clientIP == "localhost"
-
 		if authHeader == "" {
-			if isLocal {
-				// 本地请求，自动登录为默认用户
-				c.Set("user_id", uint(config.AppConfig.User.DefaultUserID))
-				c.Set("username", "admin")
-				c.Next()
+			if config.AppConfig.IsMultiUser() {
+				common.LegacyError(c, http.StatusUnauthorized, "Authorization header is required")
+				c.Abort()
 				return
 			}
 
-			// 非本地请求，必须登录
-			// 但为了兼容当前前端代码（可能没有发送token），暂时保留宽容策略，但标记为 guest
-			// 实际生产环境应该返回 401
+			// 单用户模式：本地请求视为管理员，其余视为访客，二者都映射到默认用户
+			clientIP := c.ClientIP()
+			username := "guest"
+			if clientIP == "::1" || clientIP == "127.0.0.1" || clientIP == "localhost" {
+				username = "admin"
+			}
 			c.Set("user_id", uint(config.AppConfig.User.DefaultUserID))
-			c.Set("username", "guest")
+			c.Set("username", username)
 			c.Next()
 			return
 		}
 
-		// 尝试解析token
 		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) == 2 && parts[0] == "Bearer" {
-			claims, err := ParseToken(parts[1])
-			if err == nil {
-				// Token有效,设置用户信息
-				c.Set("user_id", claims.UserID)
-				c.Set("username", claims.Username)
-				c.Next()
-				return
-			}
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			common.LegacyError(c, http.StatusUnauthorized, "Authorization header format must be Bearer {token}")
+			c.Abort()
+			return
 		}
 
-		// Token无效，继续作为guest访问
-		c.Set("user_id", uint(config.AppConfig.User.DefaultUserID))
-		c.Set("username", "guest")
+		// 无效token不再降级为访客，避免过期token悄悄写入默认用户的数据
+		claims, err := ParseToken(parts[1])
+		if err != nil {
+			common.LegacyError(c, http.StatusUnauthorized, "Invalid or expired token")
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("authenticated", true)
 		c.Next()
 	}
 }
+
+// SignedOrAuth 请求携带 signature 查询参数时跳过认证，由 handler 校验签名链接；否则交给 auth 认证
+func SignedOrAuth(auth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("signature") != "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginAttempt 登录失败记录
+type LoginAttempt struct {
+	Failures    int
+	NextAllowed time.Time // 指数退避：在此之前不允许再次尝试
+	LockedUntil time.Time // 失败次数达到上限后的锁定截止时间
+}
+
+// LoginGuard 登录防爆破：按用户名和IP分别记录失败次数，
+// 每次失败后指数退避，连续失败达到上限后临时锁定。也用于公开分享链接的密码校验，此时用户名为链接令牌
+type LoginGuard struct {
+	mu          sync.Mutex
+	store       RateLimitStore
+	name        string // key前缀，区分共享同一存储的防护
+	maxFailures int
+	lockout     time.Duration
+	backoffBase time.Duration
+}
+
+// NewLoginGuard 创建登录防护
+func NewLoginGuard(store RateLimitStore, name string, maxFailures int, lockout, backoffBase time.Duration) *LoginGuard {
+	return &LoginGuard{
+		store:       store,
+		name:        name,
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		backoffBase: backoffBase,
+	}
+}
+
+// SetPolicy 更新失败上限、锁定时长和退避基数(配置热更新时调用)，已锁定的账号保持原截止时间
+func (g *LoginGuard) SetPolicy(maxFailures int, lockout, backoffBase time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.maxFailures = maxFailures
+	g.lockout = lockout
+	g.backoffBase = backoffBase
+}
+
+func (g *LoginGuard) keys(username, ip string) []string {
+	return []string{
+		g.name + ":user:" + strings.ToLower(strings.TrimSpace(username)),
+		g.name + ":ip:" + ip,
+	}
+}
+
+// Check 检查是否允许本次登录尝试，返回是否被锁定以及需要等待的时长
+func (g *LoginGuard) Check(username, ip string) (locked bool, wait time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range g.keys(username, ip) {
+		v, ok := g.store.Get(key)
+		if !ok {
+			continue
+		}
+		attempt := v.(LoginAttempt)
+		if now.Before(attempt.LockedUntil) {
+			locked = true
+			if d := attempt.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		} else if now.Before(attempt.NextAllowed) {
+			if d := attempt.NextAllowed.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return locked, wait
+}
+
+// Fail 记录一次失败登录
+func (g *LoginGuard) Fail(username, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range g.keys(username, ip) {
+		var attempt LoginAttempt
+		if v, ok := g.store.Get(key); ok {
+			attempt = v.(LoginAttempt)
+		}
+		// 锁定已过期则重新计数
+		if !attempt.LockedUntil.IsZero() && now.After(attempt.LockedUntil) {
+			attempt = LoginAttempt{}
+		}
+
+		attempt.Failures++
+		if attempt.Failures >= g.maxFailures {
+			attempt.LockedUntil = now.Add(g.lockout)
+			attempt.NextAllowed = attempt.LockedUntil
+		} else {
+			attempt.NextAllowed = now.Add(g.backoff(attempt.Failures))
+		}
+
+		g.store.Set(key, attempt, g.lockout*2)
+	}
+}
+
+// Succeed 登录成功后清除该用户名的失败记录。IP的失败记录不清除，只随时间过期，
+// 否则攻击者可以在猜测他人密码的间隙登录自己的账号来重置计数
+func (g *LoginGuard) Succeed(username, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.store.Delete(g.keys(username, ip)[0])
+}
+
+// backoff 计算第n次失败后的退避时长：base * 2^(n-1)，不超过锁定时长
+func (g *LoginGuard) backoff(failures int) time.Duration {
+	d := g.backoffBase
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if d >= g.lockout {
+			return g.lockout
+		}
+	}
+	return d
+}
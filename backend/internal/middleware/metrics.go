@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"strconv"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 记录每个请求的计数和耗时，路由使用注册时的模板(如 /api/v1/notes/:id)以避免标签基数膨胀
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.Inc(c.Request.Method, route, status)
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+	}
+}
+
+// MetricsAuth 配置了token时要求 Authorization: Bearer <token>；未配置时只允许本机直接抓取，
+// 经反向代理转发(带 X-Forwarded-For 或 X-Real-IP)的请求同样拒绝
+func MetricsAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			if !localRequest(c) {
+				common.Forbidden(c, "metrics are only available from localhost unless a token is configured")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		got := []byte(c.GetHeader("Authorization"))
+		if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 {
+			common.Unauthorized(c, "invalid metrics token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// localRequest 判断请求是否由本机直接发出，不信任任何转发头
+func localRequest(c *gin.Context) bool {
+	if c.GetHeader("X-Forwarded-For") != "" || c.GetHeader("X-Real-IP") != "" {
+		return false
+	}
+	ip := net.ParseIP(c.RemoteIP())
+	return ip != nil && ip.IsLoopback()
+}
@@ -1,26 +1,26 @@
 package middleware
 
 import (
+	"slices"
+
 	"nexushub-personal/internal/config"
 
 	"github.com/gin-gonic/gin"
 )
 
-// UserContext adds the default user ID to context
-func UserContext() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Set("user_id", config.AppConfig.User.DefaultUserID)
-		c.Next()
-	}
-}
-
-// CORS middleware
+// CORS middleware，允许的来源取自可热更新的运行时配置
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		if origin := allowedOrigin(c.GetHeader("Origin"), config.Runtime().CORSOrigins); origin != "" {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			if origin != "*" {
+				c.Writer.Header().Add("Vary", "Origin")
+			}
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, X-Share-Password, X-Share-Access")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After, Content-Disposition, ETag")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -30,3 +30,14 @@ func CORS() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// allowedOrigin 返回应写入 Access-Control-Allow-Origin 的值，不允许时返回空
+func allowedOrigin(origin string, allowed []string) string {
+	if slices.Contains(allowed, "*") {
+		return "*"
+	}
+	if origin != "" && slices.Contains(allowed, origin) {
+		return origin
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitStore 限流状态存储接口，默认使用内存实现，可替换为Redis等共享存储
+type RateLimitStore interface {
+	// Get 获取key对应的状态，不存在或已过期时返回false
+	Get(key string) (interface{}, bool)
+	// Set 保存key对应的状态，ttl后自动过期
+	Set(key string, value interface{}, ttl time.Duration)
+	// Delete 删除key对应的状态
+	Delete(key string)
+}
+
+type memoryItem struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// MemoryStore 基于内存的限流状态存储
+type MemoryStore struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		items:     make(map[string]memoryItem),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(item.expiresAt) {
+		delete(s.items, key)
+		return nil, false
+	}
+	return item.value, true
+}
+
+func (s *MemoryStore) Set(key string, value interface{}, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.items[key] = memoryItem{value: value, expiresAt: now.Add(ttl)}
+
+	// 顺带清理过期条目，避免内存无限增长
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, item := range s.items {
+			if now.After(item.expiresAt) {
+				delete(s.items, k)
+			}
+		}
+		s.lastSweep = now
+	}
+}
+
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+}
+
+// TokenBucket 令牌桶状态
+type TokenBucket struct {
+	Tokens     float64
+	LastRefill time.Time
+}
+
+// RateLimiter 令牌桶限流器
+type RateLimiter struct {
+	mu    sync.Mutex
+	store RateLimitStore
+	name  string  // key前缀，区分共享同一存储的限流器
+	rate  float64 // 每秒补充的令牌数
+	burst int     // 桶容量
+}
+
+// NewRateLimiter 创建限流器，perMinute为每分钟允许的请求数
+func NewRateLimiter(store RateLimitStore, name string, perMinute, burst int) *RateLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &RateLimiter{
+		store: store,
+		name:  name,
+		rate:  float64(perMinute) / 60,
+		burst: burst,
+	}
+}
+
+// SetLimit 更新限流速率和桶容量(配置热更新时调用)，已有的令牌桶按新参数继续补充
+func (l *RateLimiter) SetLimit(perMinute, burst int) {
+	if burst <= 0 {
+		burst = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = float64(perMinute) / 60
+	l.burst = burst
+}
+
+// Allow 尝试消耗一个令牌，失败时返回需要等待的时长
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	storeKey := "ratelimit:" + l.name + ":" + key
+
+	bucket := TokenBucket{Tokens: float64(l.burst), LastRefill: now}
+	if v, ok := l.store.Get(storeKey); ok {
+		bucket = v.(TokenBucket)
+		elapsed := now.Sub(bucket.LastRefill).Seconds()
+		bucket.Tokens = math.Min(float64(l.burst), bucket.Tokens+elapsed*l.rate)
+		bucket.LastRefill = now
+	}
+
+	// 桶从空到满所需时间，超过后状态可以安全丢弃
+	ttl := time.Duration(float64(l.burst)/l.rate*float64(time.Second)) + time.Second
+
+	if bucket.Tokens < 1 {
+		l.store.Set(storeKey, bucket, ttl)
+		wait := time.Duration((1 - bucket.Tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	bucket.Tokens--
+	l.store.Set(storeKey, bucket, ttl)
+	return true, 0
+}
+
+// RateLimit 限流中间件，keyFunc决定限流维度(IP、用户等)
+func RateLimit(limiter *RateLimiter, keyFunc func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, wait := limiter.Allow(keyFunc(c))
+		if !allowed {
+			abortTooManyRequests(c, wait, constants.ErrTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+// KeyByIP 按客户端IP限流
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser 按当前用户限流，未识别用户时退化为按IP限流
+func KeyByUser(c *gin.Context) string {
+	userID := GetCurrentUserID(c)
+	if userID == 0 {
+		return KeyByIP(c)
+	}
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// abortTooManyRequests 返回429并设置Retry-After头
+func abortTooManyRequests(c *gin.Context, wait time.Duration, message string) {
+	SetRetryAfter(c, wait)
+	common.Error(c, http.StatusTooManyRequests, message)
+	c.Abort()
+}
+
+// SetRetryAfter 设置Retry-After响应头(秒，向上取整)
+func SetRetryAfter(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
@@ -1,13 +1,59 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"regexp"
+	"runtime/debug"
 	"time"
 
+	"nexushub-personal/internal/common"
 	"nexushub-personal/internal/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// validRequestID 客户端或上游代理传入的请求ID只接受简单字符，避免日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID 为每个请求分配ID(沿用合法的 X-Request-ID 请求头)，
+// 写入响应头、gin上下文和请求context，之后的日志与错误响应都会带上它
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(common.RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(common.RequestIDKey, id)
+		c.Header(common.RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Recovery 捕获处理过程中的panic，连同堆栈写入结构化日志并返回500
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logger.Slog().ErrorContext(c.Request.Context(), "panic recovered",
+			slog.Any("error", err),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("stack", string(debug.Stack())),
+		)
+		common.InternalServerError(c, "internal server error")
+		c.Abort()
+	})
+}
+
+// RequestLogger 每个请求结束后记录一条结构化访问日志，5xx 记为错误
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -17,9 +63,19 @@ func RequestLogger() gin.HandlerFunc {
 		c.Next()
 
 		status := c.Writer.Status()
-		duration := time.Since(start)
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
 		userID, _ := c.Get("user_id")
 
-		logger.Info("%s %s | %d | %v | user_id=%v", method, path, status, duration, userID)
+		logger.Slog().LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Any("user_id", userID),
+			slog.String("client_ip", c.ClientIP()),
+		)
 	}
 }
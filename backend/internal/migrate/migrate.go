@@ -0,0 +1,277 @@
+// Package migrate 实现版本化的数据库结构迁移：每个迁移有 up/down 两个方向，
+// 已执行的版本及其校验和记录在 schema_migrations 表中，迁移源码被修改后拒绝继续执行。
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrIrreversible     = errors.New("migration cannot be rolled back")
+	ErrUnknownVersion   = errors.New("applied migration not found in code")
+)
+
+// Migration 一次结构或数据变更
+// Version 为14位时间戳(YYYYMMDDHHMMSS)，按字典序即执行顺序
+type Migration struct {
+	Version  string
+	Name     string
+	Checksum string
+	Up       func(tx *gorm.DB) error
+	Down     func(tx *gorm.DB) error // 为空表示不可回滚
+}
+
+// SchemaMigration schema_migrations 表中的一条执行记录
+type SchemaMigration struct {
+	Version   string    `gorm:"primaryKey;size:32"`
+	Name      string    `gorm:"size:255;not null"`
+	Checksum  string    `gorm:"size:64;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// 迁移状态
+const (
+	StatePending  = "pending"  // 尚未执行
+	StateApplied  = "applied"  // 已执行且源码未变
+	StateModified = "modified" // 已执行但源码校验和已变化
+	StateMissing  = "missing"  // 数据库中有记录但代码中不存在
+)
+
+// Status 单个迁移的执行状态
+type Status struct {
+	Version   string
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New 创建迁移执行器，迁移按版本号排序，版本号重复时返回错误
+func New(db *gorm.DB, migrations []Migration) (*Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version == "" || m.Up == nil {
+			return nil, fmt.Errorf("migration %q must have a version and an up function", m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %s (%s, %s)", m.Version, sorted[i-1].Name, m.Name)
+		}
+	}
+
+	return &Migrator{db: db, migrations: sorted}, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.db.AutoMigrate(&SchemaMigration{})
+}
+
+func (m *Migrator) applied() (map[string]SchemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	var records []SchemaMigration
+	if err := m.db.Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]SchemaMigration, len(records))
+	for _, r := range records {
+		result[r.Version] = r
+	}
+	return result, nil
+}
+
+// Status 返回所有迁移(包括仅存在于数据库中的记录)的状态，按版本号排序
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations)+len(applied))
+	known := make(map[string]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		s := Status{Version: mig.Version, Name: mig.Name, State: StatePending}
+		if record, ok := applied[mig.Version]; ok {
+			appliedAt := record.AppliedAt
+			s.AppliedAt = &appliedAt
+			s.State = StateApplied
+			if record.Checksum != mig.Checksum {
+				s.State = StateModified
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, Status{Version: version, Name: record.Name, State: StateMissing, AppliedAt: &appliedAt})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending 返回尚未执行的迁移；已执行迁移的源码被修改时返回 ErrChecksumMismatch
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range m.migrations {
+		record, ok := applied[mig.Version]
+		if !ok {
+			pending = append(pending, mig)
+			continue
+		}
+		if record.Checksum != mig.Checksum {
+			return nil, fmt.Errorf("%w: %s_%s was modified after it was applied (recorded %s, now %s)",
+				ErrChecksumMismatch, mig.Version, mig.Name, short(record.Checksum), short(mig.Checksum))
+		}
+	}
+	return pending, nil
+}
+
+// Up 按版本顺序执行所有未执行的迁移，返回本次执行的迁移
+// 每个迁移在独立事务中执行(MySQL 的 DDL 会隐式提交，失败时可能需要手动清理)
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range pending {
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   mig.Version,
+				Name:      mig.Name,
+				Checksum:  mig.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %s_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down 按版本倒序回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	if steps < len(versions) {
+		versions = versions[:steps]
+	}
+
+	byVersion := make(map[string]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var done []Migration
+	for _, version := range versions {
+		mig, ok := byVersion[version]
+		if !ok {
+			return done, fmt.Errorf("%w: %s_%s", ErrUnknownVersion, version, applied[version].Name)
+		}
+		if mig.Down == nil {
+			return done, fmt.Errorf("%w: %s_%s", ErrIrreversible, mig.Version, mig.Name)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Down(tx); err != nil {
+				return err
+			}
+			return tx.Where("version = ?", mig.Version).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of %s_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+func short(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// fileTemplate 新迁移文件的模板，放在 migrations 包中并在 init 中注册
+const fileTemplate = `package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			return nil
+		},
+	)
+}
+`
+
+// Create 在 dir 下生成 <version>_<name>.go 迁移文件，返回文件路径
+func Create(dir, name string, now time.Time) (string, error) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "-", "_"))
+	if !migrationNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid migration name %q: use lowercase letters, digits and underscores", name)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.go", now.UTC().Format("20060102150405"), name))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(fileTemplate); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,211 @@
+package migrate_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/migrate"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func testMigrations() []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:  "20240102000000",
+			Name:     "add_widget_color",
+			Checksum: "b",
+			Up:       func(tx *gorm.DB) error { return tx.Exec("ALTER TABLE widgets ADD COLUMN color TEXT").Error },
+			Down:     func(tx *gorm.DB) error { return tx.Exec("ALTER TABLE widgets DROP COLUMN color").Error },
+		},
+		{
+			Version:  "20240101000000",
+			Name:     "create_widgets",
+			Checksum: "a",
+			Up:       func(tx *gorm.DB) error { return tx.AutoMigrate(&widget{}) },
+			Down:     func(tx *gorm.DB) error { return tx.Migrator().DropTable(&widget{}) },
+		},
+	}
+}
+
+func states(t *testing.T, m *migrate.Migrator) string {
+	t.Helper()
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	var parts []string
+	for _, s := range statuses {
+		parts = append(parts, s.Version+":"+s.State)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestUpDownStatus(t *testing.T) {
+	db := openDB(t)
+	m, err := migrate.New(db, testMigrations())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := states(t, m); got != "20240101000000:pending,20240102000000:pending" {
+		t.Fatalf("initial status: %s", got)
+	}
+
+	applied, err := m.Up()
+	if err != nil || len(applied) != 2 || applied[0].Name != "create_widgets" {
+		t.Fatalf("up: %v %+v", err, applied)
+	}
+	if !db.Migrator().HasColumn(&widget{}, "color") {
+		t.Fatal("color column not created")
+	}
+	if got := states(t, m); got != "20240101000000:applied,20240102000000:applied" {
+		t.Fatalf("status after up: %s", got)
+	}
+
+	// 再次执行不应重复迁移
+	if applied, err := m.Up(); err != nil || len(applied) != 0 {
+		t.Fatalf("second up: %v %+v", err, applied)
+	}
+
+	reverted, err := m.Down(1)
+	if err != nil || len(reverted) != 1 || reverted[0].Name != "add_widget_color" {
+		t.Fatalf("down: %v %+v", err, reverted)
+	}
+	if db.Migrator().HasColumn(&widget{}, "color") {
+		t.Fatal("color column not dropped")
+	}
+	if got := states(t, m); got != "20240101000000:applied,20240102000000:pending" {
+		t.Fatalf("status after down: %s", got)
+	}
+
+	if _, err := m.Down(10); err != nil {
+		t.Fatalf("down all: %v", err)
+	}
+	if db.Migrator().HasTable(&widget{}) {
+		t.Fatal("widgets table not dropped")
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db := openDB(t)
+	m, _ := migrate.New(db, testMigrations())
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	modified := testMigrations()
+	modified[1].Checksum = "changed"
+	m, _ = migrate.New(db, modified)
+
+	if _, err := m.Up(); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if got := states(t, m); got != "20240101000000:modified,20240102000000:applied" {
+		t.Fatalf("status: %s", got)
+	}
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	db := openDB(t)
+	migrations := append(testMigrations(), migrate.Migration{
+		Version:  "20240103000000",
+		Name:     "broken",
+		Checksum: "c",
+		Up:       func(tx *gorm.DB) error { return tx.Exec("ALTER TABLE missing ADD COLUMN x TEXT").Error },
+	})
+	m, _ := migrate.New(db, migrations)
+
+	applied, err := m.Up()
+	if err == nil || len(applied) != 2 {
+		t.Fatalf("expected failure after 2 migrations, got %v %+v", err, applied)
+	}
+	if got := states(t, m); !strings.HasSuffix(got, "20240103000000:pending") {
+		t.Fatalf("failed migration should stay pending: %s", got)
+	}
+
+	if _, err := m.Down(3); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+}
+
+func TestMissingAndIrreversible(t *testing.T) {
+	db := openDB(t)
+	migrations := testMigrations()
+	migrations[0].Down = nil
+	m, _ := migrate.New(db, migrations)
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Down(1); !errors.Is(err, migrate.ErrIrreversible) {
+		t.Fatalf("expected ErrIrreversible, got %v", err)
+	}
+
+	m, _ = migrate.New(db, migrations[1:])
+	if got := states(t, m); got != "20240101000000:applied,20240102000000:missing" {
+		t.Fatalf("status: %s", got)
+	}
+	if _, err := m.Down(1); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Fatalf("expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestDuplicateVersion(t *testing.T) {
+	migrations := testMigrations()
+	migrations[1].Version = migrations[0].Version
+	if _, err := migrate.New(openDB(t), migrations); err == nil {
+		t.Fatal("expected duplicate version error")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	path, err := migrate.Create(dir, "Add-Note-Color", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "20240506070809_add_note_color.go" {
+		t.Fatalf("unexpected file name: %s", path)
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.Contains(string(content), "register(") {
+		t.Fatalf("unexpected template:\n%s", content)
+	}
+
+	if _, err := migrate.Create(dir, "add_note_color", now); err == nil {
+		t.Fatal("expected error when file already exists")
+	}
+	if _, err := migrate.Create(dir, "bad name!", now); err == nil {
+		t.Fatal("expected error for invalid name")
+	}
+}
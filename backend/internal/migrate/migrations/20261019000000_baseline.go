@@ -0,0 +1,228 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 基线迁移：此前由 AutoMigrate 维护的全部表结构。
+// 这里使用结构快照而不是 model 包中的类型，后续修改模型不会改变基线；
+// 对已由 AutoMigrate 建好表的旧库执行时只会补齐缺失的表和列。
+
+type baselineUser struct {
+	ID          uint   `gorm:"primarykey"`
+	Username    string `gorm:"size:100;not null;unique"`
+	Password    string `gorm:"size:255;not null"`
+	Email       string `gorm:"size:255;unique"`
+	Nickname    string `gorm:"size:100"`
+	Avatar      string `gorm:"size:255"`
+	Bio         string `gorm:"size:500"`
+	OIDCIssuer  string `gorm:"column:oidc_issuer;size:255"`
+	OIDCSubject string `gorm:"column:oidc_subject;size:255;index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineNote struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	Title     string `gorm:"size:255;not null"`
+	Content   string
+	Tags      string `gorm:"size:500"`
+	IsPinned  bool   `gorm:"default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineNote) TableName() string { return "notes" }
+
+type baselineFile struct {
+	ID          uint   `gorm:"primarykey"`
+	UserID      uint   `gorm:"not null;index"`
+	FileName    string `gorm:"size:255;not null"`
+	FilePath    string `gorm:"size:500;not null"`
+	FileSize    int64  `gorm:"not null"`
+	FileType    string `gorm:"size:100"`
+	MimeType    string `gorm:"size:100"`
+	Extension   string `gorm:"size:20"`
+	Thumbnail   string `gorm:"size:500"`
+	Category    string `gorm:"size:50"`
+	Description string `gorm:"size:500"`
+	Tags        string `gorm:"size:500"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineFile) TableName() string { return "files" }
+
+type baselinePost struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	Title     string `gorm:"size:255;not null"`
+	Content   string `gorm:"not null"`
+	Excerpt   string `gorm:"size:500"`
+	Tags      string `gorm:"size:500"`
+	Cover     string `gorm:"size:500"`
+	Author    string `gorm:"size:100"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselinePost) TableName() string { return "posts" }
+
+type baselineTheme struct {
+	ID              uint    `gorm:"primarykey"`
+	UserID          uint    `gorm:"not null;unique;index"`
+	ThemeName       string  `gorm:"size:50;default:'dark'"`
+	PrimaryColor    string  `gorm:"size:20;default:'#000000'"`
+	SecondaryColor  string  `gorm:"size:20;default:'#ffffff'"`
+	ThemeTemplate   string  `gorm:"size:50;default:'default'"`
+	BackgroundMusic string  `gorm:"size:500"`
+	MusicVolume     float32 `gorm:"default:0.5"`
+	CustomCSS       string  `gorm:"type:text"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (baselineTheme) TableName() string { return "themes" }
+
+type baselineChatMessage struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	Role      string `gorm:"size:20;not null"`
+	Content   string `gorm:"not null"`
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineChatMessage) TableName() string { return "chat_messages" }
+
+type baselineTask struct {
+	ID          uint   `gorm:"primarykey"`
+	UserID      uint   `gorm:"not null;index"`
+	Title       string `gorm:"size:255;not null"`
+	Description string `gorm:"type:text"`
+	Status      string `gorm:"size:20;default:'pending'"`
+	Priority    string `gorm:"size:20;default:'medium'"`
+	Category    string `gorm:"size:100"`
+	DueDate     *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineTask) TableName() string { return "tasks" }
+
+type baselineBookmark struct {
+	ID          uint   `gorm:"primarykey"`
+	UserID      uint   `gorm:"not null;index"`
+	Title       string `gorm:"size:255;not null"`
+	URL         string `gorm:"size:1000;not null"`
+	Description string `gorm:"size:500"`
+	Tags        string `gorm:"size:500"`
+	Favicon     string `gorm:"size:500"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineBookmark) TableName() string { return "bookmarks" }
+
+type baselineCollection struct {
+	ID          uint   `gorm:"primarykey"`
+	UserID      uint   `gorm:"not null;index"`
+	Title       string `gorm:"size:255;not null"`
+	URL         string `gorm:"size:1000;not null"`
+	Type        string `gorm:"size:20;not null"`
+	Thumbnail   string `gorm:"size:1000"`
+	Description string `gorm:"size:1000"`
+	Tags        string `gorm:"size:500"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineCollection) TableName() string { return "collections" }
+
+type baselineEvent struct {
+	ID          uint   `gorm:"primarykey"`
+	UserID      uint   `gorm:"not null;index"`
+	Title       string `gorm:"size:255;not null"`
+	Date        string `gorm:"size:50;not null"`
+	StartTime   string `gorm:"size:20"`
+	Type        string `gorm:"size:20;default:'other'"`
+	Description string `gorm:"type:text"`
+	Remind      bool   `gorm:"default:false"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineEvent) TableName() string { return "events" }
+
+type baselineShare struct {
+	ID           uint   `gorm:"primarykey"`
+	OwnerID      uint   `gorm:"not null;index"`
+	ResourceType string `gorm:"size:20;not null;index:idx_share_resource"`
+	ResourceID   uint   `gorm:"not null;index:idx_share_resource"`
+	SharedWithID uint   `gorm:"not null;default:0;index"`
+	Permission   string `gorm:"size:10;not null;default:'read'"`
+	Token        string `gorm:"size:64;index"`
+	Password     string `gorm:"size:255"`
+	ExpiresAt    *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineShare) TableName() string { return "shares" }
+
+type baselineAuditLog struct {
+	ID           uint   `gorm:"primarykey"`
+	ActorID      uint   `gorm:"not null;index"`
+	IP           string `gorm:"size:64"`
+	ResourceType string `gorm:"size:20;not null;index:idx_audit_resource"`
+	ResourceID   uint   `gorm:"not null;index:idx_audit_resource"`
+	Action       string `gorm:"size:20;not null;index"`
+	Before       string
+	After        string
+	Diff         string
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+func (baselineAuditLog) TableName() string { return "audit_logs" }
+
+func baselineTables() []interface{} {
+	return []interface{}{
+		&baselineUser{},
+		&baselineNote{},
+		&baselineFile{},
+		&baselineTask{},
+		&baselineBookmark{},
+		&baselineTheme{},
+		&baselineChatMessage{},
+		&baselineCollection{},
+		&baselineEvent{},
+		&baselinePost{},
+		&baselineShare{},
+		&baselineAuditLog{},
+	}
+}
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineTables()...)
+		},
+		func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(baselineTables()...)
+		},
+	)
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"log"
+
+	"nexushub-personal/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// 旧版本用 user_id = 0 表示"所有人共享"的文件和收藏，现已由显式的分享模型取代，
+// 这里把这些记录归属到默认用户。回滚不恢复原归属。
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			ownerID := config.AppConfig.User.DefaultUserID
+			for _, table := range []string{"files", "collections"} {
+				result := tx.Table(table).Where("user_id = ?", 0).Update("user_id", ownerID)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected > 0 {
+					log.Printf("Migrated %d legacy shared records in %s to user %d", result.RowsAffected, table, ownerID)
+				}
+			}
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			return nil
+		},
+	)
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 笔记历史版本表。已有笔记不补写版本，首次更新时再记录更新前的内容。
+
+type noteRevisionV1 struct {
+	ID        uint   `gorm:"primarykey"`
+	NoteID    uint   `gorm:"not null;uniqueIndex:idx_note_revision"`
+	Revision  int    `gorm:"not null;uniqueIndex:idx_note_revision"`
+	AuthorID  uint   `gorm:"not null"`
+	Title     string `gorm:"size:255;not null"`
+	Content   string
+	Tags      string `gorm:"size:500"`
+	IsPinned  bool
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (noteRevisionV1) TableName() string { return "note_revisions" }
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&noteRevisionV1{})
+		},
+		func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&noteRevisionV1{})
+		},
+	)
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// 为笔记、任务和文章增加乐观锁版本号，已有记录的版本号为1
+
+type versionedNoteV1 struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (versionedNoteV1) TableName() string { return "notes" }
+
+type versionedTaskV1 struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (versionedTaskV1) TableName() string { return "tasks" }
+
+type versionedPostV1 struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (versionedPostV1) TableName() string { return "posts" }
+
+func versionedTables() []interface{} {
+	return []interface{}{&versionedNoteV1{}, &versionedTaskV1{}, &versionedPostV1{}}
+}
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			for _, m := range versionedTables() {
+				if tx.Migrator().HasColumn(m, "Version") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(m, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			for _, m := range versionedTables() {
+				if !tx.Migrator().HasColumn(m, "Version") {
+					continue
+				}
+				if err := tx.Migrator().DropColumn(m, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 笔记之间的 [[标题]] 链接表，并为已有笔记解析一次链接。
+// 解析逻辑是此刻 utils.ParseWikiLinks 的快照，之后的修改不影响本迁移。
+
+type noteLinkV1 struct {
+	ID          uint   `gorm:"primarykey"`
+	SourceID    uint   `gorm:"not null;index"`
+	UserID      uint   `gorm:"not null;index:idx_note_link_target"`
+	TargetTitle string `gorm:"size:255;not null"`
+	TargetKey   string `gorm:"size:255;not null;index:idx_note_link_target"`
+}
+
+func (noteLinkV1) TableName() string { return "note_links" }
+
+var noteLinkPatternV1 = regexp.MustCompile(`\[\[([^\[\]\n]+?)\]\]`)
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&noteLinkV1{}); err != nil {
+				return err
+			}
+
+			var notes []struct {
+				ID      uint
+				UserID  uint
+				Content string
+			}
+			if err := tx.Table("notes").Select("id, user_id, content").Where("deleted_at IS NULL").Find(&notes).Error; err != nil {
+				return err
+			}
+			var links []noteLinkV1
+			for _, n := range notes {
+				seen := map[string]bool{}
+				for _, m := range noteLinkPatternV1.FindAllStringSubmatch(n.Content, -1) {
+					target := m[1]
+					if i := strings.IndexAny(target, "#|"); i >= 0 {
+						target = target[:i]
+					}
+					target = strings.TrimSpace(target)
+					key := strings.ToLower(strings.Join(strings.Fields(target), " "))
+					if key == "" || seen[key] || len(target) > 255 {
+						continue
+					}
+					seen[key] = true
+					links = append(links, noteLinkV1{SourceID: n.ID, UserID: n.UserID, TargetTitle: target, TargetKey: key})
+				}
+			}
+			if len(links) == 0 {
+				return nil
+			}
+			return tx.CreateInBatches(links, 200).Error
+		},
+		func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&noteLinkV1{})
+		},
+	)
+}
@@ -0,0 +1,121 @@
+package migrations
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 标签表和多态关联表，并把已有记录中逗号分隔的标签导入。
+// 解析逻辑是此刻 service.ParseTags 的快照，之后的修改不影响本迁移。
+
+type tagV1 struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_tag_user_name"`
+	Name      string `gorm:"size:50;not null"`
+	NameKey   string `gorm:"size:50;not null;uniqueIndex:idx_tag_user_name"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (tagV1) TableName() string { return "tags" }
+
+type taggingV1 struct {
+	ID           uint   `gorm:"primarykey"`
+	TagID        uint   `gorm:"not null;uniqueIndex:idx_tagging"`
+	ResourceType string `gorm:"size:20;not null;uniqueIndex:idx_tagging;index:idx_tagging_resource"`
+	ResourceID   uint   `gorm:"not null;uniqueIndex:idx_tagging;index:idx_tagging_resource"`
+}
+
+func (taggingV1) TableName() string { return "taggings" }
+
+// taggedTablesV1 资源类型 -> 表名
+var taggedTablesV1 = []struct{ resource, table string }{
+	{"note", "notes"},
+	{"file", "files"},
+	{"post", "posts"},
+	{"bookmark", "bookmarks"},
+	{"collection", "collections"},
+}
+
+func parseTagsV1(tags string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, part := range strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ',' || r == '，' || r == ';' || r == '；'
+	}) {
+		name := strings.Join(strings.Fields(part), " ")
+		if utf8.RuneCountInString(name) > 50 {
+			name = strings.TrimSpace(string([]rune(name)[:50]))
+		}
+		key := strings.ToLower(name)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&tagV1{}, &taggingV1{}); err != nil {
+				return err
+			}
+
+			type ownerKey struct {
+				userID uint
+				key    string
+			}
+			tags := map[ownerKey]tagV1{}
+			for _, t := range taggedTablesV1 {
+				var rows []struct {
+					ID     uint
+					UserID uint
+					Tags   string
+				}
+				err := tx.Table(t.table).Select("id, user_id, tags").
+					Where("deleted_at IS NULL AND tags IS NOT NULL AND tags <> ''").
+					Order("id").Find(&rows).Error
+				if err != nil {
+					return err
+				}
+				for _, row := range rows {
+					names := parseTagsV1(row.Tags)
+					var taggings []taggingV1
+					for i, name := range names {
+						k := ownerKey{row.UserID, strings.ToLower(name)}
+						tag, ok := tags[k]
+						if !ok {
+							tag = tagV1{UserID: row.UserID, Name: name, NameKey: k.key}
+							if err := tx.Create(&tag).Error; err != nil {
+								return err
+							}
+							tags[k] = tag
+						}
+						names[i] = tag.Name
+						taggings = append(taggings, taggingV1{TagID: tag.ID, ResourceType: t.resource, ResourceID: row.ID})
+					}
+					if len(taggings) > 0 {
+						if err := tx.Create(&taggings).Error; err != nil {
+							return err
+						}
+					}
+					if canonical := strings.Join(names, ","); canonical != row.Tags {
+						if err := tx.Table(t.table).Where("id = ?", row.ID).Update("tags", canonical).Error; err != nil {
+							return err
+						}
+					}
+				}
+			}
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&taggingV1{}, &tagV1{})
+		},
+	)
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 全局搜索的倒排索引表。索引内容依赖分词规则，不在迁移中生成：
+// 服务启动时 SearchService.EnsureIndex 会为尚未索引的资源建立索引。
+
+type searchDocumentV1 struct {
+	ID           uint      `gorm:"primarykey"`
+	ResourceType string    `gorm:"size:20;not null;uniqueIndex:idx_search_resource"`
+	ResourceID   uint      `gorm:"not null;uniqueIndex:idx_search_resource"`
+	UserID       uint      `gorm:"not null;index"`
+	Title        string    `gorm:"size:255"`
+	Date         time.Time `gorm:"index"`
+	Length       int       `gorm:"not null"`
+	IndexVersion int       `gorm:"not null"`
+	UpdatedAt    time.Time
+}
+
+func (searchDocumentV1) TableName() string { return "search_documents" }
+
+type searchPostingV1 struct {
+	ID         uint   `gorm:"primarykey"`
+	Term       string `gorm:"size:64;not null;index"`
+	DocumentID uint   `gorm:"not null;index"`
+	TitleFreq  int    `gorm:"not null"`
+	BodyFreq   int    `gorm:"not null"`
+}
+
+func (searchPostingV1) TableName() string { return "search_postings" }
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&searchDocumentV1{}, &searchPostingV1{})
+		},
+		func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&searchPostingV1{}, &searchDocumentV1{})
+		},
+	)
+}
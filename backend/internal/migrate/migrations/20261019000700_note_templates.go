@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 笔记模板表，以及日记笔记使用的 notes.journal_date 列。
+// (user_id, journal_date) 唯一，普通笔记的 journal_date 为 NULL，不受唯一约束限制
+
+type noteTemplateV1 struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	Name      string `gorm:"size:100;not null"`
+	Title     string `gorm:"size:255"`
+	Content   string
+	Tags      string `gorm:"size:500"`
+	IsDaily   bool   `gorm:"default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (noteTemplateV1) TableName() string { return "note_templates" }
+
+type journalNoteV1 struct {
+	UserID      uint    `gorm:"not null;uniqueIndex:idx_note_journal"`
+	JournalDate *string `gorm:"size:10;uniqueIndex:idx_note_journal"`
+}
+
+func (journalNoteV1) TableName() string { return "notes" }
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&noteTemplateV1{}); err != nil {
+				return err
+			}
+			m := tx.Migrator()
+			if !m.HasColumn(&journalNoteV1{}, "JournalDate") {
+				if err := m.AddColumn(&journalNoteV1{}, "JournalDate"); err != nil {
+					return err
+				}
+			}
+			if !m.HasIndex(&journalNoteV1{}, "idx_note_journal") {
+				return m.CreateIndex(&journalNoteV1{}, "idx_note_journal")
+			}
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&journalNoteV1{}, "idx_note_journal") {
+				if err := m.DropIndex(&journalNoteV1{}, "idx_note_journal"); err != nil {
+					return err
+				}
+			}
+			if m.HasColumn(&journalNoteV1{}, "JournalDate") {
+				if err := m.DropColumn(&journalNoteV1{}, "JournalDate"); err != nil {
+					return err
+				}
+			}
+			return m.DropTable(&noteTemplateV1{})
+		},
+	)
+}
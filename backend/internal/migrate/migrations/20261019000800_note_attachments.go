@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// files.note_id：文件作为附件上传到的笔记，普通上传的文件为 NULL
+
+type attachmentFileV1 struct {
+	NoteID *uint `gorm:"index"`
+}
+
+func (attachmentFileV1) TableName() string { return "files" }
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if !m.HasColumn(&attachmentFileV1{}, "NoteID") {
+				if err := m.AddColumn(&attachmentFileV1{}, "NoteID"); err != nil {
+					return err
+				}
+			}
+			if !m.HasIndex(&attachmentFileV1{}, "NoteID") {
+				return m.CreateIndex(&attachmentFileV1{}, "NoteID")
+			}
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if m.HasIndex(&attachmentFileV1{}, "NoteID") {
+				if err := m.DropIndex(&attachmentFileV1{}, "NoteID"); err != nil {
+					return err
+				}
+			}
+			if m.HasColumn(&attachmentFileV1{}, "NoteID") {
+				return m.DropColumn(&attachmentFileV1{}, "NoteID")
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 嵌套的笔记本，以及笔记的 notebook_id、is_archived、sort_order 列
+
+type notebookV1 struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	ParentID  *uint  `gorm:"index"`
+	Name      string `gorm:"size:100;not null"`
+	Icon      string `gorm:"size:50"`
+	SortOrder int    `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (notebookV1) TableName() string { return "notebooks" }
+
+type notebookNoteV1 struct {
+	NotebookID *uint `gorm:"index"`
+	IsArchived bool  `gorm:"default:false;index"`
+	SortOrder  int   `gorm:"not null;default:0"`
+}
+
+func (notebookNoteV1) TableName() string { return "notes" }
+
+var notebookNoteColumns = []string{"NotebookID", "IsArchived", "SortOrder"}
+
+func init() {
+	register(
+		func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&notebookV1{}); err != nil {
+				return err
+			}
+			m := tx.Migrator()
+			for _, column := range notebookNoteColumns {
+				if !m.HasColumn(&notebookNoteV1{}, column) {
+					if err := m.AddColumn(&notebookNoteV1{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			for _, column := range []string{"NotebookID", "IsArchived"} {
+				if !m.HasIndex(&notebookNoteV1{}, column) {
+					if err := m.CreateIndex(&notebookNoteV1{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, column := range []string{"NotebookID", "IsArchived"} {
+				if m.HasIndex(&notebookNoteV1{}, column) {
+					if err := m.DropIndex(&notebookNoteV1{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			for _, column := range notebookNoteColumns {
+				if m.HasColumn(&notebookNoteV1{}, column) {
+					if err := m.DropColumn(&notebookNoteV1{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return m.DropTable(&notebookV1{})
+		},
+	)
+}
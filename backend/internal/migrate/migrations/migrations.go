@@ -0,0 +1,58 @@
+// Package migrations 存放所有版本化迁移。每个迁移是一个名为 <version>_<name>.go 的文件，
+// 在 init 中调用 register 注册；校验和为该文件源码的 sha256，已执行的迁移不可再修改。
+// 使用 `go run ./cmd/migrate create <name>` 生成新迁移。
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"nexushub-personal/internal/migrate"
+
+	"gorm.io/gorm"
+)
+
+//go:embed *.go
+var sources embed.FS
+
+var registered []migrate.Migration
+
+// register 注册调用方所在文件定义的迁移，版本号和名称取自文件名
+func register(up, down func(tx *gorm.DB) error) {
+	_, file, _, ok := runtime.Caller(1)
+	if !ok {
+		panic("migrations: cannot determine caller file")
+	}
+	base := filepath.Base(file)
+
+	parts := strings.SplitN(strings.TrimSuffix(base, ".go"), "_", 2)
+	if len(parts) != 2 || len(parts[0]) != 14 {
+		panic(fmt.Sprintf("migrations: file %s must be named <YYYYMMDDHHMMSS>_<name>.go", base))
+	}
+
+	source, err := sources.ReadFile(base)
+	if err != nil {
+		panic(fmt.Sprintf("migrations: cannot read embedded source of %s: %v", base, err))
+	}
+	sum := sha256.Sum256(source)
+
+	registered = append(registered, migrate.Migration{
+		Version:  parts[0],
+		Name:     parts[1],
+		Checksum: hex.EncodeToString(sum[:]),
+		Up:       up,
+		Down:     down,
+	})
+}
+
+// All 返回所有已注册的迁移
+func All() []migrate.Migration {
+	result := make([]migrate.Migration, len(registered))
+	copy(result, registered)
+	return result
+}
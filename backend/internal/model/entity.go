@@ -0,0 +1,133 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 以下方法供通用CRUD Handler读取主键、设置所有者
+
+func (n *Note) GetID() uint           { return n.ID }
+func (n *Note) SetUserID(userID uint) { n.UserID = userID }
+
+func (t *Task) GetID() uint           { return t.ID }
+func (t *Task) SetUserID(userID uint) { t.UserID = userID }
+
+func (b *Bookmark) GetID() uint           { return b.ID }
+func (b *Bookmark) SetUserID(userID uint) { b.UserID = userID }
+
+func (e *Event) GetID() uint           { return e.ID }
+func (e *Event) SetUserID(userID uint) { e.UserID = userID }
+
+func (c *Collection) GetID() uint           { return c.ID }
+func (c *Collection) SetUserID(userID uint) { c.UserID = userID }
+
+func (p *Post) GetID() uint           { return p.ID }
+func (p *Post) SetUserID(userID uint) { p.UserID = userID }
+
+func (t *NoteTemplate) GetID() uint           { return t.ID }
+func (t *NoteTemplate) SetUserID(userID uint) { t.UserID = userID }
+
+func (b *Notebook) GetID() uint           { return b.ID }
+func (b *Notebook) SetUserID(userID uint) { b.UserID = userID }
+
+func (f *File) GetID() uint        { return f.ID }
+func (m *ChatMessage) GetID() uint { return m.ID }
+
+// Versioned 支持乐观并发控制的实体，更新时只有版本号与数据库一致才会写入
+type Versioned interface {
+	GetVersion() int
+	SetVersion(version int)
+}
+
+func (n *Note) GetVersion() int        { return n.Version }
+func (n *Note) SetVersion(version int) { n.Version = version }
+
+func (t *Task) GetVersion() int        { return t.Version }
+func (t *Task) SetVersion(version int) { t.Version = version }
+
+func (p *Post) GetVersion() int        { return p.Version }
+func (p *Post) SetVersion(version int) { p.Version = version }
+
+// Tagged 带标签的实体，Tags 为逗号分隔的标签名
+type Tagged interface {
+	GetTags() string
+	SetTags(tags string)
+}
+
+func (n *Note) GetTags() string     { return n.Tags }
+func (n *Note) SetTags(tags string) { n.Tags = tags }
+
+func (f *File) GetTags() string     { return f.Tags }
+func (f *File) SetTags(tags string) { f.Tags = tags }
+
+func (p *Post) GetTags() string     { return p.Tags }
+func (p *Post) SetTags(tags string) { p.Tags = tags }
+
+func (b *Bookmark) GetTags() string     { return b.Tags }
+func (b *Bookmark) SetTags(tags string) { b.Tags = tags }
+
+func (c *Collection) GetTags() string     { return c.Tags }
+func (c *Collection) SetTags(tags string) { c.Tags = tags }
+
+// SearchContent 资源中参与全文检索的内容
+type SearchContent struct {
+	UserID uint
+	Title  string
+	Body   string
+	Date   time.Time
+}
+
+// Searchable 可被全局搜索的实体
+type Searchable interface {
+	GetID() uint
+	SearchContent() SearchContent
+}
+
+// joinText 用换行连接非空的文本片段
+func joinText(parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
+func (n *Note) SearchContent() SearchContent {
+	return SearchContent{UserID: n.UserID, Title: n.Title, Body: joinText(n.Content, n.Tags), Date: n.CreatedAt}
+}
+
+func (t *Task) SearchContent() SearchContent {
+	return SearchContent{UserID: t.UserID, Title: t.Title, Body: joinText(t.Description, t.Category), Date: t.CreatedAt}
+}
+
+func (b *Bookmark) SearchContent() SearchContent {
+	return SearchContent{UserID: b.UserID, Title: b.Title, Body: joinText(b.Description, b.URL, b.Tags), Date: b.CreatedAt}
+}
+
+// SearchContent 事件按日程日期过滤，日期无法解析时使用创建时间
+func (e *Event) SearchContent() SearchContent {
+	date, err := time.ParseInLocation("2006-01-02", e.Date, time.Local)
+	if err != nil {
+		date = e.CreatedAt
+	}
+	return SearchContent{UserID: e.UserID, Title: e.Title, Body: joinText(e.Description, e.Type), Date: date}
+}
+
+func (c *Collection) SearchContent() SearchContent {
+	return SearchContent{UserID: c.UserID, Title: c.Title, Body: joinText(c.Description, c.URL, c.Tags), Date: c.CreatedAt}
+}
+
+func (p *Post) SearchContent() SearchContent {
+	return SearchContent{UserID: p.UserID, Title: p.Title, Body: joinText(p.Excerpt, p.Content, p.Tags), Date: p.CreatedAt}
+}
+
+func (m *ChatMessage) SearchContent() SearchContent {
+	return SearchContent{UserID: m.UserID, Body: m.Content, Date: m.CreatedAt}
+}
+
+func (f *File) SearchContent() SearchContent {
+	return SearchContent{UserID: f.UserID, Title: f.FileName, Body: joinText(f.Description, f.Tags), Date: f.CreatedAt}
+}
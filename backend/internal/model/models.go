@@ -8,31 +8,94 @@ import (
 
 // User represents the single user of the system
 type User struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	Username    string         `gorm:"size:100;not null;unique" json:"username"`
+	Password    string         `gorm:"size:255;not null" json:"-"` // 密码不返回给前端
+	Email       string         `gorm:"size:255;unique" json:"email"`
+	Nickname    string         `gorm:"size:100" json:"nickname"`
+	Avatar      string         `gorm:"size:255" json:"avatar"`
+	Bio         string         `gorm:"size:500" json:"bio"`
+	OIDCIssuer  string         `gorm:"column:oidc_issuer;size:255" json:"-"`        // OIDC 登录关联的签发方
+	OIDCSubject string         `gorm:"column:oidc_subject;size:255;index" json:"-"` // OIDC 登录关联的subject
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Note represents a note/memo
+type Note struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	UserID      uint           `gorm:"not null;index;uniqueIndex:idx_note_journal" json:"user_id"`
+	Title       string         `gorm:"size:255;not null" json:"title"`
+	Content     string         `json:"content"` // 不指定列类型：MySQL 上为 longtext，PostgreSQL/SQLite 上为 text
+	Tags        string         `gorm:"size:500" json:"tags"`
+	IsPinned    bool           `gorm:"default:false" json:"is_pinned"`
+	JournalDate *string        `gorm:"size:10;uniqueIndex:idx_note_journal" json:"journal_date,omitempty"` // 日记笔记对应的日期(YYYY-MM-DD)，每个用户每天最多一篇
+	NotebookID  *uint          `gorm:"index" json:"notebook_id"`                                           // 所在笔记本，为空表示不属于任何笔记本
+	IsArchived  bool           `gorm:"default:false;index" json:"is_archived"`                             // 归档的笔记默认不出现在列表中
+	SortOrder   int            `gorm:"not null;default:0" json:"sort_order"`                               // 手动排序的位置，越小越靠前
+	Version     int            `gorm:"not null;default:1" json:"version"`                                  // 乐观锁版本号，每次更新加1
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// RewriteLinks 仅用于更新请求：标题改变时把其他笔记中指向旧标题的 [[链接]] 改为新标题
+	RewriteLinks bool `gorm:"-" json:"rewrite_links,omitempty"`
+}
+
+// NoteLink 笔记正文中的 [[标题]] 链接。目标按标题在源笔记所有者的笔记中解析，
+// 因此目标笔记改名或新建后链接会自动指向新的笔记
+type NoteLink struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	SourceID    uint   `gorm:"not null;index" json:"source_id"`
+	UserID      uint   `gorm:"not null;index:idx_note_link_target" json:"user_id"`    // 源笔记所有者
+	TargetTitle string `gorm:"size:255;not null" json:"target_title"`                 // 链接中书写的标题
+	TargetKey   string `gorm:"size:255;not null;index:idx_note_link_target" json:"-"` // 规范化后的标题，见 utils.WikiLinkKey
+}
+
+// Notebook 笔记本，ParentID 为空的是顶层笔记本。同一层级按 SortOrder、名称排列
+type Notebook struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
-	Username  string         `gorm:"size:100;not null;unique" json:"username"`
-	Password  string         `gorm:"size:255;not null" json:"-"` // 密码不返回给前端
-	Email     string         `gorm:"size:255;unique" json:"email"`
-	Nickname  string         `gorm:"size:100" json:"nickname"`
-	Avatar    string         `gorm:"size:255" json:"avatar"`
-	Bio       string         `gorm:"size:500" json:"bio"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	ParentID  *uint          `gorm:"index" json:"parent_id"`
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	Icon      string         `gorm:"size:50" json:"icon"` // emoji 或图标名称
+	SortOrder int            `gorm:"not null;default:0" json:"sort_order"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// Note represents a note/memo
-type Note struct {
+// NoteTemplate 笔记模板。标题和正文中可以使用 {{date}}、{{weekday}}、{{time}}、
+// {{open_tasks}}、{{today_events}} 等占位符，从模板创建笔记时替换为实际内容。
+// IsDaily 为true的模板用于创建日记笔记
+type NoteTemplate struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	UserID    uint           `gorm:"not null;index" json:"user_id"`
-	Title     string         `gorm:"size:255;not null" json:"title"`
-	Content   string         `gorm:"type:longtext" json:"content"`
-	Tags      string         `gorm:"size:500" json:"tags"`
-	IsPinned  bool           `gorm:"default:false" json:"is_pinned"`
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	Title     string         `gorm:"size:255" json:"title"`
+	Content   string         `json:"content"`
+	Tags      string         `gorm:"size:500" json:"tags"` // 从模板创建的笔记默认使用的标签
+	IsDaily   bool           `gorm:"default:false" json:"is_daily"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// NoteRevision 笔记的历史版本快照，创建、更新和恢复笔记时各写入一条，
+// Revision 在同一笔记内从1递增
+type NoteRevision struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	NoteID    uint      `gorm:"not null;uniqueIndex:idx_note_revision" json:"note_id"`
+	Revision  int       `gorm:"not null;uniqueIndex:idx_note_revision" json:"revision"`
+	AuthorID  uint      `gorm:"not null" json:"author_id"`
+	Title     string    `gorm:"size:255;not null" json:"title"`
+	Content   string    `json:"content,omitempty"`
+	Tags      string    `gorm:"size:500" json:"tags"`
+	IsPinned  bool      `json:"is_pinned"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
 // File represents uploaded files
 type File struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
@@ -47,6 +110,7 @@ type File struct {
 	Category    string         `gorm:"size:50" json:"category"` // media, document, code, archive, etc
 	Description string         `gorm:"size:500" json:"description"`
 	Tags        string         `gorm:"size:500" json:"tags"`
+	NoteID      *uint          `gorm:"index" json:"note_id,omitempty"` // 作为附件上传到的笔记
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -57,11 +121,12 @@ type Post struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	UserID    uint           `gorm:"not null;index" json:"user_id"`
 	Title     string         `gorm:"size:255;not null" json:"title"`
-	Content   string         `gorm:"type:longtext;not null" json:"content"`
+	Content   string         `gorm:"not null" json:"content"`
 	Excerpt   string         `gorm:"size:500" json:"excerpt"`
 	Tags      string         `gorm:"size:500" json:"tags"`
 	Cover     string         `gorm:"size:500" json:"cover"`
 	Author    string         `gorm:"size:100" json:"author"`
+	Version   int            `gorm:"not null;default:1" json:"version"` // 乐观锁版本号，每次更新加1
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -87,7 +152,7 @@ type ChatMessage struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	UserID    uint           `gorm:"not null;index" json:"user_id"`
 	Role      string         `gorm:"size:20;not null" json:"role"` // user, assistant
-	Content   string         `gorm:"type:longtext;not null" json:"content"`
+	Content   string         `gorm:"not null" json:"content"`
 	CreatedAt time.Time      `json:"created_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
@@ -102,6 +167,7 @@ type Task struct {
 	Priority    string         `gorm:"size:20;default:'medium'" json:"priority"` // low, medium, high
 	Category    string         `gorm:"size:100" json:"category"`
 	DueDate     *time.Time     `json:"due_date"`
+	Version     int            `gorm:"not null;default:1" json:"version"` // 乐观锁版本号，每次更新加1
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -136,6 +202,47 @@ type Collection struct {
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// Tag 用户的标签，同一用户内名称忽略大小写唯一。资源上的 Tags 字段仍保存以逗号分隔的
+// 标签名，写入时由服务层规范化并同步到 Tagging
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tag_user_name" json:"user_id"`
+	Name      string    `gorm:"size:50;not null" json:"name"`
+	NameKey   string    `gorm:"size:50;not null;uniqueIndex:idx_tag_user_name" json:"-"` // 小写的名称，见 service.TagKey
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Tagging 标签与资源的多态关联
+type Tagging struct {
+	ID           uint   `gorm:"primarykey" json:"id"`
+	TagID        uint   `gorm:"not null;uniqueIndex:idx_tagging" json:"tag_id"`
+	ResourceType string `gorm:"size:20;not null;uniqueIndex:idx_tagging;index:idx_tagging_resource" json:"resource_type"` // note, file, post, bookmark, collection
+	ResourceID   uint   `gorm:"not null;uniqueIndex:idx_tagging;index:idx_tagging_resource" json:"resource_id"`
+}
+
+// SearchDocument 全文检索索引中的一个资源，由服务层在资源写入时同步
+type SearchDocument struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	ResourceType string    `gorm:"size:20;not null;uniqueIndex:idx_search_resource" json:"resource_type"`
+	ResourceID   uint      `gorm:"not null;uniqueIndex:idx_search_resource" json:"resource_id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"` // 资源所有者
+	Title        string    `gorm:"size:255" json:"title"`
+	Date         time.Time `gorm:"index" json:"date"`             // before:/after: 过滤使用的日期，事件为日程日期，其余为创建时间
+	Length       int       `gorm:"not null" json:"length"`        // 词元总数，用于按文档长度归一化得分
+	IndexVersion int       `gorm:"not null" json:"index_version"` // 分词规则版本，低于当前版本的文档会被重建
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SearchPosting 倒排索引：词元在文档标题和正文中出现的次数
+type SearchPosting struct {
+	ID         uint   `gorm:"primarykey" json:"id"`
+	Term       string `gorm:"size:64;not null;index" json:"term"`
+	DocumentID uint   `gorm:"not null;index" json:"document_id"`
+	TitleFreq  int    `gorm:"not null" json:"title_freq"`
+	BodyFreq   int    `gorm:"not null" json:"body_freq"`
+}
+
 // Event represents calendar events
 type Event struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
@@ -150,3 +257,60 @@ type Event struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// Share represents a record shared with another user, or a public link when SharedWithID is 0
+type Share struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	OwnerID      uint           `gorm:"not null;index" json:"owner_id"`
+	ResourceType string         `gorm:"size:20;not null;index:idx_share_resource" json:"resource_type"` // note, file, collection, post
+	ResourceID   uint           `gorm:"not null;index:idx_share_resource" json:"resource_id"`
+	SharedWithID uint           `gorm:"not null;default:0;index" json:"shared_with_id"`    // 0 表示公开链接
+	Permission   string         `gorm:"size:10;not null;default:'read'" json:"permission"` // read, edit
+	Token        string         `gorm:"size:64;index" json:"token,omitempty"`              // 公开链接令牌
+	Password     string         `gorm:"size:255" json:"-"`                                 // 公开链接密码(哈希)
+	HasPassword  bool           `gorm:"-" json:"has_password"`
+	ExpiresAt    *time.Time     `json:"expires_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// AuditLog records a create/update/delete performed through the API
+type AuditLog struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	ActorID      uint      `gorm:"not null;index" json:"actor_id"`
+	IP           string    `gorm:"size:64" json:"ip"`
+	ResourceType string    `gorm:"size:20;not null;index:idx_audit_resource" json:"resource_type"` // note, task, file, event, collection, post, bookmark
+	ResourceID   uint      `gorm:"not null;index:idx_audit_resource" json:"resource_id"`
+	Action       string    `gorm:"size:20;not null;index" json:"action"` // create, update, delete
+	Before       string    `json:"before,omitempty"`                     // 变更前的JSON快照
+	After        string    `json:"after,omitempty"`                      // 变更后的JSON快照
+	Diff         string    `json:"diff,omitempty"`                       // 变化字段 {"field": {"from": x, "to": y}}
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// All 返回所有持久化模型，新增模型需要在这里登记(备份与恢复按此列表导出全部表)
+func All() []interface{} {
+	return []interface{}{
+		&User{},
+		&Note{},
+		&NoteRevision{},
+		&NoteLink{},
+		&NoteTemplate{},
+		&Notebook{},
+		&File{},
+		&Task{},
+		&Bookmark{},
+		&Theme{},
+		&ChatMessage{},
+		&Collection{},
+		&Event{},
+		&Post{},
+		&Tag{},
+		&Tagging{},
+		&SearchDocument{},
+		&SearchPosting{},
+		&Share{},
+		&AuditLog{},
+	}
+}
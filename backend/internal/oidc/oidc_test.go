@@ -0,0 +1,299 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeIdP 进程内的OIDC身份提供方，支持服务发现、JWKS、授权码+PKCE
+type fakeIdP struct {
+	t        *testing.T
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	kid      string
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+
+	// 用于构造异常场景
+	signWith *rsa.PrivateKey
+	audience string
+	expiry   time.Duration
+}
+
+type authRequest struct {
+	challenge string
+	nonce     string
+	redirect  string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	idp := &fakeIdP{
+		t:        t,
+		key:      key,
+		kid:      "test-key",
+		clientID: "nexushub",
+		codes:    make(map[string]authRequest),
+		expiry:   time.Hour,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (f *fakeIdP) issuer() string { return f.server.URL }
+
+func (f *fakeIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(Metadata{
+		Issuer:                f.issuer(),
+		AuthorizationEndpoint: f.issuer() + "/authorize",
+		TokenEndpoint:         f.issuer() + "/token",
+		JWKSURI:               f.issuer() + "/jwks",
+	})
+}
+
+func (f *fakeIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := f.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": f.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize 模拟用户同意授权后直接带code跳转回客户端
+func (f *fakeIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != f.clientID {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	code := "code-" + q.Get("state")
+	f.mu.Lock()
+	f.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), redirect: q.Get("redirect_uri")}
+	f.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (f *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	req, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	f.mu.Unlock()
+	if !ok || r.PostForm.Get("redirect_uri") != req.redirect {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		http.Error(w, `{"error":"invalid_grant","error_description":"pkce"}`, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken: "access",
+		TokenType:   "Bearer",
+		IDToken:     f.idToken(req.nonce),
+		ExpiresIn:   3600,
+	})
+}
+
+func (f *fakeIdP) idToken(nonce string) string {
+	audience := f.clientID
+	if f.audience != "" {
+		audience = f.audience
+	}
+	claims := IDTokenClaims{
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+		Nonce:             nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    f.issuer(),
+			Subject:   "alice-sub",
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(f.expiry)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = f.kid
+
+	key := f.key
+	if f.signWith != nil {
+		key = f.signWith
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		f.t.Fatalf("sign id token: %v", err)
+	}
+	return signed
+}
+
+// login 走完整的授权码流程，返回验证后的声明
+func login(t *testing.T, idp *fakeIdP, tamperVerifier bool, expectNonce func(string) string) (*IDTokenClaims, error) {
+	t.Helper()
+	ctx := context.Background()
+	redirectURL := "http://localhost/api/v1/auth/oidc/callback"
+
+	provider, err := NewProvider(ctx, Config{
+		IssuerURL:   idp.issuer(),
+		ClientID:    idp.clientID,
+		RedirectURL: redirectURL,
+	}, idp.server.Client())
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+
+	state, _ := RandomString(16)
+	nonce, _ := RandomString(16)
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("pkce: %v", err)
+	}
+
+	client := idp.server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(provider.AuthCodeURL(state, nonce, challenge))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	if callback.Query().Get("state") != state {
+		t.Fatalf("state not echoed back")
+	}
+
+	if tamperVerifier {
+		verifier += "x"
+	}
+	token, err := provider.Exchange(ctx, callback.Query().Get("code"), verifier)
+	if err != nil {
+		return nil, err
+	}
+	if expectNonce != nil {
+		nonce = expectNonce(nonce)
+	}
+	return provider.VerifyIDToken(ctx, token.IDToken, nonce)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	claims, err := login(t, idp, false, nil)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if claims.Subject != "alice-sub" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestPKCEVerifierMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	if _, err := login(t, idp, true, nil); err == nil {
+		t.Fatal("expected exchange to fail with wrong code_verifier")
+	}
+}
+
+func TestNonceMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	_, err := login(t, idp, false, func(string) string { return "other-nonce" })
+	if !errors.Is(err, ErrNonceMismatch) {
+		t.Fatalf("expected ErrNonceMismatch, got %v", err)
+	}
+}
+
+func TestForgedSignatureRejected(t *testing.T) {
+	idp := newFakeIdP(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.signWith = other
+
+	_, err = login(t, idp, false, nil)
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+	}
+}
+
+func TestWrongAudienceRejected(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.audience = "another-client"
+
+	_, err := login(t, idp, false, nil)
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+	}
+}
+
+func TestExpiredTokenRejected(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.expiry = -time.Hour
+
+	_, err := login(t, idp, false, nil)
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	_, err := NewProvider(context.Background(), Config{
+		IssuerURL: idp.issuer() + "/other",
+		ClientID:  idp.clientID,
+	}, idp.server.Client())
+	if err == nil {
+		t.Fatal("expected discovery to fail for unknown issuer path")
+	}
+}
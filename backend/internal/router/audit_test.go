@@ -0,0 +1,77 @@
+package router_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogFilteringAndIsolation(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+
+	status, body := alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Plan", "content": "v1"})
+	noteID := createdID(t, status, body)
+	if status, body := alice.do(http.MethodPut, fmt.Sprintf("/api/v1/notes/%d", noteID),
+		map[string]interface{}{"title": "Plan", "content": "v2", "version": 1}); status != http.StatusOK {
+		t.Fatalf("update: %d %s", status, body)
+	}
+	status, body = alice.do(http.MethodPost, "/api/v1/tasks", map[string]string{"title": "Call"})
+	createdID(t, status, body)
+	status, body = bob.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Bob's", "content": "private"})
+	createdID(t, status, body)
+
+	type auditLog struct {
+		ActorID      uint   `json:"actor_id"`
+		ResourceType string `json:"resource_type"`
+		ResourceID   uint   `json:"resource_id"`
+		Action       string `json:"action"`
+		Diff         string `json:"diff"`
+	}
+	audit := func(c *client, query string) ([]auditLog, int64) {
+		t.Helper()
+		status, body := c.do(http.MethodGet, "/api/v1/audit"+query, nil)
+		var page struct {
+			Logs  []auditLog `json:"logs"`
+			Total int64      `json:"total"`
+		}
+		if err := json.Unmarshal(body, &page); status != http.StatusOK || err != nil {
+			t.Fatalf("audit%s: %d %s", query, status, body)
+		}
+		return page.Logs, page.Total
+	}
+
+	// 每个用户只能看到自己的操作
+	logs, total := audit(alice, "")
+	if total != 3 || len(logs) != 3 {
+		t.Fatalf("alice should see her 3 changes, got %d: %+v", total, logs)
+	}
+	for _, log := range logs {
+		if log.ResourceType == "note" && log.ResourceID != noteID {
+			t.Fatalf("alice sees someone else's log: %+v", log)
+		}
+	}
+	if logs, total := audit(bob, ""); total != 1 || logs[0].ResourceType != "note" || logs[0].ResourceID == noteID {
+		t.Fatalf("bob should only see his own note: %+v", logs)
+	}
+
+	logs, total = audit(alice, fmt.Sprintf("?resource_type=note&resource_id=%d&action=update", noteID))
+	if total != 1 || !strings.Contains(logs[0].Diff, `"content":{"from":"v1","to":"v2"}`) {
+		t.Fatalf("update log should carry the content diff: %+v", logs)
+	}
+	if _, total := audit(alice, "?resource_type=task"); total != 1 {
+		t.Fatalf("resource_type filter: %d", total)
+	}
+	if _, total := audit(alice, "?from=2999-01-01"); total != 0 {
+		t.Fatalf("from filter: %d", total)
+	}
+	if logs, total := audit(alice, "?page=2&page_size=2"); total != 3 || len(logs) != 1 {
+		t.Fatalf("pagination: total=%d len=%d", total, len(logs))
+	}
+	if status, _ := alice.do(http.MethodGet, "/api/v1/audit?from=yesterday", nil); status != http.StatusBadRequest {
+		t.Fatalf("invalid from: %d", status)
+	}
+}
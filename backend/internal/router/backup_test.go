@@ -0,0 +1,41 @@
+package router_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"nexushub-personal/internal/config"
+)
+
+func TestBackupsRequireAuthenticatedAdministrator(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		// 单用户模式下未登录请求映射到默认用户，也不能管理备份
+		cfg.Auth.Mode = config.AuthModeSingle
+		cfg.Auth.AdminUsers = []string{"alice"}
+		cfg.Backup = config.BackupConfig{Target: "local", Dir: filepath.Join(t.TempDir(), "backups"), KeepDaily: 7}
+	})
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+	anonymous := &client{t: t, r: r}
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/backups"},
+		{http.MethodPost, "/api/v1/backups"},
+		{http.MethodPost, "/api/v1/backups/20260101-000000.000/restore"},
+	} {
+		if status, _ := anonymous.do(req.method, req.path, nil); status != http.StatusUnauthorized {
+			t.Errorf("anonymous %s %s: expected 401, got %d", req.method, req.path, status)
+		}
+		if status, _ := bob.do(req.method, req.path, nil); status != http.StatusForbidden {
+			t.Errorf("non-admin %s %s: expected 403, got %d", req.method, req.path, status)
+		}
+	}
+
+	if status, body := alice.do(http.MethodPost, "/api/v1/backups", nil); status != http.StatusCreated && status != http.StatusOK {
+		t.Fatalf("admin create backup: %d %s", status, body)
+	}
+	if status, body := alice.do(http.MethodGet, "/api/v1/backups", nil); status != http.StatusOK {
+		t.Fatalf("admin list backups: %d %s", status, body)
+	}
+}
@@ -0,0 +1,83 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptimisticConcurrencyOnUpdate(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+
+	for _, res := range []struct{ name, path string }{
+		{"note", "/api/v1/notes"},
+		{"task", "/api/v1/todos"},
+		{"post", "/api/v1/blog"},
+	} {
+		status, body := alice.do(http.MethodPost, res.path, map[string]interface{}{"title": "v1", "content": "v1"})
+		item := fmt.Sprintf("%s/%d", res.path, createdID(t, status, body))
+
+		// GET 返回版本号和 ETag
+		req := httptest.NewRequest(http.MethodGet, item, nil)
+		req.Header.Set("Authorization", "Bearer "+alice.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+			t.Fatalf("%s GET: %d etag=%q", res.name, w.Code, w.Header().Get("ETag"))
+		}
+
+		// 未指定版本
+		if status, _ := alice.do(http.MethodPut, item, map[string]interface{}{"title": "blind"}); status != http.StatusPreconditionRequired {
+			t.Errorf("%s PUT without version: expected 428, got %d", res.name, status)
+		}
+
+		// If-Match: * 不指定版本，不能用来跳过并发检查
+		raw, _ := json.Marshal(map[string]interface{}{"title": "blind", "content": "blind"})
+		req = httptest.NewRequest(http.MethodPut, item, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		req.Header.Set("Authorization", "Bearer "+alice.token)
+		w = httptest.NewRecorder()
+		if r.ServeHTTP(w, req); w.Code != http.StatusPreconditionRequired {
+			t.Errorf("%s PUT with If-Match *: expected 428, got %d", res.name, w.Code)
+		}
+
+		// 第一个标签页通过 If-Match 保存成功
+		raw, _ = json.Marshal(map[string]interface{}{"title": "tab one", "content": "tab one"})
+		req = httptest.NewRequest(http.MethodPut, item, bytes.NewReader(raw))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"1"`)
+		req.Header.Set("Authorization", "Bearer "+alice.token)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+			t.Fatalf("%s PUT with If-Match: %d etag=%q %s", res.name, w.Code, w.Header().Get("ETag"), w.Body.String())
+		}
+
+		// 第二个标签页仍基于版本1，返回409和服务器上的当前内容
+		status, body = alice.do(http.MethodPut, item, map[string]interface{}{"title": "tab two", "content": "tab two", "version": 1})
+		if status != http.StatusConflict {
+			t.Fatalf("%s stale PUT: expected 409, got %d", res.name, status)
+		}
+		var current struct {
+			Title   string `json:"title"`
+			Version int    `json:"version"`
+		}
+		if err := json.Unmarshal(body, &current); err != nil || current.Title != "tab one" || current.Version != 2 {
+			t.Errorf("%s conflict body: %s", res.name, body)
+		}
+
+		// 基于最新版本重新提交
+		status, body = alice.do(http.MethodPut, item, map[string]interface{}{"title": "tab two", "content": "tab two", "version": 2})
+		if status != http.StatusOK {
+			t.Fatalf("%s PUT with current version: %d %s", res.name, status, body)
+		}
+		if err := json.Unmarshal(body, &current); err != nil || current.Title != "tab two" || current.Version != 3 {
+			t.Errorf("%s updated body: %s", res.name, body)
+		}
+	}
+}
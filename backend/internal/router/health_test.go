@@ -0,0 +1,108 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nexushub-personal/internal/router"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func readyz(t *testing.T, r *gin.Engine) (int, service.HealthSummary) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if strings.Contains(w.Body.String(), "detail") || strings.Contains(w.Body.String(), "error") {
+		t.Fatalf("readyz must only expose status and check names: %s", w.Body.String())
+	}
+	var report service.HealthSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid readyz body %q: %v", w.Body.String(), err)
+	}
+	return w.Code, report
+}
+
+func TestLivenessAndReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testutil.NewConfig(t)
+	services := testutil.SetupServices(t, cfg)
+	r, stop := router.SetupRouter(services)
+	t.Cleanup(stop)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("livez: got %d", w.Code)
+	}
+
+	code, report := readyz(t, r)
+	if code != http.StatusOK || report.Status == service.HealthFail {
+		t.Fatalf("readyz: got %d %+v", code, report)
+	}
+	checks := map[string]service.HealthCheckStatus{}
+	for _, c := range report.Checks {
+		checks[c.Name] = c
+	}
+	for _, name := range []string{"database", "storage", "disk", "code_toolchains"} {
+		if _, ok := checks[name]; !ok {
+			t.Errorf("readyz missing check %q", name)
+		}
+	}
+	if checks["code_toolchains"].Status == service.HealthFail {
+		t.Error("missing toolchains should only degrade readiness")
+	}
+
+	// 要求的剩余空间不可能满足时，关键检查失败，返回503
+	cfg.Health.MinFreeDiskMB = 1 << 40
+	code, report = readyz(t, r)
+	if code != http.StatusServiceUnavailable || report.Status != service.HealthFail {
+		t.Fatalf("readyz with insufficient disk: got %d %q", code, report.Status)
+	}
+
+	// 数据库连接关闭后同样未就绪
+	cfg.Health.MinFreeDiskMB = 0
+	sqlDB, _ := services.DB.DB()
+	sqlDB.Close()
+	code, report = readyz(t, r)
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz with closed db: got %d", code)
+	}
+	for _, c := range report.Checks {
+		if c.Name == "database" && c.Status != service.HealthFail {
+			t.Errorf("database check = %+v", c)
+		}
+	}
+}
+
+func TestReadinessIsCachedAndRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testutil.NewConfig(t)
+	cfg.Health.CacheSeconds = 60
+	services := testutil.SetupServices(t, cfg)
+	r, stop := router.SetupRouter(services)
+	t.Cleanup(stop)
+
+	_, first := readyz(t, r)
+	// 缓存期内依赖的变化不会触发重新探测
+	cfg.Health.MinFreeDiskMB = 1 << 40
+	code, second := readyz(t, r)
+	if code != http.StatusOK || !second.CheckedAt.Equal(first.CheckedAt) {
+		t.Fatalf("readyz within cache period should reuse the result: %d %v %v", code, first.CheckedAt, second.CheckedAt)
+	}
+
+	limited := false
+	for i := 0; i < 20 && !limited; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		limited = w.Code == http.StatusTooManyRequests
+	}
+	if !limited {
+		t.Fatal("readyz should be rate limited per IP")
+	}
+}
@@ -0,0 +1,245 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/router"
+	"nexushub-personal/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestServer 使用临时SQLite数据库和多用户模式启动完整路由
+func newTestServer(t *testing.T) *gin.Engine {
+	t.Helper()
+	return newTestServerWith(t, nil)
+}
+
+// newTestServerWith 同 newTestServer，启动前由 configure 修改配置
+func newTestServerWith(t *testing.T, configure func(cfg *config.Config)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := testutil.NewConfig(t)
+	cfg.Auth.Mode = config.AuthModeMulti
+	if configure != nil {
+		configure(cfg)
+	}
+	r, stop := router.SetupRouter(testutil.SetupServices(t, cfg))
+	t.Cleanup(stop)
+	return r
+}
+
+type client struct {
+	t     *testing.T
+	r     *gin.Engine
+	token string
+}
+
+// do 发送请求并返回状态码与解包后的数据(兼容 common.Response 和原始JSON两种格式)
+func (c *client) do(method, path string, body interface{}) (int, json.RawMessage) {
+	c.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req)
+}
+
+func (c *client) send(req *http.Request) (int, json.RawMessage) {
+	c.t.Helper()
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	w := httptest.NewRecorder()
+	c.r.ServeHTTP(w, req)
+
+	var envelope struct {
+		Code *int            `json:"code"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err == nil && envelope.Code != nil {
+		return w.Code, envelope.Data
+	}
+	return w.Code, w.Body.Bytes()
+}
+
+func (c *client) upload(name, content string) (int, json.RawMessage) {
+	c.t.Helper()
+	return c.uploadTo("/api/v1/files/upload", name, content)
+}
+
+// uploadTo 以 multipart 表单字段 file 上传内容到 path
+func (c *client) uploadTo(path, name, content string) (int, json.RawMessage) {
+	c.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, _ := mw.CreateFormFile("file", name)
+	part.Write([]byte(content))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return c.send(req)
+}
+
+func register(t *testing.T, r *gin.Engine, username string) *client {
+	t.Helper()
+	c := &client{t: t, r: r}
+	status, body := c.do(http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"username": username,
+		"password": "password123",
+		"email":    username + "@example.com",
+	})
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("register %s: %d %s", username, status, body)
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Token == "" {
+		t.Fatalf("register %s: no token in %s", username, body)
+	}
+	c.token = resp.Token
+	return c
+}
+
+func createdID(t *testing.T, status int, body json.RawMessage) uint {
+	t.Helper()
+	if status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("create failed: %d %s", status, body)
+	}
+	var v struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil || v.ID == 0 {
+		t.Fatalf("no id in response: %s", body)
+	}
+	return v.ID
+}
+
+func TestMultiUserModeRequiresAuth(t *testing.T) {
+	r := newTestServer(t)
+
+	anonymous := &client{t: t, r: r}
+	for _, path := range []string{"/api/v1/notes", "/api/v1/events", "/api/v1/blog", "/api/v1/files"} {
+		if status, _ := anonymous.do(http.MethodGet, path, nil); status != http.StatusUnauthorized {
+			t.Errorf("GET %s without token: expected 401, got %d", path, status)
+		}
+	}
+
+	forged := &client{t: t, r: r, token: "not-a-jwt"}
+	if status, _ := forged.do(http.MethodGet, "/api/v1/notes", nil); status != http.StatusUnauthorized {
+		t.Errorf("invalid token: expected 401, got %d", status)
+	}
+}
+
+func TestUsersCannotAccessEachOthersData(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+
+	resources := []struct {
+		name   string
+		list   string
+		item   string
+		update string
+		body   interface{}
+		id     uint
+	}{
+		{name: "note", list: "/api/v1/notes", item: "/api/v1/notes/%d", update: "/api/v1/notes/%d",
+			body: map[string]interface{}{"title": "alice note", "content": "secret"}},
+		{name: "event", list: "/api/v1/events", item: "/api/v1/events/%d", update: "/api/v1/events/%d",
+			body: map[string]interface{}{"title": "alice event", "date": "2024-01-01"}},
+		{name: "post", list: "/api/v1/blog", update: "/api/v1/blog/%d",
+			body: map[string]interface{}{"title": "alice post", "content": "secret"}},
+		{name: "notebook", list: "/api/v1/notebooks", item: "/api/v1/notebooks/%d", update: "/api/v1/notebooks/%d",
+			body: map[string]interface{}{"name": "alice notebook", "icon": "📓"}},
+		{name: "note template", list: "/api/v1/note-templates", item: "/api/v1/note-templates/%d", update: "/api/v1/note-templates/%d",
+			body: map[string]interface{}{"name": "alice template", "content": "secret"}},
+	}
+
+	for i := range resources {
+		res := &resources[i]
+		status, body := alice.do(http.MethodPost, res.list, res.body)
+		res.id = createdID(t, status, body)
+	}
+	status, body := alice.upload("secret.txt", "alice's file")
+	fileID := createdID(t, status, body)
+
+	for _, res := range resources {
+		// 列表中不应出现他人的数据
+		status, body = bob.do(http.MethodGet, res.list, nil)
+		if status != http.StatusOK {
+			t.Fatalf("bob list %s: %d %s", res.name, status, body)
+		}
+		if bytes.Contains(body, []byte("alice")) {
+			t.Errorf("bob can see alice's %s in list: %s", res.name, body)
+		}
+
+		if res.item != "" {
+			if status, _ := bob.do(http.MethodGet, fmt.Sprintf(res.item, res.id), nil); status != http.StatusNotFound {
+				t.Errorf("bob GET alice's %s: expected 404, got %d", res.name, status)
+			}
+		}
+
+		status, _ = bob.do(http.MethodPut, fmt.Sprintf(res.update, res.id), map[string]interface{}{
+			"title": "hacked", "content": "hacked", "date": "2024-01-02",
+		})
+		if status != http.StatusNotFound {
+			t.Errorf("bob PUT alice's %s: expected 404, got %d", res.name, status)
+		}
+
+		if status, _ := bob.do(http.MethodDelete, fmt.Sprintf(res.update, res.id), nil); status != http.StatusNotFound {
+			t.Errorf("bob DELETE alice's %s: expected 404, got %d", res.name, status)
+		}
+	}
+
+	// 不能使用他人的模板创建笔记
+	templateID := resources[len(resources)-1].id
+	if status, _ := bob.do(http.MethodPost, fmt.Sprintf("/api/v1/notes/from-template/%d", templateID), nil); status != http.StatusNotFound {
+		t.Errorf("bob POST from alice's template: expected 404, got %d", status)
+	}
+
+	// 文件
+	if status, body := bob.do(http.MethodGet, "/api/v1/files", nil); status != http.StatusOK || bytes.Contains(body, []byte("secret.txt")) {
+		t.Errorf("bob file list: %d %s", status, body)
+	}
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, fmt.Sprintf("/api/v1/files/%d", fileID)},
+		{http.MethodGet, fmt.Sprintf("/api/v1/files/download/%d", fileID)},
+		{http.MethodPut, fmt.Sprintf("/api/v1/files/%d/rename", fileID)},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/files/%d", fileID)},
+	} {
+		status, _ := bob.do(req.method, req.path, map[string]string{"new_name": "hacked.txt"})
+		if status != http.StatusNotFound {
+			t.Errorf("bob %s %s: expected 404, got %d", req.method, req.path, status)
+		}
+	}
+
+	// alice 的数据保持不变
+	for _, res := range resources {
+		status, body := alice.do(http.MethodGet, res.list, nil)
+		if status != http.StatusOK || !bytes.Contains(body, []byte("alice")) || bytes.Contains(body, []byte("hacked")) {
+			t.Errorf("alice's %s changed: %d %s", res.name, status, body)
+		}
+	}
+	if status, body := alice.do(http.MethodGet, fmt.Sprintf("/api/v1/files/%d", fileID), nil); status != http.StatusOK || !bytes.Contains(body, []byte("secret.txt")) {
+		t.Errorf("alice's file changed: %d %s", status, body)
+	}
+	if status, body := alice.do(http.MethodGet, fmt.Sprintf("/api/v1/files/download/%d", fileID), nil); status != http.StatusOK || string(body) != "alice's file" {
+		t.Errorf("alice's file content changed: %d %s", status, body)
+	}
+}
@@ -0,0 +1,81 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nexushub-personal/internal/router"
+	"nexushub-personal/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testutil.NewConfig(t)
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Token = "scrape-secret"
+	r, stop := router.SetupRouter(testutil.SetupServices(t, cfg))
+	t.Cleanup(stop)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/notes/12345", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("scrape without token: got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-secret")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape: got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`nexushub_http_requests_total{method="GET",route="/api/v1/notes/:id",status="404"}`,
+		`nexushub_http_request_duration_seconds_bucket{method="GET",route="/api/v1/notes/:id",status="404",le="+Inf"}`,
+		"go_goroutines ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+}
+
+func TestMetricsWithoutTokenOnlyFromLocalhost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testutil.NewConfig(t)
+	cfg.Metrics.Enabled = true
+	r, stop := router.SetupRouter(testutil.SetupServices(t, cfg))
+	t.Cleanup(stop)
+
+	scrape := func(remoteAddr string, header http.Header) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := scrape("192.0.2.1:1234", nil); code != http.StatusForbidden {
+		t.Errorf("remote scrape: got %d, want 403", code)
+	}
+	// 本机反向代理转发的外部请求
+	if code := scrape("127.0.0.1:1234", http.Header{"X-Forwarded-For": {"192.0.2.1"}}); code != http.StatusForbidden {
+		t.Errorf("proxied scrape: got %d, want 403", code)
+	}
+	if code := scrape("127.0.0.1:1234", nil); code != http.StatusOK {
+		t.Errorf("local scrape: got %d, want 200", code)
+	}
+	if code := scrape("[::1]:1234", nil); code != http.StatusOK {
+		t.Errorf("local IPv6 scrape: got %d, want 200", code)
+	}
+}
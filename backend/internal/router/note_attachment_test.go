@@ -0,0 +1,172 @@
+package router_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNoteAttachments(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+
+	status, body := alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Trip", "content": "plan"})
+	noteID := createdID(t, status, body)
+	status, body = alice.uploadTo(fmt.Sprintf("/api/v1/notes/%d/attachments", noteID), "route.txt", "north then east")
+	kept := createdID(t, status, body)
+	var attachment struct {
+		NoteID   uint   `json:"note_id"`
+		URL      string `json:"url"`
+		Markdown string `json:"markdown"`
+	}
+	json.Unmarshal(body, &attachment)
+	wantURL := fmt.Sprintf("/api/v1/files/%d/content", kept)
+	if attachment.NoteID != noteID || attachment.URL != wantURL || attachment.Markdown != "[route.txt]("+wantURL+")" {
+		t.Fatalf("unexpected attachment: %s", body)
+	}
+
+	// 内容链接只依赖文件ID，改名后仍然有效
+	if status, body := alice.do(http.MethodPut, fmt.Sprintf("/api/v1/files/%d/rename", kept), map[string]string{"new_name": "renamed"}); status != http.StatusOK {
+		t.Fatalf("rename: %d %s", status, body)
+	}
+	content := func(c *client, id uint) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/files/%d/content", id), nil)
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := content(alice, kept); w.Code != http.StatusOK || w.Body.String() != "north then east" || w.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Fatalf("content: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	// 其他用户不能上传到或读取别人的笔记附件，笔记共享后可以读取
+	if status, _ := bob.uploadTo(fmt.Sprintf("/api/v1/notes/%d/attachments", noteID), "x.txt", "x"); status != http.StatusNotFound {
+		t.Fatalf("bob uploaded to alice's note: %d", status)
+	}
+	if w := content(bob, kept); w.Code != http.StatusNotFound {
+		t.Fatalf("bob read alice's attachment: %d", w.Code)
+	}
+	if status, body := alice.do(http.MethodPost, "/api/v1/shares", map[string]interface{}{
+		"resource_type": "note", "resource_id": noteID, "username": "bob",
+	}); status != http.StatusOK && status != http.StatusCreated {
+		t.Fatalf("share: %d %s", status, body)
+	}
+	if w := content(bob, kept); w.Code != http.StatusOK {
+		t.Fatalf("shared note attachment: %d", w.Code)
+	}
+
+	// 删除笔记时只删除没有被其他记录使用的附件
+	status, body = alice.uploadTo(fmt.Sprintf("/api/v1/notes/%d/attachments", noteID), "old.txt", "old")
+	dropped := createdID(t, status, body)
+	status, body = alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Other", "content": "see " + wantURL})
+	createdID(t, status, body)
+	if status, body := alice.do(http.MethodGet, fmt.Sprintf("/api/v1/notes/%d/attachments", noteID), nil); status != http.StatusOK || len(decodeList(t, body)) != 2 {
+		t.Fatalf("attachments: %d %s", status, body)
+	}
+	status, body = alice.do(http.MethodDelete, fmt.Sprintf("/api/v1/notes/%d?remove_attachments=true", noteID), nil)
+	var deleted struct {
+		Removed []uint `json:"removed_attachments"`
+	}
+	if err := json.Unmarshal(body, &deleted); status != http.StatusOK || err != nil || len(deleted.Removed) != 1 || deleted.Removed[0] != dropped {
+		t.Fatalf("delete note: %d %s", status, body)
+	}
+	if status, _ := alice.do(http.MethodGet, fmt.Sprintf("/api/v1/files/%d", dropped), nil); status != http.StatusNotFound {
+		t.Fatalf("unused attachment should be deleted: %d", status)
+	}
+	if status, _ := alice.do(http.MethodGet, fmt.Sprintf("/api/v1/files/%d", kept), nil); status != http.StatusOK {
+		t.Fatalf("attachment linked from another note should be kept: %d", status)
+	}
+}
+
+func decodeList(t *testing.T, body json.RawMessage) []json.RawMessage {
+	t.Helper()
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		t.Fatalf("not a list: %s", body)
+	}
+	return items
+}
+
+func TestSignedAttachmentLinks(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+	anonymous := &client{t: t, r: r}
+
+	status, body := alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Trip", "content": "plan"})
+	noteID := createdID(t, status, body)
+	status, body = alice.uploadTo(fmt.Sprintf("/api/v1/notes/%d/attachments", noteID), "route.txt", "north then east")
+	fileID := createdID(t, status, body)
+	stable := fmt.Sprintf("/api/v1/files/%d/content", fileID)
+	if status, body := alice.do(http.MethodPut, fmt.Sprintf("/api/v1/notes/%d", noteID),
+		map[string]interface{}{"title": "Trip", "content": "![route](" + stable + ")", "version": 1}); status != http.StatusOK {
+		t.Fatalf("update: %d %s", status, body)
+	}
+
+	// src 为签名链接，不带 Authorization 头即可加载
+	imageSrc := func(body json.RawMessage) string {
+		t.Helper()
+		var note struct {
+			ContentHTML string `json:"content_html"`
+		}
+		json.Unmarshal(body, &note)
+		m := regexp.MustCompile(`src="([^"]+)"`).FindStringSubmatch(note.ContentHTML)
+		if m == nil {
+			t.Fatalf("no image in %s", body)
+		}
+		return html.UnescapeString(m[1])
+	}
+	load := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+	_, body = alice.do(http.MethodGet, fmt.Sprintf("/api/v1/notes/%d", noteID), nil)
+	signed := imageSrc(body)
+	if !strings.HasPrefix(signed, stable+"?expires=") {
+		t.Fatalf("attachment link should be signed: %s", signed)
+	}
+	if w := load(signed); w.Code != http.StatusOK || w.Body.String() != "north then east" {
+		t.Fatalf("signed link: %d %q", w.Code, w.Body.String())
+	}
+	if w := load(stable); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned link without header: %d", w.Code)
+	}
+	if w := load(strings.Replace(signed, "signature=", "signature=x", 1)); w.Code != http.StatusNotFound {
+		t.Fatalf("tampered signature: %d", w.Code)
+	}
+	if w := load(strings.Replace(signed, stable, fmt.Sprintf("/api/v1/files/%d/content", fileID+1), 1)); w.Code != http.StatusNotFound {
+		t.Fatalf("signature must be bound to the file: %d", w.Code)
+	}
+
+	// 引用别人的文件不会得到签名链接
+	status, body = bob.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Steal", "content": "![x](" + stable + ")"})
+	createdID(t, status, body)
+	if src := imageSrc(body); src != stable {
+		t.Fatalf("bob should not get a signed link to alice's file: %s", src)
+	}
+
+	// 公开链接页面中的附件同样可以加载
+	status, body = alice.do(http.MethodPost, "/api/v1/shares/links", map[string]interface{}{"resource_type": "note", "resource_id": noteID})
+	var link struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(body, &link); status != http.StatusCreated || link.URL == "" {
+		t.Fatalf("create link: %d %s", status, body)
+	}
+	status, body = anonymous.do(http.MethodGet, link.URL, nil)
+	var page struct {
+		Resource json.RawMessage `json:"resource"`
+	}
+	json.Unmarshal(body, &page)
+	if w := load(imageSrc(page.Resource)); status != http.StatusOK || w.Code != http.StatusOK || w.Body.String() != "north then east" {
+		t.Fatalf("public page attachment: %d %d %q", status, w.Code, w.Body.String())
+	}
+}
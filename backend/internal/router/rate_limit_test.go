@@ -0,0 +1,197 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/constants"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postJSON 发送请求并返回完整响应，用于检查 Retry-After 等响应头
+func postJSON(r *gin.Engine, path, token string, body interface{}) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// assertTooManyRequests 检查429响应的 Retry-After 头和错误消息
+func assertTooManyRequests(t *testing.T, w *httptest.ResponseRecorder, message string) int {
+	t.Helper()
+	var resp struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if w.Code != http.StatusTooManyRequests || err != nil || retry < 1 || resp.Message != message {
+		t.Fatalf("expected 429 %q with Retry-After, got %d %q %s", message, w.Code, w.Header().Get("Retry-After"), w.Body.String())
+	}
+	return retry
+}
+
+func login(r *gin.Engine, username, password string) *httptest.ResponseRecorder {
+	return postJSON(r, "/api/v1/auth/login", "", map[string]string{"username": username, "password": password})
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginPerMinute = 3
+	})
+	register(t, r, "alice")
+
+	// 注册和登录共用按IP计数的限额
+	for i := 0; i < 2; i++ {
+		if w := login(r, "alice", "password123"); w.Code != http.StatusOK {
+			t.Fatalf("login %d: %d %s", i, w.Code, w.Body.String())
+		}
+	}
+	assertTooManyRequests(t, login(r, "alice", "password123"), constants.ErrTooManyRequests)
+}
+
+func TestAPIRateLimit(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.APIPerMinute = 1
+		cfg.RateLimit.APIBurst = 2
+	})
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+
+	// 请求体无效，通过限流后返回400
+	parse := func(c *client) *httptest.ResponseRecorder {
+		return postJSON(r, "/api/v1/collections/parse", c.token, map[string]string{})
+	}
+	for i := 0; i < 2; i++ {
+		if w := parse(alice); w.Code != http.StatusBadRequest {
+			t.Fatalf("request %d: %d %s", i, w.Code, w.Body.String())
+		}
+	}
+	if retry := assertTooManyRequests(t, parse(alice), constants.ErrTooManyRequests); retry > 60 {
+		t.Fatalf("one request per minute should wait at most 60s, got %d", retry)
+	}
+
+	// 按用户计数，其他用户不受影响
+	if w := parse(bob); w.Code != http.StatusBadRequest {
+		t.Fatalf("bob should not be limited: %d", w.Code)
+	}
+}
+
+func TestLoginFailureBackoff(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginBackoffSeconds = 30
+	})
+	register(t, r, "alice")
+
+	if w := login(r, "alice", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: %d", w.Code)
+	}
+	// 退避期间即使密码正确也要等待
+	if retry := assertTooManyRequests(t, login(r, "alice", "password123"), constants.ErrTooManyRequests); retry > 30 {
+		t.Fatalf("first failure should back off for the base duration, got %ds", retry)
+	}
+}
+
+func TestLoginLockoutAndResetAfterSuccess(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginMaxFailures = 3
+		cfg.RateLimit.LoginLockoutMinutes = 15
+	})
+	register(t, r, "alice")
+	register(t, r, "bob")
+
+	// 成功登录清除该用户名的失败记录，之前的失败不计入锁定
+	for i := 0; i < 2; i++ {
+		loginFrom(r, "192.0.2.1:1234", "", "alice", "wrong")
+	}
+	if w := loginFrom(r, "192.0.2.1:1234", "", "alice", "password123"); w.Code != http.StatusOK {
+		t.Fatalf("login after failures: %d %s", w.Code, w.Body.String())
+	}
+	for i := 0; i < 2; i++ {
+		if w := loginFrom(r, "192.0.2.2:1234", "", "alice", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d after reset: %d", i, w.Code)
+		}
+	}
+	if w := loginFrom(r, "192.0.2.3:1234", "", "alice", "password123"); w.Code != http.StatusOK {
+		t.Fatalf("counter should have been reset: %d %s", w.Code, w.Body.String())
+	}
+
+	// 连续失败达到上限后锁定，正确密码也被拒绝
+	for i := 0; i < 3; i++ {
+		loginFrom(r, "192.0.2.4:1234", "", "alice", "wrong")
+	}
+	if retry := assertTooManyRequests(t, loginFrom(r, "192.0.2.5:1234", "", "alice", "password123"), constants.ErrAccountLocked); retry <= 14*60 {
+		t.Fatalf("lockout should last about 15 minutes, got %ds", retry)
+	}
+	// 同一IP的其他账号也被锁定
+	assertTooManyRequests(t, loginFrom(r, "192.0.2.4:1234", "", "bob", "password123"), constants.ErrAccountLocked)
+}
+
+func TestLoginSuccessDoesNotResetIPFailures(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginMaxFailures = 3
+		cfg.RateLimit.LoginLockoutMinutes = 15
+	})
+	register(t, r, "alice")
+	register(t, r, "mallory")
+
+	// 猜测他人密码的间隙登录自己的账号，不能重置按IP的失败计数
+	const attacker = "198.51.100.7:1234"
+	for i := 0; i < 2; i++ {
+		loginFrom(r, attacker, "", "alice", "guess"+strconv.Itoa(i))
+	}
+	if w := loginFrom(r, attacker, "", "mallory", "password123"); w.Code != http.StatusOK {
+		t.Fatalf("own login: %d %s", w.Code, w.Body.String())
+	}
+	loginFrom(r, attacker, "", "bob", "guess")
+	assertTooManyRequests(t, loginFrom(r, attacker, "", "mallory", "password123"), constants.ErrAccountLocked)
+}
+
+// loginFrom 以 remoteAddr 为连接地址登录，forwardedFor 非空时携带 X-Forwarded-For
+func loginFrom(r *gin.Engine, remoteAddr, forwardedFor, username, password string) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(map[string]string{"username": username, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestForwardedForIgnoredUnlessProxyTrusted(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginPerMinute = 2
+	})
+
+	// 轮换 X-Forwarded-For 不能绕过按IP的限流
+	for i := 0; i < 2; i++ {
+		loginFrom(r, "192.0.2.1:1234", "198.51.100."+strconv.Itoa(i), "alice", "wrong")
+	}
+	assertTooManyRequests(t, loginFrom(r, "192.0.2.1:1234", "198.51.100.9", "alice", "wrong"), constants.ErrTooManyRequests)
+
+	// 来自受信任代理的请求按转发的客户端IP计数
+	r = newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginPerMinute = 2
+		cfg.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	})
+	for i := 0; i < 2; i++ {
+		loginFrom(r, "10.0.0.1:1234", "198.51.100.1", "alice", "wrong")
+	}
+	if w := loginFrom(r, "10.0.0.1:1234", "198.51.100.2", "alice", "wrong"); w.Code == http.StatusTooManyRequests {
+		t.Fatal("clients behind a trusted proxy should be limited separately")
+	}
+	assertTooManyRequests(t, loginFrom(r, "10.0.0.1:1234", "198.51.100.1", "alice", "wrong"), constants.ErrTooManyRequests)
+}
@@ -0,0 +1,37 @@
+package router_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNoteResponsesRenderMarkdown(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+
+	status, body := alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Doc", "content": "# Intro\n\n**bold**"})
+	id := createdID(t, status, body)
+
+	var note struct {
+		Title       string `json:"title"`
+		ContentHTML string `json:"content_html"`
+		TOC         []struct {
+			ID string `json:"id"`
+		} `json:"toc"`
+	}
+	status, body = alice.do(http.MethodGet, fmt.Sprintf("/api/v1/notes/%d", id), nil)
+	json.Unmarshal(body, &note)
+	if status != http.StatusOK || note.Title != "Doc" || !strings.Contains(note.ContentHTML, "<strong>bold</strong>") ||
+		len(note.TOC) != 1 || note.TOC[0].ID != "intro" {
+		t.Fatalf("note should include rendered content: %d %s", status, body)
+	}
+
+	// 列表不渲染正文
+	status, body = alice.do(http.MethodGet, "/api/v1/notes", nil)
+	if status != http.StatusOK || strings.Contains(string(body), "content_html") {
+		t.Fatalf("list should not include content_html: %d %s", status, body)
+	}
+}
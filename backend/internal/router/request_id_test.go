@@ -0,0 +1,39 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDInHeaderAndErrorBody(t *testing.T) {
+	r := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/notes/abc", nil)
+	req.Header.Set("X-Request-ID", "client-supplied-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("X-Request-ID"); got != "client-supplied-1" {
+		t.Errorf("response header X-Request-ID = %q", got)
+	}
+
+	// 非法的请求ID会被替换为新生成的ID
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/notes/abc", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	id := w.Header().Get("X-Request-ID")
+	if id == "" || id == "bad id\nwith newline" {
+		t.Fatalf("expected a generated request id, got %q", id)
+	}
+
+	var body struct {
+		RequestID string `json:"request_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code < 400 || body.RequestID != id {
+		t.Errorf("error body should carry the request id: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,22 +1,39 @@
 package router
 
 import (
-	"nexushub-personal/internal/database"
+	"time"
+
+	"nexushub-personal/internal/config"
 	"nexushub-personal/internal/handler"
+	"nexushub-personal/internal/metrics"
 	"nexushub-personal/internal/middleware"
+	"nexushub-personal/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter() *gin.Engine {
-	r := gin.Default()
+// SetupRouter 注册所有路由，handler 使用 services 中注入的服务。
+// 返回的 stop 注销限流配置的热更新回调，服务关闭时调用
+func SetupRouter(services *service.Services) (r *gin.Engine, stop func()) {
+	r = gin.New()
+	// 只信任配置的反向代理转发的客户端IP，否则任何人都能伪造 X-Forwarded-For 绕过按IP的限流。
+	// 地址已在配置校验时检查，无效时不信任任何代理
+	if err := r.SetTrustedProxies(config.AppConfig.Server.TrustedProxies); err != nil {
+		r.SetTrustedProxies(nil)
+	}
 
 	// Middleware
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Recovery())
 	r.Use(middleware.CORS())
 	r.Use(middleware.RequestLogger())
+	r.Use(middleware.Metrics())
 
 	// Serve static files from uploads directory
-	r.Static("/uploads", "./storage/uploads")
+	// 多用户模式下上传文件只能通过带权限校验的接口或分享链接访问
+	if !config.AppConfig.IsMultiUser() {
+		r.Static("/uploads", "./storage/uploads")
+	}
 
 	// Root handler
 	r.GET("/", func(c *gin.Context) {
@@ -32,15 +49,72 @@ func SetupRouter() *gin.Engine {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Liveness probe for orchestrators (readiness is registered below, behind a rate limit)
+	healthHandler := handler.NewHealthHandler(services)
+	r.GET("/livez", healthHandler.Livez)
+
+	// Prometheus metrics
+	if config.AppConfig.Metrics.Enabled {
+		r.GET("/metrics", middleware.MetricsAuth(config.AppConfig.Metrics.Token), gin.WrapH(metrics.Default.Handler()))
+	}
+
+	// Rate limiting (shared in-memory store, limits are hot-reloadable)
+	rl := config.Runtime().RateLimit
+	limitStore := middleware.NewMemoryStore()
+	loginLimiter := middleware.NewRateLimiter(limitStore, "login", rl.LoginPerMinute, rl.LoginPerMinute)
+	apiLimiter := middleware.NewRateLimiter(limitStore, "api", rl.APIPerMinute, rl.APIBurst)
+	loginGuard := middleware.NewLoginGuard(limitStore, "login", rl.LoginMaxFailures,
+		time.Duration(rl.LoginLockoutMinutes)*time.Minute,
+		time.Duration(rl.LoginBackoffSeconds)*time.Second)
+	// 带密码的公开链接与登录使用相同的限流和失败锁定策略，按链接令牌和IP计数
+	shareLimiter := middleware.NewRateLimiter(limitStore, "share", rl.LoginPerMinute, rl.LoginPerMinute)
+	shareGuard := middleware.NewLoginGuard(limitStore, "share", rl.LoginMaxFailures,
+		time.Duration(rl.LoginLockoutMinutes)*time.Minute,
+		time.Duration(rl.LoginBackoffSeconds)*time.Second)
+	expensive := middleware.RateLimit(apiLimiter, middleware.KeyByUser)
+	// 就绪探针无需认证，按IP限制为每秒1次，足够编排系统周期性探测
+	probeLimiter := middleware.NewRateLimiter(limitStore, "probe", 60, 10)
+	r.GET("/readyz", middleware.RateLimit(probeLimiter, middleware.KeyByIP), healthHandler.Readyz)
+	stop = config.OnReload(func(rc config.RuntimeConfig) {
+		rl := rc.RateLimit
+		loginLimiter.SetLimit(rl.LoginPerMinute, rl.LoginPerMinute)
+		shareLimiter.SetLimit(rl.LoginPerMinute, rl.LoginPerMinute)
+		apiLimiter.SetLimit(rl.APIPerMinute, rl.APIBurst)
+		for _, guard := range []*middleware.LoginGuard{loginGuard, shareGuard} {
+			guard.SetPolicy(rl.LoginMaxFailures,
+				time.Duration(rl.LoginLockoutMinutes)*time.Minute,
+				time.Duration(rl.LoginBackoffSeconds)*time.Second)
+		}
+	})
+
+	// Public share links (no authentication)
+	shareHandler := handler.NewShareHandler(services, shareGuard)
+	public := r.Group("/s")
+	public.Use(middleware.RateLimit(shareLimiter, middleware.KeyByIP))
+	{
+		public.GET("/:token", shareHandler.GetPublic)
+		public.GET("/:token/download", shareHandler.DownloadPublic)
+		public.POST("/:token/unlock", shareHandler.UnlockPublic)
+	}
+
 	// API v1
 	v1 := r.Group("/api/v1")
 	{
 		// Auth routes (public)
-		authHandler := handler.NewAuthHandler()
+		authHandler := handler.NewAuthHandler(services, loginGuard)
 		auth := v1.Group("/auth")
+		auth.Use(middleware.RateLimit(loginLimiter, middleware.KeyByIP))
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+
+			// OpenID Connect login
+			if config.AppConfig.OIDC.Enabled {
+				oidcHandler := handler.NewOIDCHandler(config.AppConfig.OIDC, nil, services.Users)
+				auth.GET("/oidc/login", oidcHandler.Login)
+				auth.GET("/oidc/callback", oidcHandler.Callback)
+				auth.POST("/oidc/link", middleware.AuthMiddleware(), oidcHandler.Link)
+			}
 		}
 
 		// Health check (v1)
@@ -48,6 +122,10 @@ func SetupRouter() *gin.Engine {
 			c.JSON(200, gin.H{"status": "ok"})
 		})
 
+		// 附件内容也可以通过签名链接访问，供 <img src> 和公开链接页面使用
+		fileHandler := handler.NewFileHandler(services)
+		v1.GET("/files/:id/content", middleware.SignedOrAuth(middleware.OptionalAuthMiddleware()), fileHandler.Content)
+
 		// Apply optional authentication - allows guest access
 		v1.Use(middleware.OptionalAuthMiddleware())
 
@@ -55,18 +133,56 @@ func SetupRouter() *gin.Engine {
 		v1.GET("/profile", authHandler.GetProfile)
 
 		// Notes
-		noteHandler := handler.NewNoteHandler()
+		noteHandler := handler.NewNoteHandler(services)
 		notes := v1.Group("/notes")
 		{
 			notes.GET("", noteHandler.GetAll)
+			notes.GET("/graph", noteHandler.GetGraph)
+			notes.GET("/daily/:date", noteHandler.GetDaily)
+			notes.POST("/from-template/:id", noteHandler.CreateFromTemplate)
+			notes.POST("/move", noteHandler.Move)
+			notes.POST("/reorder", noteHandler.Reorder)
 			notes.GET("/:id", noteHandler.GetByID)
 			notes.POST("", noteHandler.Create)
 			notes.PUT("/:id", noteHandler.Update)
 			notes.DELETE("/:id", noteHandler.Delete)
+			notes.GET("/:id/backlinks", noteHandler.GetBacklinks)
+			notes.POST("/:id/archive", noteHandler.Archive)
+			notes.POST("/:id/unarchive", noteHandler.Unarchive)
+			notes.GET("/:id/attachments", noteHandler.GetAttachments)
+			notes.POST("/:id/attachments", noteHandler.UploadAttachment)
+			notes.GET("/:id/revisions", noteHandler.GetRevisions)
+			notes.GET("/:id/revisions/diff", noteHandler.DiffRevisions)
+			notes.GET("/:id/revisions/:rev", noteHandler.GetRevision)
+			notes.POST("/:id/revisions/:rev/restore", noteHandler.RestoreRevision)
+		}
+
+		// Notebooks
+		notebookHandler := handler.NewNotebookHandler(services)
+		notebooks := v1.Group("/notebooks")
+		{
+			notebooks.GET("", notebookHandler.GetAll)
+			notebooks.GET("/tree", notebookHandler.GetTree)
+			notebooks.GET("/:id", notebookHandler.GetByID)
+			notebooks.POST("", notebookHandler.Create)
+			notebooks.PUT("/:id", notebookHandler.Update)
+			notebooks.POST("/:id/move", notebookHandler.Move)
+			notebooks.DELETE("/:id", notebookHandler.Delete)
+		}
+
+		// Note templates
+		noteTemplateHandler := handler.NewNoteTemplateHandler(services)
+		noteTemplates := v1.Group("/note-templates")
+		{
+			noteTemplates.GET("", noteTemplateHandler.GetAll)
+			noteTemplates.GET("/:id", noteTemplateHandler.GetByID)
+			noteTemplates.POST("", noteTemplateHandler.Create)
+			noteTemplates.PUT("/:id", noteTemplateHandler.Update)
+			noteTemplates.DELETE("/:id", noteTemplateHandler.Delete)
 		}
 
 		// Tasks / Todos
-		taskHandler := handler.NewTaskHandler()
+		taskHandler := handler.NewTaskHandler(services)
 		tasks := v1.Group("/tasks")
 		{
 			tasks.GET("", taskHandler.GetAll)
@@ -86,7 +202,7 @@ func SetupRouter() *gin.Engine {
 		}
 
 		// Bookmarks
-		bookmarkHandler := handler.NewBookmarkHandler()
+		bookmarkHandler := handler.NewBookmarkHandler(services)
 		bookmarks := v1.Group("/bookmarks")
 		{
 			bookmarks.GET("", bookmarkHandler.GetAll)
@@ -97,18 +213,17 @@ func SetupRouter() *gin.Engine {
 		}
 
 		// Events
-		eventHandler := handler.NewEventHandler()
+		eventHandler := handler.NewEventHandler(services)
 		events := v1.Group("/events")
 		{
-			events.GET("", eventHandler.GetAllEvents)
-			events.GET("/:id", eventHandler.GetEventByID)
-			events.POST("", eventHandler.CreateEvent)
-			events.PUT("/:id", eventHandler.UpdateEvent)
-			events.DELETE("/:id", eventHandler.DeleteEvent)
+			events.GET("", eventHandler.GetAll)
+			events.GET("/:id", eventHandler.GetByID)
+			events.POST("", eventHandler.Create)
+			events.PUT("/:id", eventHandler.Update)
+			events.DELETE("/:id", eventHandler.Delete)
 		}
 
 		// Files
-		fileHandler := handler.NewFileHandler()
 		files := v1.Group("/files")
 		{
 			files.GET("", fileHandler.GetAll)
@@ -117,11 +232,12 @@ func SetupRouter() *gin.Engine {
 			files.POST("/upload", fileHandler.Upload)
 			files.GET("/download/:id", fileHandler.Download)
 			files.PUT("/:id/rename", fileHandler.Rename)
+			files.PUT("/:id/tags", fileHandler.SetTags)
 			files.DELETE("/:id", fileHandler.Delete)
 		}
 
 		// Theme
-		themeHandler := handler.NewThemeHandler()
+		themeHandler := handler.NewThemeHandler(services)
 		theme := v1.Group("/theme")
 		{
 			theme.GET("", themeHandler.Get)
@@ -129,7 +245,7 @@ func SetupRouter() *gin.Engine {
 		}
 
 		// Chat
-		chatHandler := handler.NewChatHandler()
+		chatHandler := handler.NewChatHandler(services)
 		chat := v1.Group("/chat")
 		{
 			chat.GET("/history", chatHandler.GetHistory)
@@ -138,41 +254,87 @@ func SetupRouter() *gin.Engine {
 		}
 
 		// Collection
-		collectionHandler := handler.NewCollectionHandler()
+		collectionHandler := handler.NewCollectionHandler(services)
 		collection := v1.Group("/collections")
 		{
-			collection.GET("", collectionHandler.GetAllCollections)
-			collection.GET("/:id", collectionHandler.GetCollectionByID)
-			collection.POST("", collectionHandler.CreateCollection)
-			collection.POST("/parse", collectionHandler.ParseURLHandler)
-			collection.PUT("/:id", collectionHandler.UpdateCollection)
-			collection.DELETE("/:id", collectionHandler.DeleteCollection)
+			collection.GET("", collectionHandler.GetAll)
+			collection.GET("/:id", collectionHandler.GetByID)
+			collection.POST("", collectionHandler.Create)
+			collection.POST("/parse", expensive, collectionHandler.ParseURLHandler)
+			collection.PUT("/:id", collectionHandler.Update)
+			collection.DELETE("/:id", collectionHandler.Delete)
 		}
 
 		// Code Arena
 		codeHandler := handler.NewCodeHandler()
 		code := v1.Group("/code")
 		{
-			code.POST("/run", codeHandler.RunCode)
+			code.POST("/run", expensive, codeHandler.RunCode)
 		}
 
 		// Blog
-		blogHandler := handler.NewBlogHandler(database.DB)
+		blogHandler := handler.NewBlogHandler(services)
 		blog := v1.Group("/blog")
 		{
-			blog.GET("", blogHandler.GetAllPosts)
-			blog.POST("", blogHandler.CreatePost)
-			blog.PUT("/:id", blogHandler.UpdatePost)
-			blog.DELETE("/:id", blogHandler.DeletePost)
+			blog.GET("", blogHandler.GetAll)
+			blog.GET("/:id", blogHandler.GetByID)
+			blog.POST("", blogHandler.Create)
+			blog.PUT("/:id", blogHandler.Update)
+			blog.DELETE("/:id", blogHandler.Delete)
+		}
+
+		// Search
+		searchHandler := handler.NewSearchHandler(services)
+		v1.GET("/search", searchHandler.Search)
+
+		// Tags
+		tagHandler := handler.NewTagHandler(services)
+		tags := v1.Group("/tags")
+		{
+			tags.GET("", tagHandler.GetAll)
+			tags.PUT("/:id", tagHandler.Rename)
+			tags.POST("/merge", tagHandler.Merge)
+		}
+
+		// Export / Import
+		exportHandler := handler.NewExportHandler(services)
+		v1.GET("/export", expensive, exportHandler.Export)
+		v1.POST("/import", expensive, exportHandler.Import)
+		noteImportHandler := handler.NewNoteImportHandler(services)
+		v1.POST("/import/notes", expensive, noteImportHandler.Import)
+
+		// Backups (administrator only)
+		backupHandler := handler.NewBackupHandler(services)
+		backups := v1.Group("/backups")
+		{
+			backups.GET("", backupHandler.List)
+			backups.POST("", expensive, backupHandler.Create)
+			backups.POST("/:name/verify", expensive, backupHandler.Verify)
+			backups.POST("/:name/restore", expensive, backupHandler.Restore)
+		}
+
+		// Shares
+		shares := v1.Group("/shares")
+		{
+			shares.GET("", shareHandler.GetOwned)
+			shares.GET("/received", shareHandler.GetReceived)
+			shares.POST("", shareHandler.ShareWithUser)
+			shares.POST("/links", shareHandler.CreateLink)
+			shares.DELETE("/:id", shareHandler.Revoke)
 		}
+
+		// Audit log
+		auditHandler := handler.NewAuditHandler(services)
+		v1.GET("/audit", auditHandler.GetAll)
+
 		// Monitor
 		monitorHandler := handler.NewMonitorHandler()
 		v1.POST("/monitor/check", monitorHandler.CheckHealth)
 
 		// RSS
 		rssHandler := handler.NewRSSHandler()
-		v1.POST("/rss/feed", rssHandler.GetFeed)
+		v1.POST("/rss/feed", expensive, rssHandler.GetFeed)
 	}
 
-	return r
+	return r, stop
 }
@@ -0,0 +1,278 @@
+package router_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/config"
+)
+
+func TestShareWithUserPermissions(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+	bob := register(t, r, "bob")
+	carol := register(t, r, "carol")
+
+	status, body := alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Plan", "content": "v1"})
+	noteID := createdID(t, status, body)
+	note := fmt.Sprintf("/api/v1/notes/%d", noteID)
+	share := func(username, permission string) uint {
+		t.Helper()
+		status, body := alice.do(http.MethodPost, "/api/v1/shares", map[string]interface{}{
+			"resource_type": "note", "resource_id": noteID, "username": username, "permission": permission,
+		})
+		return createdID(t, status, body)
+	}
+	update := func(c *client, content string) int {
+		t.Helper()
+		_, body := alice.do(http.MethodGet, note, nil)
+		var current struct {
+			Version int `json:"version"`
+		}
+		json.Unmarshal(body, &current)
+		status, _ := c.do(http.MethodPut, note, map[string]interface{}{"title": "Plan", "content": content, "version": current.Version})
+		return status
+	}
+
+	// 只读分享可以查看，不能修改或删除
+	readID := share("bob", "read")
+	if status, _ := bob.do(http.MethodGet, note, nil); status != http.StatusOK {
+		t.Fatalf("read share should allow viewing: %d", status)
+	}
+	if status := update(bob, "by bob"); status != http.StatusNotFound {
+		t.Fatalf("read share should not allow editing: %d", status)
+	}
+	if status, _ := carol.do(http.MethodGet, note, nil); status != http.StatusNotFound {
+		t.Fatalf("unshared user read the note: %d", status)
+	}
+
+	// 编辑分享可以修改，但只有所有者可以删除
+	share("carol", "edit")
+	if status := update(carol, "by carol"); status != http.StatusOK {
+		t.Fatalf("edit share should allow editing: %d", status)
+	}
+	if status, _ := carol.do(http.MethodDelete, note, nil); status != http.StatusNotFound {
+		t.Fatalf("edit share should not allow deleting: %d", status)
+	}
+	var received []struct {
+		ResourceID uint   `json:"resource_id"`
+		Permission string `json:"permission"`
+	}
+	_, body = carol.do(http.MethodGet, "/api/v1/shares/received", nil)
+	if json.Unmarshal(body, &received); len(received) != 1 || received[0].ResourceID != noteID || received[0].Permission != "edit" {
+		t.Fatalf("received shares: %s", body)
+	}
+
+	// 撤销后立即失去访问权限，其他人不能撤销
+	if status, _ := bob.do(http.MethodDelete, fmt.Sprintf("/api/v1/shares/%d", readID), nil); status != http.StatusNotFound {
+		t.Fatalf("only the owner can revoke: %d", status)
+	}
+	if status, body := alice.do(http.MethodDelete, fmt.Sprintf("/api/v1/shares/%d", readID), nil); status != http.StatusOK {
+		t.Fatalf("revoke: %d %s", status, body)
+	}
+	if status, _ := bob.do(http.MethodGet, note, nil); status != http.StatusNotFound {
+		t.Fatalf("revoked share still readable: %d", status)
+	}
+}
+
+// getPublic 匿名访问公开链接，password 非空时通过 X-Share-Password 请求头传递
+func getPublic(r http.Handler, url, password string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if password != "" {
+		req.Header.Set("X-Share-Password", password)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func createLink(t *testing.T, c *client, body map[string]interface{}) (uint, string) {
+	t.Helper()
+	status, raw := c.do(http.MethodPost, "/api/v1/shares/links", body)
+	var link struct {
+		ID  uint   `json:"id"`
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(raw, &link); status != http.StatusCreated || link.URL == "" {
+		t.Fatalf("create link: %d %s", status, raw)
+	}
+	return link.ID, link.URL
+}
+
+func TestPublicLinkPasswordAndRevocation(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginMaxFailures = 3
+	})
+	alice := register(t, r, "alice")
+
+	status, body := alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "Secret", "content": "hidden"})
+	noteID := createdID(t, status, body)
+	linkID, url := createLink(t, alice, map[string]interface{}{"resource_type": "note", "resource_id": noteID, "password": "open sesame"})
+
+	if w := getPublic(r, url, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("missing password: %d", w.Code)
+	}
+	if w := getPublic(r, url+"?password=open+sesame", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("password must not be accepted from the query string: %d", w.Code)
+	}
+	if w := getPublic(r, url, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: %d", w.Code)
+	}
+	var page struct {
+		Data struct {
+			ResourceType string `json:"resource_type"`
+			Resource     struct {
+				Title string `json:"title"`
+			} `json:"resource"`
+		} `json:"data"`
+	}
+	w := getPublic(r, url, "open sesame")
+	if json.Unmarshal(w.Body.Bytes(), &page); w.Code != http.StatusOK || page.Data.ResourceType != "note" || page.Data.Resource.Title != "Secret" {
+		t.Fatalf("correct password: %d %s", w.Code, w.Body.String())
+	}
+
+	if status, body := alice.do(http.MethodDelete, fmt.Sprintf("/api/v1/shares/%d", linkID), nil); status != http.StatusOK {
+		t.Fatalf("revoke: %d %s", status, body)
+	}
+	_, url = createLink(t, alice, map[string]interface{}{"resource_type": "note", "resource_id": noteID})
+	if w := getPublic(r, url, ""); w.Code != http.StatusOK {
+		t.Fatalf("link without password: %d", w.Code)
+	}
+	status, body = alice.do(http.MethodGet, "/api/v1/shares", nil)
+	var owned []struct {
+		ID uint `json:"id"`
+	}
+	if json.Unmarshal(body, &owned); len(owned) != 1 {
+		t.Fatalf("revoked link should not be listed: %s", body)
+	}
+	alice.do(http.MethodDelete, fmt.Sprintf("/api/v1/shares/%d", owned[0].ID), nil)
+	if w := getPublic(r, url, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("revoked link still works: %d", w.Code)
+	}
+
+	// 连续猜错密码后锁定，正确密码也要等待
+	_, url = createLink(t, alice, map[string]interface{}{"resource_type": "note", "resource_id": noteID, "password": "open sesame"})
+	for i := 0; i < 3; i++ {
+		getPublic(r, url, "guess")
+	}
+	if w := getPublic(r, url, "open sesame"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("repeated wrong passwords should lock the link: %d %v", w.Code, w.Header())
+	}
+}
+
+// unlock 以 remoteAddr 为连接地址解锁公开链接
+func unlock(r http.Handler, url, remoteAddr, password string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, url+"/unlock", strings.NewReader(`{"password":"`+password+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPublicLinkUnlock(t *testing.T) {
+	r := newTestServerWith(t, func(cfg *config.Config) {
+		cfg.RateLimit.LoginMaxFailures = 3
+	})
+	alice := register(t, r, "alice")
+
+	status, body := alice.upload("report.txt", "quarterly numbers")
+	_, url := createLink(t, alice, map[string]interface{}{"resource_type": "file", "resource_id": createdID(t, status, body), "password": "open sesame"})
+
+	if w := unlock(r, url, "192.0.2.1:1234", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: %d", w.Code)
+	}
+	w := unlock(r, url, "192.0.2.1:1234", "open sesame")
+	var resp struct {
+		Data struct {
+			AccessToken string `json:"access_token"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusOK || resp.Data.AccessToken == "" {
+		t.Fatalf("unlock: %d %s", w.Code, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].Path != url || cookies[0].MaxAge <= 0 {
+		t.Fatalf("unlock should set an HttpOnly cookie scoped to the link: %+v", cookies)
+	}
+
+	// 浏览器直接打开下载链接时携带Cookie
+	req := httptest.NewRequest(http.MethodGet, url+"/download", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "quarterly numbers" {
+		t.Fatalf("download with cookie: %d %s", w.Code, w.Body.String())
+	}
+	// 跨域前端通过请求头或查询参数携带凭证
+	req = httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("X-Share-Access", resp.Data.AccessToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("access header: %d %s", w.Code, w.Body.String())
+	}
+	if w := getPublic(r, url+"/download?access="+resp.Data.AccessToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("access query: %d %s", w.Code, w.Body.String())
+	}
+	if w := getPublic(r, url+"?access=1."+resp.Data.AccessToken, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("forged access token: %d", w.Code)
+	}
+	_, other := createLink(t, alice, map[string]interface{}{"resource_type": "file", "resource_id": createdID(t, status, body), "password": "open sesame"})
+	if w := getPublic(r, other+"?access="+resp.Data.AccessToken, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("access token must only unlock its own link: %d", w.Code)
+	}
+
+	// 一个访问者连续猜错被锁定，不影响其他收到链接的人
+	for i := 0; i < 3; i++ {
+		unlock(r, url, "198.51.100.7:1234", "guess")
+	}
+	if w := unlock(r, url, "198.51.100.7:1234", "open sesame"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("guessing visitor should be locked: %d", w.Code)
+	}
+	if w := unlock(r, url, "203.0.113.5:1234", "open sesame"); w.Code != http.StatusOK {
+		t.Fatalf("other recipients must not be locked out: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPublicLinkExpiryAndDownload(t *testing.T) {
+	r := newTestServer(t)
+	alice := register(t, r, "alice")
+
+	status, body := alice.upload("report.txt", "quarterly numbers")
+	fileID := createdID(t, status, body)
+
+	if status, _ := alice.do(http.MethodPost, "/api/v1/shares/links", map[string]interface{}{
+		"resource_type": "file", "resource_id": fileID, "expires_at": time.Now().Add(-time.Hour),
+	}); status != http.StatusBadRequest {
+		t.Fatalf("expiry in the past should be rejected: %d", status)
+	}
+	_, url := createLink(t, alice, map[string]interface{}{
+		"resource_type": "file", "resource_id": fileID, "expires_at": time.Now().Add(time.Second),
+	})
+	w := getPublic(r, url+"/download", "")
+	if w.Code != http.StatusOK || w.Body.String() != "quarterly numbers" ||
+		w.Header().Get("Content-Disposition") != `attachment; filename=report.txt` {
+		t.Fatalf("download: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if w := getPublic(r, url, ""); w.Code != http.StatusGone {
+		t.Fatalf("expired link: %d", w.Code)
+	}
+	if w := getPublic(r, url+"/download", ""); w.Code != http.StatusGone {
+		t.Fatalf("expired download: %d", w.Code)
+	}
+
+	// 分享的不是文件时不能下载
+	status, body = alice.do(http.MethodPost, "/api/v1/notes", map[string]string{"title": "n", "content": "c"})
+	_, url = createLink(t, alice, map[string]interface{}{"resource_type": "note", "resource_id": createdID(t, status, body)})
+	if w := getPublic(r, url+"/download", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("download of a note link: %d", w.Code)
+	}
+}
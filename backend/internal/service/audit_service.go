@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type AuditService struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewAuditService(db *gorm.DB, log logger.Logger) *AuditService {
+	return &AuditService{db: db, log: log}
+}
+
+// AuditEntry 一次变更操作的审计信息，Before/After为任意可JSON序列化的快照
+type AuditEntry struct {
+	ActorID      uint
+	IP           string
+	ResourceType string
+	ResourceID   uint
+	Action       string
+	Before       interface{}
+	After        interface{}
+}
+
+// AuditFilter 审计日志查询条件
+type AuditFilter struct {
+	ActorID      uint
+	ResourceType string
+	ResourceID   uint
+	Action       string
+	From         *time.Time
+	To           *time.Time
+	Page         int
+	PageSize     int
+}
+
+// diffIgnoredFields 不参与差异比较的字段
+var diffIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Record 写入一条审计日志
+func (s *AuditService) Record(entry AuditEntry) error {
+	before, beforeMap := snapshot(entry.Before)
+	after, afterMap := snapshot(entry.After)
+
+	log := model.AuditLog{
+		ActorID:      entry.ActorID,
+		IP:           entry.IP,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		Action:       entry.Action,
+		Before:       before,
+		After:        after,
+	}
+	if diff := diffSnapshots(beforeMap, afterMap); len(diff) > 0 {
+		data, _ := json.Marshal(diff)
+		log.Diff = string(data)
+	}
+
+	return s.db.Create(&log).Error
+}
+
+// List 分页查询审计日志
+func (s *AuditService) List(filter AuditFilter) ([]model.AuditLog, int64, error) {
+	query := s.db.Model(&model.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != 0 {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []model.AuditLog
+	offset := (filter.Page - 1) * filter.PageSize
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(filter.PageSize).Find(&logs).Error
+	return logs, total, err
+}
+
+// Prune 删除早于指定时间的审计日志
+func (s *AuditService) Prune(before time.Time) (int64, error) {
+	result := s.db.Where("created_at < ?", before).Delete(&model.AuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+// RunRetention 按保留天数定期清理审计日志，直到ctx取消
+func (s *AuditService) RunRetention(ctx context.Context, retentionDays int, interval time.Duration) {
+	if retentionDays <= 0 {
+		return
+	}
+
+	prune := func() {
+		cutoff := time.Now().AddDate(0, 0, -retentionDays)
+		n, err := s.Prune(cutoff)
+		if err != nil {
+			s.log.Error("Failed to prune audit logs", "error", err)
+			return
+		}
+		if n > 0 {
+			s.log.Info("Pruned audit logs", "count", n, "before", cutoff.Format("2006-01-02"))
+		}
+	}
+
+	prune()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			prune()
+		}
+	}
+}
+
+// snapshot 将记录序列化为JSON字符串和字段映射
+func snapshot(v interface{}) (string, map[string]interface{}) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return string(data), nil
+	}
+	return string(data), fields
+}
+
+// diffSnapshots 比较前后快照，返回发生变化的字段
+func diffSnapshots(before, after map[string]interface{}) map[string]map[string]interface{} {
+	diff := make(map[string]map[string]interface{})
+	for key, newValue := range after {
+		if diffIgnoredFields[key] {
+			continue
+		}
+		oldValue, ok := before[key]
+		if !ok || !reflect.DeepEqual(oldValue, newValue) {
+			diff[key] = map[string]interface{}{"from": oldValue, "to": newValue}
+		}
+	}
+	for key, oldValue := range before {
+		if diffIgnoredFields[key] {
+			continue
+		}
+		if _, ok := after[key]; !ok {
+			diff[key] = map[string]interface{}{"from": oldValue, "to": nil}
+		}
+	}
+	return diff
+}
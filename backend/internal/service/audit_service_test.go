@@ -0,0 +1,108 @@
+package service_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func TestAuditRecordStoresSnapshotsAndDiff(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	before := model.Note{ID: 7, UserID: 1, Title: "Plan", Content: "v1", UpdatedAt: time.Now()}
+	after := before
+	after.Content, after.IsPinned, after.UpdatedAt = "v2", true, time.Now().Add(time.Minute)
+	entries := []service.AuditEntry{
+		{ActorID: 1, IP: "10.0.0.1", ResourceType: "note", ResourceID: 7, Action: "create", After: &before},
+		{ActorID: 1, IP: "10.0.0.1", ResourceType: "note", ResourceID: 7, Action: "update", Before: &before, After: &after},
+		{ActorID: 1, ResourceType: "note", ResourceID: 7, Action: "delete", Before: &after, After: (*model.Note)(nil)},
+	}
+	for _, entry := range entries {
+		if err := services.Audit.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logs, total, err := services.Audit.List(service.AuditFilter{ActorID: 1, Page: 1, PageSize: 10})
+	if err != nil || total != 3 || len(logs) != 3 {
+		t.Fatalf("list: %d logs, total=%d, err=%v", len(logs), total, err)
+	}
+	// 按时间倒序
+	deleted, updated, created := logs[0], logs[1], logs[2]
+	if created.Before != "" || created.After == "" || created.IP != "10.0.0.1" {
+		t.Fatalf("create should only have an after snapshot: %+v", created)
+	}
+	if deleted.Before == "" || deleted.After != "" {
+		t.Fatalf("delete with a nil pointer should only have a before snapshot: %+v", deleted)
+	}
+
+	// 差异只包含变化的字段，忽略时间戳
+	var diff map[string]struct {
+		From interface{} `json:"from"`
+		To   interface{} `json:"to"`
+	}
+	if err := json.Unmarshal([]byte(updated.Diff), &diff); err != nil {
+		t.Fatalf("diff is not JSON: %q", updated.Diff)
+	}
+	if len(diff) != 2 || diff["content"].From != "v1" || diff["content"].To != "v2" ||
+		diff["is_pinned"].From != false || diff["is_pinned"].To != true {
+		t.Fatalf("unexpected diff: %s", updated.Diff)
+	}
+}
+
+func TestAuditListFiltersAndPrune(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	record := func(actor uint, resourceType string, id uint, action string) {
+		t.Helper()
+		if err := services.Audit.Record(service.AuditEntry{ActorID: actor, ResourceType: resourceType, ResourceID: id, Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(1, "note", 1, "create")
+	record(1, "note", 1, "update")
+	record(1, "task", 2, "create")
+	record(2, "note", 3, "create")
+
+	count := func(filter service.AuditFilter) int64 {
+		t.Helper()
+		filter.Page, filter.PageSize = 1, 10
+		_, total, err := services.Audit.List(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		filter service.AuditFilter
+		want   int64
+	}{
+		{"actor", service.AuditFilter{ActorID: 1}, 3},
+		{"resource type", service.AuditFilter{ActorID: 1, ResourceType: "note"}, 2},
+		{"resource id", service.AuditFilter{ActorID: 1, ResourceID: 2}, 1},
+		{"action", service.AuditFilter{ActorID: 1, Action: "update"}, 1},
+		{"from", service.AuditFilter{ActorID: 1, From: &future}, 0},
+		{"to", service.AuditFilter{ActorID: 1, To: &future}, 3},
+	}
+	for _, tt := range tests {
+		if got := count(tt.filter); got != tt.want {
+			t.Errorf("%s: got %d logs, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if n, err := services.Audit.Prune(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("recent logs should be kept: pruned %d, err=%v", n, err)
+	}
+	if n, err := services.Audit.Prune(future); err != nil || n != 4 {
+		t.Fatalf("prune: pruned %d, err=%v", n, err)
+	}
+	if got := count(service.AuditFilter{}); got != 0 {
+		t.Fatalf("%d logs left after prune", got)
+	}
+}
@@ -0,0 +1,644 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/migrate"
+	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// BackupFormatVersion 备份包格式版本
+const BackupFormatVersion = 1
+
+const (
+	backupNamePrefix = "nexushub-backup-"
+	backupNameLayout = "20060102-150405"
+	// backupNameFormat 生成文件名时带毫秒，同一秒内的手动备份和定时备份不会重名；
+	// 解析时 backupNameLayout 同样接受秒后的小数部分，兼容旧的文件名
+	backupNameFormat = "20060102-150405.000"
+	backupManifest   = "manifest.json"
+)
+
+var (
+	ErrBackupNotFound = errors.New("backup not found")
+	ErrBackupCorrupt  = errors.New("backup failed integrity verification")
+	ErrBackupTooNew   = errors.New("backup was created by a newer schema version")
+	ErrBackupBusy     = errors.New("another backup or restore is in progress")
+	ErrBackupExists   = errors.New("a backup with the same name already exists")
+)
+
+// BackupEntry 备份包中一个文件的大小和校验和
+type BackupEntry struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupManifest 备份包中的 manifest.json，记录每个条目的校验和用于完整性校验
+type BackupManifest struct {
+	Version       int                    `json:"version"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Driver        string                 `json:"driver"`
+	SchemaVersion string                 `json:"schema_version"` // 创建备份时最新的已执行迁移
+	Tables        map[string]int         `json:"tables"`         // 表名 -> 行数
+	Entries       map[string]BackupEntry `json:"entries"`
+	MissingFiles  []uint                 `json:"missing_files,omitempty"`
+}
+
+// BackupVerification 完整性校验结果
+type BackupVerification struct {
+	Name     string          `json:"name"`
+	Valid    bool            `json:"valid"`
+	Errors   []string        `json:"errors,omitempty"`
+	Manifest *BackupManifest `json:"manifest,omitempty"`
+}
+
+// RestoreReport 恢复结果
+type RestoreReport struct {
+	Name     string         `json:"name"`
+	Tables   map[string]int `json:"tables"`
+	Files    int            `json:"files"`
+	Warnings []string       `json:"warnings,omitempty"`
+}
+
+// backupMu 保证同一进程内定时任务与手动触发的备份/恢复不会并发执行
+var backupMu sync.Mutex
+
+type BackupService struct {
+	db     *gorm.DB
+	files  *FileService
+	target BackupTarget
+	cfg    config.BackupConfig
+	log    logger.Logger
+}
+
+func NewBackupService(db *gorm.DB, files *FileService, target BackupTarget, cfg config.BackupConfig, log logger.Logger) *BackupService {
+	return &BackupService{
+		db:     db,
+		files:  files,
+		target: target,
+		cfg:    cfg,
+		log:    log,
+	}
+}
+
+// parseBackupName 解析备份文件名中的创建时间
+func parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupNamePrefix) || !strings.HasSuffix(name, ".zip") {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupNamePrefix), ".zip")
+	t, err := time.ParseInLocation(backupNameLayout, stamp, time.Local)
+	return t, err == nil
+}
+
+// List 列出所有备份，最新的在前
+func (s *BackupService) List() ([]BackupInfo, error) {
+	return s.target.List()
+}
+
+// Create 立即创建一个备份并执行保留策略
+func (s *BackupService) Create() (*BackupInfo, error) {
+	if !backupMu.TryLock() {
+		return nil, ErrBackupBusy
+	}
+	defer backupMu.Unlock()
+
+	now := time.Now()
+	name := backupNamePrefix + now.Format(backupNameFormat) + ".zip"
+	// 不覆盖已有的备份
+	existing, err := s.target.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range existing {
+		if b.Name == name {
+			return nil, ErrBackupExists
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "nexushub-backup-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := s.write(tmp, now); err != nil {
+		return nil, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := s.target.Put(name, tmp); err != nil {
+		return nil, fmt.Errorf("failed to store backup: %w", err)
+	}
+	s.log.Info("Backup created", "name", name, "size", size)
+
+	if err := s.applyRetention(); err != nil {
+		s.log.Warn("Failed to apply backup retention", "error", err)
+	}
+	return &BackupInfo{Name: name, Size: size, CreatedAt: now}, nil
+}
+
+// write 写出备份包：db/<表名>.json 为按列名导出的全部行(包括软删除的记录)，
+// files/<id> 为上传文件的内容，manifest.json 记录各条目的校验和
+func (s *BackupService) write(w io.Writer, now time.Time) error {
+	manifest := BackupManifest{
+		Version:   BackupFormatVersion,
+		CreatedAt: now,
+		Driver:    s.db.Dialector.Name(),
+		Tables:    make(map[string]int),
+		Entries:   make(map[string]BackupEntry),
+	}
+	version, err := currentSchemaVersion(s.db)
+	if err != nil {
+		return err
+	}
+	manifest.SchemaVersion = version
+
+	zw := zip.NewWriter(w)
+	writeEntry := func(name string, fn func(io.Writer) error) error {
+		dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		counter := &countingWriter{w: io.MultiWriter(dst, hash)}
+		if err := fn(counter); err != nil {
+			return err
+		}
+		manifest.Entries[name] = BackupEntry{Size: counter.n, SHA256: hex.EncodeToString(hash.Sum(nil))}
+		return nil
+	}
+
+	for _, m := range model.All() {
+		sch, err := parseSchema(s.db, m)
+		if err != nil {
+			return err
+		}
+		rows, err := dumpTable(s.db, m, sch)
+		if err != nil {
+			return fmt.Errorf("failed to dump %s: %w", sch.Table, err)
+		}
+		manifest.Tables[sch.Table] = len(rows)
+		if err := writeEntry("db/"+sch.Table+".json", func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(rows)
+		}); err != nil {
+			return err
+		}
+	}
+
+	var files []model.File
+	if err := s.db.Order("id").Find(&files).Error; err != nil {
+		return err
+	}
+	for i := range files {
+		src, err := s.files.OpenBlob(&files[i])
+		if err != nil {
+			s.log.Warn("Backup: cannot read file content", "error", err, "file_id", files[i].ID)
+			manifest.MissingFiles = append(manifest.MissingFiles, files[i].ID)
+			continue
+		}
+		err = writeEntry(fmt.Sprintf("files/%d", files[i].ID), func(w io.Writer) error {
+			_, err := io.Copy(w, src)
+			return err
+		})
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	dst, err := zw.Create(backupManifest)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func parseSchema(db *gorm.DB, m interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(m); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// dumpTable 按列名导出整张表，不使用模型的json标签，避免丢失密码哈希等不对外输出的字段
+func dumpTable(db *gorm.DB, m interface{}, sch *schema.Schema) ([]map[string]interface{}, error) {
+	records := reflect.New(reflect.SliceOf(reflect.TypeOf(m).Elem()))
+	if err := db.Unscoped().Order(sch.PrioritizedPrimaryField.DBName).Find(records.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	slice := records.Elem()
+	rows := make([]map[string]interface{}, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		row := make(map[string]interface{}, len(sch.Fields))
+		for _, field := range sch.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			value, _ := field.ValueOf(ctx, slice.Index(i))
+			row[field.DBName] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// currentSchemaVersion 返回最新的已执行迁移版本
+func currentSchemaVersion(db *gorm.DB) (string, error) {
+	var record migrate.SchemaMigration
+	err := db.Order("version DESC").Limit(1).Find(&record).Error
+	return record.Version, err
+}
+
+// openBackup 将备份取到本地临时文件并打开，调用方负责执行返回的清理函数
+func (s *BackupService) openBackup(name string) (*zip.ReadCloser, func(), error) {
+	if _, ok := parseBackupName(name); !ok {
+		return nil, nil, ErrBackupNotFound
+	}
+	src, err := s.target.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrBackupNotFound
+		}
+		return nil, nil, err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "nexushub-restore-*.zip")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	zr, err := zip.OpenReader(tmp.Name())
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+	}
+	return zr, func() { zr.Close(); cleanup() }, nil
+}
+
+// Verify 校验备份包中每个条目的大小与 sha256 是否与 manifest 一致
+func (s *BackupService) Verify(name string) (*BackupVerification, error) {
+	zr, cleanup, err := s.openBackup(name)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	return verifyArchive(name, &zr.Reader), nil
+}
+
+func verifyArchive(name string, zr *zip.Reader) *BackupVerification {
+	result := &BackupVerification{Name: name}
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	manifestFile, ok := entries[backupManifest]
+	if !ok {
+		result.Errors = append(result.Errors, "manifest.json is missing")
+		return result
+	}
+	var manifest BackupManifest
+	if err := readZipJSON(manifestFile, &manifest); err != nil {
+		result.Errors = append(result.Errors, "manifest.json is unreadable: "+err.Error())
+		return result
+	}
+	result.Manifest = &manifest
+
+	for entryName, expected := range manifest.Entries {
+		f, ok := entries[entryName]
+		if !ok {
+			result.Errors = append(result.Errors, entryName+": missing")
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			result.Errors = append(result.Errors, entryName+": "+err.Error())
+			continue
+		}
+		hash := sha256.New()
+		size, err := io.Copy(hash, rc)
+		rc.Close()
+		switch {
+		case err != nil:
+			result.Errors = append(result.Errors, entryName+": "+err.Error())
+		case size != expected.Size:
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: size %d, expected %d", entryName, size, expected.Size))
+		case hex.EncodeToString(hash.Sum(nil)) != expected.SHA256:
+			result.Errors = append(result.Errors, entryName+": checksum mismatch")
+		}
+	}
+	for entryName := range entries {
+		if _, ok := manifest.Entries[entryName]; !ok && entryName != backupManifest {
+			result.Errors = append(result.Errors, entryName+": not listed in manifest")
+		}
+	}
+
+	sort.Strings(result.Errors)
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+func readZipJSON(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+// Restore 校验后用备份替换全部数据：清空并重新写入所有表，再把文件内容写回原存储路径
+func (s *BackupService) Restore(name string) (*RestoreReport, error) {
+	if !backupMu.TryLock() {
+		return nil, ErrBackupBusy
+	}
+	defer backupMu.Unlock()
+
+	zr, cleanup, err := s.openBackup(name)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	verification := verifyArchive(name, &zr.Reader)
+	if !verification.Valid {
+		return nil, fmt.Errorf("%w: %s", ErrBackupCorrupt, strings.Join(verification.Errors, "; "))
+	}
+	manifest := verification.Manifest
+
+	current, err := currentSchemaVersion(s.db)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.SchemaVersion > current {
+		return nil, fmt.Errorf("%w: backup %s, database %s", ErrBackupTooNew, manifest.SchemaVersion, current)
+	}
+
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	report := &RestoreReport{Name: name, Tables: make(map[string]int)}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range model.All() {
+			sch, err := parseSchema(s.db, m)
+			if err != nil {
+				return err
+			}
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(m).Error; err != nil {
+				return fmt.Errorf("failed to clear %s: %w", sch.Table, err)
+			}
+
+			f, ok := entries["db/"+sch.Table+".json"]
+			if !ok {
+				report.Warnings = append(report.Warnings, sch.Table+": not in backup, left empty")
+				continue
+			}
+			n, err := restoreTable(tx, f, sch)
+			if err != nil {
+				return fmt.Errorf("failed to restore %s: %w", sch.Table, err)
+			}
+			report.Tables[sch.Table] = n
+		}
+		if err := resetSequences(tx); err != nil {
+			return err
+		}
+		// 早于标签表的备份只有资源上的标签字符串，据此重建标签关联
+		if _, ok := entries["db/taggings.json"]; !ok {
+			return rebuildTaggings(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 备份中没有的或过期的搜索索引在这里补齐
+	if _, err := NewSearchService(s.db, s.log).EnsureIndex(context.Background()); err != nil {
+		report.Warnings = append(report.Warnings, "search index: "+err.Error())
+	}
+
+	var files []model.File
+	if err := s.db.Order("id").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		f, ok := entries[fmt.Sprintf("files/%d", file.ID)]
+		if !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("file %d (%s): content not in backup", file.ID, file.FileName))
+			continue
+		}
+		rc, err := f.Open()
+		if err == nil {
+			err = s.files.WriteBlobAt(file.FilePath, rc, file.MimeType)
+			rc.Close()
+		}
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("file %d (%s): %v", file.ID, file.FileName, err))
+			continue
+		}
+		report.Files++
+	}
+
+	s.log.Info("Backup restored", "name", name, "tables", report.Tables, "files", report.Files)
+	return report, nil
+}
+
+// restoreTable 按模型字段类型解码导出的行并批量写入
+func restoreTable(tx *gorm.DB, f *zip.File, sch *schema.Schema) (int, error) {
+	var rows []map[string]json.RawMessage
+	if err := readZipJSON(f, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	values := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		value := make(map[string]interface{}, len(row))
+		for column, raw := range row {
+			field := sch.LookUpField(column)
+			if field == nil || field.DBName == "" {
+				continue // 备份中有、当前结构中已删除的列
+			}
+			ptr := reflect.New(field.FieldType)
+			if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+				return 0, fmt.Errorf("column %s: %w", column, err)
+			}
+			value[field.DBName] = ptr.Elem().Interface()
+		}
+		values = append(values, value)
+	}
+
+	if err := tx.Table(sch.Table).CreateInBatches(values, 200).Error; err != nil {
+		return 0, err
+	}
+	return len(values), nil
+}
+
+// resetSequences PostgreSQL 在显式写入主键后需要同步自增序列
+func resetSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, m := range model.All() {
+		sch, err := parseSchema(tx, m)
+		if err != nil {
+			return err
+		}
+		sql := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE((SELECT MAX(id) FROM %s), 0) + 1, false)", sch.Table, sch.Table)
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupsToDelete 按祖父-父-子策略选出需要删除的备份：
+// 最近N个有备份的日/周/月各保留该周期内最新的一份，最新的备份始终保留
+func backupsToDelete(backups []BackupInfo, daily, weekly, monthly int) []BackupInfo {
+	sorted := make([]BackupInfo, len(backups))
+	copy(sorted, backups)
+	sortBackups(sorted)
+
+	keep := make(map[string]bool)
+	if len(sorted) > 0 {
+		keep[sorted[0].Name] = true
+	}
+
+	periods := []struct {
+		limit int
+		key   func(time.Time) string
+	}{
+		{daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{weekly, func(t time.Time) string { y, w := t.ISOWeek(); return strconv.Itoa(y) + "-W" + strconv.Itoa(w) }},
+		{monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, period := range periods {
+		seen := make(map[string]bool)
+		for _, b := range sorted {
+			if len(seen) >= period.limit {
+				break
+			}
+			k := period.key(b.CreatedAt)
+			if !seen[k] {
+				seen[k] = true
+				keep[b.Name] = true
+			}
+		}
+	}
+
+	var remove []BackupInfo
+	for _, b := range sorted {
+		if !keep[b.Name] {
+			remove = append(remove, b)
+		}
+	}
+	return remove
+}
+
+func (s *BackupService) applyRetention() error {
+	backups, err := s.target.List()
+	if err != nil {
+		return err
+	}
+	for _, b := range backupsToDelete(backups, s.cfg.KeepDaily, s.cfg.KeepWeekly, s.cfg.KeepMonthly) {
+		if err := s.target.Delete(b.Name); err != nil {
+			s.log.Warn("Failed to delete expired backup", "name", b.Name, "error", err)
+			continue
+		}
+		s.log.Info("Deleted expired backup", "name", b.Name)
+	}
+	return nil
+}
+
+// Run 定时创建备份；启动时若距上次备份已超过一个周期则立即备份
+func (s *BackupService) Run(ctx context.Context, interval time.Duration) {
+	backup := func() {
+		if _, err := s.Create(); err != nil {
+			s.log.Error("Scheduled backup failed", "error", err)
+		}
+	}
+
+	backups, err := s.target.List()
+	if err != nil {
+		s.log.Error("Failed to list backups", "error", err)
+	}
+	if err == nil && (len(backups) == 0 || time.Since(backups[0].CreatedAt) >= interval) {
+		backup()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			backup()
+		}
+	}
+}
+
+// IsBackupError 判断是否为调用方可处理的备份错误
+func IsBackupError(err error) bool {
+	return errors.Is(err, ErrBackupNotFound) || errors.Is(err, ErrBackupCorrupt) ||
+		errors.Is(err, ErrBackupTooNew) || errors.Is(err, ErrBackupBusy) || errors.Is(err, common.ErrFilePathNotSafe)
+}
@@ -0,0 +1,194 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func newBackupService(t *testing.T, services *service.Services) (*service.BackupService, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.BackupConfig{Dir: dir, KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 6}
+	return service.NewBackupService(services.DB, services.Files, service.NewLocalBackupTarget(dir), cfg, services.Log), dir
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	fileID := seedAccount(t, services, 1)
+	var user model.User
+	services.DB.First(&user, 1)
+
+	backups, _ := newBackupService(t, services)
+	info, err := backups.Create()
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	result, err := backups.Verify(info.Name)
+	if err != nil || !result.Valid {
+		t.Fatalf("verify: %v %+v", err, result)
+	}
+	if result.Manifest.Tables["notes"] != 1 || result.Manifest.SchemaVersion == "" {
+		t.Fatalf("unexpected manifest: %+v", result.Manifest)
+	}
+
+	// 破坏数据: 删除笔记、修改密码、删除文件内容
+	var file model.File
+	services.DB.First(&file, fileID)
+	services.DB.Exec("DELETE FROM notes")
+	services.DB.Model(&model.User{}).Where("id = ?", 1).Update("password", "changed")
+	mustCreate(t, services.DB, &model.Task{UserID: 1, Title: "after backup"})
+	os.Remove(file.FilePath)
+
+	report, err := backups.Restore(info.Name)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if report.Files != 1 || len(report.Warnings) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	var note model.Note
+	if err := services.DB.Where("title = ?", "Plans").First(&note).Error; err != nil {
+		t.Fatalf("note not restored: %v", err)
+	}
+	var restored model.User
+	services.DB.First(&restored, 1)
+	if restored.Password != user.Password {
+		t.Error("password hash not restored")
+	}
+	var count int64
+	services.DB.Model(&model.Task{}).Where("title = ?", "after backup").Count(&count)
+	if count != 0 {
+		t.Error("rows created after the backup should be removed")
+	}
+	content, err := os.ReadFile(file.FilePath)
+	if err != nil || string(content) != "quarterly report" {
+		t.Errorf("file content not restored: %q %v", content, err)
+	}
+
+	// 恢复后自增ID应继续可用
+	mustCreate(t, services.DB, &model.Note{UserID: 1, Title: "new"})
+}
+
+func TestBackupVerifyDetectsTampering(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	seedAccount(t, services, 1)
+	backups, dir := newBackupService(t, services)
+	info, err := backups.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, info.Name)
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if f.Name == "db/notes.json" {
+			data = bytes.Replace(data, []byte("Plans"), []byte("Hacks"), 1)
+		}
+		w, _ := zw.Create(f.Name)
+		w.Write(data)
+	}
+	zw.Close()
+	zr.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := backups.Verify(info.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || len(result.Errors) != 1 || !strings.HasPrefix(result.Errors[0], "db/notes.json") {
+		t.Fatalf("tampering not detected: %+v", result.Errors)
+	}
+	if _, err := backups.Restore(info.Name); err == nil {
+		t.Fatal("restore of a corrupt backup should fail")
+	}
+
+	if _, err := backups.Verify("../../etc/passwd"); err == nil {
+		t.Fatal("expected error for invalid backup name")
+	}
+}
+
+func TestBackupNamesAreUniqueWithinASecond(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	backups, _ := newBackupService(t, services)
+
+	first, err := backups.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := backups.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Name == second.Name {
+		t.Fatalf("consecutive backups must not share a name: %s", first.Name)
+	}
+	// 同一天的旧备份由保留策略清理，列表中的创建时间精确到毫秒
+	list, _ := backups.List()
+	if len(list) != 1 || list[0].Name != second.Name || !list[0].CreatedAt.Equal(second.CreatedAt.Truncate(time.Millisecond)) {
+		t.Fatalf("unexpected backups: %+v", list)
+	}
+	if _, err := backups.Verify(second.Name); err != nil {
+		t.Fatalf("backup with millisecond name should be readable: %v", err)
+	}
+}
+
+func TestBackupRetention(t *testing.T) {
+	base := time.Date(2024, 6, 30, 3, 0, 0, 0, time.Local)
+	var backups []service.BackupInfo
+	// 90天内每天两份备份
+	for day := 0; day < 90; day++ {
+		for _, hour := range []int{0, 12} {
+			at := base.AddDate(0, 0, -day).Add(time.Duration(hour) * time.Hour)
+			backups = append(backups, service.BackupInfo{Name: at.Format(time.RFC3339), CreatedAt: at})
+		}
+	}
+
+	remove := service.BackupsToDelete(backups, 7, 4, 3)
+	removed := make(map[string]bool)
+	for _, b := range remove {
+		removed[b.Name] = true
+	}
+	var kept []time.Time
+	for _, b := range backups {
+		if !removed[b.Name] {
+			kept = append(kept, b.CreatedAt)
+		}
+	}
+
+	// 7天 + 额外的3个周 + 额外的2个月(6月与最近的日/周重合)
+	if len(kept) != 12 {
+		t.Fatalf("kept %d backups: %v", len(kept), kept)
+	}
+	if !kept[0].Equal(base.Add(12 * time.Hour)) {
+		t.Errorf("newest backup must be kept, got %v", kept[0])
+	}
+	if oldest := kept[len(kept)-1]; oldest.Month() != time.April {
+		t.Errorf("oldest kept backup should be from April, got %v", oldest)
+	}
+
+	if remove := service.BackupsToDelete(backups[:1], 0, 0, 0); len(remove) != 0 {
+		t.Error("the only backup must never be deleted")
+	}
+}
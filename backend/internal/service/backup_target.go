@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"nexushub-personal/internal/config"
+)
+
+// BackupInfo 一个已存在的备份
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupTarget 备份的存放位置
+type BackupTarget interface {
+	Put(name string, r io.Reader) error
+	Open(name string) (io.ReadCloser, error)
+	List() ([]BackupInfo, error)
+	Delete(name string) error
+}
+
+// NewBackupTarget 根据配置创建备份存放位置
+func NewBackupTarget(cfg config.BackupConfig) (BackupTarget, error) {
+	switch cfg.Target {
+	case config.BackupTargetCloud:
+		provider, err := NewMinIOProvider()
+		if err != nil {
+			return nil, fmt.Errorf("cloud backup target unavailable: %w", err)
+		}
+		return &CloudBackupTarget{provider: provider, prefix: "backups/"}, nil
+	default:
+		return &LocalBackupTarget{dir: cfg.Dir}, nil
+	}
+}
+
+// LocalBackupTarget 本地目录
+type LocalBackupTarget struct {
+	dir string
+}
+
+func NewLocalBackupTarget(dir string) *LocalBackupTarget {
+	return &LocalBackupTarget{dir: dir}
+}
+
+func (t *LocalBackupTarget) Put(name string, r io.Reader) error {
+	if err := os.MkdirAll(t.dir, 0750); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免列表中出现写了一半的备份
+	tmp, err := os.CreateTemp(t.dir, ".tmp-"+name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(t.dir, name))
+}
+
+func (t *LocalBackupTarget) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(t.dir, name))
+}
+
+func (t *LocalBackupTarget) List() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(t.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []BackupInfo
+	for _, entry := range entries {
+		createdAt, ok := parseBackupName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	sortBackups(backups)
+	return backups, nil
+}
+
+func (t *LocalBackupTarget) Delete(name string) error {
+	return os.Remove(filepath.Join(t.dir, name))
+}
+
+// CloudBackupTarget 云存储桶中的 backups/ 前缀
+type CloudBackupTarget struct {
+	provider CloudStorageProvider
+	prefix   string
+}
+
+func (t *CloudBackupTarget) Put(name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return t.provider.Upload(context.Background(), t.prefix+name, data, "application/zip")
+}
+
+func (t *CloudBackupTarget) Open(name string) (io.ReadCloser, error) {
+	data, err := t.provider.Download(context.Background(), t.prefix+name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (t *CloudBackupTarget) List() ([]BackupInfo, error) {
+	objects, err := t.provider.List(context.Background(), t.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []BackupInfo
+	for _, object := range objects {
+		name := path.Base(object.Key)
+		createdAt, ok := parseBackupName(name)
+		if !ok || strings.TrimPrefix(object.Key, t.prefix) != name {
+			continue
+		}
+		backups = append(backups, BackupInfo{Name: name, Size: object.Size, CreatedAt: createdAt})
+	}
+	sortBackups(backups)
+	return backups, nil
+}
+
+func (t *CloudBackupTarget) Delete(name string) error {
+	return t.provider.Delete(context.Background(), t.prefix+name)
+}
+
+// sortBackups 按创建时间倒序排列
+func sortBackups(backups []BackupInfo) {
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+}
@@ -1,47 +1,212 @@
 package service
 
 import (
+	"errors"
+	"slices"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+
 	"gorm.io/gorm"
 )
 
+// CRUDOptions 通用CRUD服务的行为配置
+type CRUDOptions struct {
+	Resource  string            // 资源类型，对应 constants.ResourceXxx
+	Shareable bool              // 是否可被分享，为true时读取/编辑包含分享给当前用户的记录
+	Order     string            // 列表排序
+	Fields    []string          // Update 允许修改的列
+	Filters   map[string]string // 列表查询参数 -> 查询条件，如 "type": "type = ?"
+}
+
 // BaseService 通用CRUD服务基类
 type BaseService[T any] struct {
-	db *gorm.DB
+	db   *gorm.DB
+	opts CRUDOptions
 }
 
 // NewBaseService 创建基础服务
-func NewBaseService[T any](db *gorm.DB) *BaseService[T] {
+func NewBaseService[T any](db *gorm.DB, opts CRUDOptions) *BaseService[T] {
 	return &BaseService[T]{
-		db: db,
+		db:   db,
+		opts: opts,
 	}
 }
 
-// GetAll 获取所有记录
-func (s *BaseService[T]) GetAll(userID uint) ([]T, error) {
+// Resource 返回资源类型
+func (s *BaseService[T]) Resource() string {
+	return s.opts.Resource
+}
+
+// scope 限定为用户自己的记录，可分享的资源还包括分享给该用户的记录
+func (s *BaseService[T]) scope(userID uint, needEdit bool) func(db *gorm.DB) *gorm.DB {
+	if s.opts.Shareable {
+		return AccessibleScope(userID, s.opts.Resource, needEdit)
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userID)
+	}
+}
+
+// identified 能读取主键的实体
+type identified interface {
+	GetID() uint
+}
+
+// tagged 实体类型是否带标签
+func (s *BaseService[T]) tagged() bool {
+	_, ok := any(new(T)).(model.Tagged)
+	return ok
+}
+
+// searchable 实体类型是否参与全局搜索
+func (s *BaseService[T]) searchable() bool {
+	_, ok := any(new(T)).(model.Searchable)
+	return ok
+}
+
+// derived 实体写入后是否需要同步派生数据
+func (s *BaseService[T]) derived() bool {
+	return s.tagged() || s.searchable()
+}
+
+// syncDerived 记录写入后同步派生数据：重建标签关联并把规范化后的标签写回 entity，再更新搜索索引
+func (s *BaseService[T]) syncDerived(tx *gorm.DB, id uint, entity *T) error {
+	if t, ok := any(entity).(model.Tagged); ok {
+		if err := syncEntityTags(tx, s.opts.Resource, id, t); err != nil {
+			return err
+		}
+	}
+	if s.searchable() {
+		return indexResource(tx, s.opts.Resource, id)
+	}
+	return nil
+}
+
+// GetAll 获取用户可访问的所有记录，filter 中只有在 Filters 中登记过的参数生效；
+// 带标签的资源还支持 "tag" 参数，只返回带有该标签的记录
+func (s *BaseService[T]) GetAll(userID uint, filter map[string]string) ([]T, error) {
+	query := s.db.Scopes(s.scope(userID, false))
+	for param, clause := range s.opts.Filters {
+		if value := filter[param]; value != "" {
+			query = query.Where(clause, value)
+		}
+	}
+	if s.tagged() {
+		query = query.Scopes(TaggedScope(s.opts.Resource, filter["tag"]))
+	}
+	if s.opts.Order != "" {
+		query = query.Order(s.opts.Order)
+	}
+
 	var items []T
-	err := s.db.Where("user_id = ?", userID).Order("updated_at DESC").Find(&items).Error
+	err := query.Find(&items).Error
 	return items, err
 }
 
-// GetByID 根据ID获取记录
+// GetByID 根据ID获取用户可访问的记录
 func (s *BaseService[T]) GetByID(id, userID uint) (*T, error) {
 	var item T
-	err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&item).Error
+	err := s.db.Scopes(s.scope(userID, false)).Where("id = ?", id).First(&item).Error
 	return &item, err
 }
 
-// Create 创建记录
+// GetEditable 获取用户可编辑的记录(自己的或以edit权限分享的)
+func (s *BaseService[T]) GetEditable(id, userID uint) (*T, error) {
+	var item T
+	err := s.db.Scopes(s.scope(userID, true)).Where("id = ?", id).First(&item).Error
+	return &item, err
+}
+
+// Create 创建记录，带版本号的记录从版本1开始；创建后同步派生数据，见 syncDerived
 func (s *BaseService[T]) Create(entity *T) error {
-	return s.db.Create(entity).Error
+	if v, ok := any(entity).(model.Versioned); ok {
+		v.SetVersion(1)
+	}
+	if !s.derived() {
+		return s.db.Create(entity).Error
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entity).Error; err != nil {
+			return err
+		}
+		return s.syncDerived(tx, any(entity).(identified).GetID(), entity)
+	})
 }
 
-// Update 更新记录
-func (s *BaseService[T]) Update(entity *T) error {
-	return s.db.Save(entity).Error
+// Update 用entity中 Fields 列的值更新记录，零值同样会写入。
+// 带版本号的记录只有在 entity 的版本号与数据库一致时才会更新，否则返回 ErrVersionConflict；
+// 版本号为0表示基于当前版本更新。更新成功后新的版本号写回 entity，并同步派生数据
+func (s *BaseService[T]) Update(id, userID uint, entity *T) error {
+	if !s.derived() {
+		return s.update(id, userID, entity)
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		scoped := &BaseService[T]{db: tx, opts: s.opts}
+		if err := scoped.update(id, userID, entity); err != nil {
+			return err
+		}
+		return s.syncDerived(tx, id, entity)
+	})
+}
+
+func (s *BaseService[T]) update(id, userID uint, entity *T) error {
+	query := s.db.Model(new(T)).
+		Scopes(s.scope(userID, true)).
+		Where("id = ?", id)
+	fields := s.opts.Fields
+
+	v, versioned := any(entity).(model.Versioned)
+	if versioned {
+		expected := v.GetVersion()
+		if expected == 0 {
+			current, err := s.GetEditable(id, userID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return common.ErrResourceNotFound
+			}
+			if err != nil {
+				return err
+			}
+			expected = any(current).(model.Versioned).GetVersion()
+		}
+		query = query.Where("version = ?", expected)
+		fields = append(slices.Clone(fields), "version")
+		v.SetVersion(expected + 1)
+	}
+
+	result := query.Select(fields).Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if versioned {
+			if _, err := s.GetEditable(id, userID); err == nil {
+				return common.ErrVersionConflict
+			}
+		}
+		return common.ErrResourceNotFound
+	}
+	return nil
 }
 
-// Delete 删除记录
+// Delete 删除记录，只有所有者可以删除；同时移除标签关联和搜索索引
 func (s *BaseService[T]) Delete(id, userID uint) error {
-	var item T
-	return s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&item).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(new(T))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return common.ErrResourceNotFound
+		}
+		if s.tagged() {
+			if err := removeResourceTags(tx, s.opts.Resource, id); err != nil {
+				return err
+			}
+		}
+		if s.searchable() {
+			return unindexResource(tx, s.opts.Resource, id)
+		}
+		return nil
+	})
 }
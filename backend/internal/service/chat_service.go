@@ -1,19 +1,23 @@
 package service
 
 import (
-	"nexushub-personal/internal/database"
+	"nexushub-personal/internal/constants"
 	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
 )
 
-type ChatService struct{}
+type ChatService struct {
+	db *gorm.DB
+}
 
-func NewChatService() *ChatService {
-	return &ChatService{}
+func NewChatService(db *gorm.DB) *ChatService {
+	return &ChatService{db: db}
 }
 
 func (s *ChatService) GetHistory(userID uint, limit int) ([]model.ChatMessage, error) {
 	var messages []model.ChatMessage
-	query := database.DB.Where("user_id = ?", userID).Order("created_at DESC")
+	query := s.db.Where("user_id = ?", userID).Order("created_at DESC")
 
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -23,10 +27,22 @@ func (s *ChatService) GetHistory(userID uint, limit int) ([]model.ChatMessage, e
 	return messages, err
 }
 
+// Create 保存聊天消息并建立搜索索引
 func (s *ChatService) Create(message *model.ChatMessage) error {
-	return database.DB.Create(message).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		return indexResource(tx, constants.ResourceChat, message.ID)
+	})
 }
 
+// DeleteHistory 清空用户的聊天记录及其搜索索引
 func (s *ChatService) DeleteHistory(userID uint) error {
-	return database.DB.Where("user_id = ?", userID).Delete(&model.ChatMessage{}).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.ChatMessage{}).Error; err != nil {
+			return err
+		}
+		return unindexUserResources(tx, constants.ResourceChat, userID)
+	})
 }
@@ -7,6 +7,7 @@ import (
 	"io"
 	"nexushub-personal/internal/config"
 	"nexushub-personal/internal/logger"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -21,6 +22,36 @@ type CloudStorageProvider interface {
 	GetFileURL(ctx context.Context, objectName string) (string, error)
 	// 删除文件
 	Delete(ctx context.Context, objectName string) error
+	// 列出指定前缀下的文件
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// ObjectInfo 云存储对象信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// NewCloudStorageProvider 根据配置创建云存储实例，未配置或初始化失败时返回nil，使用本地存储
+func NewCloudStorageProvider(log logger.Logger) CloudStorageProvider {
+	cfg := config.AppConfig.GetCloudStorageConfig()
+	switch cfg.Provider {
+	case "":
+		log.Info("Cloud storage not configured, using local storage")
+		return nil
+	case string(config.ProviderMinIO):
+		provider, err := NewMinIOProvider()
+		if err != nil {
+			log.Warn("Failed to initialize MinIO provider, falling back to local storage", "error", err)
+			return nil
+		}
+		log.Info("Cloud storage enabled", "provider", cfg.Provider, "bucket", cfg.Bucket)
+		return provider
+	default:
+		log.Warn("Unsupported cloud provider, falling back to local storage", "provider", cfg.Provider)
+		return nil
+	}
 }
 
 // MinIOProvider MinIO云存储实现
@@ -44,7 +75,7 @@ func NewMinIOProvider() (*MinIOProvider, error) {
 	})
 
 	if err != nil {
-		logger.Error("Failed to create MinIO client: %v", err)
+		logger.Error("Failed to create MinIO client", "error", err)
 		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
 	}
 
@@ -54,7 +85,7 @@ func NewMinIOProvider() (*MinIOProvider, error) {
 		// 如果是存储桶已存在的错误，则忽略
 		exists, errBucketExists := client.BucketExists(context.Background(), cfg.Bucket)
 		if errBucketExists != nil || !exists {
-			logger.Error("Failed to create bucket: %v", err)
+			logger.Error("Failed to create bucket", "error", err)
 			return nil, fmt.Errorf("failed to create bucket: %w", err)
 		}
 	}
@@ -72,7 +103,7 @@ func (m *MinIOProvider) Upload(ctx context.Context, objectName string, file []by
 	})
 
 	if err != nil {
-		logger.Error("Failed to upload file to MinIO: %v, object: %s", err, objectName)
+		logger.Error("Failed to upload file to MinIO", "error", err, "object", objectName)
 		return fmt.Errorf("failed to upload file to MinIO: %w", err)
 	}
 
@@ -83,7 +114,7 @@ func (m *MinIOProvider) Upload(ctx context.Context, objectName string, file []by
 func (m *MinIOProvider) Download(ctx context.Context, objectName string) ([]byte, error) {
 	object, err := m.client.GetObject(ctx, m.bucketName, objectName, minio.GetObjectOptions{})
 	if err != nil {
-		logger.Error("Failed to download file from MinIO: %v, object: %s", err, objectName)
+		logger.Error("Failed to download file from MinIO", "error", err, "object", objectName)
 		return nil, fmt.Errorf("failed to download file from MinIO: %w", err)
 	}
 	defer object.Close()
@@ -91,7 +122,7 @@ func (m *MinIOProvider) Download(ctx context.Context, objectName string) ([]byte
 	// 获取文件大小
 	stat, err := object.Stat()
 	if err != nil {
-		logger.Error("Failed to get file info from MinIO: %v, object: %s", err, objectName)
+		logger.Error("Failed to get file info from MinIO", "error", err, "object", objectName)
 		return nil, fmt.Errorf("failed to get file info from MinIO: %w", err)
 	}
 
@@ -99,7 +130,7 @@ func (m *MinIOProvider) Download(ctx context.Context, objectName string) ([]byte
 	fileBytes := make([]byte, stat.Size)
 	_, err = object.Read(fileBytes)
 	if err != nil && err != io.EOF {
-		logger.Error("Failed to read file from MinIO: %v, object: %s", err, objectName)
+		logger.Error("Failed to read file from MinIO", "error", err, "object", objectName)
 		return nil, fmt.Errorf("failed to read file from MinIO: %w", err)
 	}
 
@@ -127,9 +158,26 @@ func (m *MinIOProvider) Delete(ctx context.Context, objectName string) error {
 	err := m.client.RemoveObject(ctx, m.bucketName, objectName, minio.RemoveObjectOptions{})
 
 	if err != nil {
-		logger.Error("Failed to delete file from MinIO: %v, object: %s", err, objectName)
+		logger.Error("Failed to delete file from MinIO", "error", err, "object", objectName)
 		return fmt.Errorf("failed to delete file from MinIO: %w", err)
 	}
 
 	return nil
 }
+
+// List 列出MinIO中指定前缀下的对象
+func (m *MinIOProvider) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for object := range m.client.ListObjects(ctx, m.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			logger.Error("Failed to list objects from MinIO", "error", object.Err, "prefix", prefix)
+			return nil, fmt.Errorf("failed to list objects from MinIO: %w", object.Err)
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			LastModified: object.LastModified,
+		})
+	}
+	return objects, nil
+}
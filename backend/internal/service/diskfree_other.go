@@ -0,0 +1,8 @@
+//go:build !unix
+
+package service
+
+// diskFree 非unix平台暂不支持剩余空间检查
+func diskFree(string) (uint64, error) {
+	return 0, errDiskFreeUnsupported
+}
@@ -0,0 +1,691 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ExportFormatVersion 导出包格式版本，导入时拒绝更高的版本
+const ExportFormatVersion = 1
+
+// maxExportJSONSize 导出包中单个JSON文件的大小上限，防止解压炸弹
+const maxExportJSONSize = 256 << 20
+
+// 导入冲突处理策略
+const (
+	ConflictSkip      = "skip"      // 保留已有记录，跳过导入的记录
+	ConflictOverwrite = "overwrite" // 用导入的记录覆盖已有记录
+	ConflictDuplicate = "duplicate" // 始终新建记录
+)
+
+// ExportManifest 导出包中的 manifest.json
+type ExportManifest struct {
+	Version      int            `json:"version"`
+	ExportedAt   time.Time      `json:"exported_at"`
+	Username     string         `json:"username"`
+	Counts       map[string]int `json:"counts"`
+	MissingFiles []uint         `json:"missing_files,omitempty"` // 记录存在但内容无法读取的文件
+}
+
+// ExportedFile 文件元数据及其在压缩包中的内容路径
+type ExportedFile struct {
+	model.File
+	Blob string `json:"blob"`
+}
+
+// ImportReport 导入结果
+type ImportReport struct {
+	Created  map[string]int           `json:"created"`
+	Updated  map[string]int           `json:"updated"`
+	Skipped  map[string]int           `json:"skipped"`
+	IDMap    map[string]map[uint]uint `json:"id_map"` // 资源类型 -> 旧ID -> 新ID
+	Warnings []string                 `json:"warnings,omitempty"`
+}
+
+func newImportReport() *ImportReport {
+	return &ImportReport{
+		Created: make(map[string]int),
+		Updated: make(map[string]int),
+		Skipped: make(map[string]int),
+		IDMap:   make(map[string]map[uint]uint),
+	}
+}
+
+func (r *ImportReport) mapID(section string, oldID, newID uint) {
+	if r.IDMap[section] == nil {
+		r.IDMap[section] = make(map[uint]uint)
+	}
+	r.IDMap[section][oldID] = newID
+}
+
+type ExportService struct {
+	db    *gorm.DB
+	files *FileService
+	log   logger.Logger
+}
+
+func NewExportService(db *gorm.DB, files *FileService, log logger.Logger) *ExportService {
+	return &ExportService{
+		db:    db,
+		files: files,
+		log:   log,
+	}
+}
+
+// WithLogger 返回使用 log 记录日志的副本(包括读写文件内容使用的文件服务)，
+// handler 传入请求日志使服务中的日志带上请求ID
+func (s *ExportService) WithLogger(log logger.Logger) *ExportService {
+	return &ExportService{db: s.db, files: s.files.WithLogger(log), log: log}
+}
+
+// Export 将用户拥有的全部数据写成ZIP：每类资源一个JSON文件，上传的文件内容位于 files/<id>/<文件名>
+func (s *ExportService) Export(userID uint, w io.Writer) error {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return common.ErrUserNotFound
+	}
+
+	manifest := ExportManifest{
+		Version:    ExportFormatVersion,
+		ExportedAt: time.Now(),
+		Username:   user.Username,
+		Counts:     make(map[string]int),
+	}
+
+	sections := []struct {
+		name string
+		dest interface{}
+	}{
+		{"notebooks", &[]model.Notebook{}},
+		{"notes", &[]model.Note{}},
+		{"note_templates", &[]model.NoteTemplate{}},
+		{"tasks", &[]model.Task{}},
+		{"bookmarks", &[]model.Bookmark{}},
+		{"events", &[]model.Event{}},
+		{"collections", &[]model.Collection{}},
+		{"posts", &[]model.Post{}},
+		{"chat_messages", &[]model.ChatMessage{}},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, section := range sections {
+		if err := s.db.Where("user_id = ?", userID).Order("id").Find(section.dest).Error; err != nil {
+			return err
+		}
+		manifest.Counts[section.name] = reflect.ValueOf(section.dest).Elem().Len()
+		if err := writeZipJSON(zw, section.name+".json", section.dest); err != nil {
+			return err
+		}
+	}
+
+	var theme model.Theme
+	err := s.db.Where("user_id = ?", userID).First(&theme).Error
+	if err == nil {
+		manifest.Counts["theme"] = 1
+		if err := writeZipJSON(zw, "theme.json", theme); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var files []model.File
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&files).Error; err != nil {
+		return err
+	}
+	exported := make([]ExportedFile, 0, len(files))
+	for i := range files {
+		entry := ExportedFile{File: files[i], Blob: fmt.Sprintf("files/%d/%s", files[i].ID, path.Base(files[i].FileName))}
+		if err := s.writeBlob(zw, entry.Blob, &files[i]); err != nil {
+			s.log.Warn("Export: cannot read file content", "error", err, "file_id", files[i].ID)
+			manifest.MissingFiles = append(manifest.MissingFiles, files[i].ID)
+			entry.Blob = ""
+		}
+		exported = append(exported, entry)
+	}
+	manifest.Counts["files"] = len(exported)
+	if err := writeZipJSON(zw, "files.json", exported); err != nil {
+		return err
+	}
+
+	if err := writeZipJSON(zw, "manifest.json", manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (s *ExportService) writeBlob(zw *zip.Writer, name string, file *model.File) error {
+	src, err := s.files.OpenBlob(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: file.UpdatedAt}
+	dst, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// exportArchive 已打开的导出包
+type exportArchive struct {
+	entries map[string]*zip.File
+}
+
+func openExportArchive(r io.ReaderAt, size int64) (*exportArchive, *ExportManifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+	}
+	archive := &exportArchive{entries: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		archive.entries[f.Name] = f
+	}
+
+	var manifest ExportManifest
+	found, err := archive.readJSON("manifest.json", &manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("%w: manifest.json is missing", common.ErrInvalidArchive)
+	}
+	if manifest.Version < 1 || manifest.Version > ExportFormatVersion {
+		return nil, nil, fmt.Errorf("%w: %d", common.ErrUnsupportedVersion, manifest.Version)
+	}
+	return archive, &manifest, nil
+}
+
+// readJSON 解析压缩包中的JSON文件，文件不存在时返回 false
+func (a *exportArchive) readJSON(name string, v interface{}) (bool, error) {
+	f, ok := a.entries[name]
+	if !ok {
+		return false, nil
+	}
+	if f.UncompressedSize64 > maxExportJSONSize {
+		return true, fmt.Errorf("%w: %s is too large", common.ErrInvalidArchive, name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return true, fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+
+	if err := json.NewDecoder(io.LimitReader(rc, maxExportJSONSize)).Decode(v); err != nil {
+		return true, fmt.Errorf("%w: %s: %v", common.ErrInvalidArchive, name, err)
+	}
+	return true, nil
+}
+
+// Import 将导出包恢复到 userID 名下：所有记录重新分配ID，冲突按 conflict 策略处理。
+// 整个导入在一个事务中完成，失败时已写入的文件内容会被清理
+func (s *ExportService) Import(userID uint, r io.ReaderAt, size int64, conflict string) (*ImportReport, error) {
+	switch conflict {
+	case "":
+		conflict = ConflictSkip
+	case ConflictSkip, ConflictOverwrite, ConflictDuplicate:
+	default:
+		return nil, fmt.Errorf("%w: unknown conflict strategy %q", common.ErrInvalidInput, conflict)
+	}
+
+	archive, _, err := openExportArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	report := newImportReport()
+	var storedBlobs, replacedBlobs []string
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 先导入文件，笔记和文章中的文件链接需要用新ID改写，附件在笔记导入后重新关联
+		attached := make(map[uint]uint)
+		stored, replaced, err := s.importFiles(tx, archive, userID, conflict, report, attached)
+		storedBlobs, replacedBlobs = stored, replaced
+		if err != nil {
+			return err
+		}
+		rewrite := fileLinkRewriter(report.IDMap["files"])
+
+		// 父笔记本可能排在子笔记本之后，先全部导入为顶层笔记本，再按新ID恢复层级
+		var existingNotebooks []uint
+		if err := tx.Model(&model.Notebook{}).Where("user_id = ?", userID).Pluck("id", &existingNotebooks).Error; err != nil {
+			return err
+		}
+		parents := make(map[uint]uint)
+		if err := importSection(tx, archive, "notebooks", userID, conflict, report,
+			func(b *model.Notebook) *uint { return &b.ID },
+			func(b *model.Notebook) string { return b.Name },
+			func(b *model.Notebook) {
+				if b.ParentID != nil {
+					parents[b.ID] = *b.ParentID
+				}
+				b.UserID, b.ParentID = userID, nil
+			}); err != nil {
+			return err
+		}
+		if conflict == ConflictSkip {
+			// 跳过的笔记本保持原有层级
+			for oldID, newID := range report.IDMap["notebooks"] {
+				if slices.Contains(existingNotebooks, newID) {
+					delete(parents, oldID)
+				}
+			}
+		}
+		if err := relinkNotebooks(tx, userID, parents, report.IDMap["notebooks"]); err != nil {
+			return err
+		}
+
+		if err := importSection(tx, archive, "notes", userID, conflict, report,
+			func(n *model.Note) *uint { return &n.ID },
+			func(n *model.Note) string { return n.Title },
+			func(n *model.Note) {
+				n.UserID, n.Content = userID, rewrite(n.Content)
+				n.JournalDate = importedJournalDate(tx, userID, n, conflict)
+				n.NotebookID = mappedID(report.IDMap["notebooks"], n.NotebookID)
+			}); err != nil {
+			return err
+		}
+		if err := syncNoteLinksByID(tx, slices.Collect(maps.Values(report.IDMap["notes"]))); err != nil {
+			return err
+		}
+		if err := relinkAttachments(tx, attached, report.IDMap["notes"]); err != nil {
+			return err
+		}
+		// 每个用户最多一个日记模板，已有日记模板时导入的模板不再作为日记模板
+		var dailyTemplates int64
+		if err := tx.Model(&model.NoteTemplate{}).Where("user_id = ? AND is_daily = ?", userID, true).Count(&dailyTemplates).Error; err != nil {
+			return err
+		}
+		if err := importSection(tx, archive, "note_templates", userID, conflict, report,
+			func(t *model.NoteTemplate) *uint { return &t.ID },
+			func(t *model.NoteTemplate) string { return t.Name },
+			func(t *model.NoteTemplate) {
+				t.UserID = userID
+				if t.IsDaily && dailyTemplates > 0 {
+					t.IsDaily = false
+				}
+				if t.IsDaily {
+					dailyTemplates++
+				}
+			}); err != nil {
+			return err
+		}
+		if err := importSection(tx, archive, "tasks", userID, conflict, report,
+			func(t *model.Task) *uint { return &t.ID },
+			func(t *model.Task) string { return t.Title },
+			func(t *model.Task) { t.UserID = userID }); err != nil {
+			return err
+		}
+		if err := importSection(tx, archive, "bookmarks", userID, conflict, report,
+			func(b *model.Bookmark) *uint { return &b.ID },
+			func(b *model.Bookmark) string { return b.URL },
+			func(b *model.Bookmark) { b.UserID = userID }); err != nil {
+			return err
+		}
+		if err := importSection(tx, archive, "events", userID, conflict, report,
+			func(e *model.Event) *uint { return &e.ID },
+			func(e *model.Event) string { return e.Title + "\x00" + e.Date + "\x00" + e.StartTime },
+			func(e *model.Event) { e.UserID = userID }); err != nil {
+			return err
+		}
+		if err := importSection(tx, archive, "collections", userID, conflict, report,
+			func(c *model.Collection) *uint { return &c.ID },
+			func(c *model.Collection) string { return c.URL },
+			func(c *model.Collection) { c.UserID = userID }); err != nil {
+			return err
+		}
+		if err := importSection(tx, archive, "posts", userID, conflict, report,
+			func(p *model.Post) *uint { return &p.ID },
+			func(p *model.Post) string { return p.Title },
+			func(p *model.Post) { p.UserID, p.Content = userID, rewrite(p.Content) }); err != nil {
+			return err
+		}
+		if err := importSection(tx, archive, "chat_messages", userID, conflict, report,
+			func(m *model.ChatMessage) *uint { return &m.ID },
+			func(m *model.ChatMessage) string {
+				return m.Role + "\x00" + m.CreatedAt.UTC().Format(time.RFC3339Nano) + "\x00" + m.Content
+			},
+			func(m *model.ChatMessage) { m.UserID = userID }); err != nil {
+			return err
+		}
+		for section, resourceType := range map[string]string{
+			"files":         constants.ResourceFile,
+			"notes":         constants.ResourceNote,
+			"tasks":         constants.ResourceTask,
+			"bookmarks":     constants.ResourceBookmark,
+			"events":        constants.ResourceEvent,
+			"collections":   constants.ResourceCollection,
+			"posts":         constants.ResourcePost,
+			"chat_messages": constants.ResourceChat,
+		} {
+			ids := slices.Sorted(maps.Values(report.IDMap[section]))
+			if _, err := taggedModel(resourceType); err == nil {
+				if err := syncResourceTagsByID(tx, resourceType, ids); err != nil {
+					return err
+				}
+			}
+			if err := indexResourcesByID(tx, resourceType, ids); err != nil {
+				return err
+			}
+		}
+		return s.importTheme(tx, archive, userID, conflict, report)
+	})
+	if err != nil {
+		for _, blob := range storedBlobs {
+			s.files.DeleteBlob(blob)
+		}
+		return nil, err
+	}
+
+	// 被覆盖的文件内容已不再被引用
+	for _, blob := range replacedBlobs {
+		s.files.DeleteBlob(blob)
+	}
+
+	s.log.Info("Import finished", "user_id", userID, "created", report.Created, "updated", report.Updated, "skipped", report.Skipped)
+	return report, nil
+}
+
+// importSection 导入一类资源。id 返回记录ID字段的指针；key 返回用于判断冲突的自然键；
+// prepare 把记录归属改为当前用户并做必要的内容改写
+func importSection[T any](tx *gorm.DB, archive *exportArchive, section string, userID uint, conflict string,
+	report *ImportReport, id func(*T) *uint, key func(*T) string, prepare func(*T)) error {
+
+	var records []T
+	found, err := archive.readJSON(section+".json", &records)
+	if err != nil || !found {
+		return err
+	}
+
+	existing := make(map[string]uint)
+	if conflict != ConflictDuplicate {
+		var current []T
+		if err := tx.Where("user_id = ?", userID).Find(&current).Error; err != nil {
+			return err
+		}
+		for i := range current {
+			existing[key(&current[i])] = *id(&current[i])
+		}
+	}
+
+	for i := range records {
+		record := &records[i]
+		oldID := *id(record)
+		prepare(record)
+		existingID, conflicted := existing[key(record)]
+
+		switch {
+		case conflicted && conflict == ConflictSkip:
+			report.Skipped[section]++
+		case conflicted && conflict == ConflictOverwrite:
+			*id(record) = existingID
+			if err := tx.Save(record).Error; err != nil {
+				return fmt.Errorf("failed to overwrite %s %d: %w", section, oldID, err)
+			}
+			report.Updated[section]++
+		default:
+			*id(record) = 0
+			if err := tx.Create(record).Error; err != nil {
+				return fmt.Errorf("failed to import %s %d: %w", section, oldID, err)
+			}
+			existingID = *id(record)
+			if conflict != ConflictDuplicate {
+				existing[key(record)] = existingID
+			}
+			report.Created[section]++
+		}
+		report.mapID(section, oldID, existingID)
+	}
+	return nil
+}
+
+// importedJournalDate 导入的日记笔记保留其日期，除非当天已有其他日记；
+// 覆盖同名的日记笔记时日期不变
+func importedJournalDate(tx *gorm.DB, userID uint, n *model.Note, conflict string) *string {
+	if n.JournalDate == nil {
+		return nil
+	}
+	var taken model.Note
+	err := tx.Unscoped().Where("user_id = ? AND journal_date = ?", userID, *n.JournalDate).First(&taken).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return n.JournalDate
+	case err == nil && conflict == ConflictOverwrite && !taken.DeletedAt.Valid && taken.Title == n.Title:
+		return n.JournalDate
+	}
+	return nil
+}
+
+// importFiles 导入文件记录及内容，返回本次写入的存储路径(失败时清理)和被覆盖的旧存储路径(成功后清理)。
+// 笔记附件先不关联笔记，attached 记录新文件ID对应的旧笔记ID
+func (s *ExportService) importFiles(tx *gorm.DB, archive *exportArchive, userID uint, conflict string, report *ImportReport, attached map[uint]uint) (stored, replaced []string, err error) {
+	var files []ExportedFile
+	found, err := archive.readJSON("files.json", &files)
+	if err != nil || !found {
+		return nil, nil, err
+	}
+
+	existing := make(map[string]uint)
+	if conflict != ConflictDuplicate {
+		var current []model.File
+		if err := tx.Where("user_id = ?", userID).Find(&current).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, f := range current {
+			existing[fileKey(&f)] = f.ID
+		}
+	}
+
+	maxSize := config.AppConfig.Storage.MaxUploadSize
+	for _, entry := range files {
+		file := entry.File
+		oldID := file.ID
+
+		if existingID, ok := existing[fileKey(&file)]; ok && conflict == ConflictSkip {
+			report.mapID("files", oldID, existingID)
+			report.Skipped["files"]++
+			continue
+		}
+
+		blob, ok := archive.entries[entry.Blob]
+		if entry.Blob == "" || !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("file %d (%s) has no content in the archive, skipped", oldID, file.FileName))
+			report.Skipped["files"]++
+			continue
+		}
+		if int64(blob.UncompressedSize64) > maxSize {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("file %d (%s) exceeds the upload size limit, skipped", oldID, file.FileName))
+			report.Skipped["files"]++
+			continue
+		}
+
+		rc, err := blob.Open()
+		if err != nil {
+			return stored, replaced, fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+		}
+		category := file.Category
+		if category == "" {
+			category = s.files.CategoryOf(file.FileName)
+		}
+		storagePath, written, err := s.files.StoreBlob(userID, category, file.FileName, io.LimitReader(rc, maxSize), file.MimeType)
+		rc.Close()
+		if err != nil {
+			return stored, replaced, err
+		}
+		stored = append(stored, storagePath)
+
+		file.UserID = userID
+		file.FilePath = storagePath
+		file.FileSize = written
+		file.Category = category
+		file.Thumbnail = ""
+		file.NoteID = nil
+
+		if existingID, ok := existing[fileKey(&entry.File)]; ok && conflict == ConflictOverwrite {
+			var previous model.File
+			if err := tx.First(&previous, existingID).Error; err == nil {
+				replaced = append(replaced, previous.FilePath)
+			}
+			file.ID = existingID
+			if err := tx.Save(&file).Error; err != nil {
+				return stored, replaced, err
+			}
+			report.Updated["files"]++
+		} else {
+			file.ID = 0
+			if err := tx.Create(&file).Error; err != nil {
+				return stored, replaced, err
+			}
+			report.Created["files"]++
+		}
+		report.mapID("files", oldID, file.ID)
+		if entry.NoteID != nil {
+			attached[file.ID] = *entry.NoteID
+		}
+	}
+	return stored, replaced, nil
+}
+
+// relinkNotebooks 按新ID恢复导入的笔记本层级；父笔记本没有导入或会形成环时保持为顶层笔记本
+func relinkNotebooks(tx *gorm.DB, userID uint, parents map[uint]uint, idMap map[uint]uint) error {
+	for oldID, oldParent := range parents {
+		id, ok := idMap[oldID]
+		parent, parentOK := idMap[oldParent]
+		if !ok || !parentOK {
+			continue
+		}
+		descendants, err := notebookDescendants(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(descendants, parent) {
+			continue
+		}
+		if err := tx.Model(&model.Notebook{}).Where("id = ?", id).UpdateColumn("parent_id", parent).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mappedID 把导入记录中引用的旧ID换成新ID，没有导入的引用置空
+func mappedID(idMap map[uint]uint, oldID *uint) *uint {
+	if oldID == nil {
+		return nil
+	}
+	id, ok := idMap[*oldID]
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// relinkAttachments 把导入的附件关联到导入后的笔记，笔记没有导入时附件作为普通文件保留
+func relinkAttachments(tx *gorm.DB, attached map[uint]uint, noteIDs map[uint]uint) error {
+	for fileID, oldNoteID := range attached {
+		noteID, ok := noteIDs[oldNoteID]
+		if !ok {
+			continue
+		}
+		if err := tx.Model(&model.File{}).Where("id = ?", fileID).UpdateColumn("note_id", noteID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fileKey(f *model.File) string {
+	return f.FileName + "\x00" + strconv.FormatInt(f.FileSize, 10)
+}
+
+// importTheme 主题每个用户只有一份：除 skip 策略且已有主题外，总是用导入的主题替换
+func (s *ExportService) importTheme(tx *gorm.DB, archive *exportArchive, userID uint, conflict string, report *ImportReport) error {
+	var theme model.Theme
+	found, err := archive.readJSON("theme.json", &theme)
+	if err != nil || !found {
+		return err
+	}
+
+	var current model.Theme
+	err = tx.Where("user_id = ?", userID).First(&current).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		theme.ID = 0
+		theme.UserID = userID
+		if err := tx.Create(&theme).Error; err != nil {
+			return err
+		}
+		report.Created["theme"]++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if conflict == ConflictSkip {
+		report.Skipped["theme"]++
+		return nil
+	}
+
+	theme.ID = current.ID
+	theme.UserID = userID
+	theme.CreatedAt = current.CreatedAt
+	if err := tx.Save(&theme).Error; err != nil {
+		return err
+	}
+	report.Updated["theme"]++
+	return nil
+}
+
+var fileLinkPattern = regexp.MustCompile(`/api/v1/files/(download/)?(\d+)\b`)
+
+// fileLinkRewriter 把内容中指向旧文件ID的链接改写为新ID
+func fileLinkRewriter(idMap map[uint]uint) func(string) string {
+	return func(content string) string {
+		if len(idMap) == 0 || !strings.Contains(content, "/api/v1/files/") {
+			return content
+		}
+		return fileLinkPattern.ReplaceAllStringFunc(content, func(link string) string {
+			m := fileLinkPattern.FindStringSubmatch(link)
+			oldID, err := strconv.ParseUint(m[2], 10, 32)
+			if err != nil {
+				return link
+			}
+			newID, ok := idMap[uint(oldID)]
+			if !ok {
+				return link
+			}
+			return "/api/v1/files/" + m[1] + strconv.FormatUint(uint64(newID), 10)
+		})
+	}
+}
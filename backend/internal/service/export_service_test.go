@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// seedAccount 为用户创建覆盖所有导出类型的数据，返回文件ID
+func seedAccount(t *testing.T, services *service.Services, userID uint) uint {
+	t.Helper()
+	path, size, err := services.Files.StoreBlob(userID, "document", "report.txt", strings.NewReader("quarterly report"), "text/plain")
+	if err != nil {
+		t.Fatalf("store blob: %v", err)
+	}
+	file := model.File{UserID: userID, FileName: "report.txt", FilePath: path, FileSize: size, Category: "document"}
+	mustCreate(t, services.DB, &file)
+
+	work := model.Notebook{UserID: userID, Name: "Work"}
+	mustCreate(t, services.DB, &work)
+	projects := model.Notebook{UserID: userID, Name: "Projects", ParentID: &work.ID}
+	mustCreate(t, services.DB, &projects)
+
+	mustCreate(t, services.DB, &model.Note{UserID: userID, Title: "Plans", NotebookID: &projects.ID, Content: fmt.Sprintf("see [report](/api/v1/files/download/%d)", file.ID)})
+	mustCreate(t, services.DB, &model.Task{UserID: userID, Title: "Ship export", Status: "pending", Priority: "high"})
+	mustCreate(t, services.DB, &model.Bookmark{UserID: userID, Title: "Go", URL: "https://go.dev"})
+	mustCreate(t, services.DB, &model.Event{UserID: userID, Title: "Review", Date: "2024-03-01"})
+	mustCreate(t, services.DB, &model.Collection{UserID: userID, Title: "Pic", URL: "https://example.com/a.png", Type: "image"})
+	mustCreate(t, services.DB, &model.Post{UserID: userID, Title: "Hello", Content: "first post"})
+	mustCreate(t, services.DB, &model.ChatMessage{UserID: userID, Role: "user", Content: "hi"})
+	if err := services.DB.Model(&model.Theme{}).Where("user_id = ?", userID).Update("theme_name", "neon").Error; err != nil {
+		t.Fatal(err)
+	}
+	return file.ID
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, v interface{}) {
+	t.Helper()
+	if err := db.Create(v).Error; err != nil {
+		t.Fatalf("create %T: %v", v, err)
+	}
+}
+
+func exportArchive(t *testing.T, services *service.Services, userID uint) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := services.Export.Export(userID, &buf); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	oldFileID := seedAccount(t, services, 1)
+	// 让新实例中的ID与原实例不同
+	mustCreate(t, services.DB, &model.File{UserID: 1, FileName: "padding", FilePath: "x", FileSize: 1})
+	archive := exportArchive(t, services, 1)
+
+	// 全新实例
+	services = testutil.SetupServices(t, nil)
+	mustCreate(t, services.DB, &model.File{UserID: 1, FileName: "other", FilePath: "y", FileSize: 2})
+	mustCreate(t, services.DB, &model.File{UserID: 1, FileName: "other2", FilePath: "z", FileSize: 3})
+
+	exports := services.Export
+	report, err := exports.Import(1, bytes.NewReader(archive), int64(len(archive)), "")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	for _, section := range []string{"notebooks", "notes", "tasks", "bookmarks", "events", "collections", "posts", "chat_messages", "files"} {
+		if report.Created[section] < 1 {
+			t.Errorf("%s not imported: %+v", section, report)
+		}
+	}
+	if report.Updated["theme"] != 0 || report.Skipped["theme"] != 1 {
+		t.Errorf("theme should be kept with skip strategy: %+v", report)
+	}
+
+	newFileID := report.IDMap["files"][oldFileID]
+	if newFileID == 0 || newFileID == oldFileID {
+		t.Fatalf("file id not remapped: %v", report.IDMap["files"])
+	}
+
+	var note model.Note
+	services.DB.Where("title = ?", "Plans").First(&note)
+	if want := fmt.Sprintf("/api/v1/files/download/%d", newFileID); !strings.Contains(note.Content, want) {
+		t.Errorf("note link not rewritten: %q, want %s", note.Content, want)
+	}
+
+	var projects, work model.Notebook
+	services.DB.Where("name = ?", "Projects").First(&projects)
+	services.DB.Where("name = ?", "Work").First(&work)
+	if note.NotebookID == nil || *note.NotebookID != projects.ID || projects.ParentID == nil || *projects.ParentID != work.ID {
+		t.Errorf("notebook hierarchy not restored: note=%v projects=%+v work=%d", note.NotebookID, projects, work.ID)
+	}
+
+	files := services.Files
+	file, err := files.GetByID(newFileID, 1)
+	if err != nil {
+		t.Fatalf("imported file: %v", err)
+	}
+	blob, err := files.OpenBlob(file)
+	if err != nil {
+		t.Fatalf("open imported blob: %v", err)
+	}
+	content, _ := io.ReadAll(blob)
+	blob.Close()
+	if string(content) != "quarterly report" {
+		t.Errorf("blob content = %q", content)
+	}
+
+	// 再次导入: skip 全部跳过，overwrite 覆盖，duplicate 新建
+	report, err = exports.Import(1, bytes.NewReader(archive), int64(len(archive)), "skip")
+	if err != nil || len(report.Created) != 0 || report.Skipped["notes"] != 1 {
+		t.Fatalf("skip import: %v %+v", err, report)
+	}
+	report, err = exports.Import(1, bytes.NewReader(archive), int64(len(archive)), "overwrite")
+	if err != nil || len(report.Created) != 0 || report.Updated["notes"] != 1 || report.Updated["theme"] != 1 {
+		t.Fatalf("overwrite import: %v %+v", err, report)
+	}
+	var theme model.Theme
+	services.DB.Where("user_id = ?", 1).First(&theme)
+	if theme.ThemeName != "neon" {
+		t.Errorf("theme not overwritten: %s", theme.ThemeName)
+	}
+	report, err = exports.Import(1, bytes.NewReader(archive), int64(len(archive)), "duplicate")
+	if err != nil || report.Created["notes"] != 1 {
+		t.Fatalf("duplicate import: %v %+v", err, report)
+	}
+	var count int64
+	services.DB.Model(&model.Note{}).Where("title = ?", "Plans").Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 notes after duplicate import, got %d", count)
+	}
+}
+
+func TestImportRejectsInvalidArchive(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	exports := services.Export
+
+	if _, err := exports.Import(1, strings.NewReader("not a zip"), 9, ""); err == nil {
+		t.Fatal("expected error for invalid archive")
+	}
+
+	archive := exportArchive(t, services, 1)
+	if _, err := exports.Import(1, bytes.NewReader(archive), int64(len(archive)), "merge"); err == nil {
+		t.Fatal("expected error for unknown conflict strategy")
+	}
+}
@@ -0,0 +1,4 @@
+package service
+
+// BackupsToDelete 供测试使用
+var BackupsToDelete = backupsToDelete
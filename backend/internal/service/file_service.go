@@ -1,18 +1,22 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"nexushub-personal/internal/common"
 	"nexushub-personal/internal/config"
-	"nexushub-personal/internal/database"
+	"nexushub-personal/internal/constants"
 	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/metrics"
 	"nexushub-personal/internal/model"
 	"nexushub-personal/internal/validator"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,43 +24,31 @@ import (
 )
 
 type FileService struct {
+	db            *gorm.DB
 	cloudProvider CloudStorageProvider
 	useCloud      bool
+	log           logger.Logger
 }
 
-func NewFileService() *FileService {
-	s := &FileService{}
-
-	// 检查是否配置了云存储
-	cfg := config.AppConfig.GetCloudStorageConfig()
-	if cfg.Provider != "" {
-		switch cfg.Provider {
-		case "minio":
-			provider, err := NewMinIOProvider()
-			if err != nil {
-				logger.Warn("Failed to initialize MinIO provider: %v, falling back to local storage", err)
-				s.useCloud = false
-				s.cloudProvider = nil
-			} else {
-				s.cloudProvider = provider
-				s.useCloud = true
-				logger.Info("Cloud storage enabled: provider=%s, bucket=%s", cfg.Provider, cfg.Bucket)
-			}
-		default:
-			logger.Warn("Unsupported cloud provider: %s, falling back to local storage", cfg.Provider)
-			s.useCloud = false
-			s.cloudProvider = nil
-		}
-	} else {
-		logger.Info("Cloud storage not configured, using local storage")
-		s.useCloud = false
-		s.cloudProvider = nil
+// NewFileService storage 为 nil 时使用本地存储
+func NewFileService(db *gorm.DB, storage CloudStorageProvider, log logger.Logger) *FileService {
+	return &FileService{
+		db:            db,
+		cloudProvider: storage,
+		useCloud:      storage != nil,
+		log:           log,
 	}
+}
 
-	return s
+// WithLogger 返回使用 log 记录日志的副本，handler 传入请求日志使服务中的日志带上请求ID
+func (s *FileService) WithLogger(log logger.Logger) *FileService {
+	scoped := *s
+	scoped.log = log
+	return &scoped
 }
 
-func (s *FileService) GetAll(userID uint, page, pageSize int) ([]model.File, int64, error) {
+// GetAll 分页获取用户可访问的文件，tag 不为空时只返回带有该标签的文件
+func (s *FileService) GetAll(userID uint, tag string, page, pageSize int) ([]model.File, int64, error) {
 	var files []model.File
 	var total int64
 	
@@ -64,8 +56,8 @@ func (s *FileService) GetAll(userID uint, page, pageSize int) ([]model.File, int
 	offset := (page - 1) * pageSize
 	
 	// 查询总数
-	db := database.DB.Where("user_id IN (?, ?)", userID, 0)
-	err := db.Model(&model.File{}).Count(&total).Error
+	db := s.db.Model(&model.File{}).Scopes(AccessibleScope(userID, constants.ResourceFile, false), TaggedScope(constants.ResourceFile, tag))
+	err := db.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -79,15 +71,32 @@ func (s *FileService) GetAll(userID uint, page, pageSize int) ([]model.File, int
 	return files, total, nil
 }
 
+// StorageUsage 存储用量统计
+type StorageUsage struct {
+	Files int64 `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Usage 统计所有用户的文件数量和总大小
+func (s *FileService) Usage() (StorageUsage, error) {
+	var usage StorageUsage
+	err := s.db.Model(&model.File{}).
+		Select("COUNT(*) AS files, COALESCE(SUM(file_size), 0) AS bytes").
+		Scan(&usage).Error
+	return usage, err
+}
+
 func (s *FileService) GetByID(id, userID uint) (*model.File, error) {
 	var file model.File
-	err := database.DB.Where("id = ? AND (user_id = ? OR user_id = 0)", id, userID).First(&file).Error
+	err := s.db.Scopes(AccessibleScope(userID, constants.ResourceFile, false)).Where("id = ?", id).First(&file).Error
 	return &file, err
 }
 
-func (s *FileService) GetByCategory(category string, userID uint) ([]model.File, error) {
+// GetByCategory 获取某个分类下用户可访问的文件，tag 不为空时只返回带有该标签的文件
+func (s *FileService) GetByCategory(category, tag string, userID uint) ([]model.File, error) {
 	var files []model.File
-	err := database.DB.Where("category = ? AND user_id IN (?, ?)", category, userID, 0).Order("created_at DESC").Find(&files).Error
+	err := s.db.Scopes(AccessibleScope(userID, constants.ResourceFile, false), TaggedScope(constants.ResourceFile, tag)).
+		Where("category = ?", category).Order("created_at DESC").Find(&files).Error
 	return files, err
 }
 
@@ -95,7 +104,7 @@ func (s *FileService) Upload(fileHeader *multipart.FileHeader, userID uint) (*mo
 	// 验证文件上传
 	maxSize := config.AppConfig.Storage.MaxUploadSize
 	if err := validator.ValidateFileUpload(fileHeader, maxSize, nil); err != nil {
-		logger.Warn("File validation failed: %v, file: %s, size: %d", err, fileHeader.Filename, fileHeader.Size)
+		s.log.Warn("File validation failed", "error", err, "filename", fileHeader.Filename, "size", fileHeader.Size)
 		return nil, err
 	}
 
@@ -104,16 +113,16 @@ func (s *FileService) Upload(fileHeader *multipart.FileHeader, userID uint) (*mo
 	category := s.getCategoryByExtension(ext)
 
 	// 开始数据库事务
-	tx := database.DB.Begin()
+	tx := s.db.Begin()
 	if tx.Error != nil {
-		logger.Error("Failed to begin transaction: %v", tx.Error)
+		s.log.Error("Failed to begin transaction", "error", tx.Error)
 		return nil, fmt.Errorf("%w: database transaction error", common.ErrInternalServer)
 	}
 
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
-			logger.Error("Panic during file upload: %v", r)
+			s.log.Error("Panic during file upload", "panic", r)
 		}
 	}()
 
@@ -128,12 +137,13 @@ func (s *FileService) Upload(fileHeader *multipart.FileHeader, userID uint) (*mo
 		if err := tx.Commit().Error; err != nil {
 			// 如果云存储已上传但事务失败，尝试删除云存储文件
 			s.deleteFromCloud(storagePath)
-			logger.Error("Failed to commit cloud storage transaction: %v", err)
+			s.log.Error("Failed to commit cloud storage transaction", "error", err)
 			return nil, fmt.Errorf("%w: transaction commit failed", common.ErrInternalServer)
 		}
 
-		logger.Info("File uploaded to cloud successfully: id=%d, filename=%s, size=%d, category=%s, user_id=%d, storage=%s",
-			file.ID, file.FileName, file.FileSize, file.Category, userID, storagePath)
+		s.log.Info("File uploaded to cloud successfully", "id", file.ID, "filename", file.FileName, "size", file.FileSize,
+			"category", file.Category, "user_id", userID, "storage", storagePath)
+		metrics.UploadBytes.Add(float64(file.FileSize), "cloud")
 
 		return file, nil
 	} else {
@@ -147,12 +157,13 @@ func (s *FileService) Upload(fileHeader *multipart.FileHeader, userID uint) (*mo
 		if err := tx.Commit().Error; err != nil {
 			// 如果本地文件已上传但事务失败，删除本地文件
 			os.Remove(file.FilePath)
-			logger.Error("Failed to commit local storage transaction: %v", err)
+			s.log.Error("Failed to commit local storage transaction", "error", err)
 			return nil, fmt.Errorf("%w: transaction commit failed", common.ErrInternalServer)
 		}
 
-		logger.Info("File uploaded locally successfully: id=%d, filename=%s, size=%d, category=%s, user_id=%d",
-			file.ID, file.FileName, file.FileSize, file.Category, userID)
+		s.log.Info("File uploaded locally successfully", "id", file.ID, "filename", file.FileName, "size", file.FileSize,
+			"category", file.Category, "user_id", userID)
+		metrics.UploadBytes.Add(float64(file.FileSize), "local")
 
 		return file, nil
 	}
@@ -166,7 +177,7 @@ func (s *FileService) Rename(id, userID uint, newName string) error {
 	}
 
 	var file model.File
-	if err := database.DB.Where("id = ? AND (user_id = ? OR user_id = 0)", id, userID).First(&file).Error; err != nil {
+	if err := s.db.Scopes(AccessibleScope(userID, constants.ResourceFile, true)).Where("id = ?", id).First(&file).Error; err != nil {
 		return common.ErrFileNotFound
 	}
 
@@ -180,46 +191,72 @@ func (s *FileService) Rename(id, userID uint, newName string) error {
 	newPath := filepath.Join(filepath.Dir(oldPath), safeName)
 
 	if err := os.Rename(oldPath, newPath); err != nil {
-		logger.Error("Failed to rename physical file: %v", err)
+		s.log.Error("Failed to rename physical file", "error", err)
 		return common.ErrInternalServer
 	}
 
 	file.FileName = safeName
 	file.FilePath = newPath
-	return database.DB.Save(&file).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&file).Error; err != nil {
+			return err
+		}
+		return indexResource(tx, constants.ResourceFile, file.ID)
+	})
+}
+
+// SetTags 修改文件的标签(需要编辑权限)，返回更新后的文件
+func (s *FileService) SetTags(id, userID uint, tags string) (*model.File, error) {
+	var file model.File
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(AccessibleScope(userID, constants.ResourceFile, true)).Where("id = ?", id).First(&file).Error; err != nil {
+			return common.ErrFileNotFound
+		}
+		if err := tx.Model(&file).UpdateColumn("tags", tags).Error; err != nil {
+			return err
+		}
+		if err := syncEntityTags(tx, constants.ResourceFile, file.ID, &file); err != nil {
+			return err
+		}
+		return indexResource(tx, constants.ResourceFile, file.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
 }
 
 func (s *FileService) Delete(id, userID uint) error {
 	// 验证ID
 	if err := validator.ValidateID(id); err != nil {
-		logger.Error("Invalid file ID for deletion: %v", err)
+		s.log.Error("Invalid file ID for deletion", "error", err)
 		return err
 	}
 
 	if err := validator.ValidateID(userID); err != nil {
-		logger.Error("Invalid user ID for file deletion: %v", err)
+		s.log.Error("Invalid user ID for file deletion", "error", err)
 		return err
 	}
 
 	// 开始事务
-	tx := database.DB.Begin()
+	tx := s.db.Begin()
 	if tx.Error != nil {
-		logger.Error("Failed to begin transaction for file deletion: %v", tx.Error)
+		s.log.Error("Failed to begin transaction for file deletion", "error", tx.Error)
 		return fmt.Errorf("%w: database transaction error", common.ErrInternalServer)
 	}
 
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
-			logger.Error("Panic during file deletion: %v", r)
+			s.log.Error("Panic during file deletion", "panic", r)
 		}
 	}()
 
 	// 查询文件记录
 	var file model.File
-	if err := tx.Where("id = ? AND (user_id = ? OR user_id = 0)", id, userID).First(&file).Error; err != nil {
+	if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&file).Error; err != nil {
 		tx.Rollback()
-		logger.Warn("File not found for deletion: id=%d, user_id=%d", id, userID)
+		s.log.Warn("File not found for deletion", "id", id, "user_id", userID)
 		return common.ErrFileNotFound
 	}
 
@@ -227,19 +264,30 @@ func (s *FileService) Delete(id, userID uint) error {
 	result := tx.Delete(&file)
 	if result.Error != nil {
 		tx.Rollback()
-		logger.Error("Failed to delete file record from database: %v, id=%d", result.Error, id)
+		s.log.Error("Failed to delete file record from database", "error", result.Error, "id", id)
 		return fmt.Errorf("%w: database delete failed", common.ErrFileDeleteFailed)
 	}
 
 	if result.RowsAffected == 0 {
 		tx.Rollback()
-		logger.Warn("No file record deleted (likely permission issue): id=%d, user_id=%d", id, userID)
+		s.log.Warn("No file record deleted (likely permission issue)", "id", id, "user_id", userID)
 		return common.ErrFileNotFound
 	}
 
+	if err := removeResourceTags(tx, constants.ResourceFile, file.ID); err != nil {
+		tx.Rollback()
+		s.log.Error("Failed to remove file tags", "error", err, "id", id)
+		return fmt.Errorf("%w: database delete failed", common.ErrFileDeleteFailed)
+	}
+	if err := unindexResource(tx, constants.ResourceFile, file.ID); err != nil {
+		tx.Rollback()
+		s.log.Error("Failed to remove file from search index", "error", err, "id", id)
+		return fmt.Errorf("%w: database delete failed", common.ErrFileDeleteFailed)
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
-		logger.Error("Failed to commit file deletion transaction: %v, id=%d", err, id)
+		s.log.Error("Failed to commit file deletion transaction", "error", err, "id", id)
 		return fmt.Errorf("%w: transaction commit failed", common.ErrInternalServer)
 	}
 
@@ -247,14 +295,14 @@ func (s *FileService) Delete(id, userID uint) error {
 	if s.useCloud && s.cloudProvider != nil && strings.Contains(file.FilePath, "/") && len(strings.Split(file.FilePath, "/")) > 1 {
 		// 云存储文件删除
 		if err := s.cloudProvider.Delete(context.Background(), file.FilePath); err != nil {
-			logger.Warn("Failed to delete cloud file (record deleted): %v, object=%s", err, file.FilePath)
+			s.log.Warn("Failed to delete cloud file (record deleted)", "error", err, "object", file.FilePath)
 		} else {
-			logger.Info("Successfully deleted cloud file: %s", file.FilePath)
+			s.log.Info("Successfully deleted cloud file", "object", file.FilePath)
 		}
 	} else {
 		// 本地文件删除
 		if err := os.Remove(file.FilePath); err != nil {
-			logger.Warn("Failed to delete physical file (record deleted): %v, path=%s", err, file.FilePath)
+			s.log.Warn("Failed to delete physical file (record deleted)", "error", err, "path", file.FilePath)
 			// 不返回错误,因为数据库记录已经删除
 		}
 	}
@@ -264,17 +312,17 @@ func (s *FileService) Delete(id, userID uint) error {
 		if s.useCloud && s.cloudProvider != nil && strings.Contains(file.Thumbnail, "/") && len(strings.Split(file.Thumbnail, "/")) > 1 {
 			// 云存储缩略图删除
 			if err := s.cloudProvider.Delete(context.Background(), file.Thumbnail); err != nil {
-				logger.Warn("Failed to delete cloud thumbnail (ignored): %v, object=%s", err, file.Thumbnail)
+				s.log.Warn("Failed to delete cloud thumbnail (ignored)", "error", err, "object", file.Thumbnail)
 			}
 		} else {
 			// 本地缩略图删除
 			if err := os.Remove(file.Thumbnail); err != nil {
-				logger.Warn("Failed to delete thumbnail (ignored): %v, path=%s", err, file.Thumbnail)
+				s.log.Warn("Failed to delete thumbnail (ignored)", "error", err, "path", file.Thumbnail)
 			}
 		}
 	}
 
-	logger.Info("File deleted successfully: id=%d, filename=%s, user_id=%d", id, file.FileName, userID)
+	s.log.Info("File deleted successfully", "id", id, "filename", file.FileName, "user_id", userID)
 	return nil
 }
 
@@ -322,7 +370,7 @@ func (s *FileService) uploadToLocal(fileHeader *multipart.FileHeader, userID uin
 
 	// Create storage directory if not exists
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		logger.Error("Failed to create upload directory: %v, path: %s", err, uploadDir)
+		s.log.Error("Failed to create upload directory", "error", err, "path", uploadDir)
 		return nil, fmt.Errorf("%w: failed to create storage directory", common.ErrInternalServer)
 	}
 
@@ -335,7 +383,7 @@ func (s *FileService) uploadToLocal(fileHeader *multipart.FileHeader, userID uin
 	// Open and validate uploaded file
 	src, err := fileHeader.Open()
 	if err != nil {
-		logger.Error("Failed to open uploaded file: %v, filename: %s", err, fileHeader.Filename)
+		s.log.Error("Failed to open uploaded file", "error", err, "filename", fileHeader.Filename)
 		return nil, fmt.Errorf("%w: cannot open uploaded file", common.ErrFileUploadFailed)
 	}
 	defer src.Close()
@@ -343,7 +391,7 @@ func (s *FileService) uploadToLocal(fileHeader *multipart.FileHeader, userID uin
 	// Create destination file
 	dst, err := os.Create(filePath)
 	if err != nil {
-		logger.Error("Failed to create destination file: %v, path: %s", err, filePath)
+		s.log.Error("Failed to create destination file", "error", err, "path", filePath)
 		return nil, fmt.Errorf("%w: cannot create destination file", common.ErrFileUploadFailed)
 	}
 	defer dst.Close()
@@ -352,14 +400,14 @@ func (s *FileService) uploadToLocal(fileHeader *multipart.FileHeader, userID uin
 	written, err := io.Copy(dst, src)
 	if err != nil {
 		os.Remove(filePath) // 清理失败的文件
-		logger.Error("Failed to copy file content: %v, filename: %s", err, fileHeader.Filename)
+		s.log.Error("Failed to copy file content", "error", err, "filename", fileHeader.Filename)
 		return nil, fmt.Errorf("%w: file copy failed", common.ErrFileUploadFailed)
 	}
 
 	// 验证写入的字节数
 	if written != fileHeader.Size {
 		os.Remove(filePath)
-		logger.Error("File size mismatch: expected %d, got %d, filename: %s", fileHeader.Size, written, fileHeader.Filename)
+		s.log.Error("File size mismatch", "expected", fileHeader.Size, "written", written, "filename", fileHeader.Filename)
 		return nil, fmt.Errorf("%w: file size mismatch", common.ErrFileUploadFailed)
 	}
 
@@ -375,9 +423,9 @@ func (s *FileService) uploadToLocal(fileHeader *multipart.FileHeader, userID uin
 		Category:  category,
 	}
 
-	if err := tx.Create(file).Error; err != nil {
+	if err := createFileRecord(tx, file); err != nil {
 		os.Remove(filePath) // 数据库插入失败时清理文件
-		logger.Error("Failed to create file record in database: %v, filename: %s", err, fileHeader.Filename)
+		s.log.Error("Failed to create file record in database", "error", err, "filename", fileHeader.Filename)
 		return nil, fmt.Errorf("%w: database insert failed", common.ErrInternalServer)
 	}
 
@@ -394,7 +442,7 @@ func (s *FileService) uploadToCloud(fileHeader *multipart.FileHeader, userID uin
 	// Open file for reading
 	file, err := fileHeader.Open()
 	if err != nil {
-		logger.Error("Failed to open file for cloud upload: %v, filename: %s", err, fileHeader.Filename)
+		s.log.Error("Failed to open file for cloud upload", "error", err, "filename", fileHeader.Filename)
 		return nil, "", fmt.Errorf("%w: cannot open file", common.ErrFileUploadFailed)
 	}
 	defer file.Close()
@@ -402,14 +450,14 @@ func (s *FileService) uploadToCloud(fileHeader *multipart.FileHeader, userID uin
 	// Read file content
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
-		logger.Error("Failed to read file content: %v, filename: %s", err, fileHeader.Filename)
+		s.log.Error("Failed to read file content", "error", err, "filename", fileHeader.Filename)
 		return nil, "", fmt.Errorf("%w: cannot read file content", common.ErrFileUploadFailed)
 	}
 
 	// Upload to cloud storage
 	contentType := fileHeader.Header.Get("Content-Type")
 	if err := s.cloudProvider.Upload(context.Background(), objectName, fileBytes, contentType); err != nil {
-		logger.Error("Failed to upload file to cloud storage: %v, filename: %s", err, fileHeader.Filename)
+		s.log.Error("Failed to upload file to cloud storage", "error", err, "filename", fileHeader.Filename)
 		return nil, "", fmt.Errorf("%w: cloud storage upload failed", common.ErrFileUploadFailed)
 	}
 
@@ -425,10 +473,10 @@ func (s *FileService) uploadToCloud(fileHeader *multipart.FileHeader, userID uin
 		Category:  category,
 	}
 
-	if err := tx.Create(fileRecord).Error; err != nil {
+	if err := createFileRecord(tx, fileRecord); err != nil {
 		// 数据库插入失败时删除云存储文件
 		s.deleteFromCloud(objectName)
-		logger.Error("Failed to create file record in database: %v, filename: %s", err, fileHeader.Filename)
+		s.log.Error("Failed to create file record in database", "error", err, "filename", fileHeader.Filename)
 		return nil, "", fmt.Errorf("%w: database insert failed", common.ErrInternalServer)
 	}
 
@@ -436,12 +484,259 @@ func (s *FileService) uploadToCloud(fileHeader *multipart.FileHeader, userID uin
 }
 
 // deleteFromCloud 从云存储删除文件
+// createFileRecord 写入文件记录并建立搜索索引
+func createFileRecord(tx *gorm.DB, file *model.File) error {
+	if err := tx.Create(file).Error; err != nil {
+		return err
+	}
+	return indexResource(tx, constants.ResourceFile, file.ID)
+}
+
 func (s *FileService) deleteFromCloud(objectName string) {
 	if s.cloudProvider != nil {
 		if err := s.cloudProvider.Delete(context.Background(), objectName); err != nil {
-			logger.Warn("Failed to delete file from cloud storage (cleanup): %v, object: %s", err, objectName)
+			s.log.Warn("Failed to delete file from cloud storage (cleanup)", "error", err, "object", objectName)
 		} else {
-			logger.Info("Successfully deleted file from cloud storage: %s", objectName)
+			s.log.Info("Successfully deleted file from cloud storage", "object", objectName)
+		}
+	}
+}
+
+// isCloudObject 判断存储路径是否为云存储对象(本地不存在且已启用云存储)
+func (s *FileService) isCloudObject(path string) bool {
+	if !s.useCloud || s.cloudProvider == nil {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err != nil
+}
+
+// OpenBlob 打开文件内容，支持本地存储和云存储
+func (s *FileService) OpenBlob(file *model.File) (io.ReadCloser, error) {
+	if s.isCloudObject(file.FilePath) {
+		data, err := s.cloudProvider.Download(context.Background(), file.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return os.Open(file.FilePath)
+}
+
+// StoreBlob 将内容写入当前存储后端(云存储或本地 uploads/<category>)，返回存储路径和写入字节数
+// 只负责写入内容，文件记录由调用方创建
+func (s *FileService) StoreBlob(userID uint, category, filename string, r io.Reader, contentType string) (string, int64, error) {
+	safeFilename := filepath.Base(validator.SanitizeFilePath(filename))
+	if safeFilename == "" || safeFilename == "." || safeFilename == string(filepath.Separator) {
+		return "", 0, common.ErrInvalidFileName
+	}
+	stamp := time.Now().UnixNano()
+
+	if s.useCloud && s.cloudProvider != nil {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: cannot read file content", common.ErrFileUploadFailed)
+		}
+		objectName := fmt.Sprintf("%d_%s_%d/%s", userID, category, stamp, safeFilename)
+		if err := s.cloudProvider.Upload(context.Background(), objectName, data, contentType); err != nil {
+			s.log.Error("Failed to store blob in cloud storage", "error", err, "object", objectName)
+			return "", 0, fmt.Errorf("%w: cloud storage upload failed", common.ErrFileUploadFailed)
+		}
+		return objectName, int64(len(data)), nil
+	}
+
+	uploadDir := filepath.Join(validator.SanitizeFilePath(config.AppConfig.Storage.Path), "uploads", category)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		s.log.Error("Failed to create upload directory", "error", err, "path", uploadDir)
+		return "", 0, fmt.Errorf("%w: failed to create storage directory", common.ErrInternalServer)
+	}
+
+	filePath := filepath.Join(uploadDir, fmt.Sprintf("%d_%s", stamp, safeFilename))
+	dst, err := os.Create(filePath)
+	if err != nil {
+		s.log.Error("Failed to create destination file", "error", err, "path", filePath)
+		return "", 0, fmt.Errorf("%w: cannot create destination file", common.ErrFileUploadFailed)
+	}
+	written, err := io.Copy(dst, r)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		s.log.Error("Failed to write blob", "error", err, "path", filePath)
+		return "", 0, fmt.Errorf("%w: file copy failed", common.ErrFileUploadFailed)
+	}
+	return filePath, written, nil
+}
+
+// DeleteBlob 删除 StoreBlob 写入的内容，用于失败时清理
+func (s *FileService) DeleteBlob(path string) {
+	if s.isCloudObject(path) {
+		s.deleteFromCloud(path)
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		s.log.Warn("Failed to delete blob", "error", err, "path", path)
+	}
+}
+
+// CategoryOf 根据扩展名返回文件分类
+func (s *FileService) CategoryOf(filename string) string {
+	return s.getCategoryByExtension(strings.ToLower(filepath.Ext(filename)))
+}
+
+// isLocalPath 判断存储路径是否位于本地存储目录下
+func (s *FileService) isLocalPath(path string) bool {
+	base := filepath.Clean(validator.SanitizeFilePath(config.AppConfig.Storage.Path))
+	return strings.HasPrefix(filepath.Clean(path), base+string(filepath.Separator))
+}
+
+// WriteBlobAt 将内容写回指定的存储路径(用于从备份恢复)，本地路径必须位于存储目录下
+func (s *FileService) WriteBlobAt(path string, r io.Reader, contentType string) error {
+	if !s.isLocalPath(path) {
+		if !s.useCloud || s.cloudProvider == nil {
+			return fmt.Errorf("%w: %s is outside the storage directory", common.ErrFilePathNotSafe, path)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return s.cloudProvider.Upload(context.Background(), path, data, contentType)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// FileContentURL 文件内容的稳定链接，只依赖文件ID，文件改名或迁移到云存储后仍然有效
+func FileContentURL(id uint) string {
+	return "/api/v1/files/" + strconv.FormatUint(uint64(id), 10) + "/content"
+}
+
+// GetReadable 获取用户可读取内容的文件：自己的、共享给用户的，
+// 以及作为附件上传到用户可访问的笔记中的文件
+func (s *FileService) GetReadable(id, userID uint) (*model.File, error) {
+	file, err := s.GetByID(id, userID)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return file, err
+	}
+	notes := s.db.Model(&model.Note{}).Select("id").Scopes(AccessibleScope(userID, constants.ResourceNote, false))
+	var attached model.File
+	if err := s.db.Where("id = ? AND note_id IN (?)", id, notes).First(&attached).Error; err != nil {
+		return nil, err
+	}
+	return &attached, nil
+}
+
+// UploadToNote 上传文件作为笔记的附件，需要笔记的编辑权限。文件归上传者所有
+func (s *FileService) UploadToNote(noteID uint, fileHeader *multipart.FileHeader, userID uint) (*model.File, error) {
+	var note model.Note
+	err := s.db.Scopes(AccessibleScope(userID, constants.ResourceNote, true)).Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, common.ErrResourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.Upload(fileHeader, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(file).UpdateColumn("note_id", noteID).Error; err != nil {
+		s.log.Error("Failed to attach file to note", "error", err, "file_id", file.ID, "note_id", noteID)
+		if delErr := s.Delete(file.ID, userID); delErr != nil {
+			s.log.Warn("Failed to clean up unattached file", "error", delErr, "file_id", file.ID)
+		}
+		return nil, fmt.Errorf("%w: database update failed", common.ErrInternalServer)
+	}
+	file.NoteID = &noteID
+	return file, nil
+}
+
+// NoteAttachments 返回用户可访问的笔记的附件，按上传时间排列
+func (s *FileService) NoteAttachments(noteID, userID uint) ([]model.File, error) {
+	var note model.Note
+	err := s.db.Scopes(AccessibleScope(userID, constants.ResourceNote, false)).Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, common.ErrResourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []model.File
+	err = s.db.Where("note_id = ?", noteID).Order("created_at ASC, id ASC").Find(&files).Error
+	return files, err
+}
+
+// RemoveUnusedAttachments 删除用户上传到笔记中、且没有被其他记录使用的附件，返回删除的文件。
+// 应在笔记删除之后调用，已删除笔记中的引用不算使用
+func (s *FileService) RemoveUnusedAttachments(noteID, userID uint) ([]model.File, error) {
+	var files []model.File
+	if err := s.db.Where("note_id = ? AND user_id = ?", noteID, userID).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	removed := make([]model.File, 0, len(files))
+	for _, file := range files {
+		used, err := s.inUse(file.ID)
+		if err != nil {
+			return removed, err
+		}
+		if used {
+			continue
+		}
+		if err := s.Delete(file.ID, userID); err != nil {
+			return removed, err
+		}
+		removed = append(removed, file)
+	}
+	return removed, nil
+}
+
+// inUse 判断文件是否仍被使用：存在有效的分享，或者笔记、文章正文中链接了该文件
+func (s *FileService) inUse(fileID uint) (bool, error) {
+	var shares int64
+	if err := s.db.Model(&model.Share{}).Where("resource_type = ? AND resource_id = ?", constants.ResourceFile, fileID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Count(&shares).Error; err != nil {
+		return false, err
+	}
+	if shares > 0 {
+		return true, nil
+	}
+
+	// LIKE 只做初筛(/files/1 也会匹配 /files/12)，再按链接中的ID精确判断
+	like := "%/api/v1/files/%" + strconv.FormatUint(uint64(fileID), 10) + "%"
+	for _, m := range []interface{}{&model.Note{}, &model.Post{}} {
+		var contents []string
+		if err := s.db.Model(m).Where("content LIKE ?", like).Pluck("content", &contents).Error; err != nil {
+			return false, err
+		}
+		for _, content := range contents {
+			if linksFile(content, fileID) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// linksFile 判断内容中是否有指向 fileID 的文件链接
+func linksFile(content string, fileID uint) bool {
+	for _, m := range fileLinkPattern.FindAllStringSubmatch(content, -1) {
+		if id, err := strconv.ParseUint(m[2], 10, 32); err == nil && uint(id) == fileID {
+			return true
 		}
 	}
+	return false
 }
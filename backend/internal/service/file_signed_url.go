@@ -0,0 +1,86 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/model"
+)
+
+// signedURLPeriod 签名链接的有效期按小时对齐，同一小时内生成的链接相同，浏览器可以缓存；
+// 链接在生成后1到2小时内有效
+const signedURLPeriod = time.Hour
+
+// contentLinkRe 匹配渲染后HTML中指向 FileContentURL 的 src 和 href 属性
+var contentLinkRe = regexp.MustCompile(`(src|href)="/api/v1/files/(\d+)/content"`)
+
+// contentSignature 计算文件内容链接的签名，密钥取自JWT密钥
+func contentSignature(id uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWT.Secret))
+	mac.Write([]byte("file-content:" + strconv.FormatUint(uint64(id), 10) + ":" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignedContentURL 生成带签名、会过期的文件内容链接。<img src> 等无法携带 Authorization 头的请求
+// 通过它访问附件，持有链接即可读取文件
+func SignedContentURL(id uint, now time.Time) string {
+	expires := now.Truncate(signedURLPeriod).Add(2 * signedURLPeriod).Unix()
+	return FileContentURL(id) + "?expires=" + strconv.FormatInt(expires, 10) + "&signature=" + contentSignature(id, expires)
+}
+
+// GetBySignature 校验签名链接的 expires 和 signature 参数并返回文件，签名无效或已过期时返回 common.ErrResourceNotFound
+func (s *FileService) GetBySignature(id uint, expires, signature string) (*model.File, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp ||
+		!hmac.Equal([]byte(signature), []byte(contentSignature(id, exp))) {
+		return nil, common.ErrResourceNotFound
+	}
+	var file model.File
+	if err := s.db.First(&file, id).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// SignContentLinks 把渲染后HTML中的文件内容链接替换为签名链接，只替换 readable 返回 true 的文件，
+// 其余链接保持不变，仍需认证才能访问
+func (s *FileService) SignContentLinks(html string, readable func(file *model.File) bool) string {
+	matches := contentLinkRe.FindAllStringSubmatch(html, -1)
+	if len(matches) == 0 {
+		return html
+	}
+	ids := make([]uint64, 0, len(matches))
+	for _, m := range matches {
+		if id, err := strconv.ParseUint(m[2], 10, 32); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	var files []model.File
+	if err := s.db.Where("id IN ?", ids).Find(&files).Error; err != nil {
+		s.log.Error("Failed to load linked files", "error", err)
+		return html
+	}
+	signed := make(map[string]string, len(files))
+	now := time.Now()
+	for i := range files {
+		if readable(&files[i]) {
+			signed[strconv.FormatUint(uint64(files[i].ID), 10)] = SignedContentURL(files[i].ID, now)
+		}
+	}
+	return contentLinkRe.ReplaceAllStringFunc(html, func(attr string) string {
+		m := contentLinkRe.FindStringSubmatch(attr)
+		url, ok := signed[m[2]]
+		if !ok {
+			return attr
+		}
+		// 属性值中的 & 需要转义
+		return m[1] + `="` + strings.ReplaceAll(url, "&", "&amp;") + `"`
+	})
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// 检查结果状态
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // 非关键依赖异常，仍可对外服务
+	HealthFail     = "fail"
+)
+
+// errDiskFreeUnsupported 当前平台无法获取磁盘剩余空间
+var errDiskFreeUnsupported = errors.New("disk free space not supported")
+
+// HealthCheckResult 单项依赖检查结果
+type HealthCheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"` // 关键依赖失败时整体未就绪
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// HealthReport 就绪检查报告
+type HealthReport struct {
+	Status    string              `json:"status"`
+	Checks    []HealthCheckResult `json:"checks"`
+	CheckedAt time.Time           `json:"checked_at"`
+}
+
+// Ready 是否可以接收流量
+func (r *HealthReport) Ready() bool {
+	return r.Status != HealthFail
+}
+
+// HealthCheckStatus 对外公开的单项检查状态
+type HealthCheckStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// HealthSummary 对外公开的就绪检查结果，不包含依赖的版本、连接数和错误信息等细节
+type HealthSummary struct {
+	Status    string              `json:"status"`
+	Checks    []HealthCheckStatus `json:"checks"`
+	CheckedAt time.Time           `json:"checked_at"`
+}
+
+// Summary 返回只包含整体状态和各项检查名称与状态的摘要，用于未认证的探针接口
+func (r *HealthReport) Summary() HealthSummary {
+	summary := HealthSummary{Status: r.Status, Checks: make([]HealthCheckStatus, len(r.Checks)), CheckedAt: r.CheckedAt}
+	for i, c := range r.Checks {
+		summary.Checks[i] = HealthCheckStatus{Name: c.Name, Status: c.Status}
+	}
+	return summary
+}
+
+// healthCheck 依赖检查，返回的 detail 写入报告；critical 为false的检查失败只会使状态降级
+type healthCheck struct {
+	name     string
+	critical bool
+	run      func(ctx context.Context) (detail string, err error)
+}
+
+// HealthService 依赖健康检查
+type HealthService struct {
+	db      *gorm.DB
+	storage CloudStorageProvider
+	log     logger.Logger
+	checks  []healthCheck
+
+	mu     sync.Mutex    // 同一时间只执行一轮检查，并发请求等待并共用结果
+	cached *HealthReport // 最近一次检查结果，Health.CacheSeconds 内直接返回
+}
+
+// NewHealthService 创建健康检查服务，storage 为 nil 时检查本地存储目录
+func NewHealthService(db *gorm.DB, storage CloudStorageProvider, log logger.Logger) *HealthService {
+	s := &HealthService{db: db, storage: storage, log: log}
+	s.checks = []healthCheck{
+		{name: "database", critical: true, run: s.checkDatabase},
+		{name: "storage", critical: true, run: s.checkStorage},
+		{name: "disk", critical: true, run: s.checkDisk},
+		{name: "code_toolchains", critical: false, run: checkToolchains},
+	}
+	return s
+}
+
+// Readiness 返回依赖检查结果。Health.CacheSeconds 内的重复请求直接返回上次的结果，
+// 避免频繁探测对云存储产生写入费用
+func (s *HealthService) Readiness(ctx context.Context) *HealthReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ttl := time.Duration(config.AppConfig.Health.CacheSeconds) * time.Second
+	if s.cached != nil && time.Since(s.cached.CheckedAt) < ttl {
+		return s.cached
+	}
+	s.cached = s.runChecks(ctx)
+	return s.cached
+}
+
+// runChecks 并发执行所有依赖检查，每项检查受 Health.CheckTimeoutSeconds 限制
+func (s *HealthService) runChecks(ctx context.Context) *HealthReport {
+	timeout := time.Duration(config.AppConfig.Health.CheckTimeoutSeconds) * time.Second
+	report := &HealthReport{
+		Status:    HealthOK,
+		Checks:    make([]HealthCheckResult, len(s.checks)),
+		CheckedAt: time.Now(),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = runHealthCheck(ctx, check, timeout)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status == HealthOK {
+			continue
+		}
+		if r.Critical {
+			report.Status = HealthFail
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+		s.log.Warn("Health check failed", "check", r.Name, "error", r.Error)
+	}
+	return report
+}
+
+// runHealthCheck 在超时内执行单项检查；检查函数未响应ctx取消时也会按超时返回
+func runHealthCheck(ctx context.Context, check healthCheck, timeout time.Duration) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		detail, err := check.run(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	result := HealthCheckResult{Name: check.name, Critical: check.critical, Status: HealthOK}
+	select {
+	case o := <-done:
+		result.Detail = o.detail
+		if o.err != nil {
+			result.Error = o.err.Error()
+		}
+	case <-ctx.Done():
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+	if result.Error != "" {
+		result.Status = HealthFail
+		if !check.critical {
+			result.Status = HealthDegraded
+		}
+	}
+	return result
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) (string, error) {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return "", err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return "", err
+	}
+	stats := sqlDB.Stats()
+	return fmt.Sprintf("%s, %d open / %d in use connections", s.db.Dialector.Name(), stats.OpenConnections, stats.InUse), nil
+}
+
+// checkStorage 写入、读回并删除一个探测文件
+func (s *HealthService) checkStorage(ctx context.Context) (string, error) {
+	probe := make([]byte, 16)
+	rand.Read(probe)
+	name := ".healthcheck-" + hex.EncodeToString(probe[:6])
+
+	if s.storage != nil {
+		key := "healthchecks/" + name
+		if err := s.storage.Upload(ctx, key, probe, "application/octet-stream"); err != nil {
+			return "", fmt.Errorf("write probe: %w", err)
+		}
+		defer s.storage.Delete(context.Background(), key)
+		got, err := s.storage.Download(ctx, key)
+		if err != nil {
+			return "", fmt.Errorf("read probe: %w", err)
+		}
+		if !bytes.Equal(got, probe) {
+			return "", errors.New("probe content mismatch")
+		}
+		return "cloud bucket read/write ok", nil
+	}
+
+	dir := config.AppConfig.Storage.Path
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, probe, 0600); err != nil {
+		return "", fmt.Errorf("write probe: %w", err)
+	}
+	defer os.Remove(path)
+	got, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read probe: %w", err)
+	}
+	if !bytes.Equal(got, probe) {
+		return "", errors.New("probe content mismatch")
+	}
+	return "local directory read/write ok", nil
+}
+
+// checkDisk 检查本地存储目录所在磁盘的剩余空间
+func (s *HealthService) checkDisk(context.Context) (string, error) {
+	free, err := diskFree(config.AppConfig.Storage.Path)
+	if errors.Is(err, errDiskFreeUnsupported) {
+		return "free space check not supported on this platform", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	freeMB := int64(free / (1024 * 1024))
+	detail := fmt.Sprintf("%d MB free", freeMB)
+	if minFree := config.AppConfig.Health.MinFreeDiskMB; freeMB < minFree {
+		return detail, fmt.Errorf("only %d MB free, minimum is %d MB", freeMB, minFree)
+	}
+	return detail, nil
+}
+
+// checkToolchains 检查 Code Arena 需要的编译器/解释器是否可用
+func checkToolchains(context.Context) (string, error) {
+	var missing, found []string
+	for lang, cmd := range constants.CodeToolchains {
+		if _, err := exec.LookPath(cmd); err != nil {
+			missing = append(missing, lang+" ("+cmd+")")
+		} else {
+			found = append(found, lang)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(found)
+	detail := "available: " + strings.Join(found, ", ")
+	if len(missing) > 0 {
+		return detail, fmt.Errorf("missing toolchains: %s", strings.Join(missing, ", "))
+	}
+	return detail, nil
+}
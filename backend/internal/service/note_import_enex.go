@@ -0,0 +1,624 @@
+package service
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+
+	"golang.org/x/net/html"
+)
+
+// enexTimeLayout Evernote 导出中的时间格式(UTC)
+const enexTimeLayout = "20060102T150405Z"
+
+// enexNote Evernote 导出(.enex)中的一篇笔记
+type enexNote struct {
+	Title     string
+	Content   string
+	Created   string
+	Updated   string
+	Tags      []string
+	Resources []enexResource
+}
+
+// enexResource 笔记中的附件，正文中的 <en-media hash="..."> 通过内容的MD5引用。
+// 内容在解析时解码到临时文件，超过上传大小上限的附件只记录大小
+type enexResource struct {
+	Mime     string
+	FileName string
+	Path     string // 临时文件，附件过大或无效时为空
+	Size     int64
+	Hash     string
+	Invalid  bool
+}
+
+// parseENEX 解析 Evernote 导出的笔记本，ENML 正文转换为 Markdown，附件按MD5与正文中的引用对应
+func parseENEX(r io.Reader, name string, folder []string, source *noteSource) error {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	found := false
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return enexError(err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		en, err := decodeENEXNote(d, source)
+		if err != nil {
+			return err
+		}
+		found = true
+		source.notes = append(source.notes, convertENEXNote(en, name, folder, source))
+	}
+	if !found {
+		return fmt.Errorf("%w: no Evernote notes found", common.ErrInvalidArchive)
+	}
+	return nil
+}
+
+// enexError 保留超出导入限制的错误，其他解析错误包装为 ErrInvalidArchive
+func enexError(err error) error {
+	if errors.Is(err, errImportLimit) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+}
+
+// decodeENEXNote 逐个读取 <note> 中的元素直到 </note>。附件的 base64 内容边读边解码写入临时文件，
+// 不在内存中保留
+func decodeENEXNote(d *xml.Decoder, source *noteSource) (*enexNote, error) {
+	en := &enexNote{}
+	var stack []string
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, enexError(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "data" && len(stack) == 1 && stack[0] == "resource" && len(en.Resources) > 0 {
+				if err := source.spoolResource(d, &en.Resources[len(en.Resources)-1]); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if t.Name.Local == "resource" && len(stack) == 0 {
+				en.Resources = append(en.Resources, enexResource{})
+			}
+			stack = append(stack, t.Name.Local)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return en, nil
+			}
+			value := text.String()
+			switch strings.Join(stack, ">") {
+			case "title":
+				en.Title = value
+			case "content":
+				if err := source.addText(len(value)); err != nil {
+					return nil, err
+				}
+				en.Content = value
+			case "created":
+				en.Created = value
+			case "updated":
+				en.Updated = value
+			case "tag":
+				en.Tags = append(en.Tags, value)
+			case "resource>mime":
+				en.Resources[len(en.Resources)-1].Mime = strings.TrimSpace(value)
+			case "resource>resource-attributes>file-name":
+				en.Resources[len(en.Resources)-1].FileName = value
+			}
+			stack = stack[:len(stack)-1]
+			text.Reset()
+		}
+	}
+}
+
+// spoolResource 读取 <data> 直到 </data>，解码后写入临时目录并计算MD5。
+// 超过上传大小上限的附件只计算MD5和大小，不写入
+func (s *noteSource) spoolResource(d *xml.Decoder, res *enexResource) error {
+	if s.spoolDir == "" {
+		dir, err := os.MkdirTemp("", "nexushub-import-")
+		if err != nil {
+			return err
+		}
+		s.spoolDir = dir
+	}
+	f, err := os.CreateTemp(s.spoolDir, "resource-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := md5.New()
+	out := &spoolWriter{f: f, limit: config.AppConfig.Storage.MaxUploadSize}
+	sink := &base64Sink{w: io.MultiWriter(hash, out)}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return enexError(err)
+		}
+		if data, ok := tok.(xml.CharData); ok {
+			if err := sink.Write(data); err != nil {
+				res.Invalid = true
+			}
+			continue
+		}
+		if _, ok := tok.(xml.EndElement); ok {
+			break
+		}
+	}
+	if err := sink.Close(); err != nil || out.err != nil {
+		res.Invalid = true
+	}
+
+	res.Size, res.Hash = out.size, hex.EncodeToString(hash.Sum(nil))
+	if s.spoolSize += min(out.size, out.limit); s.spoolSize > maxImportTotalSize {
+		return fmt.Errorf("%w: attachments expand to more than %d GB", errImportLimit, maxImportTotalSize>>30)
+	}
+	if res.Invalid || out.size > out.limit {
+		os.Remove(f.Name())
+		return nil
+	}
+	res.Path = f.Name()
+	return nil
+}
+
+// spoolWriter 写入不超过 limit 字节，超出部分只计数
+type spoolWriter struct {
+	f     *os.File
+	limit int64
+	size  int64
+	err   error
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.size; room > 0 && w.err == nil {
+		_, w.err = w.f.Write(p[:min(int64(len(p)), room)])
+	}
+	w.size += int64(len(p))
+	return len(p), nil
+}
+
+// base64Sink 分块解码 base64 文本，忽略其中的空白
+type base64Sink struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (b *base64Sink) Write(data []byte) error {
+	for _, c := range data {
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			b.pending = append(b.pending, c)
+		}
+	}
+	n := len(b.pending) / 4 * 4
+	if n < 64<<10 {
+		return nil
+	}
+	return b.flush(n)
+}
+
+func (b *base64Sink) Close() error {
+	return b.flush(len(b.pending))
+}
+
+func (b *base64Sink) flush(n int) error {
+	buf := make([]byte, base64.StdEncoding.DecodedLen(n))
+	written, err := base64.StdEncoding.Decode(buf, b.pending[:n])
+	if err != nil {
+		return err
+	}
+	b.pending = append(b.pending[:0], b.pending[n:]...)
+	_, err = b.w.Write(buf[:written])
+	return err
+}
+
+func convertENEXNote(en *enexNote, name string, folder []string, source *noteSource) *importedNote {
+	note := &importedNote{
+		Source: name + ": " + strings.TrimSpace(en.Title),
+		Title:  strings.TrimSpace(en.Title),
+		Tags:   en.Tags,
+		Folder: folder,
+	}
+	if t, err := time.Parse(enexTimeLayout, strings.TrimSpace(en.Created)); err == nil {
+		note.CreatedAt, note.UpdatedAt = t, t
+	}
+	if t, err := time.Parse(enexTimeLayout, strings.TrimSpace(en.Updated)); err == nil {
+		note.UpdatedAt = t
+	}
+
+	resources := make(map[string]*importedAttachment)
+	for i, res := range en.Resources {
+		if res.Invalid {
+			source.warnf("%s: attachment %d of %q is not valid base64, skipped", name, i+1, note.Title)
+			continue
+		}
+		filename := strings.TrimSpace(res.FileName)
+		if filename == "" {
+			filename = "attachment-" + strconv.Itoa(i+1)
+			if exts, _ := mime.ExtensionsByType(res.Mime); len(exts) > 0 {
+				filename += exts[0]
+			}
+		}
+		spooled := res.Path
+		resources[res.Hash] = &importedAttachment{
+			Name:     filename,
+			MimeType: res.Mime,
+			Size:     res.Size,
+			open:     func() (io.ReadCloser, error) { return os.Open(spooled) },
+		}
+	}
+
+	used := make(map[string]string) // MD5 -> 占位符
+	w := &enmlWriter{
+		media: func(hash, mimeType string) string {
+			hash = strings.ToLower(hash)
+			att, ok := resources[hash]
+			if !ok {
+				source.warnf("%s: %q references a missing attachment", name, note.Title)
+				return ""
+			}
+			placeholder, ok := used[hash]
+			if !ok {
+				placeholder = source.addAttachment(note, att)
+				used[hash] = placeholder
+			}
+			if strings.HasPrefix(mimeType, "image/") {
+				return "![" + escapeMarkdown(att.Name) + "](" + placeholder + ")"
+			}
+			return "[" + escapeMarkdown(att.Name) + "](" + placeholder + ")"
+		},
+		warn: func(msg string) { source.warnf("%s: %q %s", name, note.Title, msg) },
+	}
+	doc, err := html.Parse(strings.NewReader(en.Content))
+	if err != nil {
+		source.warnf("%s: cannot parse the content of %q", name, note.Title)
+		return note
+	}
+	w.walk(doc)
+	w.flush()
+	note.Content = strings.Join(w.blocks, "\n\n")
+
+	// 正文中没有引用的附件附加在末尾，避免丢失
+	for _, hash := range sortedKeys(resources) {
+		if att := resources[hash]; used[hash] == "" {
+			used[hash] = source.addAttachment(note, att)
+			note.Content += "\n\n[" + escapeMarkdown(att.Name) + "](" + used[hash] + ")"
+		}
+	}
+	return note
+}
+
+// enmlWriter 把 ENML(Evernote 的 XHTML 子集)转换为 Markdown 块
+type enmlWriter struct {
+	blocks []string
+	inline strings.Builder
+	media  func(hash, mimeType string) string
+	warn   func(msg string)
+}
+
+// flush 结束当前段落
+func (w *enmlWriter) flush() {
+	lines := strings.Split(w.inline.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	if text := strings.TrimSpace(strings.Join(lines, "  \n")); text != "" {
+		w.blocks = append(w.blocks, text)
+	}
+	w.inline.Reset()
+}
+
+// child 用同样的设置转换 n 的子节点，返回生成的块
+func (w *enmlWriter) child(n *html.Node) []string {
+	sub := &enmlWriter{media: w.media, warn: w.warn}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sub.walk(c)
+	}
+	sub.flush()
+	return sub.blocks
+}
+
+// inlineText 把 n 的内容转换为单行文本
+func (w *enmlWriter) inlineText(n *html.Node) string {
+	return strings.Join(strings.Fields(strings.Join(w.child(n), " ")), " ")
+}
+
+func (w *enmlWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.inline.WriteString(collapseSpace(escapeMarkdown(n.Data)))
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.walk(c)
+		}
+		return
+	}
+
+	switch tag := n.Data; tag {
+	case "head", "script", "style", "title":
+	case "en-crypt":
+		w.warn("contains encrypted content, which was skipped")
+	case "br":
+		w.inline.WriteString("\n")
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.flush()
+		if text := w.inlineText(n); text != "" {
+			w.blocks = append(w.blocks, strings.Repeat("#", int(tag[1]-'0'))+" "+text)
+		}
+	case "hr":
+		w.flush()
+		w.blocks = append(w.blocks, "---")
+	case "pre":
+		w.flush()
+		w.blocks = append(w.blocks, codeFence(rawText(n)))
+	case "div", "p", "section", "center", "en-note", "body":
+		if strings.Contains(attr(n, "style"), "-en-codeblock") {
+			w.flush()
+			w.blocks = append(w.blocks, codeFence(rawText(n)))
+			return
+		}
+		w.flush()
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.walk(c)
+		}
+		w.flush()
+	case "blockquote":
+		w.flush()
+		var lines []string
+		for _, line := range strings.Split(strings.Join(w.child(n), "\n\n"), "\n") {
+			lines = append(lines, strings.TrimRight("> "+line, " "))
+		}
+		w.blocks = append(w.blocks, strings.Join(lines, "\n"))
+	case "ul", "ol":
+		w.flush()
+		if list := w.list(n, ""); list != "" {
+			w.blocks = append(w.blocks, list)
+		}
+	case "table":
+		w.flush()
+		if table := w.table(n); table != "" {
+			w.blocks = append(w.blocks, table)
+		}
+	case "en-todo", "en-media":
+		if tag == "en-media" {
+			w.inline.WriteString(w.media(attr(n, "hash"), attr(n, "type")))
+		} else if attr(n, "checked") == "true" {
+			w.inline.WriteString("- [x] ")
+		} else {
+			w.inline.WriteString("- [ ] ")
+		}
+		// HTML 解析器不认识自闭合的 <en-todo/>，其后的内容会成为它的子节点
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.walk(c)
+		}
+	case "img":
+		if src := attr(n, "src"); strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			w.inline.WriteString("![" + escapeMarkdown(attr(n, "alt")) + "](" + src + ")")
+		}
+	case "a":
+		text, href := w.inlineText(n), attr(n, "href")
+		switch {
+		case href == "" || strings.HasPrefix(href, "evernote:"):
+			w.inline.WriteString(text)
+		case text == "":
+			w.inline.WriteString("<" + href + ">")
+		default:
+			w.inline.WriteString("[" + text + "](" + href + ")")
+		}
+	case "b", "strong":
+		w.wrap(n, "**")
+	case "i", "em":
+		w.wrap(n, "*")
+	case "s", "strike", "del":
+		w.wrap(n, "~~")
+	case "code":
+		if text := rawText(n); text != "" {
+			w.inline.WriteString("`" + text + "`")
+		}
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.walk(c)
+		}
+	}
+}
+
+// wrap 用强调标记包围 n 的内容，标记放在首尾空白之外
+func (w *enmlWriter) wrap(n *html.Node, mark string) {
+	text := w.inlineText(n)
+	if text == "" {
+		return
+	}
+	raw := rawText(n)
+	if strings.TrimLeft(raw, " \t\n") != raw {
+		w.inline.WriteString(" ")
+	}
+	w.inline.WriteString(mark + text + mark)
+	if strings.TrimRight(raw, " \t\n") != raw {
+		w.inline.WriteString(" ")
+	}
+}
+
+// list 转换列表，嵌套列表按层级缩进；新版 Evernote 的清单为带 --en-todo 样式的 ul
+func (w *enmlWriter) list(n *html.Node, indent string) string {
+	todo := strings.Contains(attr(n, "style"), "--en-todo:true")
+	var lines []string
+	index := 0
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		index++
+		marker := "- "
+		switch {
+		case n.Data == "ol":
+			marker = strconv.Itoa(index) + ". "
+		case todo && strings.Contains(attr(li, "style"), "--en-checked:true"):
+			marker = "- [x] "
+		case todo:
+			marker = "- [ ] "
+		}
+
+		// 先取出嵌套列表，剩余内容作为列表项的文字
+		var nested []*html.Node
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "ul" || c.Data == "ol") {
+				nested = append(nested, c)
+			}
+		}
+		for _, c := range nested {
+			li.RemoveChild(c)
+		}
+		lines = append(lines, indent+marker+w.inlineText(li))
+		for _, c := range nested {
+			if sub := w.list(c, indent+strings.Repeat(" ", len(marker))); sub != "" {
+				lines = append(lines, sub)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// table 转换为 GFM 表格，第一行作为表头
+func (w *enmlWriter) table(n *html.Node) string {
+	var rows [][]string
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.Data != "tr" {
+				collect(c)
+				continue
+			}
+			var row []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+					row = append(row, strings.ReplaceAll(w.inlineText(cell), "|", "\\|"))
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	collect(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return ""
+	}
+	var lines []string
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", cols))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rawText 返回元素的纯文本，br 和块级元素之间换行，用于代码块
+func rawText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			b.WriteString("\n")
+		default:
+			block := n.Type == html.ElementNode && (n.Data == "div" || n.Data == "p")
+			if block && b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString("\n")
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return strings.Trim(b.String(), "\n")
+}
+
+// codeFence 用足够长的反引号围栏包围代码，代码中已有的围栏不会提前结束代码块
+func codeFence(code string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + code + "\n" + fence
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// collapseSpace 把连续空白合并为一个空格，保留首尾的空白以分隔相邻的行内元素
+func collapseSpace(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	out := strings.Join(fields, " ")
+	if strings.TrimLeft(s, " \t\r\n") != s {
+		out = " " + out
+	}
+	if strings.TrimRight(s, " \t\r\n") != s {
+		out += " "
+	}
+	return out
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+)
+
+// escapeMarkdown 转义普通文本中的 Markdown 标记字符
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,568 @@
+package service
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"nexushub-personal/internal/common"
+)
+
+// 笔记导入的解压限制，防止解压炸弹耗尽内存和磁盘
+const (
+	maxImportNoteSize   = 64 << 20  // 单篇笔记(Markdown 文件或 .enex 文件)的大小上限
+	maxImportTextSize   = 256 << 20 // 同时读入内存的笔记正文总大小上限
+	maxImportTotalSize  = 4 << 30   // 压缩包解压后的总大小上限，附件不读入内存但计入此限制
+	maxImportEntryCount = 20000     // 压缩包中的文件数上限
+)
+
+// importedNote 从外部格式解析出的一篇笔记。Content 中的附件链接为 attachmentPlaceholder 占位符
+type importedNote struct {
+	Source      string
+	Title       string
+	Content     string
+	Tags        []string
+	Folder      []string // 相对导入根目录的文件夹，按选项转为笔记本或标签
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Attachments []int // 引用的附件序号
+}
+
+// importedAttachment 笔记引用的附件
+type importedAttachment struct {
+	Name     string
+	MimeType string
+	Size     int64
+	Link     string // 原文中的链接，附件无法导入时还原
+	open     func() (io.ReadCloser, error)
+
+	rejected bool // 未通过上传检查，不导入
+}
+
+// noteSource 解析结果：全部笔记及其引用的附件。ENEX 中的附件解码到临时目录，导入结束后调用 cleanup 删除
+type noteSource struct {
+	notes       []*importedNote
+	attachments []*importedAttachment
+	warnings    []string
+
+	textSize  int64  // 已读入内存的笔记正文大小
+	spoolSize int64  // 已写入临时目录的附件大小
+	spoolDir  string // 临时目录，第一次写入附件时创建
+}
+
+// addText 累计读入内存的正文大小，超过 maxImportTextSize 时失败
+func (s *noteSource) addText(n int) error {
+	s.textSize += int64(n)
+	if s.textSize > maxImportTextSize {
+		return fmt.Errorf("%w: notes exceed %d MB of text", common.ErrInvalidArchive, maxImportTextSize>>20)
+	}
+	return nil
+}
+
+// cleanup 删除解码附件使用的临时目录
+func (s *noteSource) cleanup() {
+	if s.spoolDir != "" {
+		os.RemoveAll(s.spoolDir)
+	}
+}
+
+func (s *noteSource) warnf(format string, args ...interface{}) {
+	s.warnings = append(s.warnings, fmt.Sprintf(format, args...))
+}
+
+// addAttachment 登记附件，返回正文中使用的占位符
+func (s *noteSource) addAttachment(note *importedNote, att *importedAttachment) string {
+	s.attachments = append(s.attachments, att)
+	index := len(s.attachments) - 1
+	note.Attachments = append(note.Attachments, index)
+	return attachmentPlaceholder(index)
+}
+
+// attachmentPlaceholder 附件在正文中的占位符，导入时替换为文件的内容链接
+func attachmentPlaceholder(index int) string {
+	return "nexushub-attachment:" + strconv.Itoa(index)
+}
+
+var attachmentPlaceholderRe = regexp.MustCompile(`nexushub-attachment:(\d+)`)
+
+// markdownArchive 压缩包中的 Markdown 笔记和其他文件，路径已去掉公共的顶层目录
+type markdownArchive struct {
+	notes  map[string]*zip.File
+	files  map[string]*zip.File
+	byName map[string][]string // 小写文件名 -> 路径，用于解析 Obsidian 的 ![[文件名]]
+	titles map[string]string   // 笔记路径 -> 标题
+
+	attachments map[string]int // 已登记为附件的文件路径 -> 附件序号
+}
+
+// parseMarkdownArchive 解析 Markdown 文件夹或 Obsidian 仓库的ZIP：每个 .md 文件是一篇笔记，
+// 其中的 .enex 文件按 Evernote 导出解析；隐藏目录(.obsidian、.trash 等)被忽略
+func parseMarkdownArchive(r io.ReaderAt, size int64, source *noteSource) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+	}
+	if len(zr.File) > maxImportEntryCount {
+		return fmt.Errorf("%w: archive contains more than %d files", common.ErrInvalidArchive, maxImportEntryCount)
+	}
+
+	var entries []*zip.File
+	var total uint64
+	for _, f := range zr.File {
+		name := path.Clean(strings.ReplaceAll(f.Name, "\\", "/"))
+		if f.FileInfo().IsDir() || hiddenPath(name) || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			continue
+		}
+		// 声明的大小可能不实，读取时另有限制
+		if total += f.UncompressedSize64; total > maxImportTotalSize {
+			return fmt.Errorf("%w: archive expands to more than %d GB", common.ErrInvalidArchive, maxImportTotalSize>>30)
+		}
+		entries = append(entries, f)
+	}
+	root := commonRoot(entries)
+
+	archive := &markdownArchive{
+		notes:  make(map[string]*zip.File),
+		files:  make(map[string]*zip.File),
+		byName: make(map[string][]string),
+		titles: make(map[string]string),
+
+		attachments: make(map[string]int),
+	}
+	for _, f := range entries {
+		name := strings.TrimPrefix(path.Clean(strings.ReplaceAll(f.Name, "\\", "/")), root)
+		switch strings.ToLower(path.Ext(name)) {
+		case ".md", ".markdown", ".enex":
+			archive.notes[name] = f
+		default:
+			archive.files[name] = f
+			lower := strings.ToLower(path.Base(name))
+			archive.byName[lower] = append(archive.byName[lower], name)
+		}
+	}
+	if len(archive.notes) == 0 {
+		return fmt.Errorf("%w: no Markdown or .enex files found", common.ErrInvalidArchive)
+	}
+
+	type pending struct {
+		name string
+		note *importedNote
+		body string
+	}
+	var parsed []pending
+	for _, name := range sortedKeys(archive.notes) {
+		f := archive.notes[name]
+		if f.UncompressedSize64 > maxImportNoteSize {
+			source.warnf("%s is too large, skipped", name)
+			continue
+		}
+		folder := splitFolder(path.Dir(name))
+		if strings.EqualFold(path.Ext(name), ".enex") {
+			// 每个 .enex 文件是一个 Evernote 笔记本，作为同名文件夹处理
+			folder = append(folder, strings.TrimSuffix(path.Base(name), path.Ext(name)))
+			if err := parseZippedENEX(f, name, folder, source); err != nil {
+				if errors.Is(err, errImportLimit) {
+					return err
+				}
+				source.warnf("%s: %v", name, err)
+			}
+			continue
+		}
+
+		data, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+		if err := source.addText(len(data)); err != nil {
+			return err
+		}
+		note, body := parseMarkdownNote(name, string(data), f.Modified)
+		note.Folder = folder
+		archive.titles[name] = note.Title
+		parsed = append(parsed, pending{name, note, body})
+	}
+
+	// 所有标题确定后再改写链接，笔记之间的相对链接需要目标笔记的标题
+	for _, p := range parsed {
+		p.note.Content = archive.rewriteLinks(source, p.note, p.name, p.body)
+		source.notes = append(source.notes, p.note)
+	}
+	return nil
+}
+
+// parseZippedENEX 流式解析压缩包中的 .enex 文件
+func parseZippedENEX(f *zip.File, name string, folder []string, source *noteSource) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+	return parseENEX(&limitedReader{r: rc, n: maxImportNoteSize, name: name}, name, folder, source)
+}
+
+// parseMarkdownNote 解析一篇 Markdown 笔记的 front matter，返回笔记和去掉 front matter 的正文
+func parseMarkdownNote(name, text string, modified time.Time) (*importedNote, string) {
+	text = strings.TrimPrefix(strings.ReplaceAll(text, "\r\n", "\n"), "\ufeff")
+	meta, body := parseFrontMatter(text)
+
+	note := &importedNote{
+		Source:    name,
+		Title:     strings.TrimSuffix(path.Base(name), path.Ext(name)),
+		CreatedAt: modified,
+		UpdatedAt: modified,
+	}
+	if title := firstValue(meta, "title"); title != "" {
+		note.Title = title
+	}
+	for _, key := range []string{"tags", "tag", "keywords"} {
+		for _, tag := range meta[key] {
+			if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+				note.Tags = append(note.Tags, tag)
+			}
+		}
+	}
+	if t, ok := parseImportTime(firstValue(meta, "created", "created_at", "date")); ok {
+		note.CreatedAt, note.UpdatedAt = t, t
+	}
+	if t, ok := parseImportTime(firstValue(meta, "updated", "updated_at", "modified")); ok {
+		note.UpdatedAt = t
+	}
+	return note, body
+}
+
+// parseFrontMatter 拆出开头两个 --- 之间的 YAML 头部。只支持笔记常用的写法：
+// key: value、key: [a, b] 以及其后以 "- " 开头的列表项；标量中逗号分隔的多个值按列表处理
+func parseFrontMatter(text string) (map[string][]string, string) {
+	meta := make(map[string][]string)
+	if !strings.HasPrefix(text, "---\n") {
+		return meta, text
+	}
+	lines := strings.Split(text, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimSpace(lines[i]); line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return meta, text
+	}
+
+	var key string
+	for _, line := range lines[1:end] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") && key != "" {
+			meta[key] = append(meta[key], unquote(strings.TrimSpace(trimmed[2:])))
+			continue
+		}
+		k, v, ok := strings.Cut(trimmed, ":")
+		if !ok || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "":
+			meta[key] = nil
+		case strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]"):
+			for _, item := range strings.Split(v[1:len(v)-1], ",") {
+				if item = unquote(strings.TrimSpace(item)); item != "" {
+					meta[key] = append(meta[key], item)
+				}
+			}
+		case key == "title":
+			meta[key] = []string{unquote(v)}
+		default:
+			for _, item := range strings.Split(unquote(v), ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					meta[key] = append(meta[key], item)
+				}
+			}
+		}
+	}
+	return meta, strings.TrimLeft(strings.Join(lines[end+1:], "\n"), "\n")
+}
+
+func firstValue(meta map[string][]string, keys ...string) string {
+	for _, key := range keys {
+		if values := meta[key]; len(values) > 0 {
+			return strings.Join(values, ", ")
+		}
+	}
+	return ""
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseImportTime 解析 front matter 中常见的日期格式
+func parseImportTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+var (
+	// markdownLinkRe 行内链接和图片：[文字](目标 "标题")，目标可以用 <> 包围
+	markdownLinkRe = regexp.MustCompile(`(!?)\[([^\]\n]*)\]\((<[^>\n]+>|[^)\s]+)((?:\s+"[^"\n]*")?)\)`)
+	// obsidianEmbedRe Obsidian 的嵌入和 wiki 链接：![[文件]]、[[文件|显示文字]]
+	obsidianEmbedRe = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+?)\]\]`)
+)
+
+// rewriteLinks 改写笔记中的相对链接：指向压缩包内其他文件的变为附件占位符，
+// 指向其他笔记的变为 [[标题]]；代码块中的内容不变
+func (a *markdownArchive) rewriteLinks(source *noteSource, note *importedNote, name, body string) string {
+	dir := path.Dir(name)
+	attach := func(target, link string) string {
+		// 多篇笔记引用的同一文件只上传一次，作为第一篇笔记的附件
+		if index, ok := a.attachments[target]; ok {
+			if !slices.Contains(note.Attachments, index) {
+				note.Attachments = append(note.Attachments, index)
+			}
+			return attachmentPlaceholder(index)
+		}
+		f := a.files[target]
+		placeholder := source.addAttachment(note, &importedAttachment{
+			Name: path.Base(target),
+			Size: int64(f.UncompressedSize64),
+			Link: link,
+			open: func() (io.ReadCloser, error) { return f.Open() },
+		})
+		a.attachments[target] = len(source.attachments) - 1
+		return placeholder
+	}
+
+	return outsideCode(body, func(text string) string {
+		text = markdownLinkRe.ReplaceAllStringFunc(text, func(m string) string {
+			g := markdownLinkRe.FindStringSubmatch(m)
+			bang, label, target, title := g[1], g[2], strings.Trim(g[3], "<>"), g[4]
+			resolved, ok := a.resolve(dir, target)
+			if !ok {
+				return m
+			}
+			if _, isNote := a.notes[resolved]; isNote {
+				return wikiLink(a.titles[resolved], label)
+			}
+			return bang + "[" + label + "](" + attach(resolved, target) + title + ")"
+		})
+		return obsidianEmbedRe.ReplaceAllStringFunc(text, func(m string) string {
+			g := obsidianEmbedRe.FindStringSubmatch(m)
+			bang := g[1]
+			target, alias, _ := strings.Cut(g[2], "|")
+			target = strings.TrimSpace(target)
+			ext := strings.ToLower(path.Ext(target))
+			if ext == "" || ext == ".md" || ext == ".markdown" {
+				// 笔记之间的 wiki 链接保持不变，笔记嵌入转为链接
+				if resolved, ok := a.resolve(dir, target); ok {
+					if _, isNote := a.notes[resolved]; isNote {
+						return wikiLink(a.titles[resolved], alias)
+					}
+				}
+				return strings.TrimPrefix(m, "!")
+			}
+			resolved, ok := a.resolve(dir, target)
+			if !ok {
+				source.warnf("%s: attachment %s not found in the archive", name, target)
+				return m
+			}
+			// ![[图片|300]] 中的数字是显示宽度，不作为文字
+			if _, err := strconv.Atoi(alias); err == nil || alias == "" {
+				alias = path.Base(target)
+			}
+			return bang + "[" + alias + "](" + attach(resolved, target) + ")"
+		})
+	})
+}
+
+// resolve 把链接目标解析为压缩包中的路径：先相对笔记所在目录，再相对根目录，
+// 最后按文件名查找(Obsidian 的默认行为)；外部链接和找不到的文件返回false
+func (a *markdownArchive) resolve(dir, target string) (string, bool) {
+	if target == "" || strings.HasPrefix(target, "#") || strings.Contains(target, "://") ||
+		strings.HasPrefix(target, "mailto:") || strings.HasPrefix(target, "/") {
+		return "", false
+	}
+	target, _, _ = strings.Cut(target, "#")
+	if decoded, err := url.PathUnescape(target); err == nil {
+		target = decoded
+	}
+	candidates := []string{path.Join(dir, target), path.Clean(target)}
+	if path.Ext(target) == "" {
+		candidates = append(candidates, path.Join(dir, target)+".md", path.Clean(target)+".md")
+	}
+	for _, c := range candidates {
+		if _, ok := a.files[c]; ok {
+			return c, true
+		}
+		if _, ok := a.notes[c]; ok {
+			return c, true
+		}
+	}
+	base := strings.ToLower(path.Base(target))
+	if matches := a.byName[base]; len(matches) == 1 {
+		return matches[0], true
+	}
+	if path.Ext(base) == "" {
+		base += ".md"
+	}
+	for name := range a.notes {
+		if strings.ToLower(path.Base(name)) == base {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// wikiLink 生成指向标题的 wiki 链接，显示文字与标题不同时保留显示文字
+func wikiLink(title, label string) string {
+	if label == "" || label == title {
+		return "[[" + title + "]]"
+	}
+	return "[[" + title + "|" + label + "]]"
+}
+
+// outsideCode 对代码块和行内代码以外的文本应用 fn
+func outsideCode(body string, fn func(string) string) string {
+	var out, chunk strings.Builder
+	flush := func() {
+		out.WriteString(rewriteOutsideInlineCode(chunk.String(), fn))
+		chunk.Reset()
+	}
+	fence := ""
+	for _, line := range strings.SplitAfter(body, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+			flush()
+			fence = trimmed[:3]
+			out.WriteString(line)
+			continue
+		}
+		if fence != "" {
+			out.WriteString(line)
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		chunk.WriteString(line)
+	}
+	flush()
+	return out.String()
+}
+
+func rewriteOutsideInlineCode(text string, fn func(string) string) string {
+	parts := strings.Split(text, "`")
+	for i := 0; i < len(parts); i += 2 {
+		// 奇数个反引号时最后一段不是行内代码
+		parts[i] = fn(parts[i])
+	}
+	return strings.Join(parts, "`")
+}
+
+// commonRoot 所有文件都位于同一个顶层目录下时(压缩整个文件夹的常见情况)返回该目录，导入时去掉
+func commonRoot(entries []*zip.File) string {
+	root := ""
+	for _, f := range entries {
+		name := path.Clean(strings.ReplaceAll(f.Name, "\\", "/"))
+		first, _, nested := strings.Cut(name, "/")
+		if !nested || (root != "" && first != root) {
+			return ""
+		}
+		root = first
+	}
+	if root == "" {
+		return ""
+	}
+	return root + "/"
+}
+
+// hiddenPath 路径中有以 . 开头的部分(.obsidian、.trash、.git)或为 macOS 的资源目录
+func hiddenPath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+func splitFolder(dir string) []string {
+	if dir == "." || dir == "" {
+		return nil
+	}
+	return strings.Split(dir, "/")
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(&limitedReader{r: rc, n: maxImportNoteSize, name: f.Name})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", common.ErrInvalidArchive, f.Name, err)
+	}
+	if !utf8.Valid(data) {
+		data = []byte(strings.ToValidUTF8(string(data), "\ufffd"))
+	}
+	return data, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// errImportLimit 超出导入限制，整个导入失败而不是跳过单个文件
+var errImportLimit = fmt.Errorf("%w: import size limit exceeded", common.ErrInvalidArchive)
+
+// limitedReader 读取超过 n 字节时返回 errImportLimit，
+// 与 io.LimitReader 不同，不会把超出部分静默截断(压缩包中声明的大小可能不实)
+type limitedReader struct {
+	r    io.Reader
+	n    int64
+	name string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, fmt.Errorf("%w: %s is larger than %d MB", errImportLimit, l.name, maxImportNoteSize>>20)
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, fmt.Errorf("%w: %s is larger than %d MB", errImportLimit, l.name, maxImportNoteSize>>20)
+	}
+	return n, err
+}
@@ -0,0 +1,464 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"path"
+	"strconv"
+	"strings"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/utils"
+	"nexushub-personal/internal/validator"
+
+	"gorm.io/gorm"
+)
+
+// 笔记导入的来源格式
+const (
+	NoteImportMarkdown = "markdown" // Markdown 文件夹或 Obsidian 仓库的ZIP
+	NoteImportENEX     = "enex"     // Evernote 导出的 .enex 文件
+)
+
+// 文件夹的导入方式
+const (
+	FoldersAsNotebooks = "notebooks" // 文件夹转为嵌套的笔记本
+	FoldersAsTags      = "tags"      // 文件夹路径作为标签
+)
+
+// 单篇笔记的导入操作
+const (
+	NoteImportCreate    = "create"
+	NoteImportOverwrite = "overwrite"
+	NoteImportSkip      = "skip"
+)
+
+// NoteImportOptions 笔记导入选项
+type NoteImportOptions struct {
+	Format     string // 为空时按文件扩展名判断
+	Folders    string // FoldersAsNotebooks(默认) 或 FoldersAsTags
+	Conflict   string // 与已有笔记标题相同时的处理：ConflictSkip(默认)、ConflictOverwrite、ConflictDuplicate
+	NotebookID *uint  // 导入到该笔记本下，为空时导入到顶层
+	DryRun     bool   // 只生成报告，不写入任何数据
+}
+
+// NoteImportReport 笔记导入报告，试运行时描述将要执行的操作
+type NoteImportReport struct {
+	DryRun      bool             `json:"dry_run"`
+	Format      string           `json:"format"`
+	Notes       []NoteImportItem `json:"notes"`
+	Notebooks   []string         `json:"notebooks"`   // 需要新建的笔记本路径
+	Attachments int              `json:"attachments"` // 上传为文件的附件数
+	Created     int              `json:"created"`
+	Overwritten int              `json:"overwritten"`
+	Skipped     int              `json:"skipped"`
+	Warnings    []string         `json:"warnings"`
+}
+
+// NoteImportItem 报告中的一篇笔记
+type NoteImportItem struct {
+	Source      string `json:"source"`
+	Title       string `json:"title"`
+	Notebook    string `json:"notebook,omitempty"` // 笔记本路径，以 / 分隔
+	Tags        string `json:"tags,omitempty"`
+	Action      string `json:"action"`                 // NoteImportCreate、NoteImportOverwrite 或 NoteImportSkip
+	DuplicateOf uint   `json:"duplicate_of,omitempty"` // 标题相同的已有笔记
+	NoteID      uint   `json:"note_id,omitempty"`      // 导入后的笔记ID，试运行时为空
+	Attachments int    `json:"attachments,omitempty"`
+}
+
+type NoteImportService struct {
+	db    *gorm.DB
+	files *FileService
+	log   logger.Logger
+}
+
+func NewNoteImportService(db *gorm.DB, files *FileService, log logger.Logger) *NoteImportService {
+	return &NoteImportService{db: db, files: files, log: log}
+}
+
+// WithLogger 返回使用 log 记录日志的副本(包括上传附件使用的文件服务)，
+// handler 传入请求日志使服务中的日志带上请求ID
+func (s *NoteImportService) WithLogger(log logger.Logger) *NoteImportService {
+	return &NoteImportService{db: s.db, files: s.files.WithLogger(log), log: log}
+}
+
+// Import 从 Markdown 压缩包或 Evernote 导出导入笔记到 userID 名下。
+// 文件夹按选项转为笔记本或标签，引用的图片和文件上传为笔记附件，标题与已有笔记相同(忽略大小写)时按 Conflict 处理。
+// 整个导入在一个事务中完成，失败时已写入的附件内容会被清理
+func (s *NoteImportService) Import(userID uint, filename string, r io.ReaderAt, size int64, opts NoteImportOptions) (*NoteImportReport, error) {
+	if err := normalizeNoteImportOptions(&opts, filename); err != nil {
+		return nil, err
+	}
+	if err := checkNotebook(s.db, userID, opts.NotebookID); err != nil {
+		return nil, err
+	}
+
+	source := &noteSource{}
+	defer source.cleanup()
+	var err error
+	if opts.Format == NoteImportENEX {
+		name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+		folder := []string{strings.TrimSuffix(name, path.Ext(name))}
+		err = parseENEX(io.NewSectionReader(r, 0, size), name, folder, source)
+	} else {
+		err = parseMarkdownArchive(r, size, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.plan(userID, source, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return plan.report, nil
+	}
+
+	var stored []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return plan.execute(tx, s.files, s.log, userID, &stored)
+	})
+	if err != nil {
+		for _, p := range stored {
+			s.files.DeleteBlob(p)
+		}
+		return nil, err
+	}
+	s.log.Info("Imported notes", "user_id", userID, "format", opts.Format, "created", plan.report.Created,
+		"overwritten", plan.report.Overwritten, "skipped", plan.report.Skipped, "attachments", plan.report.Attachments)
+	return plan.report, nil
+}
+
+func normalizeNoteImportOptions(opts *NoteImportOptions, filename string) error {
+	if opts.Format == "" {
+		switch strings.ToLower(path.Ext(filename)) {
+		case ".enex":
+			opts.Format = NoteImportENEX
+		default:
+			opts.Format = NoteImportMarkdown
+		}
+	}
+	if opts.Format != NoteImportMarkdown && opts.Format != NoteImportENEX {
+		return fmt.Errorf("%w: unknown import format %q", common.ErrInvalidInput, opts.Format)
+	}
+	switch opts.Folders {
+	case "":
+		opts.Folders = FoldersAsNotebooks
+	case FoldersAsNotebooks, FoldersAsTags:
+	default:
+		return fmt.Errorf("%w: folders must be %q or %q", common.ErrInvalidInput, FoldersAsNotebooks, FoldersAsTags)
+	}
+	switch opts.Conflict {
+	case "":
+		opts.Conflict = ConflictSkip
+	case ConflictSkip, ConflictOverwrite, ConflictDuplicate:
+	default:
+		return fmt.Errorf("%w: unknown conflict strategy %q", common.ErrInvalidInput, opts.Conflict)
+	}
+	return nil
+}
+
+// noteImportPlan 导入计划：每篇笔记的目标位置和操作，试运行直接返回其报告
+type noteImportPlan struct {
+	report    *NoteImportReport
+	source    *noteSource
+	notes     []*importedNote // 与 report.Notes 一一对应
+	paths     [][]string      // 每篇笔记的笔记本路径，为空表示直接放在根笔记本中
+	root      *uint
+	notebooks map[string]uint // 已有笔记本：父笔记本ID/小写名称 -> ID
+}
+
+func (s *NoteImportService) plan(userID uint, source *noteSource, opts NoteImportOptions) (*noteImportPlan, error) {
+	var existing []model.Note
+	if err := s.db.Select("id, title").Where("user_id = ?", userID).Order("id ASC").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byTitle := make(map[string]uint, len(existing))
+	for _, n := range existing {
+		if key := utils.WikiLinkKey(n.Title); byTitle[key] == 0 {
+			byTitle[key] = n.ID
+		}
+	}
+
+	var notebooks []model.Notebook
+	if err := s.db.Select("id, parent_id, name").Where("user_id = ?", userID).Find(&notebooks).Error; err != nil {
+		return nil, err
+	}
+	p := &noteImportPlan{
+		report: &NoteImportReport{
+			DryRun:    opts.DryRun,
+			Format:    opts.Format,
+			Notes:     []NoteImportItem{},
+			Notebooks: []string{},
+			Warnings:  append([]string{}, source.warnings...),
+		},
+		source:    source,
+		root:      opts.NotebookID,
+		notebooks: make(map[string]uint, len(notebooks)),
+	}
+	for _, nb := range notebooks {
+		p.notebooks[notebookKey(nb.ParentID, nb.Name)] = nb.ID
+	}
+
+	newNotebooks := make(map[string]bool)
+	seen := make(map[string]string) // 本次导入中的标题 -> 第一篇的来源
+	uploads := make(map[int]bool)
+	checked := make(map[int]bool)
+	maxSize := config.AppConfig.Storage.MaxUploadSize
+	for _, note := range source.notes {
+		note.Title = importTitle(note.Title)
+		var folder []string
+		for _, name := range note.Folder {
+			if name = strings.TrimSpace(name); name != "" {
+				folder = append(folder, utils.TruncateRunes(name, 100))
+			}
+		}
+		tags := append([]string(nil), note.Tags...)
+		if opts.Folders == FoldersAsTags && len(folder) > 0 {
+			tags = append(tags, strings.Join(folder, "/"))
+			folder = nil
+		}
+
+		item := NoteImportItem{
+			Source:   note.Source,
+			Title:    note.Title,
+			Notebook: strings.Join(folder, "/"),
+			Tags:     strings.Join(ParseTags(strings.Join(tags, ",")), ","),
+			Action:   NoteImportCreate,
+		}
+		key := utils.WikiLinkKey(note.Title)
+		first, repeated := seen[key]
+		if repeated {
+			p.report.Warnings = append(p.report.Warnings,
+				fmt.Sprintf("%s has the same title as %s", note.Source, first))
+		} else {
+			seen[key] = note.Source
+		}
+		if id := byTitle[key]; id != 0 {
+			item.DuplicateOf = id
+			switch {
+			case opts.Conflict == ConflictSkip:
+				item.Action = NoteImportSkip
+			case opts.Conflict == ConflictOverwrite && !repeated:
+				// 已有笔记只被导入中第一篇同名笔记覆盖，其余的新建
+				item.Action = NoteImportOverwrite
+			}
+		}
+
+		switch item.Action {
+		case NoteImportSkip:
+			p.report.Skipped++
+		case NoteImportOverwrite:
+			p.report.Overwritten++
+		default:
+			p.report.Created++
+		}
+		if item.Action != NoteImportSkip {
+			for i := range folder {
+				if full := strings.Join(folder[:i+1], "/"); !newNotebooks[full] && !p.exists(folder[:i+1]) {
+					newNotebooks[full] = true
+					p.report.Notebooks = append(p.report.Notebooks, full)
+				}
+			}
+			for _, index := range note.Attachments {
+				att := source.attachments[index]
+				if !checked[index] {
+					checked[index] = true
+					if err := validateImportedAttachment(att, maxSize); err != nil {
+						att.rejected = true
+						p.report.Warnings = append(p.report.Warnings,
+							fmt.Sprintf("%s: attachment %s was not imported (%v), the original link is kept", note.Source, att.Name, err))
+					}
+				}
+				if att.rejected {
+					continue
+				}
+				item.Attachments++
+				if !uploads[index] {
+					uploads[index] = true
+					p.report.Attachments++
+				}
+			}
+		}
+
+		p.report.Notes = append(p.report.Notes, item)
+		p.notes = append(p.notes, note)
+		p.paths = append(p.paths, folder)
+	}
+	return p, nil
+}
+
+// exists 判断笔记本路径是否已全部存在
+func (p *noteImportPlan) exists(folder []string) bool {
+	parent := p.root
+	for _, name := range folder {
+		id, ok := p.notebooks[notebookKey(parent, name)]
+		if !ok {
+			return false
+		}
+		parent = &id
+	}
+	return true
+}
+
+// execute 按计划写入笔记本、附件和笔记，stored 记录已写入的附件内容以便失败时清理
+func (p *noteImportPlan) execute(tx *gorm.DB, files *FileService, log logger.Logger, userID uint, stored *[]string) error {
+	notes := NewNoteService(tx, log)
+	notebooks := NewNotebookService(tx)
+	maxSize := config.AppConfig.Storage.MaxUploadSize
+	uploaded := make(map[int]uint) // 附件序号 -> 文件ID
+
+	for i, note := range p.notes {
+		item := &p.report.Notes[i]
+		if item.Action == NoteImportSkip {
+			continue
+		}
+
+		// 逐级查找或创建笔记本
+		notebookID := p.root
+		for _, name := range p.paths[i] {
+			key := notebookKey(notebookID, name)
+			id, ok := p.notebooks[key]
+			if !ok {
+				nb := model.Notebook{UserID: userID, ParentID: notebookID, Name: name}
+				if err := notebooks.Create(&nb); err != nil {
+					return err
+				}
+				id = nb.ID
+				p.notebooks[key] = id
+			}
+			notebookID = &id
+		}
+
+		var fresh []uint // 本篇笔记新上传的附件，笔记创建后关联
+		for _, index := range note.Attachments {
+			att := p.source.attachments[index]
+			if _, ok := uploaded[index]; ok || att.rejected {
+				continue
+			}
+			file, err := storeImportedAttachment(tx, files, userID, att, maxSize, stored)
+			if err != nil {
+				return fmt.Errorf("import attachment %s of %s: %w", att.Name, note.Source, err)
+			}
+			uploaded[index] = file.ID
+			fresh = append(fresh, file.ID)
+		}
+		content := attachmentPlaceholderRe.ReplaceAllStringFunc(note.Content, func(m string) string {
+			index, _ := strconv.Atoi(m[len("nexushub-attachment:"):])
+			if id, ok := uploaded[index]; ok {
+				return FileContentURL(id)
+			}
+			if index < len(p.source.attachments) {
+				return p.source.attachments[index].Link
+			}
+			return m
+		})
+
+		var noteID uint
+		if item.Action == NoteImportOverwrite {
+			var existing model.Note
+			if err := tx.Where("id = ? AND user_id = ?", item.DuplicateOf, userID).First(&existing).Error; err != nil {
+				return err
+			}
+			update := model.Note{Title: note.Title, Content: content, Tags: item.Tags, IsPinned: existing.IsPinned}
+			if err := notes.Update(existing.ID, userID, &update); err != nil {
+				return err
+			}
+			if err := tx.Model(&existing).UpdateColumn("notebook_id", notebookID).Error; err != nil {
+				return err
+			}
+			noteID = existing.ID
+		} else {
+			created := model.Note{
+				UserID:     userID,
+				Title:      note.Title,
+				Content:    content,
+				Tags:       item.Tags,
+				NotebookID: notebookID,
+				CreatedAt:  note.CreatedAt,
+				UpdatedAt:  note.UpdatedAt,
+			}
+			if err := notes.Create(&created); err != nil {
+				return err
+			}
+			noteID = created.ID
+		}
+
+		if len(fresh) > 0 {
+			if err := tx.Model(&model.File{}).Where("id IN ?", fresh).UpdateColumn("note_id", noteID).Error; err != nil {
+				return err
+			}
+		}
+		item.NoteID = noteID
+	}
+	return nil
+}
+
+// validateImportedAttachment 对附件做与 FileService.Upload 相同的检查：大小、文件名和扩展名
+func validateImportedAttachment(att *importedAttachment, maxSize int64) error {
+	return validator.ValidateFileUpload(&multipart.FileHeader{Filename: att.Name, Size: att.Size}, maxSize, nil)
+}
+
+// storeImportedAttachment 写入附件内容并创建文件记录，内容超过 maxSize 时失败
+func storeImportedAttachment(tx *gorm.DB, files *FileService, userID uint, att *importedAttachment, maxSize int64, stored *[]string) (*model.File, error) {
+	rc, err := att.open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", common.ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+
+	ext := strings.ToLower(path.Ext(att.Name))
+	mimeType := att.MimeType
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(ext)
+	}
+	category := files.CategoryOf(att.Name)
+	storagePath, written, err := files.StoreBlob(userID, category, att.Name, io.LimitReader(rc, maxSize+1), mimeType)
+	if err != nil {
+		return nil, err
+	}
+	*stored = append(*stored, storagePath)
+	if written > maxSize {
+		return nil, fmt.Errorf("%w: attachment exceeds the maximum upload size", common.ErrInvalidArchive)
+	}
+
+	file := &model.File{
+		UserID:    userID,
+		FileName:  utils.TruncateRunes(path.Base(att.Name), 255),
+		FilePath:  storagePath,
+		FileSize:  written,
+		FileType:  mimeType,
+		MimeType:  mimeType,
+		Extension: ext,
+		Category:  category,
+	}
+	if err := createFileRecord(tx, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// notebookKey 在同一父笔记本下按名称(忽略大小写)查找笔记本的键
+func notebookKey(parentID *uint, name string) string {
+	parent := "0"
+	if parentID != nil {
+		parent = strconv.FormatUint(uint64(*parentID), 10)
+	}
+	return parent + "/" + strings.ToLower(name)
+}
+
+// importTitle 规范化导入的标题：没有标题时使用 Untitled，超出长度时截断
+func importTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return "Untitled"
+	}
+	return utils.TruncateRunes(title, 255)
+}
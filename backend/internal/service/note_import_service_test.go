@@ -0,0 +1,194 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func buildVault(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestImportMarkdownVault(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	existing := model.Note{UserID: 1, Title: "Inbox", Content: "keep me", IsPinned: true}
+	if err := services.Notes.Create(&existing); err != nil {
+		t.Fatal(err)
+	}
+
+	vault := buildVault(t, map[string]string{
+		"vault/.obsidian/app.json": "{}",
+		"vault/assets/diagram.png": "png-bytes",
+		"vault/assets/tool.exe":    "MZ",
+		"vault/inbox.md":           "new inbox",
+		"vault/Work/Meeting.md":    "Notes ![[diagram.png]] [tool](../assets/tool.exe)\n\n```\n![x](../assets/diagram.png)\n```\n",
+		"vault/Work/Projects/Plan.md": "---\ntitle: Project Plan\ntags: [work, \"planning\"]\ncreated: 2020-01-02\n---\n" +
+			"![diagram](../../assets/diagram.png) see [the meeting](../Meeting.md) and [site](https://example.com)\n",
+	})
+	importVault := func(opts service.NoteImportOptions) *service.NoteImportReport {
+		t.Helper()
+		report, err := services.NoteImport.Import(1, "vault.zip", vault, vault.Size(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	dry := importVault(service.NoteImportOptions{DryRun: true})
+	if dry.Created != 2 || dry.Skipped != 1 || dry.Attachments != 1 || strings.Join(dry.Notebooks, ",") != "Work,Work/Projects" {
+		t.Fatalf("unexpected dry run report: %+v", dry)
+	}
+	if len(dry.Warnings) != 1 || !strings.Contains(dry.Warnings[0], "tool.exe") {
+		t.Fatalf("rejected attachment should be reported: %q", dry.Warnings)
+	}
+	if _, total, _ := services.Notes.List(1, service.NoteQuery{}); total != 1 {
+		t.Fatalf("dry run must not create notes, have %d", total)
+	}
+
+	report := importVault(service.NoteImportOptions{})
+	byTitle := make(map[string]service.NoteImportItem)
+	for _, item := range report.Notes {
+		byTitle[item.Title] = item
+	}
+	if byTitle["inbox"].Action != service.NoteImportSkip || byTitle["inbox"].DuplicateOf != existing.ID {
+		t.Fatalf("duplicate title should be skipped: %+v", byTitle["inbox"])
+	}
+
+	// 两篇笔记引用同一图片，只上传一次，作为第一篇笔记的附件
+	meeting, _ := services.Notes.GetByID(byTitle["Meeting"].NoteID, 1)
+	files, _ := services.Files.NoteAttachments(meeting.ID, 1)
+	if len(files) != 1 || files[0].FileName != "diagram.png" || report.Attachments != 1 {
+		t.Fatalf("image should be uploaded once as an attachment: %+v", files)
+	}
+	url := service.FileContentURL(files[0].ID)
+	// 未通过上传检查的附件保留原链接
+	wantMeeting := "Notes ![diagram.png](" + url + ") [tool](../assets/tool.exe)\n\n```\n![x](../assets/diagram.png)\n```\n"
+	if meeting.Content != wantMeeting {
+		t.Fatalf("unexpected content: %q", meeting.Content)
+	}
+
+	plan, err := services.Notes.GetByID(byTitle["Project Plan"].NoteID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlan := "![diagram](" + url + ") see [[Meeting|the meeting]] and [site](https://example.com)\n"
+	if plan.Content != wantPlan || plan.Tags != "work,planning" || plan.CreatedAt.Year() != 2020 {
+		t.Fatalf("unexpected note: %q tags=%q created=%v", plan.Content, plan.Tags, plan.CreatedAt)
+	}
+
+	tree, _ := services.Notebooks.Tree(1)
+	if len(tree) != 1 || tree[0].Name != "Work" || tree[0].NoteCount != 1 ||
+		len(tree[0].Children) != 1 || tree[0].Children[0].Name != "Projects" || *plan.NotebookID != tree[0].Children[0].ID {
+		t.Fatalf("folders should become notebooks: %+v", tree)
+	}
+
+	// 覆盖时保留置顶状态，文件夹作为标签
+	overwrite := importVault(service.NoteImportOptions{Conflict: service.ConflictOverwrite, Folders: service.FoldersAsTags})
+	if overwrite.Overwritten != 3 || overwrite.Created != 0 || len(overwrite.Notebooks) != 0 {
+		t.Fatalf("unexpected overwrite report: %+v", overwrite)
+	}
+	inbox, _ := services.Notes.GetByID(existing.ID, 1)
+	if inbox.Content != "new inbox" || !inbox.IsPinned {
+		t.Fatalf("existing note should be overwritten: %+v", inbox)
+	}
+	plan, _ = services.Notes.GetByID(plan.ID, 1)
+	if plan.Tags != "work,planning,Work/Projects" || plan.NotebookID != nil {
+		t.Fatalf("folders should become tags: tags=%q notebook=%v", plan.Tags, plan.NotebookID)
+	}
+}
+
+func TestImportENEX(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	image := []byte("fake image")
+	sum := md5.Sum(image)
+	enex := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export4.dtd">
+<en-export>
+  <note>
+    <title>Trip</title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><h2>Packing</h2><div><en-todo checked="true"/>passport</div><div><en-todo/>charger</div>
+<div>See <b>map</b> <a href="https://example.com">here</a></div><ul><li>one<ul><li>nested</li></ul></li><li>two</li></ul>
+<en-media hash="` + hex.EncodeToString(sum[:]) + `" type="image/png"/></en-note>]]></content>
+    <created>20190305T101500Z</created>
+    <tag>travel</tag>
+    <resource>
+      <data encoding="base64">` + base64.StdEncoding.EncodeToString(image) + `</data>
+      <mime>image/png</mime>
+      <resource-attributes><file-name>map.png</file-name></resource-attributes>
+    </resource>
+  </note>
+</en-export>`
+
+	r := strings.NewReader(enex)
+	report, err := services.NoteImport.Import(1, "Travel.enex", r, r.Size(), service.NoteImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Format != service.NoteImportENEX || report.Created != 1 || report.Attachments != 1 || report.Notes[0].Notebook != "Travel" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	note, _ := services.Notes.GetByID(report.Notes[0].NoteID, 1)
+	files, _ := services.Files.NoteAttachments(note.ID, 1)
+	if len(files) != 1 {
+		t.Fatalf("resource should be uploaded: %+v", files)
+	}
+	want := "## Packing\n\n- [x] passport\n\n- [ ] charger\n\nSee **map** [here](https://example.com)\n\n" +
+		"- one\n  - nested\n- two\n\n![map.png](" + service.FileContentURL(files[0].ID) + ")"
+	if note.Content != want || note.Tags != "travel" || note.CreatedAt.Year() != 2019 {
+		t.Fatalf("unexpected note: %q tags=%q created=%v", note.Content, note.Tags, note.CreatedAt)
+	}
+}
+
+func TestImportRejectsOversizedArchives(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	var many bytes.Buffer
+	zw := zip.NewWriter(&many)
+	for i := 0; i <= 20000; i++ {
+		zw.Create(fmt.Sprintf("n%d.md", i))
+	}
+	zw.Close()
+
+	// 声明解压后为 8 GB 的文件
+	var bomb bytes.Buffer
+	zw = zip.NewWriter(&bomb)
+	w, _ := zw.CreateRaw(&zip.FileHeader{Name: "big.md", Method: zip.Store, UncompressedSize64: 8 << 30})
+	w.Write([]byte("x"))
+	zw.Close()
+
+	for name, data := range map[string][]byte{"entries": many.Bytes(), "size": bomb.Bytes()} {
+		r := bytes.NewReader(data)
+		_, err := services.NoteImport.Import(1, "vault.zip", r, r.Size(), service.NoteImportOptions{DryRun: true})
+		if !errors.Is(err, common.ErrInvalidArchive) {
+			t.Errorf("%s: expected ErrInvalidArchive, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// NoteRef 笔记的简要信息
+type NoteRef struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NoteGraphEdge 源笔记指向目标笔记的链接
+type NoteGraphEdge struct {
+	Source uint `json:"source"`
+	Target uint `json:"target"`
+}
+
+// UnresolvedLink 目标笔记不存在的链接
+type UnresolvedLink struct {
+	Source uint   `json:"source"`
+	Title  string `json:"title"`
+}
+
+// NoteGraph 笔记关系图
+type NoteGraph struct {
+	Nodes      []NoteRef        `json:"nodes"`
+	Edges      []NoteGraphEdge  `json:"edges"`
+	Unresolved []UnresolvedLink `json:"unresolved"`
+}
+
+// syncNoteLinks 根据正文重建笔记的出链
+func syncNoteLinks(tx *gorm.DB, note *model.Note) error {
+	if err := tx.Where("source_id = ?", note.ID).Delete(&model.NoteLink{}).Error; err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	var links []model.NoteLink
+	for _, link := range utils.ParseWikiLinks(note.Content) {
+		key := utils.WikiLinkKey(link.Target)
+		if seen[key] || len(link.Target) > 255 {
+			continue
+		}
+		seen[key] = true
+		links = append(links, model.NoteLink{
+			SourceID:    note.ID,
+			UserID:      note.UserID,
+			TargetTitle: link.Target,
+			TargetKey:   key,
+		})
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return tx.Create(&links).Error
+}
+
+// syncNoteLinksByID 重建多篇笔记的出链，用于导入等直接写入笔记的场景
+func syncNoteLinksByID(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var notes []model.Note
+	if err := tx.Where("id IN ?", ids).Find(&notes).Error; err != nil {
+		return err
+	}
+	for i := range notes {
+		if err := syncNoteLinks(tx, &notes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteIncomingLinks 把笔记所有者其他笔记中指向 oldTitle 的链接改为 newTitle，
+// 只改写 userID 有编辑权限的笔记，每篇被改写的笔记记录一个新版本。返回改写的链接数
+func (s *NoteService) rewriteIncomingLinks(tx *gorm.DB, note *model.Note, oldTitle string, userID uint) (int, error) {
+	var sourceIDs []uint
+	err := tx.Model(&model.NoteLink{}).
+		Where("user_id = ? AND target_key = ? AND source_id <> ?", note.UserID, utils.WikiLinkKey(oldTitle), note.ID).
+		Distinct().
+		Pluck("source_id", &sourceIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	notes := NewNoteService(tx, s.log)
+	total := 0
+	for _, id := range sourceIDs {
+		source, err := notes.GetEditable(id, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return total, err
+		}
+		content, n := utils.RewriteWikiLinks(source.Content, oldTitle, note.Title)
+		if n == 0 {
+			continue
+		}
+		err = tx.Model(&model.Note{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"content": content,
+			"version": gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return total, err
+		}
+		if err := tx.First(source, id).Error; err != nil {
+			return total, err
+		}
+		if err := recordNoteRevision(tx, source, userID, time.Now()); err != nil {
+			return total, err
+		}
+		if err := syncNoteLinks(tx, source); err != nil {
+			return total, err
+		}
+		if err := indexResource(tx, constants.ResourceNote, id); err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Backlinks 返回链接到该笔记的其他笔记，只包含当前用户可访问的笔记
+func (s *NoteService) Backlinks(noteID, userID uint) ([]NoteRef, error) {
+	note, err := s.GetByID(noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := s.db.Model(&model.NoteLink{}).
+		Select("source_id").
+		Where("user_id = ? AND target_key = ?", note.UserID, utils.WikiLinkKey(note.Title))
+	refs := []NoteRef{}
+	err = s.db.Model(&model.Note{}).
+		Scopes(AccessibleScope(userID, constants.ResourceNote, false)).
+		Where("id IN (?) AND id <> ?", sources, noteID).
+		Order("updated_at DESC").
+		Find(&refs).Error
+	return refs, err
+}
+
+// Graph 返回当前用户可访问的全部笔记及其之间的链接。
+// 同名笔记以ID最小的为链接目标；只有用户自己笔记中的失效链接会出现在 Unresolved 中
+func (s *NoteService) Graph(userID uint) (*NoteGraph, error) {
+	var notes []struct {
+		ID        uint
+		UserID    uint
+		Title     string
+		UpdatedAt time.Time
+	}
+	err := s.db.Model(&model.Note{}).
+		Scopes(AccessibleScope(userID, constants.ResourceNote, false)).
+		Select("id, user_id, title, updated_at").
+		Order("id").
+		Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type ownerKey struct {
+		userID uint
+		key    string
+	}
+	graph := &NoteGraph{
+		Nodes:      make([]NoteRef, 0, len(notes)),
+		Edges:      []NoteGraphEdge{},
+		Unresolved: []UnresolvedLink{},
+	}
+	byTitle := make(map[ownerKey]uint, len(notes))
+	for _, n := range notes {
+		graph.Nodes = append(graph.Nodes, NoteRef{ID: n.ID, Title: n.Title, UpdatedAt: n.UpdatedAt})
+		k := ownerKey{n.UserID, utils.WikiLinkKey(n.Title)}
+		if _, ok := byTitle[k]; !ok {
+			byTitle[k] = n.ID
+		}
+	}
+
+	visible := s.db.Model(&model.Note{}).
+		Scopes(AccessibleScope(userID, constants.ResourceNote, false)).
+		Select("id")
+	var links []model.NoteLink
+	if err := s.db.Where("source_id IN (?)", visible).Order("source_id, id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		target, ok := byTitle[ownerKey{link.UserID, link.TargetKey}]
+		switch {
+		case ok && target != link.SourceID:
+			graph.Edges = append(graph.Edges, NoteGraphEdge{Source: link.SourceID, Target: target})
+		case !ok && link.UserID == userID:
+			graph.Unresolved = append(graph.Unresolved, UnresolvedLink{Source: link.SourceID, Title: link.TargetTitle})
+		}
+	}
+	return graph, nil
+}
@@ -1,43 +1,486 @@
 package service
 
 import (
-	"nexushub-personal/internal/database"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/logger"
 	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/utils"
+
+	"gorm.io/gorm"
 )
 
-type NoteService struct{}
+type NoteService struct {
+	*BaseService[model.Note]
+	log logger.Logger
+}
 
-func NewNoteService() *NoteService {
-	return &NoteService{}
+func NewNoteService(db *gorm.DB, log logger.Logger) *NoteService {
+	return &NoteService{
+		BaseService: NewBaseService[model.Note](db, CRUDOptions{
+			Resource:  constants.ResourceNote,
+			Shareable: true,
+			Order:     "is_pinned DESC, updated_at DESC",
+			Fields:    []string{"title", "content", "tags", "is_pinned"},
+		}),
+		log: log,
+	}
 }
 
-func (s *NoteService) GetAll(userID uint) ([]model.Note, error) {
-	var notes []model.Note
-	err := database.DB.Where("user_id = ?", userID).Order("is_pinned DESC, updated_at DESC").Find(&notes).Error
-	return notes, err
+// WithLogger 返回使用 log 记录日志的副本，handler 传入请求日志使服务中的日志带上请求ID
+func (s *NoteService) WithLogger(log logger.Logger) *NoteService {
+	scoped := *s
+	scoped.log = log
+	return &scoped
 }
 
-func (s *NoteService) GetByID(id, userID uint) (*model.Note, error) {
-	var note model.Note
-	err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&note).Error
-	return &note, err
+// NoteRevisionInfo 历史版本及作者用户名
+type NoteRevisionInfo struct {
+	model.NoteRevision
+	AuthorName string `json:"author_name"`
 }
 
+// NoteRevisionDiff 两个版本之间的差异
+type NoteRevisionDiff struct {
+	From      int              `json:"from"`
+	To        int              `json:"to"`
+	FromTitle string           `json:"from_title"`
+	ToTitle   string           `json:"to_title"`
+	Added     int              `json:"added"`
+	Removed   int              `json:"removed"`
+	Lines     []utils.DiffLine `json:"lines"`
+}
+
+// NoteQuery 笔记列表的查询条件
+type NoteQuery struct {
+	Tag        string
+	NotebookID *uint  // 只返回该笔记本中的笔记
+	Unfiled    bool   // 只返回不属于任何笔记本的笔记
+	Recursive  bool   // 与 NotebookID 一起使用，同时返回子笔记本中的笔记
+	Archived   *bool  // 为空时不按归档状态过滤
+	Sort       string // NoteSortManual 按手动排序，否则按更新时间；置顶的笔记总是在前
+	Page       int
+	PageSize   int // 为0时不分页
+}
+
+// NoteSortManual 按 sort_order 手动排序
+const NoteSortManual = "manual"
+
+// Create 创建笔记、建立标签关联、出链和搜索索引，并记录第一个版本
 func (s *NoteService) Create(note *model.Note) error {
-	return database.DB.Create(note).Error
+	note.Version = 1
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkNotebook(tx, note.UserID, note.NotebookID); err != nil {
+			return err
+		}
+		if err := tx.Create(note).Error; err != nil {
+			return err
+		}
+		if err := syncEntityTags(tx, constants.ResourceNote, note.ID, note); err != nil {
+			return err
+		}
+		if err := indexResource(tx, constants.ResourceNote, note.ID); err != nil {
+			return err
+		}
+		if err := syncNoteLinks(tx, note); err != nil {
+			return err
+		}
+		return recordNoteRevision(tx, note, note.UserID, note.CreatedAt)
+	})
 }
 
-func (s *NoteService) Update(note *model.Note) error {
-	return database.DB.Model(&model.Note{}).
-		Where("id = ? AND user_id = ?", note.ID, note.UserID).
-		Updates(map[string]interface{}{
-			"title":     note.Title,
-			"content":   note.Content,
-			"tags":      note.Tags,
-			"is_pinned": note.IsPinned,
-		}).Error
+// Update 更新笔记、重建出链并记录新版本；没有任何历史版本的旧笔记会先补记更新前的内容。
+// note.RewriteLinks 为true且标题改变时，同时改写其他笔记中指向旧标题的链接
+func (s *NoteService) Update(id, userID uint, note *model.Note) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		notes := NewNoteService(tx, s.log)
+		before, err := notes.GetEditable(id, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return common.ErrResourceNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := ensureBaselineRevision(tx, before); err != nil {
+			return err
+		}
+		if err := notes.BaseService.Update(id, userID, note); err != nil {
+			return err
+		}
+		var after model.Note
+		if err := tx.First(&after, id).Error; err != nil {
+			return err
+		}
+		if err := syncNoteLinks(tx, &after); err != nil {
+			return err
+		}
+		if note.RewriteLinks && utils.WikiLinkKey(before.Title) != utils.WikiLinkKey(after.Title) {
+			n, err := notes.rewriteIncomingLinks(tx, &after, before.Title, userID)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				s.log.Info("Rewrote links after renaming note", "count", n, "from", before.Title, "to", after.Title, "note_id", id)
+			}
+		}
+		return recordNoteRevision(tx, &after, userID, time.Now())
+	})
 }
 
+// Delete 删除笔记及其历史版本和出链，只有所有者可以删除
 func (s *NoteService) Delete(id, userID uint) error {
-	return database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Note{}).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := NewNoteService(tx, s.log).BaseService.Delete(id, userID); err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", id).Delete(&model.NoteRevision{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("source_id = ?", id).Delete(&model.NoteLink{}).Error
+	})
+}
+
+// List 按条件查询用户可访问的笔记，返回当前页和符合条件的总数
+func (s *NoteService) List(userID uint, q NoteQuery) ([]model.Note, int64, error) {
+	query := s.db.Model(&model.Note{}).Scopes(AccessibleScope(userID, constants.ResourceNote, false), TaggedScope(constants.ResourceNote, q.Tag))
+	switch {
+	case q.NotebookID != nil && q.Recursive:
+		ids, err := notebookDescendants(s.db, userID, *q.NotebookID)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("notebook_id IN ?", ids)
+	case q.NotebookID != nil:
+		query = query.Where("notebook_id = ?", *q.NotebookID)
+	case q.Unfiled:
+		query = query.Where("notebook_id IS NULL")
+	}
+	if q.Archived != nil {
+		query = query.Where("is_archived = ?", *q.Archived)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.Sort == NoteSortManual {
+		query = query.Order("is_pinned DESC, sort_order ASC, updated_at DESC")
+	} else {
+		query = query.Order("is_pinned DESC, updated_at DESC")
+	}
+	if q.PageSize > 0 {
+		query = query.Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize)
+	}
+	var notes []model.Note
+	err := query.Find(&notes).Error
+	return notes, total, err
+}
+
+// Move 把用户自己的笔记移到 notebookID 指定的笔记本(为空时移出笔记本)，
+// 返回移动前的笔记；ids 中不属于该用户的笔记被忽略
+func (s *NoteService) Move(ids []uint, userID uint, notebookID *uint) ([]model.Note, error) {
+	var moved []model.Note
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkNotebook(tx, userID, notebookID); err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ? AND user_id = ?", ids, userID).Order("id").Find(&moved).Error; err != nil {
+			return err
+		}
+		if len(moved) == 0 {
+			return common.ErrResourceNotFound
+		}
+		return tx.Model(&model.Note{}).Where("id IN ? AND user_id = ?", ids, userID).
+			UpdateColumn("notebook_id", notebookID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// SetArchived 归档或取消归档用户自己的笔记，返回更新后的笔记
+func (s *NoteService) SetArchived(id, userID uint, archived bool) (*model.Note, error) {
+	result := s.db.Model(&model.Note{}).Where("id = ? AND user_id = ?", id, userID).UpdateColumn("is_archived", archived)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, common.ErrResourceNotFound
+	}
+	return s.GetByID(id, userID)
+}
+
+// Reorder 按 ids 的顺序设置用户自己的笔记的手动排序位置(从1开始)
+func (s *NoteService) Reorder(ids []uint, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			if err := tx.Model(&model.Note{}).Where("id = ? AND user_id = ?", id, userID).
+				UpdateColumn("sort_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Revisions 返回用户可访问的笔记的全部历史版本(不含正文)，最新的在前
+func (s *NoteService) Revisions(noteID, userID uint) ([]NoteRevisionInfo, error) {
+	if _, err := s.GetByID(noteID, userID); err != nil {
+		return nil, err
+	}
+	var revisions []NoteRevisionInfo
+	err := s.revisionQuery(noteID).
+		Select("note_revisions.id, note_revisions.note_id, note_revisions.revision, note_revisions.author_id, " +
+			"note_revisions.title, note_revisions.tags, note_revisions.is_pinned, note_revisions.created_at, users.username AS author_name").
+		Order("note_revisions.revision DESC").
+		Scan(&revisions).Error
+	return revisions, err
+}
+
+// Revision 返回笔记的某个历史版本
+func (s *NoteService) Revision(noteID, userID uint, rev int) (*NoteRevisionInfo, error) {
+	if _, err := s.GetByID(noteID, userID); err != nil {
+		return nil, err
+	}
+	return s.findRevision(noteID, rev)
+}
+
+// DiffRevisions 按行比较笔记的两个历史版本
+func (s *NoteService) DiffRevisions(noteID, userID uint, from, to int) (*NoteRevisionDiff, error) {
+	if _, err := s.GetByID(noteID, userID); err != nil {
+		return nil, err
+	}
+	older, err := s.findRevision(noteID, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.findRevision(noteID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &NoteRevisionDiff{
+		From:      from,
+		To:        to,
+		FromTitle: older.Title,
+		ToTitle:   newer.Title,
+		Lines:     utils.DiffLines(older.Content, newer.Content),
+	}
+	for _, line := range diff.Lines {
+		switch line.Op {
+		case utils.DiffInsert:
+			diff.Added++
+		case utils.DiffDelete:
+			diff.Removed++
+		}
+	}
+	return diff, nil
+}
+
+// RestoreRevision 把笔记的标题、正文和标签恢复为某个历史版本，恢复本身记录为一个新版本
+func (s *NoteService) RestoreRevision(noteID, userID uint, rev int) (*model.Note, error) {
+	var restored model.Note
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		notes := NewNoteService(tx, s.log)
+		current, err := notes.GetEditable(noteID, userID)
+		if err != nil {
+			return err
+		}
+		revision, err := notes.findRevision(noteID, rev)
+		if err != nil {
+			return err
+		}
+		update := model.Note{
+			Title:    revision.Title,
+			Content:  revision.Content,
+			Tags:     revision.Tags,
+			IsPinned: current.IsPinned,
+		}
+		if err := notes.Update(noteID, userID, &update); err != nil {
+			return err
+		}
+		return tx.First(&restored, noteID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+func (s *NoteService) revisionQuery(noteID uint) *gorm.DB {
+	return s.db.Model(&model.NoteRevision{}).
+		Joins("LEFT JOIN users ON users.id = note_revisions.author_id").
+		Where("note_revisions.note_id = ?", noteID)
+}
+
+func (s *NoteService) findRevision(noteID uint, rev int) (*NoteRevisionInfo, error) {
+	var revision NoteRevisionInfo
+	result := s.revisionQuery(noteID).
+		Select("note_revisions.*, users.username AS author_name").
+		Where("note_revisions.revision = ?", rev).
+		Limit(1).
+		Scan(&revision)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, common.ErrRevisionNotFound
+	}
+	return &revision, nil
+}
+
+// ensureBaselineRevision 笔记还没有任何版本时，把当前内容记为第一个版本
+func ensureBaselineRevision(tx *gorm.DB, note *model.Note) error {
+	var count int64
+	if err := tx.Model(&model.NoteRevision{}).Where("note_id = ?", note.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordNoteRevision(tx, note, note.UserID, note.UpdatedAt)
+}
+
+// recordNoteRevision 记录笔记的当前内容为新版本；与最新版本完全相同时不重复记录
+func recordNoteRevision(tx *gorm.DB, note *model.Note, authorID uint, at time.Time) error {
+	var latest model.NoteRevision
+	err := tx.Where("note_id = ?", note.ID).Order("revision DESC").First(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && latest.Title == note.Title && latest.Content == note.Content &&
+		latest.Tags == note.Tags && latest.IsPinned == note.IsPinned {
+		return nil
+	}
+
+	return tx.Create(&model.NoteRevision{
+		NoteID:    note.ID,
+		Revision:  latest.Revision + 1,
+		AuthorID:  authorID,
+		Title:     note.Title,
+		Content:   note.Content,
+		Tags:      note.Tags,
+		IsPinned:  note.IsPinned,
+		CreatedAt: at,
+	}).Error
+}
+
+// revisionMeta 清理历史版本时只需要的字段
+type revisionMeta struct {
+	ID        uint
+	NoteID    uint
+	Revision  int
+	CreatedAt time.Time
+}
+
+// PruneRevisions 按保留策略删除旧的历史版本，并删除笔记已不存在的版本，返回删除的数量
+func (s *NoteService) PruneRevisions(policy config.RevisionConfig, now time.Time) (int64, error) {
+	orphans := s.db.Where("note_id NOT IN (?)", s.db.Model(&model.Note{}).Select("id")).Delete(&model.NoteRevision{})
+	if orphans.Error != nil {
+		return 0, orphans.Error
+	}
+	deleted := orphans.RowsAffected
+
+	var metas []revisionMeta
+	err := s.db.Model(&model.NoteRevision{}).
+		Select("id, note_id, revision, created_at").
+		Where("created_at < ?", now.AddDate(0, 0, -policy.KeepAllDays)).
+		Find(&metas).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// 每篇笔记的最新版本始终保留，即使它早于保留期
+	var latest []revisionMeta
+	err = s.db.Model(&model.NoteRevision{}).
+		Select("note_id, MAX(revision) AS revision").
+		Group("note_id").
+		Find(&latest).Error
+	if err != nil {
+		return 0, err
+	}
+	latestRev := make(map[uint]int, len(latest))
+	for _, l := range latest {
+		latestRev[l.NoteID] = l.Revision
+	}
+
+	ids := revisionsToPrune(metas, latestRev, policy, now)
+	for start := 0; start < len(ids); start += 500 {
+		end := min(start+500, len(ids))
+		result := s.db.Where("id IN ?", ids[start:end]).Delete(&model.NoteRevision{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
+
+// revisionsToPrune 从保留期外的版本中选出要删除的：每篇笔记每天只保留当天最后一个版本，
+// 超过 KeepDailyDays 的全部删除，latestRev 中记录的各笔记最新版本不删除
+func revisionsToPrune(metas []revisionMeta, latestRev map[uint]int, policy config.RevisionConfig, now time.Time) []uint {
+	sort.Slice(metas, func(i, j int) bool {
+		if metas[i].NoteID != metas[j].NoteID {
+			return metas[i].NoteID < metas[j].NoteID
+		}
+		return metas[i].Revision > metas[j].Revision
+	})
+
+	dailyCutoff := now.AddDate(0, 0, -policy.KeepDailyDays)
+	var ids []uint
+	keptDays := make(map[string]bool)
+	for i, m := range metas {
+		if i == 0 || metas[i-1].NoteID != m.NoteID {
+			clear(keptDays)
+		}
+		if latestRev[m.NoteID] == m.Revision {
+			keptDays[m.CreatedAt.Local().Format("2006-01-02")] = true
+			continue
+		}
+		if policy.KeepDailyDays > 0 && m.CreatedAt.Before(dailyCutoff) {
+			ids = append(ids, m.ID)
+			continue
+		}
+		day := m.CreatedAt.Local().Format("2006-01-02")
+		if keptDays[day] {
+			ids = append(ids, m.ID)
+			continue
+		}
+		keptDays[day] = true
+	}
+	return ids
+}
+
+// RunRevisionPruning 定期按保留策略清理笔记历史版本，直到ctx取消
+func (s *NoteService) RunRevisionPruning(ctx context.Context, policy config.RevisionConfig, interval time.Duration) {
+	prune := func() {
+		n, err := s.PruneRevisions(policy, time.Now())
+		if err != nil {
+			s.log.Error("Failed to prune note revisions", "error", err)
+			return
+		}
+		if n > 0 {
+			s.log.Info("Pruned note revisions", "count", n)
+		}
+	}
+
+	prune()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			prune()
+		}
+	}
 }
@@ -0,0 +1,228 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/testutil"
+)
+
+func TestNoteRevisionsDiffAndRestore(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	note := model.Note{UserID: 1, Title: "plan", Content: "a\nb\nc"}
+	if err := services.Notes.Create(&note); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Notes.Update(note.ID, 1, &model.Note{Title: "plan", Content: "a\nB\nc\nd"}); err != nil {
+		t.Fatal(err)
+	}
+	// 内容没有变化的保存不产生新版本
+	if err := services.Notes.Update(note.ID, 1, &model.Note{Title: "plan", Content: "a\nB\nc\nd"}); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := services.Notes.Revisions(note.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[0].AuthorName != "admin" || revisions[0].Content != "" {
+		t.Fatalf("unexpected revisions: %+v", revisions)
+	}
+
+	diff, err := services.Notes.DiffRevisions(note.ID, 1, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Added != 2 || diff.Removed != 1 {
+		t.Errorf("diff +%d -%d, want +2 -1: %+v", diff.Added, diff.Removed, diff.Lines)
+	}
+
+	restored, err := services.Notes.RestoreRevision(note.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Content != "a\nb\nc" {
+		t.Errorf("restored content = %q", restored.Content)
+	}
+	if revisions, _ := services.Notes.Revisions(note.ID, 1); len(revisions) != 3 {
+		t.Errorf("restore should be recorded as a new revision, got %d", len(revisions))
+	}
+
+	if _, err := services.Notes.Revision(note.ID, 1, 9); !errors.Is(err, common.ErrRevisionNotFound) {
+		t.Errorf("missing revision: got %v", err)
+	}
+	if _, err := services.Notes.Revisions(note.ID, 2); err == nil {
+		t.Error("other users must not list revisions")
+	}
+	if _, err := services.Notes.RestoreRevision(note.ID, 2, 1); err == nil {
+		t.Error("other users must not restore revisions")
+	}
+}
+
+func TestNoteRevisionBaselineForExistingNote(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	// 迁移前创建的笔记没有任何版本
+	note := model.Note{UserID: 1, Title: "old", Content: "before"}
+	mustCreate(t, services.DB, &note)
+	if err := services.Notes.Update(note.ID, 1, &model.Note{Title: "old", Content: "after"}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := services.Notes.Revision(note.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Content != "before" {
+		t.Errorf("baseline revision content = %q", first.Content)
+	}
+}
+
+func TestPruneRevisions(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	now := time.Date(2026, 5, 20, 12, 0, 0, 0, time.Local)
+
+	note := model.Note{UserID: 1, Title: "journal"}
+	mustCreate(t, services.DB, &note)
+	at := []time.Time{
+		now.AddDate(0, 0, -100),                // 超过每日保留期
+		now.AddDate(0, 0, -10).Add(-time.Hour), // 同一天的两个版本只保留较新的
+		now.AddDate(0, 0, -10),
+		now.AddDate(0, 0, -9),
+		now.AddDate(0, 0, -1).Add(-time.Hour), // 保留期内全部保留
+		now.AddDate(0, 0, -1),
+	}
+	for i, ts := range at {
+		mustCreate(t, services.DB, &model.NoteRevision{NoteID: note.ID, Revision: i + 1, AuthorID: 1, Title: "journal", CreatedAt: ts})
+	}
+	// 只有一个很旧版本的笔记，最新版本始终保留
+	other := model.Note{UserID: 1, Title: "archived"}
+	mustCreate(t, services.DB, &other)
+	mustCreate(t, services.DB, &model.NoteRevision{NoteID: other.ID, Revision: 1, AuthorID: 1, Title: "archived", CreatedAt: now.AddDate(-1, 0, 0)})
+	// 笔记已不存在的版本全部删除
+	mustCreate(t, services.DB, &model.NoteRevision{NoteID: other.ID + 100, Revision: 1, AuthorID: 1, Title: "deleted", CreatedAt: now})
+
+	n, err := services.Notes.PruneRevisions(config.RevisionConfig{KeepAllDays: 7, KeepDailyDays: 90}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("pruned %d revisions, want 3", n)
+	}
+
+	var kept []int
+	services.DB.Model(&model.NoteRevision{}).Where("note_id = ?", note.ID).Order("revision").Pluck("revision", &kept)
+	want := []int{3, 4, 5, 6}
+	if len(kept) != len(want) {
+		t.Fatalf("kept revisions %v, want %v", kept, want)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Fatalf("kept revisions %v, want %v", kept, want)
+		}
+	}
+	if _, err := services.Notes.Revision(other.ID, 1, 1); err != nil {
+		t.Errorf("latest revision of a note must be kept: %v", err)
+	}
+}
+
+func TestDeleteNoteRemovesRevisionsAndLinks(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	note := model.Note{UserID: 1, Title: "secret", Content: "links to [[other]]"}
+	if err := services.Notes.Create(&note); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Notes.Update(note.ID, 1, &model.Note{Title: "secret", Content: "still [[other]]", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Notes.Delete(note.ID, 2); !errors.Is(err, common.ErrResourceNotFound) {
+		t.Fatalf("other users must not delete the note: %v", err)
+	}
+	if err := services.Notes.Delete(note.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var revisions, links int64
+	services.DB.Model(&model.NoteRevision{}).Where("note_id = ?", note.ID).Count(&revisions)
+	services.DB.Model(&model.NoteLink{}).Where("source_id = ?", note.ID).Count(&links)
+	if revisions != 0 || links != 0 {
+		t.Errorf("deleted note left %d revisions and %d links", revisions, links)
+	}
+}
+
+func TestNoteLinksBacklinksAndRename(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	create := func(title, content string) *model.Note {
+		t.Helper()
+		n := &model.Note{UserID: 1, Title: title, Content: content}
+		if err := services.Notes.Create(n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	target := create("Project Plan", "")
+	a := create("Monday", "Discussed [[project plan#Goals|goals]] and [[Someday]]")
+	b := create("Tuesday", "Follow up on [[Project Plan]]")
+	create("Unlinked", "") // 没有任何链接的笔记
+
+	backlinks, err := services.Notes.Backlinks(target.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 2 {
+		t.Fatalf("backlinks = %+v", backlinks)
+	}
+
+	graph, err := services.Notes.Graph(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Nodes) != 4 || len(graph.Edges) != 2 || len(graph.Unresolved) != 1 || graph.Unresolved[0].Title != "Someday" {
+		t.Errorf("unexpected graph: %+v", graph)
+	}
+
+	// 新建被引用的笔记后，失效链接自动解析
+	create("someday", "")
+	if graph, _ := services.Notes.Graph(1); len(graph.Edges) != 3 || len(graph.Unresolved) != 0 {
+		t.Errorf("link should resolve to the new note: %+v", graph)
+	}
+
+	// 改名并改写入链
+	if err := services.Notes.Update(target.ID, 1, &model.Note{Title: "Roadmap", RewriteLinks: true}); err != nil {
+		t.Fatal(err)
+	}
+	updatedA, _ := services.Notes.GetByID(a.ID, 1)
+	if updatedA.Content != "Discussed [[Roadmap#Goals|goals]] and [[Someday]]" || updatedA.Version != 2 {
+		t.Errorf("link not rewritten: %+v", updatedA)
+	}
+	if backlinks, _ := services.Notes.Backlinks(target.ID, 1); len(backlinks) != 2 {
+		t.Errorf("backlinks after rename = %+v", backlinks)
+	}
+
+	// 不改写时旧链接失效
+	if err := services.Notes.Update(target.ID, 1, &model.Note{Title: "Roadmap 2026"}); err != nil {
+		t.Fatal(err)
+	}
+	if backlinks, _ := services.Notes.Backlinks(target.ID, 1); len(backlinks) != 0 {
+		t.Errorf("backlinks without rewrite = %+v", backlinks)
+	}
+	updatedB, _ := services.Notes.GetByID(b.ID, 1)
+	if updatedB.Content != "Follow up on [[Roadmap]]" {
+		t.Errorf("content changed without rewrite: %q", updatedB.Content)
+	}
+
+	// 其他用户看不到链接关系
+	if _, err := services.Notes.Backlinks(target.ID, 2); err == nil {
+		t.Error("other users must not read backlinks")
+	}
+	if graph, _ := services.Notes.Graph(2); len(graph.Nodes) != 0 {
+		t.Errorf("other user's graph = %+v", graph)
+	}
+}
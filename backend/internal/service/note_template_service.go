@@ -0,0 +1,259 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// DailyNoteTags 没有日记模板时日记笔记使用的标签
+const DailyNoteTags = "daily"
+
+// defaultDailyTemplate 用户没有设置日记模板时使用的模板
+var defaultDailyTemplate = model.NoteTemplate{
+	Title:   "{{date}}",
+	Content: "# {{date}} {{weekday}}\n\n## 今日日程\n\n{{today_events}}\n\n## 待办\n\n{{open_tasks}}\n\n## 笔记\n\n",
+	Tags:    DailyNoteTags,
+}
+
+// placeholderRe 模板中的 {{name}} 占位符，名称两侧允许空格
+var placeholderRe = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+type NoteTemplateService struct {
+	*BaseService[model.NoteTemplate]
+	notes  *NoteService
+	tasks  *TaskService
+	events *EventService
+}
+
+// NewNoteTemplateService 创建笔记模板服务，占位符的内容取自任务和事件服务
+func NewNoteTemplateService(db *gorm.DB, notes *NoteService, tasks *TaskService, events *EventService) *NoteTemplateService {
+	return &NoteTemplateService{
+		BaseService: NewBaseService[model.NoteTemplate](db, CRUDOptions{
+			Resource: constants.ResourceNoteTemplate,
+			Order:    "is_daily DESC, name ASC",
+			Fields:   []string{"name", "title", "content", "tags", "is_daily"},
+		}),
+		notes:  notes,
+		tasks:  tasks,
+		events: events,
+	}
+}
+
+// Create 创建模板。设为日记模板时取消该用户其他模板的日记标记
+func (s *NoteTemplateService) Create(tpl *model.NoteTemplate) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tpl).Error; err != nil {
+			return err
+		}
+		return clearOtherDailyTemplates(tx, tpl)
+	})
+}
+
+// Update 更新模板。设为日记模板时取消该用户其他模板的日记标记
+func (s *NoteTemplateService) Update(id, userID uint, tpl *model.NoteTemplate) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		templates := NewNoteTemplateService(tx, s.notes, s.tasks, s.events)
+		if err := templates.BaseService.Update(id, userID, tpl); err != nil {
+			return err
+		}
+		tpl.ID, tpl.UserID = id, userID
+		return clearOtherDailyTemplates(tx, tpl)
+	})
+}
+
+// clearOtherDailyTemplates 保证每个用户最多只有一个日记模板
+func clearOtherDailyTemplates(tx *gorm.DB, tpl *model.NoteTemplate) error {
+	if !tpl.IsDaily {
+		return nil
+	}
+	return tx.Model(&model.NoteTemplate{}).
+		Where("user_id = ? AND id <> ? AND is_daily = ?", tpl.UserID, tpl.ID, true).
+		Update("is_daily", false).Error
+}
+
+// Expand 把文本中的占位符替换为 date 当天的内容，未知的占位符保持原样。
+// 支持 {{date}}、{{weekday}}、{{time}}、{{open_tasks}} 和 {{today_events}}
+func (s *NoteTemplateService) Expand(userID uint, text string, date time.Time) (string, error) {
+	var firstErr error
+	values := make(map[string]string)
+	value := func(name string) (string, bool) {
+		if v, ok := values[name]; ok {
+			return v, true
+		}
+		var v string
+		var err error
+		switch name {
+		case "date":
+			v = date.Format(time.DateOnly)
+		case "weekday":
+			v = date.Weekday().String()
+		case "time":
+			v = time.Now().Format("15:04")
+		case "open_tasks":
+			v, err = s.openTasksMarkdown(userID)
+		case "today_events":
+			v, err = s.eventsMarkdown(userID, date)
+		default:
+			return "", false
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		values[name] = v
+		return v, true
+	}
+
+	expanded := placeholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholderRe.FindStringSubmatch(match)[1]
+		if v, ok := value(name); ok {
+			return v
+		}
+		return match
+	})
+	return expanded, firstErr
+}
+
+// openTasksMarkdown 把未完成的任务渲染为Markdown任务列表
+func (s *NoteTemplateService) openTasksMarkdown(userID uint) (string, error) {
+	tasks, err := s.tasks.Open(userID)
+	if err != nil {
+		return "", err
+	}
+	if len(tasks) == 0 {
+		return "- 无", nil
+	}
+	lines := make([]string, len(tasks))
+	for i, task := range tasks {
+		line := "- [ ] " + task.Title
+		if task.DueDate != nil {
+			line += " (截止 " + task.DueDate.Format(time.DateOnly) + ")"
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// eventsMarkdown 把某天的事件渲染为Markdown列表，有开始时间的在前面标出时间
+func (s *NoteTemplateService) eventsMarkdown(userID uint, date time.Time) (string, error) {
+	events, err := s.events.OnDate(userID, date.Format(time.DateOnly))
+	if err != nil {
+		return "", err
+	}
+	if len(events) == 0 {
+		return "- 无", nil
+	}
+	lines := make([]string, len(events))
+	for i, event := range events {
+		line := "- "
+		if event.StartTime != "" {
+			line += event.StartTime + " "
+		}
+		lines[i] = line + event.Title
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// newNoteFrom 按模板生成 date 当天的笔记(未保存)，title 非空时代替模板标题
+func (s *NoteTemplateService) newNoteFrom(tpl *model.NoteTemplate, userID uint, date time.Time, title string) (*model.Note, error) {
+	if title == "" {
+		title = tpl.Title
+	}
+	if title == "" {
+		title = tpl.Name
+	}
+	title, err := s.Expand(userID, title, date)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.Expand(userID, tpl.Content, date)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Note{UserID: userID, Title: title, Content: content, Tags: tpl.Tags}, nil
+}
+
+// CreateNote 从模板创建 date 当天的笔记，title 非空时代替模板标题
+func (s *NoteTemplateService) CreateNote(templateID, userID uint, date time.Time, title string) (*model.Note, error) {
+	tpl, err := s.GetByID(templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.newNoteFrom(tpl, userID, date, title)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notes.Create(note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Daily 返回用户某天的日记笔记，不存在时用日记模板(没有则用默认模板)创建，created 表示是否新建。
+// 同一天重复调用返回同一篇笔记；之前删除的日记不会恢复，而是重新创建
+func (s *NoteTemplateService) Daily(userID uint, date time.Time) (note *model.Note, created bool, err error) {
+	day := date.Format(time.DateOnly)
+	if note, err := s.findDaily(userID, day); err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return note, false, err
+	}
+
+	// 已删除的日记仍占用唯一索引，释放日期后再新建
+	if err := s.db.Unscoped().Model(&model.Note{}).
+		Where("user_id = ? AND journal_date = ? AND deleted_at IS NOT NULL", userID, day).
+		Update("journal_date", nil).Error; err != nil {
+		return nil, false, err
+	}
+
+	tpl := defaultDailyTemplate
+	var custom model.NoteTemplate
+	err = s.db.Where("user_id = ? AND is_daily = ?", userID, true).First(&custom).Error
+	switch {
+	case err == nil:
+		tpl = custom
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, false, err
+	}
+
+	note, err = s.newNoteFrom(&tpl, userID, date, "")
+	if err != nil {
+		return nil, false, err
+	}
+	note.JournalDate = &day
+	if err := s.notes.Create(note); err != nil {
+		// 并发请求已经创建了当天的日记
+		if existing, findErr := s.findDaily(userID, day); findErr == nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+	return note, true, nil
+}
+
+func (s *NoteTemplateService) findDaily(userID uint, day string) (*model.Note, error) {
+	var note model.Note
+	if err := s.db.Where("user_id = ? AND journal_date = ?", userID, day).First(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// ParseNoteDate 解析 YYYY-MM-DD 格式的日期(本地时区)，"today" 表示今天，空字符串同today
+func ParseNoteDate(value string) (time.Time, error) {
+	if value == "" || value == "today" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+	date, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date must be YYYY-MM-DD or today", common.ErrInvalidInput)
+	}
+	return date, nil
+}
@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func TestCreateNoteFromTemplate(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	due := time.Date(2026, 10, 25, 0, 0, 0, 0, time.Local)
+	for _, task := range []*model.Task{
+		{UserID: 1, Title: "later"},
+		{UserID: 1, Title: "soon", DueDate: &due},
+		{UserID: 1, Title: "finished", Status: "completed"},
+	} {
+		if err := services.Tasks.Create(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, event := range []*model.Event{
+		{UserID: 1, Title: "Standup", Date: "2026-10-19", StartTime: "09:30"},
+		{UserID: 1, Title: "Tomorrow", Date: "2026-10-20"},
+	} {
+		if err := services.Events.Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tpl := model.NoteTemplate{
+		UserID:  1,
+		Name:    "standup",
+		Title:   "Standup {{date}}",
+		Content: "{{weekday}}\n{{ open_tasks }}\n{{today_events}}\n{{unknown}}",
+		Tags:    "work",
+	}
+	if err := services.Templates.Create(&tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	date, _ := service.ParseNoteDate("2026-10-19")
+	note, err := services.Templates.CreateNote(tpl.ID, 1, date, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Monday\n- [ ] soon (截止 2026-10-25)\n- [ ] later\n- 09:30 Standup\n{{unknown}}"
+	if note.Title != "Standup 2026-10-19" || note.Content != want || note.Tags != "work" {
+		t.Fatalf("unexpected note: %q %q %q", note.Title, note.Content, note.Tags)
+	}
+	if note.JournalDate != nil {
+		t.Fatalf("template notes are not journal notes: %v", *note.JournalDate)
+	}
+
+	if _, err := services.Templates.CreateNote(tpl.ID, 2, date, ""); err == nil {
+		t.Fatal("other users must not use the template")
+	}
+	if _, err := service.ParseNoteDate("19/10/2026"); !errors.Is(err, common.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestDailyNote(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	date, _ := service.ParseNoteDate("2026-10-19")
+
+	first, created, err := services.Templates.Daily(1, date)
+	if err != nil || !created {
+		t.Fatalf("Daily = %v, %v", created, err)
+	}
+	if first.Title != "2026-10-19" || first.Tags != service.DailyNoteTags || !strings.HasPrefix(first.Content, "# 2026-10-19 Monday") {
+		t.Fatalf("unexpected default daily note: %+v", first)
+	}
+
+	again, created, err := services.Templates.Daily(1, date)
+	if err != nil || created || again.ID != first.ID {
+		t.Fatalf("Daily should return the same note, got %+v created=%v err=%v", again, created, err)
+	}
+	if other, _, _ := services.Templates.Daily(2, date); other.ID == first.ID {
+		t.Fatal("daily notes are per user")
+	}
+
+	// 设置日记模板后，删除的日记重新按新模板创建；只保留一个日记模板
+	old := model.NoteTemplate{UserID: 1, Name: "old", Content: "old", IsDaily: true}
+	tpl := model.NoteTemplate{UserID: 1, Name: "journal", Title: "Journal {{date}}", Content: "mood:", IsDaily: true}
+	for _, tp := range []*model.NoteTemplate{&old, &tpl} {
+		if err := services.Templates.Create(tp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stored, _ := services.Templates.GetByID(old.ID, 1); stored.IsDaily {
+		t.Fatal("only one template can be the daily template")
+	}
+	if err := services.Notes.Delete(first.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	recreated, created, err := services.Templates.Daily(1, date)
+	if err != nil || !created || recreated.ID == first.ID {
+		t.Fatalf("deleted daily note should be recreated: %+v created=%v err=%v", recreated, created, err)
+	}
+	if recreated.Title != "Journal 2026-10-19" || recreated.Content != "mood:" {
+		t.Fatalf("daily template not used: %+v", recreated)
+	}
+}
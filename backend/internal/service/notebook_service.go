@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type NotebookService struct {
+	*BaseService[model.Notebook]
+}
+
+// NotebookNode 笔记本树中的一个节点
+type NotebookNode struct {
+	model.Notebook
+	NoteCount int64           `json:"note_count"` // 直接位于该笔记本中、未归档的笔记数
+	Children  []*NotebookNode `json:"children"`
+}
+
+// NewNotebookService 创建笔记本服务。更新只修改名称和图标，移动使用 Move
+func NewNotebookService(db *gorm.DB) *NotebookService {
+	return &NotebookService{
+		BaseService: NewBaseService[model.Notebook](db, CRUDOptions{
+			Resource: constants.ResourceNotebook,
+			Order:    "sort_order ASC, name ASC",
+			Fields:   []string{"name", "icon"},
+		}),
+	}
+}
+
+// Create 创建笔记本，父笔记本必须属于同一用户
+func (s *NotebookService) Create(notebook *model.Notebook) error {
+	if err := validateNotebook(notebook); err != nil {
+		return err
+	}
+	if err := checkNotebook(s.db, notebook.UserID, notebook.ParentID); err != nil {
+		return err
+	}
+	return s.BaseService.Create(notebook)
+}
+
+// Update 修改笔记本的名称和图标
+func (s *NotebookService) Update(id, userID uint, notebook *model.Notebook) error {
+	if err := validateNotebook(notebook); err != nil {
+		return err
+	}
+	return s.BaseService.Update(id, userID, notebook)
+}
+
+func validateNotebook(notebook *model.Notebook) error {
+	notebook.Name = strings.TrimSpace(notebook.Name)
+	if notebook.Name == "" || utf8.RuneCountInString(notebook.Name) > 100 {
+		return fmt.Errorf("%w: notebook name must be 1-100 characters", common.ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(notebook.Icon) > 50 {
+		return fmt.Errorf("%w: notebook icon must be at most 50 characters", common.ErrInvalidInput)
+	}
+	return nil
+}
+
+// Tree 以树的形式返回用户的全部笔记本，同一层级按 sort_order、名称排列
+func (s *NotebookService) Tree(userID uint) ([]*NotebookNode, error) {
+	notebooks, err := s.GetAll(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		NotebookID uint
+		Count      int64
+	}
+	err = s.db.Model(&model.Note{}).
+		Select("notebook_id, COUNT(*) AS count").
+		Where("user_id = ? AND notebook_id IS NOT NULL AND is_archived = ?", userID, false).
+		Group("notebook_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*NotebookNode, len(notebooks))
+	for i := range notebooks {
+		nodes[notebooks[i].ID] = &NotebookNode{Notebook: notebooks[i], Children: []*NotebookNode{}}
+	}
+	for _, c := range counts {
+		if node, ok := nodes[c.NotebookID]; ok {
+			node.NoteCount = c.Count
+		}
+	}
+
+	roots := []*NotebookNode{}
+	for i := range notebooks {
+		node := nodes[notebooks[i].ID]
+		if parent, ok := parentNode(nodes, node.ParentID); ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// parentNode 返回父节点；没有父笔记本或父笔记本已不存在时返回false，节点作为顶层显示
+func parentNode(nodes map[uint]*NotebookNode, parentID *uint) (*NotebookNode, bool) {
+	if parentID == nil {
+		return nil, false
+	}
+	parent, ok := nodes[*parentID]
+	return parent, ok
+}
+
+// Move 把笔记本移动到 parentID 下(为空时移到顶层)并设置排序位置，
+// 不能移动到自己或自己的子笔记本下
+func (s *NotebookService) Move(id, userID uint, parentID *uint, sortOrder int) (*model.Notebook, error) {
+	var notebook model.Notebook
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&notebook).Error; err != nil {
+			return err
+		}
+		if err := checkNotebook(tx, userID, parentID); err != nil {
+			return err
+		}
+		if parentID != nil {
+			descendants, err := notebookDescendants(tx, userID, id)
+			if err != nil {
+				return err
+			}
+			for _, d := range descendants {
+				if d == *parentID {
+					return fmt.Errorf("%w: cannot move a notebook into itself or its sub-notebooks", common.ErrInvalidInput)
+				}
+			}
+		}
+		notebook.ParentID, notebook.SortOrder = parentID, sortOrder
+		return tx.Model(&notebook).Updates(map[string]interface{}{"parent_id": parentID, "sort_order": sortOrder}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &notebook, nil
+}
+
+// Delete 删除笔记本，其中的子笔记本和笔记移到上一级(顶层笔记本的移出笔记本)
+func (s *NotebookService) Delete(id, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var notebook model.Notebook
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&notebook).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return common.ErrResourceNotFound
+			}
+			return err
+		}
+		if err := tx.Model(&model.Notebook{}).Where("parent_id = ?", id).
+			Update("parent_id", notebook.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Note{}).Where("notebook_id = ?", id).
+			UpdateColumn("notebook_id", notebook.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&notebook).Error
+	})
+}
+
+// checkNotebook 确认笔记本存在且属于该用户，id 为空表示不属于任何笔记本，总是有效
+func checkNotebook(tx *gorm.DB, userID uint, id *uint) error {
+	if id == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&model.Notebook{}).Where("id = ? AND user_id = ?", *id, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: notebook %d not found", common.ErrInvalidInput, *id)
+	}
+	return nil
+}
+
+// notebookDescendants 返回笔记本自身及其全部子孙笔记本的ID
+func notebookDescendants(tx *gorm.DB, userID, rootID uint) ([]uint, error) {
+	var notebooks []model.Notebook
+	if err := tx.Select("id, parent_id").Where("user_id = ?", userID).Find(&notebooks).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint)
+	for _, nb := range notebooks {
+		if nb.ParentID != nil {
+			children[*nb.ParentID] = append(children[*nb.ParentID], nb.ID)
+		}
+	}
+
+	ids := []uint{rootID}
+	seen := map[uint]bool{rootID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
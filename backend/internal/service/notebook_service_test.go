@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func TestNotebookTreeAndMove(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	notebooks := services.Notebooks
+
+	work := model.Notebook{UserID: 1, Name: "Work", Icon: "💼", SortOrder: 2}
+	home := model.Notebook{UserID: 1, Name: "Home", SortOrder: 1}
+	for _, nb := range []*model.Notebook{&work, &home} {
+		if err := notebooks.Create(nb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	projects := model.Notebook{UserID: 1, Name: "Projects", ParentID: &work.ID}
+	if err := notebooks.Create(&projects); err != nil {
+		t.Fatal(err)
+	}
+	if err := notebooks.Create(&model.Notebook{UserID: 2, Name: "Stolen", ParentID: &work.ID}); !errors.Is(err, common.ErrInvalidInput) {
+		t.Fatalf("parent must belong to the same user, got %v", err)
+	}
+	if err := services.Notes.Create(&model.Note{UserID: 1, Title: "Plan", NotebookID: &projects.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := notebooks.Tree(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || tree[0].Name != "Home" || tree[1].Name != "Work" ||
+		len(tree[1].Children) != 1 || tree[1].Children[0].Name != "Projects" || tree[1].Children[0].NoteCount != 1 {
+		t.Fatalf("unexpected tree: %+v", tree)
+	}
+
+	if _, err := notebooks.Move(work.ID, 1, &projects.ID, 0); !errors.Is(err, common.ErrInvalidInput) {
+		t.Fatalf("moving a notebook into its child must fail, got %v", err)
+	}
+	moved, err := notebooks.Move(projects.ID, 1, &home.ID, 3)
+	if err != nil || *moved.ParentID != home.ID || moved.SortOrder != 3 {
+		t.Fatalf("Move = %+v, %v", moved, err)
+	}
+
+	// 删除笔记本后，其中的笔记移到上一级
+	if err := notebooks.Delete(projects.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	notes, _, err := services.Notes.List(1, service.NoteQuery{NotebookID: &home.ID})
+	if err != nil || len(notes) != 1 || notes[0].Title != "Plan" {
+		t.Fatalf("notes of deleted notebook should move to its parent: %+v %v", notes, err)
+	}
+}
+
+func TestListNotes(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	notes := services.Notes
+
+	parent := model.Notebook{UserID: 1, Name: "Parent"}
+	if err := services.Notebooks.Create(&parent); err != nil {
+		t.Fatal(err)
+	}
+	child := model.Notebook{UserID: 1, Name: "Child", ParentID: &parent.ID}
+	if err := services.Notebooks.Create(&child); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]uint, 5)
+	for i := range ids {
+		note := model.Note{UserID: 1, Title: string(rune('a' + i))}
+		if err := notes.Create(&note); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = note.ID
+	}
+	if _, err := notes.Move(ids[:2], 1, &parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notes.Move(ids[2:3], 1, &child.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notes.Move(ids[3:4], 2, &child.ID); err == nil {
+		t.Fatal("other users cannot move the note")
+	}
+	if _, err := notes.SetArchived(ids[4], 1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	active := false
+	count := func(q service.NoteQuery) int64 {
+		t.Helper()
+		q.Archived = &active
+		_, total, err := notes.List(1, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+	if n := count(service.NoteQuery{}); n != 4 {
+		t.Errorf("archived notes should be hidden, got %d", n)
+	}
+	if n := count(service.NoteQuery{NotebookID: &parent.ID}); n != 2 {
+		t.Errorf("notebook filter: %d", n)
+	}
+	if n := count(service.NoteQuery{NotebookID: &parent.ID, Recursive: true}); n != 3 {
+		t.Errorf("recursive notebook filter: %d", n)
+	}
+	if n := count(service.NoteQuery{Unfiled: true}); n != 1 {
+		t.Errorf("unfiled filter: %d", n)
+	}
+
+	if err := notes.Reorder([]uint{ids[3], ids[1], ids[0]}, 1); err != nil {
+		t.Fatal(err)
+	}
+	page, total, err := notes.List(1, service.NoteQuery{Archived: &active, Sort: service.NoteSortManual, Page: 2, PageSize: 2})
+	if err != nil || total != 4 || len(page) != 2 || page[0].ID != ids[1] || page[1].ID != ids[0] {
+		t.Fatalf("manual order page 2: %+v total=%d err=%v", page, total, err)
+	}
+}
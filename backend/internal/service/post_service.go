@@ -0,0 +1,61 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/markdown"
+	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type PostService struct {
+	*BaseService[model.Post]
+}
+
+func NewPostService(db *gorm.DB) *PostService {
+	return &PostService{
+		BaseService: NewBaseService[model.Post](db, CRUDOptions{
+			Resource:  constants.ResourcePost,
+			Shareable: true,
+			Order:     "created_at DESC",
+			Fields:    []string{"title", "content", "tags", "excerpt", "cover"},
+		}),
+	}
+}
+
+// excerptOf 取渲染后正文的前100个字符作为摘要，不含Markdown标记、标题和代码块
+func excerptOf(content string) string {
+	return markdown.Render(content).Excerpt(100)
+}
+
+// Create 创建文章，未填写的摘要、封面和作者使用默认值
+func (s *PostService) Create(post *model.Post) error {
+	if post.Excerpt == "" {
+		post.Excerpt = excerptOf(post.Content)
+	}
+	if post.Cover == "" {
+		post.Cover = "https://picsum.photos/seed/" + strconv.FormatInt(time.Now().Unix(), 10) + "/300/200"
+	}
+	if post.Author == "" {
+		post.Author = "Admin" // 简化处理，实际可取当前用户名
+	}
+	return s.BaseService.Create(post)
+}
+
+// Update 更新文章，未填写摘要时根据正文重新生成，未填写封面时保留原封面
+func (s *PostService) Update(id, userID uint, post *model.Post) error {
+	if post.Excerpt == "" {
+		post.Excerpt = excerptOf(post.Content)
+	}
+	if post.Cover == "" {
+		existing, err := s.GetEditable(id, userID)
+		if err != nil {
+			return err
+		}
+		post.Cover = existing.Cover
+	}
+	return s.BaseService.Update(id, userID, post)
+}
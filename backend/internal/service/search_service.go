@@ -0,0 +1,662 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// searchIndexVersion 分词规则版本，修改 utils.Tokenize 或 SearchContent 后加1，启动时会重建旧版本的文档
+const searchIndexVersion = 1
+
+const (
+	bm25K1          = 1.2
+	bm25B           = 0.75
+	titleWeight     = 3.0 // 标题中的词元按3倍计
+	prefixWeight    = 0.5 // 前缀匹配(如 go 匹配 golang)按一半计
+	snippetWidth    = 160
+	defaultPerGroup = 5
+	maxPerGroup     = 50
+	indexBatchSize  = 200
+)
+
+// SearchTypes 可搜索的资源类型，也是无检索词时分组的顺序
+var SearchTypes = []string{
+	constants.ResourceNote,
+	constants.ResourceTask,
+	constants.ResourceBookmark,
+	constants.ResourceEvent,
+	constants.ResourceCollection,
+	constants.ResourcePost,
+	constants.ResourceChat,
+	constants.ResourceFile,
+}
+
+// searchTypeAliases type: 过滤条件接受的写法
+var searchTypeAliases = map[string]string{
+	"note": constants.ResourceNote, "notes": constants.ResourceNote,
+	"task": constants.ResourceTask, "tasks": constants.ResourceTask, "todo": constants.ResourceTask, "todos": constants.ResourceTask,
+	"bookmark": constants.ResourceBookmark, "bookmarks": constants.ResourceBookmark,
+	"event": constants.ResourceEvent, "events": constants.ResourceEvent,
+	"collection": constants.ResourceCollection, "collections": constants.ResourceCollection,
+	"post": constants.ResourcePost, "posts": constants.ResourcePost, "blog": constants.ResourcePost,
+	"chat": constants.ResourceChat, "message": constants.ResourceChat, "messages": constants.ResourceChat,
+	"file": constants.ResourceFile, "files": constants.ResourceFile,
+}
+
+// searchSource 一类可搜索资源的读取方式
+type searchSource struct {
+	ids  func(db *gorm.DB) ([]uint, error)
+	load func(db *gorm.DB, ids []uint) ([]model.Searchable, error)
+}
+
+func sourceOf[T any, P interface {
+	*T
+	model.Searchable
+}]() searchSource {
+	return searchSource{
+		ids: func(db *gorm.DB) ([]uint, error) {
+			var ids []uint
+			err := db.Model(new(T)).Order("id").Pluck("id", &ids).Error
+			return ids, err
+		},
+		load: func(db *gorm.DB, ids []uint) ([]model.Searchable, error) {
+			var items []T
+			if err := db.Where("id IN ?", ids).Find(&items).Error; err != nil {
+				return nil, err
+			}
+			result := make([]model.Searchable, len(items))
+			for i := range items {
+				result[i] = P(&items[i])
+			}
+			return result, nil
+		},
+	}
+}
+
+var searchSources = map[string]searchSource{
+	constants.ResourceNote:       sourceOf[model.Note](),
+	constants.ResourceTask:       sourceOf[model.Task](),
+	constants.ResourceBookmark:   sourceOf[model.Bookmark](),
+	constants.ResourceEvent:      sourceOf[model.Event](),
+	constants.ResourceCollection: sourceOf[model.Collection](),
+	constants.ResourcePost:       sourceOf[model.Post](),
+	constants.ResourceChat:       sourceOf[model.ChatMessage](),
+	constants.ResourceFile:       sourceOf[model.File](),
+}
+
+// indexResource 重建资源的索引文档，资源不存在(或已删除)时移除其文档
+func indexResource(tx *gorm.DB, resourceType string, id uint) error {
+	src, ok := searchSources[resourceType]
+	if !ok {
+		return common.ErrInvalidResourceType
+	}
+	items, err := src.load(tx, []uint{id})
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return unindexResource(tx, resourceType, id)
+	}
+	return writeSearchDocument(tx, resourceType, items[0])
+}
+
+// indexResourcesByID 重建多条记录的索引文档，用于导入等直接写入记录的场景
+func indexResourcesByID(tx *gorm.DB, resourceType string, ids []uint) error {
+	for _, id := range ids {
+		if err := indexResource(tx, resourceType, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSearchDocument 写入资源的索引文档及其倒排记录
+func writeSearchDocument(tx *gorm.DB, resourceType string, item model.Searchable) error {
+	content := item.SearchContent()
+	titleTokens := utils.Tokenize(content.Title)
+	bodyTokens := utils.Tokenize(content.Body)
+
+	freqs := make(map[string]*model.SearchPosting)
+	posting := func(term string) *model.SearchPosting {
+		p, ok := freqs[term]
+		if !ok {
+			p = &model.SearchPosting{Term: term}
+			freqs[term] = p
+		}
+		return p
+	}
+	for _, term := range titleTokens {
+		posting(term).TitleFreq++
+	}
+	for _, term := range bodyTokens {
+		posting(term).BodyFreq++
+	}
+
+	var doc model.SearchDocument
+	err := tx.Where("resource_type = ? AND resource_id = ?", resourceType, item.GetID()).Take(&doc).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	doc.ResourceType = resourceType
+	doc.ResourceID = item.GetID()
+	doc.UserID = content.UserID
+	doc.Title = utils.TruncateRunes(content.Title, 255)
+	doc.Date = content.Date
+	doc.Length = len(titleTokens) + len(bodyTokens)
+	doc.IndexVersion = searchIndexVersion
+	if err := tx.Save(&doc).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("document_id = ?", doc.ID).Delete(&model.SearchPosting{}).Error; err != nil {
+		return err
+	}
+	if len(freqs) == 0 {
+		return nil
+	}
+
+	postings := make([]model.SearchPosting, 0, len(freqs))
+	for _, term := range slices.Sorted(maps.Keys(freqs)) {
+		p := freqs[term]
+		p.DocumentID = doc.ID
+		postings = append(postings, *p)
+	}
+	return tx.CreateInBatches(postings, 500).Error
+}
+
+// unindexResource 移除资源的索引文档
+func unindexResource(tx *gorm.DB, resourceType string, id uint) error {
+	docs := tx.Session(&gorm.Session{NewDB: true}).Model(&model.SearchDocument{}).
+		Select("id").
+		Where("resource_type = ? AND resource_id = ?", resourceType, id)
+	return deleteSearchDocuments(tx, docs)
+}
+
+// unindexUserResources 移除用户某类资源的全部索引文档
+func unindexUserResources(tx *gorm.DB, resourceType string, userID uint) error {
+	docs := tx.Session(&gorm.Session{NewDB: true}).Model(&model.SearchDocument{}).
+		Select("id").
+		Where("resource_type = ? AND user_id = ?", resourceType, userID)
+	return deleteSearchDocuments(tx, docs)
+}
+
+// deleteSearchDocuments 删除子查询 docs 选出的文档及其倒排记录
+func deleteSearchDocuments(tx *gorm.DB, docs *gorm.DB) error {
+	var ids []uint
+	if err := docs.Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("document_id IN ?", ids).Delete(&model.SearchPosting{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(&model.SearchDocument{}).Error
+}
+
+type SearchService struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewSearchService(db *gorm.DB, log logger.Logger) *SearchService {
+	return &SearchService{db: db, log: log}
+}
+
+// SearchQuery 解析后的搜索条件
+type SearchQuery struct {
+	Words  []string   // 自由文本中的词，用于生成摘要
+	Terms  []string   // 自由文本分词后的词元，全部命中的文档才会返回
+	Types  []string   // type: 资源类型
+	Tags   []string   // tag: 标签，需全部带有
+	Before *time.Time // before: 日期之前(不含当天)
+	After  *time.Time // after: 日期之后(不含当天)
+}
+
+// ParseSearchQuery 解析搜索字符串，支持 type:note、tag:go、before:2026-01-01、after:2025-12-31 过滤条件，
+// 其余部分作为检索词
+func ParseSearchQuery(q string) (*SearchQuery, error) {
+	query := &SearchQuery{}
+	var text []string
+	for _, field := range strings.Fields(q) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			text = append(text, field)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "type":
+			t, ok := searchTypeAliases[strings.ToLower(value)]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown type %q", common.ErrInvalidInput, value)
+			}
+			if !slices.Contains(query.Types, t) {
+				query.Types = append(query.Types, t)
+			}
+		case "tag":
+			query.Tags = append(query.Tags, value)
+		case "before", "after":
+			day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a date like 2026-01-01", common.ErrInvalidInput, key)
+			}
+			if strings.EqualFold(key, "before") {
+				query.Before = &day
+			} else {
+				next := day.AddDate(0, 0, 1)
+				query.After = &next
+			}
+		default:
+			text = append(text, field)
+		}
+	}
+
+	query.Words = text
+	seen := make(map[string]bool)
+	for _, term := range utils.Tokenize(strings.Join(text, " ")) {
+		if !seen[term] {
+			seen[term] = true
+			query.Terms = append(query.Terms, term)
+		}
+	}
+	return query, nil
+}
+
+// empty 没有任何检索词和过滤条件
+func (q *SearchQuery) empty() bool {
+	return len(q.Terms) == 0 && len(q.Types) == 0 && len(q.Tags) == 0 && q.Before == nil && q.After == nil
+}
+
+// scope 按过滤条件限定索引文档
+func (q *SearchQuery) scope(db *gorm.DB) *gorm.DB {
+	conn := db.Session(&gorm.Session{NewDB: true})
+	if len(q.Types) > 0 {
+		db = db.Where("search_documents.resource_type IN ?", q.Types)
+	}
+	if q.Before != nil {
+		db = db.Where("search_documents.date < ?", *q.Before)
+	}
+	if q.After != nil {
+		db = db.Where("search_documents.date >= ?", *q.After)
+	}
+	for _, tag := range q.Tags {
+		tagged := conn.Model(&model.Tagging{}).
+			Select("1").
+			Joins("JOIN tags ON tags.id = taggings.tag_id").
+			Where("taggings.resource_type = search_documents.resource_type AND taggings.resource_id = search_documents.resource_id").
+			Where("tags.name_key = ?", TagKey(tag))
+		db = db.Where("EXISTS (?)", tagged)
+	}
+	return db
+}
+
+// searchScope 限定为用户自己的文档以及分享给该用户的资源的文档
+func searchScope(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		conn := db.Session(&gorm.Session{NewDB: true})
+		shared := conn.Model(&model.Share{}).
+			Select("1").
+			Where("shares.resource_type = search_documents.resource_type AND shares.resource_id = search_documents.resource_id").
+			Where("shares.shared_with_id = ?", userID).
+			Where("shares.expires_at IS NULL OR shares.expires_at > ?", time.Now())
+		return db.Where(conn.Where("search_documents.user_id = ?", userID).Or("EXISTS (?)", shared))
+	}
+}
+
+// SearchResult 一条搜索结果
+type SearchResult struct {
+	Type    string    `json:"type"`
+	ID      uint      `json:"id"`
+	Title   string    `json:"title"`
+	Snippet string    `json:"snippet"`
+	Score   float64   `json:"score"`
+	Date    time.Time `json:"date"`
+}
+
+// SearchGroup 同一类资源的搜索结果，Total 为该类命中的总数
+type SearchGroup struct {
+	Type    string         `json:"type"`
+	Total   int            `json:"total"`
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResponse 按资源类型分组的搜索结果，分组按其最高得分排序
+type SearchResponse struct {
+	Query  string        `json:"query"`
+	Total  int           `json:"total"`
+	Groups []SearchGroup `json:"groups"`
+}
+
+// scoredDocument 命中的文档及其得分
+type scoredDocument struct {
+	model.SearchDocument
+	score float64
+}
+
+// Search 在用户可访问的全部资源中搜索，每组最多返回 perGroup 条结果。
+// 有检索词时按 BM25 排序；只有过滤条件时按日期倒序
+func (s *SearchService) Search(userID uint, q string, perGroup int) (*SearchResponse, error) {
+	query, err := ParseSearchQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if query.empty() {
+		return nil, fmt.Errorf("%w: search query is empty", common.ErrInvalidInput)
+	}
+	if perGroup <= 0 {
+		perGroup = defaultPerGroup
+	}
+	perGroup = min(perGroup, maxPerGroup)
+
+	var groups []SearchGroup
+	if len(query.Terms) > 0 {
+		groups, err = s.rankedGroups(userID, query, perGroup)
+	} else {
+		groups, err = s.recentGroups(userID, query, perGroup)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillSnippets(groups, query.Words); err != nil {
+		return nil, err
+	}
+
+	response := &SearchResponse{Query: q, Groups: groups}
+	for _, g := range groups {
+		response.Total += g.Total
+	}
+	return response, nil
+}
+
+// documents 返回限定在用户可访问范围和过滤条件内的文档查询
+func (s *SearchService) documents(userID uint, query *SearchQuery) *gorm.DB {
+	return s.db.Model(&model.SearchDocument{}).Scopes(searchScope(userID), query.scope)
+}
+
+// rankedGroups 按 BM25 为同时命中全部检索词元的文档打分
+func (s *SearchService) rankedGroups(userID uint, query *SearchQuery, perGroup int) ([]SearchGroup, error) {
+	var stats struct {
+		Count  int64
+		AvgLen float64
+	}
+	err := s.documents(userID, query).
+		Select("COUNT(*) AS count, COALESCE(AVG(length), 0) AS avg_len").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	if stats.Count == 0 {
+		return []SearchGroup{}, nil
+	}
+
+	// 每个词元在各文档中的加权词频，词元作为前缀匹配时降权
+	termFreqs := make([]map[uint]float64, len(query.Terms))
+	for i, term := range query.Terms {
+		var rows []struct {
+			DocumentID uint
+			Term       string
+			TitleFreq  int
+			BodyFreq   int
+		}
+		err := s.db.Model(&model.SearchPosting{}).
+			Select("document_id, term, title_freq, body_freq").
+			Where("term LIKE ?", term+"%").
+			Where("document_id IN (?)", s.documents(userID, query).Select("search_documents.id")).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		tf := make(map[uint]float64)
+		for _, r := range rows {
+			w := float64(r.BodyFreq) + titleWeight*float64(r.TitleFreq)
+			if r.Term != term {
+				w *= prefixWeight
+			}
+			tf[r.DocumentID] += w
+		}
+		termFreqs[i] = tf
+	}
+
+	var ids []uint
+	for id := range termFreqs[0] {
+		all := true
+		for _, tf := range termFreqs[1:] {
+			if _, ok := tf[id]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []SearchGroup{}, nil
+	}
+
+	docs, err := s.loadDocuments(ids)
+	if err != nil {
+		return nil, err
+	}
+	n := float64(stats.Count)
+	avgLen := math.Max(stats.AvgLen, 1)
+	for i := range docs {
+		norm := bm25K1 * (1 - bm25B + bm25B*float64(docs[i].Length)/avgLen)
+		for _, tf := range termFreqs {
+			df := float64(len(tf))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			f := tf[docs[i].ID]
+			docs[i].score += idf * f * (bm25K1 + 1) / (f + norm)
+		}
+		docs[i].score = math.Round(docs[i].score*1000) / 1000
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		if docs[i].score != docs[j].score {
+			return docs[i].score > docs[j].score
+		}
+		return docs[i].Date.After(docs[j].Date)
+	})
+
+	// 文档已按得分排序，分组出现的先后即为分组按最高得分的排序
+	var groups []SearchGroup
+	index := make(map[string]int)
+	for _, doc := range docs {
+		i, ok := index[doc.ResourceType]
+		if !ok {
+			i = len(groups)
+			index[doc.ResourceType] = i
+			groups = append(groups, SearchGroup{Type: doc.ResourceType, Results: []SearchResult{}})
+		}
+		groups[i].Total++
+		if len(groups[i].Results) < perGroup {
+			groups[i].Results = append(groups[i].Results, newSearchResult(doc))
+		}
+	}
+	return groups, nil
+}
+
+// recentGroups 只有过滤条件时，每类资源按日期倒序返回
+func (s *SearchService) recentGroups(userID uint, query *SearchQuery, perGroup int) ([]SearchGroup, error) {
+	var totals []struct {
+		ResourceType string
+		Count        int
+	}
+	err := s.documents(userID, query).
+		Select("resource_type, COUNT(*) AS count").
+		Group("resource_type").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	count := make(map[string]int, len(totals))
+	for _, t := range totals {
+		count[t.ResourceType] = t.Count
+	}
+
+	groups := []SearchGroup{}
+	for _, t := range SearchTypes {
+		if count[t] == 0 {
+			continue
+		}
+		var docs []model.SearchDocument
+		err := s.documents(userID, query).
+			Where("resource_type = ?", t).
+			Order("date DESC, id DESC").
+			Limit(perGroup).
+			Find(&docs).Error
+		if err != nil {
+			return nil, err
+		}
+		group := SearchGroup{Type: t, Total: count[t], Results: make([]SearchResult, 0, len(docs))}
+		for _, doc := range docs {
+			group.Results = append(group.Results, newSearchResult(scoredDocument{SearchDocument: doc}))
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func newSearchResult(doc scoredDocument) SearchResult {
+	return SearchResult{Type: doc.ResourceType, ID: doc.ResourceID, Title: doc.Title, Score: doc.score, Date: doc.Date}
+}
+
+// loadDocuments 分批读取文档
+func (s *SearchService) loadDocuments(ids []uint) ([]scoredDocument, error) {
+	docs := make([]scoredDocument, 0, len(ids))
+	for start := 0; start < len(ids); start += 500 {
+		var batch []model.SearchDocument
+		if err := s.db.Where("id IN ?", ids[start:min(start+500, len(ids))]).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, doc := range batch {
+			docs = append(docs, scoredDocument{SearchDocument: doc})
+		}
+	}
+	return docs, nil
+}
+
+// fillSnippets 读取结果对应的资源生成摘要；没有标题的结果(如聊天消息)用正文开头作为标题
+func (s *SearchService) fillSnippets(groups []SearchGroup, words []string) error {
+	for gi := range groups {
+		group := &groups[gi]
+		ids := make([]uint, len(group.Results))
+		for i, r := range group.Results {
+			ids[i] = r.ID
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		items, err := searchSources[group.Type].load(s.db, ids)
+		if err != nil {
+			return err
+		}
+		contents := make(map[uint]model.SearchContent, len(items))
+		for _, item := range items {
+			contents[item.GetID()] = item.SearchContent()
+		}
+		for i := range group.Results {
+			content, ok := contents[group.Results[i].ID]
+			if !ok {
+				continue
+			}
+			group.Results[i].Snippet = utils.Snippet(content.Body, words, snippetWidth)
+			if group.Results[i].Title == "" {
+				group.Results[i].Title = utils.Snippet(content.Body, nil, 60)
+			}
+		}
+	}
+	return nil
+}
+
+// EnsureIndex 为尚未索引或索引版本过旧的资源建立索引，并移除已删除资源的文档，返回处理的资源数
+func (s *SearchService) EnsureIndex(ctx context.Context) (int, error) {
+	total := 0
+	for _, resourceType := range SearchTypes {
+		src := searchSources[resourceType]
+		live, err := src.ids(s.db)
+		if err != nil {
+			return total, err
+		}
+		var indexed []struct {
+			ResourceID   uint
+			IndexVersion int
+		}
+		err = s.db.Model(&model.SearchDocument{}).
+			Select("resource_id, index_version").
+			Where("resource_type = ?", resourceType).
+			Scan(&indexed).Error
+		if err != nil {
+			return total, err
+		}
+
+		versions := make(map[uint]int, len(indexed))
+		for _, doc := range indexed {
+			versions[doc.ResourceID] = doc.IndexVersion
+		}
+		var pending []uint
+		for _, id := range live {
+			if v, ok := versions[id]; !ok || v < searchIndexVersion {
+				pending = append(pending, id)
+			}
+			delete(versions, id)
+		}
+		// 剩下的是资源已不存在的文档
+		for id := range versions {
+			if err := unindexResource(s.db, resourceType, id); err != nil {
+				return total, err
+			}
+			total++
+		}
+
+		for start := 0; start < len(pending); start += indexBatchSize {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+			batch := pending[start:min(start+indexBatchSize, len(pending))]
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				items, err := src.load(tx, batch)
+				if err != nil {
+					return err
+				}
+				for _, item := range items {
+					if err := writeSearchDocument(tx, resourceType, item); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return total, err
+			}
+			total += len(batch)
+		}
+	}
+	return total, nil
+}
+
+// RunIndexer 启动时补全搜索索引
+func (s *SearchService) RunIndexer(ctx context.Context) {
+	n, err := s.EnsureIndex(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		s.log.Error("Failed to build search index", "error", err)
+		return
+	}
+	if n > 0 {
+		s.log.Info("Search index updated", "resources", n)
+	}
+}
@@ -0,0 +1,145 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+// searchIDs 返回某类结果的资源ID，按排序先后
+func searchIDs(res *service.SearchResponse, resourceType string) []uint {
+	for _, g := range res.Groups {
+		if g.Type == resourceType {
+			ids := make([]uint, len(g.Results))
+			for i, r := range g.Results {
+				ids[i] = r.ID
+			}
+			return ids
+		}
+	}
+	return nil
+}
+
+func TestSearchAcrossResources(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	title := model.Note{UserID: 1, Title: "Golang tips", Content: "error handling patterns", Tags: "go"}
+	body := model.Note{UserID: 1, Title: "misc", Content: "a long note that mentions golang once among many other words here", Tags: "misc"}
+	for _, n := range []*model.Note{&title, &body} {
+		if err := services.Notes.Create(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	task := model.Task{UserID: 1, Title: "Learn golang generics"}
+	if err := services.Tasks.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	event := model.Event{UserID: 1, Title: "Golang meetup", Date: "2025-06-01"}
+	if err := services.Events.Create(&event); err != nil {
+		t.Fatal(err)
+	}
+	chat := model.ChatMessage{UserID: 1, Role: "user", Content: "怎么学习全文搜索和 golang"}
+	if err := services.Chat.Create(&chat); err != nil {
+		t.Fatal(err)
+	}
+	other := model.Note{UserID: 2, Title: "golang secrets"}
+	if err := services.Notes.Create(&other); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := services.Search.Search(1, "golang", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 5 {
+		t.Fatalf("expected 5 results, got %+v", res)
+	}
+	if ids := searchIDs(res, "note"); len(ids) != 2 || ids[0] != title.ID {
+		t.Fatalf("title match should rank first, got %v", ids)
+	}
+	for _, g := range res.Groups {
+		for _, r := range g.Results {
+			if r.Title == "" {
+				t.Fatalf("result without title: %+v", r)
+			}
+		}
+	}
+
+	// 前缀匹配与中文
+	if res, _ := services.Search.Search(1, "gola", 10); res.Total != 5 {
+		t.Fatalf("prefix search returned %d results", res.Total)
+	}
+	if res, _ := services.Search.Search(1, "全文搜索", 10); len(searchIDs(res, "chat")) != 1 {
+		t.Fatalf("CJK search failed: %+v", res)
+	}
+
+	// 过滤条件
+	res, err = services.Search.Search(1, "golang type:note tag:go", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 1 || searchIDs(res, "note")[0] != title.ID {
+		t.Fatalf("filtered search returned %+v", res)
+	}
+	res, err = services.Search.Search(1, "type:event before:2025-06-02 after:2025-05-31", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 1 || searchIDs(res, "event")[0] != event.ID {
+		t.Fatalf("date filter returned %+v", res)
+	}
+	if res, _ := services.Search.Search(1, "type:event before:2025-06-01", 10); res.Total != 0 {
+		t.Fatalf("before: should exclude the day itself, got %+v", res)
+	}
+	for _, q := range []string{"", "type:unknown", "before:yesterday"} {
+		if _, err := services.Search.Search(1, q, 10); !errors.Is(err, common.ErrInvalidInput) {
+			t.Errorf("Search(%q) error = %v, want ErrInvalidInput", q, err)
+		}
+	}
+
+	// 更新和删除同步到索引
+	if err := services.Tasks.Update(task.ID, 1, &model.Task{Title: "Learn rust"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Notes.Delete(body.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Chat.DeleteHistory(1); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := services.Search.Search(1, "golang", 10); res.Total != 2 {
+		t.Fatalf("expected index to follow updates, got %+v", res)
+	}
+	if res, _ := services.Search.Search(1, "rust", 10); len(searchIDs(res, "task")) != 1 {
+		t.Fatalf("updated task not found: %+v", res)
+	}
+}
+
+func TestSearchEnsureIndex(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	// 绕过服务直接写入的记录由 EnsureIndex 补上索引
+	note := model.Note{UserID: 1, Title: "imported", Content: "written directly", CreatedAt: time.Now()}
+	if err := services.DB.Create(&note).Error; err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := services.Search.Search(1, "directly", 10); res.Total != 0 {
+		t.Fatalf("note should not be indexed yet: %+v", res)
+	}
+	n, err := services.Search.EnsureIndex(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("EnsureIndex = %d, %v", n, err)
+	}
+	if res, _ := services.Search.Search(1, "directly", 10); res.Total != 1 {
+		t.Fatalf("note should be indexed: %+v", res)
+	}
+	if n, _ := services.Search.EnsureIndex(context.Background()); n != 0 {
+		t.Fatalf("second EnsureIndex should be a no-op, got %d", n)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"nexushub-personal/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// Services 应用使用的全部服务，由 main 组装后注入到路由和后台任务
+type Services struct {
+	DB      *gorm.DB
+	Storage CloudStorageProvider // 为nil时使用本地存储
+	Log     logger.Logger
+
+	Users       *UserService
+	Notes       *NoteService
+	Tasks       *TaskService
+	Bookmarks   *BookmarkService
+	Events      *EventService
+	Collections *CollectionService
+	Posts       *PostService
+	Files       *FileService
+	Themes      *ThemeService
+	Chat        *ChatService
+	Shares      *ShareService
+	Audit       *AuditService
+	Export      *ExportService
+	Health      *HealthService
+	Tags        *TagService
+	Search      *SearchService
+	Templates   *NoteTemplateService
+	Notebooks   *NotebookService
+	NoteImport  *NoteImportService
+}
+
+// NewServices 用给定的数据库连接、存储和日志创建全部服务
+func NewServices(db *gorm.DB, storage CloudStorageProvider, log logger.Logger) *Services {
+	files := NewFileService(db, storage, log)
+	notes := NewNoteService(db, log)
+	tasks := NewTaskService(db)
+	events := NewEventService(db)
+	return &Services{
+		DB:      db,
+		Storage: storage,
+		Log:     log,
+
+		Users:       NewUserService(db),
+		Notes:       notes,
+		Tasks:       tasks,
+		Bookmarks:   NewBookmarkService(db),
+		Events:      events,
+		Collections: NewCollectionService(db),
+		Posts:       NewPostService(db),
+		Files:       files,
+		Themes:      NewThemeService(db),
+		Chat:        NewChatService(db),
+		Shares:      NewShareService(db),
+		Audit:       NewAuditService(db, log),
+		Export:      NewExportService(db, files, log),
+		Health:      NewHealthService(db, storage, log),
+		Tags:        NewTagService(db, log),
+		Search:      NewSearchService(db, log),
+		Templates:   NewNoteTemplateService(db, notes, tasks, events),
+		Notebooks:   NewNotebookService(db),
+		NoteImport:  NewNoteImportService(db, files, log),
+	}
+}
+
+// Transaction 在一个数据库事务中执行fn，传给fn的服务全部绑定到该事务，
+// fn返回错误或panic时回滚
+func (s *Services) Transaction(fn func(tx *Services) error) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(NewServices(tx, s.Storage, s.Log))
+	})
+}
@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/markdown"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func TestTransactionSpansServices(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	failed := errors.New("rollback")
+
+	err := services.Transaction(func(tx *service.Services) error {
+		if err := tx.Notes.Create(&model.Note{UserID: 1, Title: "draft"}); err != nil {
+			return err
+		}
+		if err := tx.Tasks.Create(&model.Task{UserID: 1, Title: "follow up"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if notes, _ := services.Notes.GetAll(1, nil); len(notes) != 0 {
+		t.Errorf("note should be rolled back: %+v", notes)
+	}
+	if tasks, _ := services.Tasks.GetAll(1, nil); len(tasks) != 0 {
+		t.Errorf("task should be rolled back: %+v", tasks)
+	}
+
+	err = services.Transaction(func(tx *service.Services) error {
+		if err := tx.Notes.Create(&model.Note{UserID: 1, Title: "kept"}); err != nil {
+			return err
+		}
+		return tx.Tasks.Create(&model.Task{UserID: 1, Title: "kept"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notes, _ := services.Notes.GetAll(1, nil); len(notes) != 1 {
+		t.Errorf("expected committed note, got %+v", notes)
+	}
+}
+
+func TestBaseServiceUpdateAndFilters(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	note := model.Note{UserID: 1, Title: "pinned", IsPinned: true}
+	mustCreate(t, services.DB, &note)
+
+	// 零值字段同样写入，未登记的列(user_id)不会被修改
+	if err := services.Notes.Update(note.ID, 1, &model.Note{UserID: 2, Title: "renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := services.Notes.GetByID(note.ID, 1)
+	if updated.Title != "renamed" || updated.IsPinned || updated.UserID != 1 {
+		t.Errorf("unexpected note after update: %+v", updated)
+	}
+
+	if err := services.Notes.Update(note.ID, 2, &model.Note{Title: "stolen"}); !errors.Is(err, common.ErrResourceNotFound) {
+		t.Errorf("other users must not update the note, got %v", err)
+	}
+	if err := services.Notes.Delete(note.ID, 2); !errors.Is(err, common.ErrResourceNotFound) {
+		t.Errorf("other users must not delete the note, got %v", err)
+	}
+
+	mustCreate(t, services.DB, &model.Event{UserID: 1, Title: "a", Date: "2024-01-10"})
+	mustCreate(t, services.DB, &model.Event{UserID: 1, Title: "b", Date: "2024-02-10"})
+	events, err := services.Events.GetAll(1, map[string]string{"start_date": "2024-02-01", "ignored": "x"})
+	if err != nil || len(events) != 1 || events[0].Title != "b" {
+		t.Errorf("date filter: %v %+v", err, events)
+	}
+}
+
+func TestPostExcerptFromRenderedContent(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	post := model.Post{UserID: 1, Title: "p", Content: "# Heading\n\nSome **bold** and [a link](https://example.com).\n\n```go\nfunc main() {}\n```"}
+	if err := services.Posts.Create(&post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Excerpt != "Some bold and a link." {
+		t.Errorf("excerpt = %q", post.Excerpt)
+	}
+
+	if err := services.Posts.Update(post.ID, 1, &model.Post{Title: "p", Content: "- [x] done", Version: post.Version}); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := services.Posts.GetByID(post.ID, 1)
+	if updated.Excerpt != "done" {
+		t.Errorf("excerpt after update = %q", updated.Excerpt)
+	}
+	if html := markdown.Render(updated.Content).HTML; !strings.Contains(html, `<input checked="" disabled="" type="checkbox"/>`) {
+		t.Errorf("content_html = %q", html)
+	}
+}
+
+// recordingLogger 记录所有日志消息
+type recordingLogger struct{ lines *[]string }
+
+func (l recordingLogger) record(msg string, args ...any) {
+	*l.lines = append(*l.lines, fmt.Sprint(append([]any{msg}, args...)...))
+}
+func (l recordingLogger) Debug(msg string, args ...any) { l.record(msg, args...) }
+func (l recordingLogger) Info(msg string, args ...any)  { l.record(msg, args...) }
+func (l recordingLogger) Warn(msg string, args ...any)  { l.record(msg, args...) }
+func (l recordingLogger) Error(msg string, args ...any) { l.record(msg, args...) }
+
+func TestWithLoggerRoutesServiceLogs(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+	var lines []string
+	log := recordingLogger{&lines}
+
+	tag := model.Tag{UserID: 1, Name: "old", NameKey: "old"}
+	if err := services.DB.Create(&tag).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.Tags.WithLogger(log).Rename(1, tag.ID, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || !strings.Contains(lines[0], `Renamed tag`) {
+		t.Fatalf("service log should go to the request logger: %q", lines)
+	}
+
+	// 原服务不受影响
+	if _, err := services.Tags.Rename(1, tag.ID, "newer"); err != nil || len(lines) != 1 {
+		t.Fatalf("WithLogger must not change the shared service: %q %v", lines, err)
+	}
+}
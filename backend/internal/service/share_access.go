@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/model"
+)
+
+// ShareAccessTTL 带密码的公开链接解锁后访问凭证的有效期
+const ShareAccessTTL = time.Hour
+
+// shareAccessSignature 计算访问凭证的签名，包含密码哈希，链接重新设置密码后旧凭证失效
+func shareAccessSignature(share *model.Share, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWT.Secret))
+	mac.Write([]byte("share-access:" + share.Token + ":" + strconv.FormatInt(expires, 10) + ":" + share.Password))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// UnlockLink 校验公开链接的密码，返回短期有效的访问凭证。浏览器直接打开链接时无法携带
+// X-Share-Password 请求头，解锁后凭其访问
+func (s *ShareService) UnlockLink(token, password string, now time.Time) (access string, expiresAt time.Time, err error) {
+	share, _, err := s.ResolveLink(token, password)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt = now.Add(ShareAccessTTL)
+	if share.ExpiresAt != nil && share.ExpiresAt.Before(expiresAt) {
+		expiresAt = *share.ExpiresAt
+	}
+	expires := expiresAt.Unix()
+	return strconv.FormatInt(expires, 10) + "." + shareAccessSignature(share, expires), expiresAt, nil
+}
+
+// ResolveLinkWithAccess 同 ResolveLink，以 UnlockLink 返回的访问凭证代替密码。
+// 凭证无效或已过期时返回 common.ErrSharePasswordRequired，需要重新解锁
+func (s *ShareService) ResolveLinkWithAccess(token, access string) (*model.Share, interface{}, error) {
+	return s.resolveLink(token, func(share *model.Share) error {
+		expiresStr, signature, _ := strings.Cut(access, ".")
+		expires, err := strconv.ParseInt(expiresStr, 10, 64)
+		if err != nil || time.Now().Unix() > expires ||
+			!hmac.Equal([]byte(signature), []byte(shareAccessSignature(share, expires))) {
+			return common.ErrSharePasswordRequired
+		}
+		return nil
+	})
+}
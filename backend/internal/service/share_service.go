@@ -0,0 +1,214 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type ShareService struct {
+	db *gorm.DB
+}
+
+func NewShareService(db *gorm.DB) *ShareService {
+	return &ShareService{db: db}
+}
+
+// newResourceModel 根据资源类型返回对应的模型实例
+func newResourceModel(resourceType string) (interface{}, error) {
+	switch resourceType {
+	case constants.ResourceNote:
+		return &model.Note{}, nil
+	case constants.ResourceFile:
+		return &model.File{}, nil
+	case constants.ResourceCollection:
+		return &model.Collection{}, nil
+	case constants.ResourcePost:
+		return &model.Post{}, nil
+	default:
+		return nil, common.ErrInvalidResourceType
+	}
+}
+
+// AccessibleScope 限定查询范围为用户自己的记录以及分享给该用户的记录，
+// needEdit为true时只包含以edit权限分享的记录
+func AccessibleScope(userID uint, resourceType string, needEdit bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		// 子查询与条件分组基于同一连接(或事务)新建语句，避免继承外层查询的条件
+		conn := db.Session(&gorm.Session{NewDB: true})
+		shared := conn.Model(&model.Share{}).
+			Select("resource_id").
+			Where("resource_type = ? AND shared_with_id = ?", resourceType, userID).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now())
+		if needEdit {
+			shared = shared.Where("permission = ?", constants.SharePermissionEdit)
+		}
+		return db.Where(conn.Where("user_id = ?", userID).Or("id IN (?)", shared))
+	}
+}
+
+// ensureOwner 确认资源存在且属于ownerID
+func (s *ShareService) ensureOwner(resourceType string, resourceID, ownerID uint) error {
+	m, err := newResourceModel(resourceType)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := s.db.Model(m).Where("id = ? AND user_id = ?", resourceID, ownerID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return common.ErrResourceNotFound
+	}
+	return nil
+}
+
+// ShareWithUser 将资源分享给另一个用户，重复分享时更新权限
+func (s *ShareService) ShareWithUser(ownerID uint, resourceType string, resourceID uint, username, permission string) (*model.Share, error) {
+	if err := s.ensureOwner(resourceType, resourceID, ownerID); err != nil {
+		return nil, err
+	}
+
+	var target model.User
+	if err := s.db.Where("username = ?", username).First(&target).Error; err != nil {
+		return nil, common.ErrUserNotFound
+	}
+	if target.ID == ownerID {
+		return nil, common.ErrShareWithSelf
+	}
+	if permission == "" {
+		permission = constants.SharePermissionRead
+	}
+
+	var share model.Share
+	err := s.db.Where("owner_id = ? AND resource_type = ? AND resource_id = ? AND shared_with_id = ?",
+		ownerID, resourceType, resourceID, target.ID).First(&share).Error
+	if err == nil {
+		share.Permission = permission
+		return &share, s.db.Save(&share).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	share = model.Share{
+		OwnerID:      ownerID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		SharedWithID: target.ID,
+		Permission:   permission,
+	}
+	return &share, s.db.Create(&share).Error
+}
+
+// CreateLink 创建公开分享链接(只读)，可选密码和过期时间
+func (s *ShareService) CreateLink(ownerID uint, resourceType string, resourceID uint, password string, expiresAt *time.Time) (*model.Share, error) {
+	if err := s.ensureOwner(resourceType, resourceID, ownerID); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateSecureToken(24)
+	if err != nil {
+		return nil, err
+	}
+
+	share := model.Share{
+		OwnerID:      ownerID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Permission:   constants.SharePermissionRead,
+		Token:        token,
+		ExpiresAt:    expiresAt,
+	}
+	if password != "" {
+		hashed, err := utils.HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		share.Password = hashed
+		share.HasPassword = true
+	}
+
+	return &share, s.db.Create(&share).Error
+}
+
+// ListOwned 获取用户创建的所有分享
+func (s *ShareService) ListOwned(ownerID uint) ([]model.Share, error) {
+	var shares []model.Share
+	err := s.db.Where("owner_id = ?", ownerID).Order("created_at DESC").Find(&shares).Error
+	for i := range shares {
+		shares[i].HasPassword = shares[i].Password != ""
+	}
+	return shares, err
+}
+
+// ListReceived 获取分享给用户的所有记录
+func (s *ShareService) ListReceived(userID uint) ([]model.Share, error) {
+	var shares []model.Share
+	err := s.db.Where("shared_with_id = ?", userID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("created_at DESC").Find(&shares).Error
+	return shares, err
+}
+
+// Revoke 撤销分享
+func (s *ShareService) Revoke(id, ownerID uint) error {
+	result := s.db.Where("id = ? AND owner_id = ?", id, ownerID).Delete(&model.Share{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return common.ErrResourceNotFound
+	}
+	return nil
+}
+
+// ResolveLink 校验公开链接令牌、过期时间与密码，返回分享记录及其资源
+func (s *ShareService) ResolveLink(token, password string) (*model.Share, interface{}, error) {
+	return s.resolveLink(token, func(share *model.Share) error {
+		if password == "" {
+			return common.ErrSharePasswordRequired
+		}
+		if ok, err := utils.VerifyPassword(password, share.Password); err != nil || !ok {
+			return common.ErrSharePasswordInvalid
+		}
+		return nil
+	})
+}
+
+// resolveLink 校验公开链接令牌和过期时间，带密码的链接由 verify 校验访问者的凭证
+func (s *ShareService) resolveLink(token string, verify func(share *model.Share) error) (*model.Share, interface{}, error) {
+	if token == "" {
+		return nil, nil, common.ErrResourceNotFound
+	}
+
+	var share model.Share
+	if err := s.db.Where("token = ? AND shared_with_id = 0", token).First(&share).Error; err != nil {
+		return nil, nil, common.ErrResourceNotFound
+	}
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		return nil, nil, common.ErrShareExpired
+	}
+	if share.Password != "" {
+		share.HasPassword = true
+		if err := verify(&share); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resource, err := newResourceModel(share.ResourceType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.db.Where("id = ? AND user_id = ?", share.ResourceID, share.OwnerID).First(resource).Error; err != nil {
+		return nil, nil, common.ErrResourceNotFound
+	}
+
+	return &share, resource, nil
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/constants"
+	"nexushub-personal/internal/logger"
+	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// maxTagLength 单个标签名的最大字符数，超出部分截断
+const maxTagLength = 50
+
+// TaggedResources 支持标签的资源类型
+var TaggedResources = []string{
+	constants.ResourceNote,
+	constants.ResourceFile,
+	constants.ResourcePost,
+	constants.ResourceBookmark,
+	constants.ResourceCollection,
+}
+
+type TagService struct {
+	db  *gorm.DB
+	log logger.Logger
+}
+
+func NewTagService(db *gorm.DB, log logger.Logger) *TagService {
+	return &TagService{db: db, log: log}
+}
+
+// WithLogger 返回使用 log 记录日志的副本，handler 传入请求日志使服务中的日志带上请求ID
+func (s *TagService) WithLogger(log logger.Logger) *TagService {
+	scoped := *s
+	scoped.log = log
+	return &scoped
+}
+
+// TagUsage 标签及其在各类资源上的使用次数
+type TagUsage struct {
+	ID     uint           `json:"id"`
+	Name   string         `json:"name"`
+	Total  int64          `json:"total"`
+	Counts map[string]int `json:"counts"` // 资源类型 -> 使用次数
+}
+
+// isTagSeparator 标签分隔符：英文/中文逗号和分号
+func isTagSeparator(r rune) bool {
+	return r == ',' || r == '，' || r == ';' || r == '；'
+}
+
+// TagKey 返回用于比较标签名的规范化形式：忽略大小写和多余空白
+func TagKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ParseTags 解析逗号分隔的标签字符串，去掉空白和重复(忽略大小写)的标签，保持原有顺序
+func ParseTags(tags string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.FieldsFunc(tags, isTagSeparator) {
+		name := strings.Join(strings.Fields(part), " ")
+		if utf8.RuneCountInString(name) > maxTagLength {
+			name = strings.TrimSpace(string([]rune(name)[:maxTagLength]))
+		}
+		key := TagKey(name)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// taggedModel 根据资源类型返回对应的模型实例
+func taggedModel(resourceType string) (interface{}, error) {
+	switch resourceType {
+	case constants.ResourceNote:
+		return &model.Note{}, nil
+	case constants.ResourceFile:
+		return &model.File{}, nil
+	case constants.ResourcePost:
+		return &model.Post{}, nil
+	case constants.ResourceBookmark:
+		return &model.Bookmark{}, nil
+	case constants.ResourceCollection:
+		return &model.Collection{}, nil
+	default:
+		return nil, common.ErrInvalidResourceType
+	}
+}
+
+// syncResourceTags 按资源的 Tags 字段重建其标签关联：不存在的标签自动创建，
+// 已存在的标签沿用其名称的大小写；规范化后的标签字符串写回资源并返回
+func syncResourceTags(tx *gorm.DB, resourceType string, resourceID uint) (string, error) {
+	m, err := taggedModel(resourceType)
+	if err != nil {
+		return "", err
+	}
+	var row struct {
+		UserID uint
+		Tags   string
+	}
+	if err := tx.Model(m).Select("user_id, tags").Where("id = ?", resourceID).Take(&row).Error; err != nil {
+		return "", err
+	}
+
+	names := ParseTags(row.Tags)
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = TagKey(name)
+	}
+	byKey := make(map[string]model.Tag, len(names))
+	if len(keys) > 0 {
+		var existing []model.Tag
+		if err := tx.Where("user_id = ? AND name_key IN ?", row.UserID, keys).Find(&existing).Error; err != nil {
+			return "", err
+		}
+		for _, tag := range existing {
+			byKey[tag.NameKey] = tag
+		}
+	}
+
+	tagIDs := make([]uint, 0, len(names))
+	canonical := make([]string, 0, len(names))
+	for i, name := range names {
+		tag, ok := byKey[keys[i]]
+		if !ok {
+			tag = model.Tag{UserID: row.UserID, Name: name, NameKey: keys[i]}
+			if err := tx.Create(&tag).Error; err != nil {
+				return "", err
+			}
+		}
+		tagIDs = append(tagIDs, tag.ID)
+		canonical = append(canonical, tag.Name)
+	}
+
+	var oldIDs []uint
+	if err := tx.Model(&model.Tagging{}).
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Pluck("tag_id", &oldIDs).Error; err != nil {
+		return "", err
+	}
+	if err := removeTaggings(tx, resourceType, resourceID); err != nil {
+		return "", err
+	}
+	if len(tagIDs) > 0 {
+		taggings := make([]model.Tagging, len(tagIDs))
+		for i, id := range tagIDs {
+			taggings[i] = model.Tagging{TagID: id, ResourceType: resourceType, ResourceID: resourceID}
+		}
+		if err := tx.Create(&taggings).Error; err != nil {
+			return "", err
+		}
+	}
+	if err := deleteUnusedTags(tx, oldIDs); err != nil {
+		return "", err
+	}
+
+	tags := strings.Join(canonical, ",")
+	if tags != row.Tags {
+		if err := tx.Model(m).Where("id = ?", resourceID).UpdateColumn("tags", tags).Error; err != nil {
+			return "", err
+		}
+	}
+	return tags, nil
+}
+
+// syncEntityTags 同步实体的标签关联，并把规范化后的标签字符串写回实体
+func syncEntityTags(tx *gorm.DB, resourceType string, id uint, entity model.Tagged) error {
+	tags, err := syncResourceTags(tx, resourceType, id)
+	if err != nil {
+		return err
+	}
+	entity.SetTags(tags)
+	return nil
+}
+
+// syncResourceTagsByID 同步多条记录的标签关联，用于导入等直接写入记录的场景
+func syncResourceTagsByID(tx *gorm.DB, resourceType string, ids []uint) error {
+	for _, id := range ids {
+		if _, err := syncResourceTags(tx, resourceType, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildTaggings 按全部资源的标签字符串重建标签关联
+func rebuildTaggings(tx *gorm.DB) error {
+	for _, resourceType := range TaggedResources {
+		m, err := taggedModel(resourceType)
+		if err != nil {
+			return err
+		}
+		var ids []uint
+		if err := tx.Model(m).Where("tags <> ''").Order("id").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err := syncResourceTagsByID(tx, resourceType, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeTaggings 删除资源的全部标签关联
+func removeTaggings(tx *gorm.DB, resourceType string, resourceID uint) error {
+	return tx.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Delete(&model.Tagging{}).Error
+}
+
+// removeResourceTags 资源被删除后移除其标签关联，不再被使用的标签一并删除
+func removeResourceTags(tx *gorm.DB, resourceType string, resourceID uint) error {
+	var tagIDs []uint
+	if err := tx.Model(&model.Tagging{}).
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Pluck("tag_id", &tagIDs).Error; err != nil {
+		return err
+	}
+	if err := removeTaggings(tx, resourceType, resourceID); err != nil {
+		return err
+	}
+	return deleteUnusedTags(tx, tagIDs)
+}
+
+// deleteUnusedTags 删除给定标签中已没有任何关联的标签
+func deleteUnusedTags(tx *gorm.DB, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	used := tx.Model(&model.Tagging{}).Select("tag_id")
+	return tx.Where("id IN ? AND id NOT IN (?)", tagIDs, used).Delete(&model.Tag{}).Error
+}
+
+// TaggedScope 限定查询为带有指定标签(忽略大小写)的资源，标签为空时不做限制
+func TaggedScope(resourceType, tag string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		key := TagKey(tag)
+		if key == "" {
+			return db
+		}
+		ids := db.Session(&gorm.Session{NewDB: true}).
+			Model(&model.Tagging{}).
+			Select("taggings.resource_id").
+			Joins("JOIN tags ON tags.id = taggings.tag_id").
+			Where("taggings.resource_type = ? AND tags.name_key = ?", resourceType, key)
+		return db.Where("id IN (?)", ids)
+	}
+}
+
+// List 返回用户的全部标签及其在各类资源上的使用次数，按使用次数降序
+func (s *TagService) List(userID uint) ([]TagUsage, error) {
+	var tags []model.Tag
+	if err := s.db.Where("user_id = ?", userID).Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		TagID        uint
+		ResourceType string
+		Count        int
+	}
+	err := s.db.Model(&model.Tagging{}).
+		Select("taggings.tag_id, taggings.resource_type, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = taggings.tag_id").
+		Where("tags.user_id = ?", userID).
+		Group("taggings.tag_id, taggings.resource_type").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make([]TagUsage, len(tags))
+	index := make(map[uint]int, len(tags))
+	for i, tag := range tags {
+		usage[i] = TagUsage{ID: tag.ID, Name: tag.Name, Counts: map[string]int{}}
+		index[tag.ID] = i
+	}
+	for _, c := range counts {
+		if i, ok := index[c.TagID]; ok {
+			usage[i].Counts[c.ResourceType] = c.Count
+			usage[i].Total += int64(c.Count)
+		}
+	}
+	sort.SliceStable(usage, func(i, j int) bool { return usage[i].Total > usage[j].Total })
+	return usage, nil
+}
+
+// Rename 重命名标签，并改写所有使用该标签的资源。新名称与用户已有的另一个标签相同(忽略大小写)时
+// 合并到那个标签。返回重命名或合并后的标签
+func (s *TagService) Rename(userID, tagID uint, name string) (*model.Tag, error) {
+	names := ParseTags(name)
+	if len(names) != 1 {
+		return nil, fmt.Errorf("%w: tag name must be a single non-empty tag", common.ErrInvalidInput)
+	}
+	name = names[0]
+
+	var result model.Tag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		tag, err := findTag(tx, userID, tagID)
+		if err != nil {
+			return err
+		}
+
+		var other model.Tag
+		err = tx.Where("user_id = ? AND name_key = ? AND id <> ?", userID, TagKey(name), tagID).First(&other).Error
+		switch {
+		case err == nil:
+			if err := mergeTags(tx, &other, []model.Tag{*tag}); err != nil {
+				return err
+			}
+			result = other
+			return nil
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		oldKey := tag.NameKey
+		tag.Name, tag.NameKey = name, TagKey(name)
+		if err := tx.Save(tag).Error; err != nil {
+			return err
+		}
+		if err := retagResources(tx, []uint{tag.ID}, map[string]bool{oldKey: true}, tag.Name); err != nil {
+			return err
+		}
+		result = *tag
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("Renamed tag", "tag_id", tagID, "name", result.Name, "user_id", userID)
+	return &result, nil
+}
+
+// Merge 把 sourceIDs 中的标签合并到 targetID：使用这些标签的资源改为使用目标标签，随后删除这些标签
+func (s *TagService) Merge(userID, targetID uint, sourceIDs []uint) (*model.Tag, error) {
+	var target *model.Tag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		target, err = findTag(tx, userID, targetID)
+		if err != nil {
+			return err
+		}
+		var sources []model.Tag
+		for _, id := range sourceIDs {
+			if id == targetID {
+				continue
+			}
+			source, err := findTag(tx, userID, id)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, *source)
+		}
+		return mergeTags(tx, target, sources)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("Merged tags", "source_ids", sourceIDs, "target_id", targetID, "user_id", userID)
+	return target, nil
+}
+
+// findTag 查找用户自己的标签
+func findTag(tx *gorm.DB, userID, tagID uint) (*model.Tag, error) {
+	var tag model.Tag
+	err := tx.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, common.ErrTagNotFound
+	}
+	return &tag, err
+}
+
+// mergeTags 把使用 sources 的资源改为使用 target，resync 后 sources 不再被使用而被删除
+func mergeTags(tx *gorm.DB, target *model.Tag, sources []model.Tag) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(sources))
+	keys := make(map[string]bool, len(sources))
+	for i, source := range sources {
+		ids[i] = source.ID
+		keys[source.NameKey] = true
+	}
+	if err := retagResources(tx, ids, keys, target.Name); err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(&model.Tag{}).Error
+}
+
+// retagResources 在使用 tagIDs 中任一标签的资源上，把名称属于 fromKeys 的标签替换为 to，
+// 然后重建这些资源的标签关联和搜索索引
+func retagResources(tx *gorm.DB, tagIDs []uint, fromKeys map[string]bool, to string) error {
+	var taggings []model.Tagging
+	if err := tx.Where("tag_id IN ?", tagIDs).Order("resource_type, resource_id").Find(&taggings).Error; err != nil {
+		return err
+	}
+	for _, t := range taggings {
+		m, err := taggedModel(t.ResourceType)
+		if err != nil {
+			return err
+		}
+		var tags string
+		if err := tx.Model(m).Select("tags").Where("id = ?", t.ResourceID).Scan(&tags).Error; err != nil {
+			return err
+		}
+		names := ParseTags(tags)
+		for i, name := range names {
+			if fromKeys[TagKey(name)] {
+				names[i] = to
+			}
+		}
+		if err := tx.Model(m).Where("id = ?", t.ResourceID).UpdateColumn("tags", strings.Join(names, ",")).Error; err != nil {
+			return err
+		}
+		if _, err := syncResourceTags(tx, t.ResourceType, t.ResourceID); err != nil {
+			return err
+		}
+		if err := indexResource(tx, t.ResourceType, t.ResourceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func TestParseTags(t *testing.T) {
+	got := service.ParseTags(" Go ,go；web  dev,, Web Dev;读书，")
+	want := []string{"Go", "web dev", "读书"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseTags = %q, want %q", got, want)
+	}
+}
+
+func TestTagsSyncFilterRenameMerge(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	note := model.Note{UserID: 1, Title: "n", Tags: "Go, work"}
+	if err := services.Notes.Create(&note); err != nil {
+		t.Fatal(err)
+	}
+	// 已有标签沿用原来的大小写
+	bookmark := model.Bookmark{UserID: 1, Title: "b", URL: "https://example.com", Tags: "go;golang"}
+	if err := services.Bookmarks.Create(&bookmark); err != nil {
+		t.Fatal(err)
+	}
+	if bookmark.Tags != "Go,golang" {
+		t.Fatalf("bookmark tags = %q", bookmark.Tags)
+	}
+	post := model.Post{UserID: 1, Title: "p", Content: "x", Tags: "work"}
+	if err := services.Posts.Create(&post); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := func() map[string]service.TagUsage {
+		t.Helper()
+		tags, err := services.Tags.List(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := make(map[string]service.TagUsage)
+		for _, tag := range tags {
+			byName[tag.Name] = tag
+		}
+		return byName
+	}
+	tags := counts()
+	if len(tags) != 3 || tags["Go"].Total != 2 || tags["Go"].Counts["note"] != 1 || tags["Go"].Counts["bookmark"] != 1 {
+		t.Fatalf("unexpected usage: %+v", tags)
+	}
+
+	notes, err := services.Notes.GetAll(1, map[string]string{"tag": "GO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].ID != note.ID {
+		t.Fatalf("tag filter returned %+v", notes)
+	}
+	if posts, _ := services.Posts.GetAll(1, map[string]string{"tag": "go"}); len(posts) != 0 {
+		t.Fatalf("post should not match tag go: %+v", posts)
+	}
+
+	// 改名为已有标签时合并
+	renamed, err := services.Tags.Rename(1, tags["golang"].ID, "GO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.ID != tags["Go"].ID {
+		t.Fatalf("rename onto existing tag should merge, got %+v", renamed)
+	}
+	var stored model.Bookmark
+	services.DB.First(&stored, bookmark.ID)
+	if stored.Tags != "Go" {
+		t.Fatalf("bookmark tags after merge = %q", stored.Tags)
+	}
+
+	if _, err := services.Tags.Rename(1, tags["work"].ID, "Job"); err != nil {
+		t.Fatal(err)
+	}
+	var storedNote model.Note
+	services.DB.First(&storedNote, note.ID)
+	if storedNote.Tags != "Go,Job" {
+		t.Fatalf("note tags after rename = %q", storedNote.Tags)
+	}
+
+	if _, err := services.Tags.Merge(1, tags["Go"].ID, []uint{tags["work"].ID}); err != nil {
+		t.Fatal(err)
+	}
+	tags = counts()
+	if len(tags) != 1 || tags["Go"].Total != 3 || tags["Go"].Counts["post"] != 1 {
+		t.Fatalf("unexpected usage after merge: %+v", tags)
+	}
+
+	// 其他用户不能修改该标签
+	if _, err := services.Tags.Rename(2, tags["Go"].ID, "x"); !errors.Is(err, common.ErrTagNotFound) {
+		t.Fatalf("expected ErrTagNotFound, got %v", err)
+	}
+
+	// 删除资源后不再使用的标签被清理
+	for _, del := range []func() error{
+		func() error { return services.Notes.Delete(note.ID, 1) },
+		func() error { return services.Bookmarks.Delete(bookmark.ID, 1) },
+		func() error { return services.Posts.Delete(post.ID, 1) },
+	} {
+		if err := del(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tags = counts(); len(tags) != 0 {
+		t.Fatalf("tags should be removed with their last resource: %+v", tags)
+	}
+}
@@ -1,36 +1,31 @@
 package service
 
 import (
-	"nexushub-personal/internal/database"
+	"nexushub-personal/internal/constants"
 	"nexushub-personal/internal/model"
+
+	"gorm.io/gorm"
 )
 
-type TaskService struct{}
+type TaskService struct {
+	*BaseService[model.Task]
+}
 
-func NewTaskService() *TaskService {
-	return &TaskService{}
+func NewTaskService(db *gorm.DB) *TaskService {
+	return &TaskService{
+		BaseService: NewBaseService[model.Task](db, CRUDOptions{
+			Resource: constants.ResourceTask,
+			Order:    "priority DESC, created_at DESC",
+			Fields:   []string{"title", "description", "status", "priority", "category", "due_date"},
+		}),
+	}
 }
 
-func (s *TaskService) GetAll(userID uint) ([]model.Task, error) {
+// Open 返回用户未完成的任务，有截止日期的按日期先后排在前面
+func (s *TaskService) Open(userID uint) ([]model.Task, error) {
 	var tasks []model.Task
-	err := database.DB.Where("user_id = ?", userID).Order("priority DESC, created_at DESC").Find(&tasks).Error
+	err := s.db.Where("user_id = ? AND status <> ?", userID, constants.TaskStatusCompleted).
+		Order("due_date IS NULL, due_date ASC, created_at ASC").
+		Find(&tasks).Error
 	return tasks, err
 }
-
-func (s *TaskService) GetByID(id, userID uint) (*model.Task, error) {
-	var task model.Task
-	err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&task).Error
-	return &task, err
-}
-
-func (s *TaskService) Create(task *model.Task) error {
-	return database.DB.Create(task).Error
-}
-
-func (s *TaskService) Update(task *model.Task) error {
-	return database.DB.Save(task).Error
-}
-
-func (s *TaskService) Delete(id, userID uint) error {
-	return database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Task{}).Error
-}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type UserService struct {
+	db *gorm.DB
+}
+
+func NewUserService(db *gorm.DB) *UserService {
+	return &UserService{db: db}
+}
+
+// OIDCIdentity 外部身份提供方返回的用户信息
+type OIDCIdentity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+// GetByID 根据ID获取用户
+func (s *UserService) GetByID(id uint) (*model.User, error) {
+	var user model.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername 根据用户名获取用户
+func (s *UserService) GetByUsername(username string) (*model.User, error) {
+	var user model.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Register 创建密码登录的用户，用户名或邮箱已存在时返回 ErrUserAlreadyExists
+func (s *UserService) Register(username, email, password string) (*model.User, error) {
+	var count int64
+	if err := s.db.Model(&model.User{}).
+		Where("username = ? OR email = ?", username, email).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, common.ErrUserAlreadyExists
+	}
+
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := model.User{
+		Username: username,
+		Password: hashed,
+		Email:    email,
+		Nickname: username,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]+`)
+
+// FindOrCreateOIDCUser 按签发方+subject查找用户，找不到则即时创建新用户。
+// 邮箱已被本地账号使用时拒绝登录，不自动关联：本地账号的邮箱未经验证，
+// 否则攻击者可以预先用受害者的邮箱注册来接管其OIDC登录。已有账号需登录后通过 LinkOIDCIdentity 关联
+func (s *UserService) FindOrCreateOIDCUser(identity OIDCIdentity) (*model.User, error) {
+	var user model.User
+	err := s.db.Where("oidc_issuer = ? AND oidc_subject = ?", identity.Issuer, identity.Subject).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, fmt.Errorf("%w: identity provider did not return an email", common.ErrInvalidInput)
+	}
+
+	var count int64
+	if err := s.db.Model(&model.User{}).Where("email = ?", identity.Email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w: an account with email %s already exists, sign in and link the identity provider from that account",
+			common.ErrUserAlreadyExists, identity.Email)
+	}
+
+	username, err := s.uniqueUsername(identity)
+	if err != nil {
+		return nil, err
+	}
+	nickname := identity.Name
+	if nickname == "" {
+		nickname = username
+	}
+
+	user = model.User{
+		Username:    username,
+		Email:       identity.Email,
+		Nickname:    nickname,
+		OIDCIssuer:  identity.Issuer,
+		OIDCSubject: identity.Subject,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkOIDCIdentity 把OIDC身份关联到已登录的用户，之后可以用该身份登录。
+// 身份已关联到其他用户时返回 common.ErrDuplicateEntry
+func (s *UserService) LinkOIDCIdentity(userID uint, identity OIDCIdentity) (*model.User, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, common.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	var count int64
+	err := s.db.Model(&model.User{}).
+		Where("oidc_issuer = ? AND oidc_subject = ? AND id <> ?", identity.Issuer, identity.Subject, userID).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w: identity is already linked to another account", common.ErrDuplicateEntry)
+	}
+
+	user.OIDCIssuer = identity.Issuer
+	user.OIDCSubject = identity.Subject
+	if err := s.db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// uniqueUsername 根据preferred_username或邮箱前缀生成不重复的用户名
+func (s *UserService) uniqueUsername(identity OIDCIdentity) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "_")
+	if len(base) < 3 {
+		base = "user_" + base
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 2; i < 100; i++ {
+		var count int64
+		if err := s.db.Unscoped().Model(&model.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", common.ErrUserAlreadyExists
+}
@@ -0,0 +1,54 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"nexushub-personal/internal/common"
+	"nexushub-personal/internal/model"
+	"nexushub-personal/internal/service"
+	"nexushub-personal/internal/testutil"
+)
+
+func TestOIDCLoginDoesNotTakeOverLocalAccountByEmail(t *testing.T) {
+	services := testutil.SetupServices(t, nil)
+
+	// 攻击者预先用受害者的邮箱注册本地账号
+	squatter := model.User{Username: "squatter", Password: "x", Email: "victim@example.com"}
+	mustCreate(t, services.DB, &squatter)
+
+	identity := service.OIDCIdentity{
+		Issuer: "https://idp.example.com", Subject: "victim", Email: "victim@example.com", EmailVerified: true,
+	}
+	if _, err := services.Users.FindOrCreateOIDCUser(identity); !errors.Is(err, common.ErrUserAlreadyExists) {
+		t.Fatalf("OIDC login must not be merged into an existing account by email: %v", err)
+	}
+	var linked int64
+	services.DB.Model(&model.User{}).Where("oidc_subject = ?", "victim").Count(&linked)
+	if linked != 0 {
+		t.Fatal("identity was linked to the local account")
+	}
+
+	// 已登录的用户可以显式关联，之后用该身份登录
+	owner := model.User{Username: "owner", Password: "x", Email: "owner@example.com"}
+	mustCreate(t, services.DB, &owner)
+	identity = service.OIDCIdentity{Issuer: "https://idp.example.com", Subject: "owner", Email: "owner@example.com"}
+	if _, err := services.Users.LinkOIDCIdentity(owner.ID, identity); err != nil {
+		t.Fatal(err)
+	}
+	user, err := services.Users.FindOrCreateOIDCUser(identity)
+	if err != nil || user.ID != owner.ID {
+		t.Fatalf("login with linked identity: %+v %v", user, err)
+	}
+	if _, err := services.Users.LinkOIDCIdentity(squatter.ID, identity); !errors.Is(err, common.ErrDuplicateEntry) {
+		t.Fatalf("identity linked to another account: %v", err)
+	}
+
+	// 没有本地账号使用该邮箱时创建新用户
+	user, err = services.Users.FindOrCreateOIDCUser(service.OIDCIdentity{
+		Issuer: "https://idp.example.com", Subject: "new", Email: "new@example.com", PreferredUsername: "newbie",
+	})
+	if err != nil || user.Username != "newbie" {
+		t.Fatalf("new OIDC user: %+v %v", user, err)
+	}
+}
@@ -0,0 +1,76 @@
+// Package testutil 提供测试用的公共初始化逻辑：在临时目录中创建SQLite数据库，
+// 使测试不依赖外部MySQL/PostgreSQL服务。
+package testutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"nexushub-personal/internal/config"
+	"nexushub-personal/internal/database"
+	applogger "nexushub-personal/internal/logger"
+	"nexushub-personal/internal/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewConfig 返回适用于测试的配置，数据库与存储都位于临时目录
+func NewConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+
+	return &config.Config{
+		Server: config.ServerConfig{Port: "0", GinMode: "test"},
+		DB: config.DBConfig{
+			Driver:      config.DBDriverSQLite,
+			Path:        filepath.Join(dir, "nexushub.db"),
+			AutoMigrate: true,
+		},
+		Storage: config.StorageConfig{Path: dir, MaxUploadSize: 1 << 20, MaxImportSize: 1 << 24},
+		User:    config.UserConfig{DefaultUserID: 1},
+		Auth:    config.AuthConfig{Mode: config.AuthModeSingle},
+		JWT:     config.JWTConfig{Secret: "test-secret", ExpireHours: 1},
+		Log:     config.LogConfig{Level: config.LogLevelInfo, Format: config.LogFormatText, Dir: filepath.Join(dir, "logs")},
+		CORS:    config.CORSConfig{AllowedOrigins: []string{"*"}},
+		Health:  config.HealthConfig{CheckTimeoutSeconds: 3},
+		RateLimit: config.RateLimitConfig{
+			LoginPerMinute:      1000,
+			LoginMaxFailures:    100,
+			LoginLockoutMinutes: 1,
+			APIPerMinute:        1000,
+			APIBurst:            1000,
+		},
+	}
+}
+
+// SetupDB 使用 cfg(为空时调用 NewConfig)初始化全局配置和SQLite数据库，
+// 测试结束时自动关闭连接
+func SetupDB(t *testing.T, cfg *config.Config) *gorm.DB {
+	t.Helper()
+	if cfg == nil {
+		cfg = NewConfig(t)
+	}
+	config.Set(cfg)
+
+	db, err := database.Open(cfg.DB, logger.Default.LogMode(logger.Silent))
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := database.Setup(db); err != nil {
+		t.Fatalf("setup test database: %v", err)
+	}
+	return db
+}
+
+// SetupServices 初始化测试数据库，返回注入该数据库的全部服务(本地存储，丢弃日志)
+func SetupServices(t *testing.T, cfg *config.Config) *service.Services {
+	t.Helper()
+	return service.NewServices(SetupDB(t, cfg), nil, applogger.Discard())
+}
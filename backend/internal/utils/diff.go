@@ -0,0 +1,162 @@
+package utils
+
+import "strings"
+
+// 行差异类型
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffEdits 编辑距离上限，超过后不再寻找最短编辑序列，直接把差异部分视为整段删除+插入，
+// 避免超大且差异很多的文本占用过多内存
+const maxDiffEdits = 2000
+
+// DiffLine 一行差异，OldLine/NewLine 为在旧/新文本中的行号(从1开始)，不存在时为0
+type DiffLine struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// DiffLines 按行比较两段文本，返回 Myers 算法得到的最短编辑序列
+func DiffLines(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	// 先去掉公共前缀和后缀，只对中间不同的部分求编辑序列
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]string, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+
+	lines := make([]DiffLine, 0, len(ops))
+	x, y := 0, 0
+	for _, op := range ops {
+		switch op {
+		case DiffEqual:
+			lines = append(lines, DiffLine{Op: op, Text: a[x], OldLine: x + 1, NewLine: y + 1})
+			x++
+			y++
+		case DiffDelete:
+			lines = append(lines, DiffLine{Op: op, Text: a[x], OldLine: x + 1})
+			x++
+		case DiffInsert:
+			lines = append(lines, DiffLine{Op: op, Text: b[y], NewLine: y + 1})
+			y++
+		}
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// myers 返回把a变为b的操作序列。trace[d] 保存第d步开始前对角线 -d-1..d+1 上能到达的最远x，
+// 用于回溯路径
+func myers(a, b []string) []string {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(n, m)
+	}
+
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	final := -1
+	for d := 0; d <= n+m && d <= maxDiffEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // 从 k+1 下移：插入
+			} else {
+				x = v[offset+k-1] + 1 // 从 k-1 右移：删除
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				final = d
+				break
+			}
+		}
+		if final >= 0 {
+			break
+		}
+	}
+	if final < 0 {
+		return replaceAll(n, m)
+	}
+
+	ops := make([]string, 0, n+m)
+	x, y := n, m
+	for d := final; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, DiffEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, DiffInsert)
+		} else {
+			ops = append(ops, DiffDelete)
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, DiffEqual)
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceAll 把a整体删除、b整体插入
+func replaceAll(n, m int) []string {
+	ops := make([]string, 0, n+m)
+	for i := 0; i < n; i++ {
+		ops = append(ops, DiffDelete)
+	}
+	for i := 0; i < m; i++ {
+		ops = append(ops, DiffInsert)
+	}
+	return ops
+}
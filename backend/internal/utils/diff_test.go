@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	lines := DiffLines("a\nb\nc\nd\n", "a\nc\nx\nd\n")
+	var got string
+	for _, l := range lines {
+		got += l.Op[:1] + l.Text + " "
+	}
+	if got != "ea db ec ix ed " {
+		t.Errorf("diff = %q", got)
+	}
+	if lines[3].NewLine != 3 || lines[3].OldLine != 0 || lines[4].OldLine != 4 {
+		t.Errorf("line numbers: %+v", lines)
+	}
+	if len(DiffLines("", "")) != 0 {
+		t.Error("empty texts should have no diff")
+	}
+}
+
+// lcs 动态规划求最长公共子序列长度，用于校验编辑序列最短
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				dp[i][j] = dp[i-1][j-1] + 1
+			} else {
+				dp[i][j] = max(dp[i-1][j], dp[i][j-1])
+			}
+		}
+	}
+	return dp[len(a)][len(b)]
+}
+
+// 随机文本的差异必须能还原出两边的原文，且编辑数最少
+func TestDiffLinesReconstructs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomText(), randomText()
+		var oldLines, newLines []string
+		edits := 0
+		for _, l := range DiffLines(a, b) {
+			if l.Op != DiffInsert {
+				oldLines = append(oldLines, l.Text)
+			}
+			if l.Op != DiffDelete {
+				newLines = append(newLines, l.Text)
+			}
+			if l.Op != DiffEqual {
+				edits++
+			}
+		}
+		if strings.Join(oldLines, "\n") != a || strings.Join(newLines, "\n") != b {
+			t.Fatalf("diff of %q -> %q does not reconstruct", a, b)
+		}
+		al, bl := splitLines(a), splitLines(b)
+		if want := len(al) + len(bl) - 2*lcs(al, bl); edits != want {
+			t.Fatalf("diff of %q -> %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
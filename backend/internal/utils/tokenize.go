@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTokenLength 词元的最大字节数，更长的词元(如长URL片段)不参与索引
+const MaxTokenLength = 64
+
+// isHan 判断是否为汉字(以及日文汉字等统一表意文字)
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+// Tokenize 把文本切分为用于全文检索的词元：字母和数字组成的连续片段作为一个词元并转为小写，
+// 连续的汉字按相邻两字切分(单个汉字单独成词)，其余字符作为分隔符
+func Tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	var han []rune
+
+	flushWord := func() {
+		if word.Len() > 0 && word.Len() <= MaxTokenLength {
+			tokens = append(tokens, word.String())
+		}
+		word.Reset()
+	}
+	flushHan := func() {
+		switch len(han) {
+		case 0:
+		case 1:
+			tokens = append(tokens, string(han))
+		default:
+			for i := 0; i+1 < len(han); i++ {
+				tokens = append(tokens, string(han[i:i+2]))
+			}
+		}
+		han = han[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isHan(r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// Snippet 截取文本中第一个命中 words 之一(忽略大小写)的位置附近约 width 个字符，
+// 截断处用省略号表示；没有命中时返回文本开头
+func Snippet(text string, words []string, width int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= width {
+		return string(runes)
+	}
+
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	hit := -1
+	for _, w := range words {
+		needle := []rune(strings.ToLower(w))
+		if len(needle) == 0 {
+			continue
+		}
+		if i := indexRunes(lower, needle); i >= 0 && (hit < 0 || i < hit) {
+			hit = i
+		}
+	}
+
+	start := 0
+	if hit > width/3 {
+		start = hit - width/3
+	}
+	end := min(start+width, len(runes))
+	if end-start < width {
+		start = max(0, end-width)
+	}
+
+	snippet := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// indexRunes 返回 needle 在 haystack 中第一次出现的位置，不存在时返回-1
+func indexRunes(haystack, needle []rune) int {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j, r := range needle {
+			if haystack[i+j] != r {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// TruncateRunes 把字符串截断为最多 n 个字符
+func TruncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, World! go1.22", []string{"hello", "world", "go1", "22"}},
+		{"全文搜索", []string{"全文", "文搜", "搜索"}},
+		{"学Go语言", []string{"学", "go", "语言"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := "aaaa bbbb cccc dddd Needle eeee ffff gggg hhhh"
+	if got := Snippet(text, []string{"needle"}, 16); got != "…dddd Needle eeee…" {
+		t.Errorf("Snippet = %q", got)
+	}
+	if got := Snippet("short text", []string{"x"}, 16); got != "short text" {
+		t.Errorf("Snippet = %q", got)
+	}
+	if got := Snippet(text, nil, 9); got != "aaaa bbbb…" {
+		t.Errorf("Snippet = %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// wikiLinkPattern 匹配 [[目标]]、[[目标#小标题]]、[[目标|显示文字]]
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]\n]+?)\]\]`)
+
+// WikiLink 正文中的一个 wiki 链接
+type WikiLink struct {
+	Target  string // 目标笔记标题
+	Heading string // # 之后的小标题，可为空
+	Alias   string // | 之后的显示文字，可为空
+}
+
+// splitWikiLink 把链接内部文本拆成目标标题和其后的 #小标题|别名 部分
+func splitWikiLink(inner string) (target, suffix string) {
+	if i := strings.IndexAny(inner, "#|"); i >= 0 {
+		return inner[:i], inner[i:]
+	}
+	return inner, ""
+}
+
+// ParseWikiLinks 解析正文中的全部 wiki 链接，按出现顺序返回；只有 #小标题 的页内链接会被忽略
+func ParseWikiLinks(content string) []WikiLink {
+	var links []WikiLink
+	for _, m := range wikiLinkPattern.FindAllStringSubmatch(content, -1) {
+		target, suffix := splitWikiLink(m[1])
+		link := WikiLink{Target: strings.TrimSpace(target)}
+		if link.Target == "" {
+			continue
+		}
+		if i := strings.IndexByte(suffix, '|'); i >= 0 {
+			link.Alias = strings.TrimSpace(suffix[i+1:])
+			suffix = suffix[:i]
+		}
+		link.Heading = strings.TrimSpace(strings.TrimPrefix(suffix, "#"))
+		links = append(links, link)
+	}
+	return links
+}
+
+// WikiLinkKey 返回用于匹配笔记标题的规范化形式：忽略大小写和多余空白
+func WikiLinkKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// RewriteWikiLinks 把指向 from 的链接改为指向 to，保留小标题和显示文字，返回新正文和改写的链接数
+func RewriteWikiLinks(content, from, to string) (string, int) {
+	key := WikiLinkKey(from)
+	count := 0
+	result := wikiLinkPattern.ReplaceAllStringFunc(content, func(match string) string {
+		target, suffix := splitWikiLink(match[2 : len(match)-2])
+		if WikiLinkKey(target) != key {
+			return match
+		}
+		count++
+		return "[[" + to + suffix + "]]"
+	})
+	return result, count
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestParseWikiLinks(t *testing.T) {
+	links := ParseWikiLinks("See [[Project Plan]], [[ project  plan #Goals|the goals]] and [[#local]].\n[[Inbox|]]")
+	want := []WikiLink{
+		{Target: "Project Plan"},
+		{Target: "project  plan", Heading: "Goals", Alias: "the goals"},
+		{Target: "Inbox"},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("links = %+v", links)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d = %+v, want %+v", i, links[i], want[i])
+		}
+	}
+	if WikiLinkKey(links[0].Target) != WikiLinkKey(links[1].Target) {
+		t.Error("keys should ignore case and extra spaces")
+	}
+}
+
+func TestRewriteWikiLinks(t *testing.T) {
+	got, n := RewriteWikiLinks("[[Old]] [[old#Intro|see intro]] [[Older]]", "Old", "New Name")
+	if want := "[[New Name]] [[New Name#Intro|see intro]] [[Older]]"; got != want || n != 2 {
+		t.Errorf("rewrite = %q (%d), want %q", got, n, want)
+	}
+}
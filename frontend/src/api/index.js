@@ -100,6 +100,11 @@ api.interceptors.response.use(
       responseData = data
     }
     
+    // 写操作成功后清空缓存，避免之后读到旧数据(及旧的版本号)
+    if (cacheConfig.noCacheMethods.includes(response.config.method?.toLowerCase())) {
+      requestCache.clear()
+    }
+
     // 缓存成功响应
     if (shouldCacheRequest(response.config)) {
       const cacheKey = generateCacheKey(response.config)